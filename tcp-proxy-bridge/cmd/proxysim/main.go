@@ -0,0 +1,53 @@
+// cmd/proxysim/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// main 出站代理模拟器入口
+// 在本地模拟跳板代理（SOCKS5或HTTP CONNECT），用于测试经代理连接源服务器和目标服务器
+func main() {
+	listen := flag.String("listen", ":1080", "proxy listen address")
+	proxyType := flag.String("type", config.ProxySOCKS5, "proxy type: socks5 or http")
+	username := flag.String("user", "", "required username (empty disables authentication)")
+	password := flag.String("pass", "", "required password")
+	allow := flag.String("allow", "", "comma separated host:port destinations allowed (empty allows all)")
+	flag.Parse()
+
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+
+	cfg := config.ProxyConfig{Type: *proxyType, Address: *listen, Username: *username, Password: *password}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid proxy settings: %v", err)
+	}
+
+	server, err := newProxyServer(cfg, *allow)
+	if err != nil {
+		log.Fatalf("Failed to start proxy: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		server.Serve(ctx)
+		close(done)
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutting down proxy simulator...")
+	cancel()
+	<-done
+}
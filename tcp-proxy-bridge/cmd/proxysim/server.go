@@ -0,0 +1,343 @@
+// cmd/proxysim/server.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// dialTimeout 连接目标地址的超时时间
+const dialTimeout = 10 * time.Second
+
+// SOCKS5应答码
+const (
+	socksSucceeded       = 0x00 // 成功
+	socksNotAllowed      = 0x02 // 规则不允许
+	socksHostUnreach     = 0x04 // 主机不可达
+	socksCmdUnsupported  = 0x07 // 不支持的命令
+	socksAddrUnsupported = 0x08 // 不支持的地址类型
+)
+
+// proxyServer 模拟代理服务器
+type proxyServer struct {
+	cfg      config.ProxyConfig
+	allow    map[string]bool // 允许的目标地址（为空时允许所有）
+	listener net.Listener
+
+	mu          sync.Mutex
+	connections int // 已接受的连接数
+
+	wg sync.WaitGroup
+}
+
+// newProxyServer 创建模拟代理服务器并开始监听
+// 参数: cfg - 代理配置（address为监听地址）, allow - 逗号分隔的允许目标地址
+// 返回: 模拟代理服务器和错误信息
+func newProxyServer(cfg config.ProxyConfig, allow string) (*proxyServer, error) {
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", cfg.Address, err)
+	}
+
+	s := &proxyServer{cfg: cfg, allow: make(map[string]bool), listener: listener}
+	for _, address := range strings.Split(allow, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			s.allow[address] = true
+		}
+	}
+	return s, nil
+}
+
+// Serve 接受连接直到上下文取消
+// 参数: ctx - 上下文
+func (s *proxyServer) Serve(ctx context.Context) {
+	log.Printf("[%s] Listening on %s (auth=%v)", s.cfg.Type, s.listener.Addr(), s.cfg.Username != "")
+
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				s.wg.Wait()
+				return
+			default:
+			}
+			log.Printf("[%s] Accept error: %v", s.cfg.Type, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.connections++
+		id := s.connections
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(ctx, id, conn)
+		}()
+	}
+}
+
+// handle 处理一个客户端连接：建立隧道后双向转发
+// 参数: ctx - 上下文, id - 连接编号, conn - 客户端连接
+func (s *proxyServer) handle(ctx context.Context, id int, conn net.Conn) {
+	defer conn.Close()
+
+	// 握手阶段限时，避免半开连接占用
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	var client io.Reader
+	var upstream net.Conn
+	var err error
+	switch s.cfg.Type {
+	case config.ProxyHTTP:
+		client, upstream, err = s.httpHandshake(conn)
+	default:
+		client, upstream, err = s.socks5Handshake(conn)
+	}
+	if err != nil {
+		log.Printf("[%s] #%d from %s: %v", s.cfg.Type, id, conn.RemoteAddr(), err)
+		return
+	}
+	defer upstream.Close()
+	conn.SetDeadline(time.Time{})
+
+	log.Printf("[%s] #%d %s -> %s tunnel established", s.cfg.Type, id, conn.RemoteAddr(), upstream.RemoteAddr())
+
+	// 上下文取消时关闭两端，结束转发
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+		upstream.Close()
+	})
+	defer stop()
+
+	var sent, received int64
+	done := make(chan struct{})
+	go func() {
+		received, _ = io.Copy(conn, upstream)
+		conn.Close()
+		close(done)
+	}()
+	sent, _ = io.Copy(upstream, client)
+	upstream.Close()
+	<-done
+
+	log.Printf("[%s] #%d closed: %d bytes up, %d bytes down", s.cfg.Type, id, sent, received)
+}
+
+// dial 连接目标地址
+// 参数: address - 目标地址
+// 返回: 连接和错误信息（目标地址不在允许列表中时返回errNotAllowed）
+func (s *proxyServer) dial(address string) (net.Conn, error) {
+	if len(s.allow) > 0 && !s.allow[address] {
+		return nil, errNotAllowed
+	}
+	return net.DialTimeout("tcp", address, dialTimeout)
+}
+
+// errNotAllowed 目标地址不在允许列表中
+var errNotAllowed = errors.New("destination not allowed")
+
+// socks5Handshake 处理SOCKS5认证和CONNECT请求
+// 参数: conn - 客户端连接
+// 返回: 客户端数据读取器、到目标地址的连接和错误信息
+func (s *proxyServer) socks5Handshake(conn net.Conn) (io.Reader, net.Conn, error) {
+	br := bufio.NewReader(conn)
+
+	// 认证方式协商
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		return nil, nil, fmt.Errorf("read greeting: %v", err)
+	}
+	if head[0] != 0x05 {
+		return nil, nil, fmt.Errorf("unsupported socks version %d", head[0])
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return nil, nil, fmt.Errorf("read greeting: %v", err)
+	}
+
+	want := byte(0x00)
+	if s.cfg.Username != "" {
+		want = 0x02
+	}
+	if bytes.IndexByte(methods, want) < 0 {
+		conn.Write([]byte{0x05, 0xFF})
+		return nil, nil, fmt.Errorf("no acceptable authentication method in %v", methods)
+	}
+	if _, err := conn.Write([]byte{0x05, want}); err != nil {
+		return nil, nil, err
+	}
+
+	if want == 0x02 {
+		username, password, err := readSocksCredentials(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		if username != s.cfg.Username || password != s.cfg.Password {
+			conn.Write([]byte{0x01, 0x01})
+			return nil, nil, fmt.Errorf("authentication failed for user '%s'", username)
+		}
+		if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// CONNECT请求
+	var req [4]byte
+	if _, err := io.ReadFull(br, req[:]); err != nil {
+		return nil, nil, fmt.Errorf("read request: %v", err)
+	}
+	host, err := readSocksAddr(br, req[3])
+	if err != nil {
+		socksReply(conn, socksAddrUnsupported, nil)
+		return nil, nil, err
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return nil, nil, fmt.Errorf("read request: %v", err)
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+	if req[1] != 0x01 {
+		socksReply(conn, socksCmdUnsupported, nil)
+		return nil, nil, fmt.Errorf("unsupported command %d", req[1])
+	}
+
+	upstream, err := s.dial(address)
+	if err != nil {
+		code := byte(socksHostUnreach)
+		if err == errNotAllowed {
+			code = socksNotAllowed
+		}
+		socksReply(conn, code, nil)
+		return nil, nil, fmt.Errorf("connect %s: %v", address, err)
+	}
+	if err := socksReply(conn, socksSucceeded, upstream.LocalAddr()); err != nil {
+		upstream.Close()
+		return nil, nil, err
+	}
+	return br, upstream, nil
+}
+
+// readSocksCredentials 读取SOCKS5用户名/密码认证请求
+func readSocksCredentials(br *bufio.Reader) (string, string, error) {
+	var fields [2]string
+	if _, err := br.ReadByte(); err != nil {
+		return "", "", fmt.Errorf("read credentials: %v", err)
+	}
+	for i := range fields {
+		n, err := br.ReadByte()
+		if err != nil {
+			return "", "", fmt.Errorf("read credentials: %v", err)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return "", "", fmt.Errorf("read credentials: %v", err)
+		}
+		fields[i] = string(buf)
+	}
+	return fields[0], fields[1], nil
+}
+
+// readSocksAddr 读取SOCKS5请求中的目标主机
+func readSocksAddr(br *bufio.Reader, addrType byte) (string, error) {
+	switch addrType {
+	case 0x01, 0x04:
+		size := net.IPv4len
+		if addrType == 0x04 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(br, ip); err != nil {
+			return "", fmt.Errorf("read address: %v", err)
+		}
+		return net.IP(ip).String(), nil
+	case 0x03:
+		n, err := br.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("read address: %v", err)
+		}
+		host := make([]byte, n)
+		if _, err := io.ReadFull(br, host); err != nil {
+			return "", fmt.Errorf("read address: %v", err)
+		}
+		return string(host), nil
+	default:
+		return "", fmt.Errorf("unsupported address type %d", addrType)
+	}
+}
+
+// socksReply 发送SOCKS5应答
+func socksReply(conn net.Conn, code byte, bound net.Addr) error {
+	reply := []byte{0x05, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0}
+	if tcpAddr, ok := bound.(*net.TCPAddr); ok {
+		if ip4 := tcpAddr.IP.To4(); ip4 != nil {
+			copy(reply[4:8], ip4)
+		}
+		binary.BigEndian.PutUint16(reply[8:10], uint16(tcpAddr.Port))
+	}
+	_, err := conn.Write(reply)
+	return err
+}
+
+// httpHandshake 处理HTTP CONNECT请求
+// 参数: conn - 客户端连接
+// 返回: 客户端数据读取器、到目标地址的连接和错误信息
+func (s *proxyServer) httpHandshake(conn net.Conn) (io.Reader, net.Conn, error) {
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read request: %v", err)
+	}
+
+	if req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 0\r\n\r\n")
+		return nil, nil, fmt.Errorf("unsupported method %s", req.Method)
+	}
+
+	if s.cfg.Username != "" {
+		expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(s.cfg.Username+":"+s.cfg.Password))
+		if req.Header.Get("Proxy-Authorization") != expected {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n"+
+				"Proxy-Authenticate: Basic realm=\"proxysim\"\r\nContent-Length: 0\r\n\r\n")
+			return nil, nil, fmt.Errorf("authentication failed for CONNECT %s", req.Host)
+		}
+	}
+
+	upstream, err := s.dial(req.Host)
+	if err == errNotAllowed {
+		io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n")
+		return nil, nil, fmt.Errorf("connect %s: %v", req.Host, err)
+	}
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return nil, nil, fmt.Errorf("connect %s: %v", req.Host, err)
+	}
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		upstream.Close()
+		return nil, nil, err
+	}
+	return br, upstream, nil
+}
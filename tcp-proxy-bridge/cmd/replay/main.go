@@ -0,0 +1,295 @@
+// cmd/replay/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"tcp-proxy-bridge/internal/capture"
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/database"
+	"tcp-proxy-bridge/internal/ingest"
+	"tcp-proxy-bridge/internal/pipeline"
+	"tcp-proxy-bridge/internal/source"
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// replayOptions 重放参数
+type replayOptions struct {
+	speed  float64   // 重放速度倍数，0表示不等待
+	stream string    // 只重放指定源服务器
+	from   time.Time // 起始时间（零值不限制）
+	to     time.Time // 结束时间（零值不限制）
+}
+
+// replayStats 重放统计
+type replayStats struct {
+	records   int       // 重放的记录数
+	bytes     int       // 重放的数据字节数
+	skipped   int       // 跳过的记录数（方向、流或时间不符）
+	delivered int       // 交给数据处理函数的数据包数
+	at        time.Time // 当前记录的抓包时间
+}
+
+// main 抓包重放入口
+// 把抓包文件中从源服务器读取的原始数据重新送入分帧、协议解析、去重、包序号检查和数据段校验流程，
+// 默认把得到的数据包以JSON行输出到标准输出，-ingest时写入数据库
+func main() {
+	defaultConfig := os.Getenv("CONFIG_FILE")
+	if defaultConfig == "" {
+		defaultConfig = "configs/config.yaml"
+	}
+
+	configPath := flag.String("config", defaultConfig, "bridge configuration file")
+	speed := flag.Float64("speed", 0, "replay speed relative to capture time (0 replays as fast as possible, 1 is original speed)")
+	stream := flag.String("stream", "", "only replay the given source server ID")
+	from := flag.String("from", "", "skip records before this time (RFC3339)")
+	to := flag.String("to", "", "stop at records after this time (RFC3339)")
+	ingestMode := flag.Bool("ingest", false, "write replayed packages to the database through the ingest queue")
+	verbose := flag.Bool("v", false, "keep bridge processing logs on stderr")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <capture file or dir>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *speed < 0 {
+		log.Fatalf("speed cannot be negative")
+	}
+
+	opts := replayOptions{speed: *speed, stream: *stream}
+	var err error
+	if opts.from, err = parseTime(*from); err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	if opts.to, err = parseTime(*to); err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration from %s: %v", *configPath, err)
+	}
+	if err := cfg.ValidateFraming(); err != nil {
+		log.Fatalf("Framing configuration validation failed: %v", err)
+	}
+	if err := cfg.ValidateProtocol(); err != nil {
+		log.Fatalf("Protocol configuration validation failed: %v", err)
+	}
+	if err := cfg.ValidatePayload(); err != nil {
+		log.Fatalf("Payload configuration validation failed: %v", err)
+	}
+	layouts, err := xftype.LoadLayoutFiles(cfg.Payload.Layouts)
+	if err != nil {
+		log.Fatalf("Failed to load payload layouts: %v", err)
+	}
+
+	files, err := capture.ListFiles(flag.Args())
+	if err != nil {
+		log.Fatalf("Failed to list capture files: %v", err)
+	}
+
+	// 处理流程的日志默认不输出，避免与结果混在一起
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	var stats replayStats
+	var processing *pipeline.Pipeline
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *ingestMode {
+		if err := cfg.ValidateIngest(); err != nil {
+			logger.Fatalf("Ingest configuration validation failed: %v", err)
+		}
+		db, err := database.NewPostgres(cfg.Database)
+		if err != nil {
+			logger.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+
+		if err := cfg.ValidatePipeline(); err != nil {
+			logger.Fatalf("Pipeline configuration validation failed: %v", err)
+		}
+		ingestPipeline, err := ingest.NewPipeline(cfg.Ingest, db)
+		if err != nil {
+			logger.Fatalf("Failed to create ingest pipeline: %v", err)
+		}
+		ingestPipeline.Start(ctx)
+		defer func() {
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer stopCancel()
+			ingestPipeline.Stop(stopCtx)
+		}()
+
+		// 重放的数据与实时接收的数据经过相同的处理流水线，但不推进续传检查点
+		processing, err = pipeline.New(cfg, func(pkt *pipeline.Packet) error {
+			stats.delivered++
+			return ingestPipeline.Enqueue(&database.Message{
+				SourceIP:       pkt.Meta.SourceIP,
+				SourceIdentity: pkt.Meta.SourceIdentity,
+				SourceGroup:    pkt.Group,
+				OriginalData:   pkt.Data,
+				DataLength:     len(pkt.Data),
+				CreatedAt:      time.Now(),
+				Status:         database.StatusPending,
+				Targets:        pkt.Targets,
+				Attributes:     pkt.Attributes,
+			})
+		})
+		if err != nil {
+			logger.Fatalf("Failed to create processing pipeline: %v", err)
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+
+	// 每个源分组使用独立的源服务器管理器，按抓包记录的源服务器ID找到所属分组
+	replayers := make(map[string]*source.Replayer)
+	groups := cfg.Groups()
+	for i := range groups {
+		group := &groups[i]
+		sourceManager, err := source.NewManager(cfg.ForGroup(group))
+		if err != nil {
+			logger.Fatalf("Failed to create source server manager: %v", err)
+		}
+		if err := sourceManager.RegisterLayouts(layouts); err != nil {
+			logger.Fatalf("Failed to register payload layouts: %v", err)
+		}
+
+		var handler source.DataHandler
+		if processing != nil {
+			handler = processing.Handler(group)
+		} else {
+			registry := sourceManager.PayloadRegistry()
+			handler = func(data []byte, meta *source.PacketMeta) error {
+				stats.delivered++
+				out := packetOutput(registry, data, meta)
+				out["group"] = group.Name
+				out["captured_at"] = stats.at.Format(time.RFC3339Nano)
+				return encoder.Encode(out)
+			}
+		}
+
+		replayer := sourceManager.NewReplayer(handler)
+		for _, server := range group.SourceServers.Servers {
+			replayers[server.ID] = replayer
+		}
+	}
+
+	for _, file := range files {
+		if err := replayFile(file, replayers, opts, &stats); err != nil {
+			logger.Printf("Replay of %s stopped: %v", file, err)
+		}
+	}
+
+	logger.Printf("Replayed %d records (%d bytes) from %d files, skipped %d records, delivered %d packages",
+		stats.records, stats.bytes, len(files), stats.skipped, stats.delivered)
+}
+
+// replayFile 重放一个抓包文件
+// 参数: path - 文件路径, replayers - 源服务器ID到所属分组重放器的映射, opts - 重放参数, stats - 重放统计
+// 返回: 错误信息
+func replayFile(path string, replayers map[string]*source.Replayer, opts replayOptions, stats *replayStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := capture.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	var lastCapture, lastReplay time.Time
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// 进程异常退出时最后一条记录可能不完整
+			return err
+		}
+
+		if record.Direction != capture.DirectionIn ||
+			(opts.stream != "" && record.Stream != opts.stream) ||
+			(!opts.from.IsZero() && record.Time.Before(opts.from)) {
+			stats.skipped++
+			continue
+		}
+		if !opts.to.IsZero() && record.Time.After(opts.to) {
+			stats.skipped++
+			continue
+		}
+
+		// 按抓包时的时间间隔等待
+		if opts.speed > 0 && !lastCapture.IsZero() {
+			wait := time.Duration(float64(record.Time.Sub(lastCapture)) / opts.speed)
+			if sleep := wait - time.Since(lastReplay); sleep > 0 {
+				time.Sleep(sleep)
+			}
+		}
+		lastCapture, lastReplay = record.Time, time.Now()
+
+		replayer, exists := replayers[record.Stream]
+		if !exists {
+			return fmt.Errorf("unknown source server '%s' in capture", record.Stream)
+		}
+
+		stats.records++
+		stats.bytes += len(record.Data)
+		stats.at = record.Time
+		if err := replayer.Feed(record.Stream, record.Remote, record.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// packetOutput 构建一个数据包的输出
+// 参数: registry - 报文编解码器注册表, data - 数据内容, meta - 数据包元信息
+// 返回: 输出对象
+func packetOutput(registry *xftype.Registry, data []byte, meta *source.PacketMeta) map[string]interface{} {
+	out := map[string]interface{}{
+		"server":    meta.ServerID,
+		"source_ip": meta.SourceIP,
+		"length":    len(data),
+	}
+
+	if meta.Header == nil {
+		// 协议解析失败，输出原始数据
+		out["raw"] = xftype.HexBytes(data)
+		return out
+	}
+
+	out["source"] = meta.Header.SourceInfo
+	out["host"] = meta.Header.HostInfo
+	out["package_no"] = meta.Header.PackageNo
+	if seg, err := registry.DecodeSegment(data); err == nil {
+		out["segment"] = seg
+	} else {
+		out["raw"] = xftype.HexBytes(data)
+		out["error"] = err.Error()
+	}
+	return out
+}
+
+// parseTime 解析RFC3339时间，空字符串返回零值
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
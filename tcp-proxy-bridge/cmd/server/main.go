@@ -10,12 +10,17 @@ import (
 	"syscall"
 	"time"
 
+	"tcp-proxy-bridge/internal/capture"
 	"tcp-proxy-bridge/internal/config"
 	"tcp-proxy-bridge/internal/database"
 	"tcp-proxy-bridge/internal/forwarder"
 	"tcp-proxy-bridge/internal/health"
+	"tcp-proxy-bridge/internal/ingest"
 	"tcp-proxy-bridge/internal/metrics"
+	"tcp-proxy-bridge/internal/pipeline"
 	"tcp-proxy-bridge/internal/source"
+	"tcp-proxy-bridge/internal/tcp"
+	"tcp-proxy-bridge/internal/xftype"
 )
 
 // main 应用主入口函数
@@ -24,7 +29,7 @@ func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Println("Starting TCP Proxy Bridge...")
 
-	// 1. 加载配置文件
+	// 加载配置文件
 	configPath := os.Getenv("CONFIG_FILE")
 	if configPath == "" {
 		configPath = "configs/config.yaml"
@@ -36,37 +41,77 @@ func main() {
 	}
 	log.Println("Configuration loaded successfully")
 
-	// 2. 验证源服务器配置
-	if err := cfg.ValidateSourceServers(); err != nil {
-		log.Fatalf("Source servers configuration validation failed: %v", err)
+	// 验证入库队列和数据接入方式配置
+	if err := cfg.ValidateIngest(); err != nil {
+		log.Fatalf("Ingest configuration validation failed: %v", err)
 	}
-	log.Println("Source servers configuration validated")
+	log.Printf("Ingest configuration validated: modes=%s", cfg.Ingest.Modes)
 
-	// 3. 验证身份认证配置
-	if err := cfg.ValidateAuthentication(); err != nil {
-		log.Fatalf("Authentication configuration validation failed: %v", err)
+	// 验证源分组配置（各分组的源服务器、身份认证、心跳和分隔符配置），只在主动连接时需要
+	var groups []config.SourceGroup
+	if cfg.Ingest.ActiveEnabled() {
+		if err := cfg.ValidateSourceGroups(); err != nil {
+			log.Fatalf("Source groups configuration validation failed: %v", err)
+		}
+		groups = cfg.Groups()
+		log.Printf("Source groups configuration validated: %d groups configured", len(groups))
+	}
+
+	// 验证监听端口配置，只在被动接收时需要
+	if err := cfg.ValidateListener(); err != nil {
+		log.Fatalf("Listener configuration validation failed: %v", err)
+	}
+	if cfg.Ingest.PassiveEnabled() {
+		log.Printf("Listener configuration validated: port %d", cfg.Server.TCPListenPort)
+	}
+
+	// 验证BasePackage编解码配置
+	if err := cfg.ValidateProtocol(); err != nil {
+		log.Fatalf("Protocol configuration validation failed: %v", err)
+	}
+	log.Println("Protocol configuration validated")
+
+	// 验证包序号跟踪配置
+	if err := cfg.ValidateSequence(); err != nil {
+		log.Fatalf("Sequence configuration validation failed: %v", err)
 	}
-	log.Println("Authentication configuration validated")
+	log.Println("Sequence configuration validated")
 
-	// 4. 验证心跳配置
-	if err := cfg.ValidateHeartbeat(); err != nil {
-		log.Fatalf("Heartbeat configuration validation failed: %v", err)
+	// 验证并加载报文布局定义
+	if err := cfg.ValidatePayload(); err != nil {
+		log.Fatalf("Payload configuration validation failed: %v", err)
+	}
+	layouts, err := xftype.LoadLayoutFiles(cfg.Payload.Layouts)
+	if err != nil {
+		log.Fatalf("Failed to load payload layouts: %v", err)
 	}
-	log.Println("Heartbeat configuration validated")
+	log.Printf("Payload configuration validated: %d layouts loaded", len(layouts))
 
-	// 5. 验证分隔符配置
-	if err := cfg.ValidateDelimiter(); err != nil {
-		log.Fatalf("Delimiter configuration validation failed: %v", err)
+	// 验证分帧配置
+	if err := cfg.ValidateFraming(); err != nil {
+		log.Fatalf("Framing configuration validation failed: %v", err)
 	}
-	log.Println("Delimiter configuration validated")
+	log.Println("Framing configuration validated")
 
-	// 6. 验证目标服务器配置
+	// 验证抓包配置
+	if err := cfg.ValidateCapture(); err != nil {
+		log.Fatalf("Capture configuration validation failed: %v", err)
+	}
+	log.Println("Capture configuration validated")
+
+	// 验证处理流水线配置
+	if err := cfg.ValidatePipeline(); err != nil {
+		log.Fatalf("Pipeline configuration validation failed: %v", err)
+	}
+	log.Printf("Pipeline configuration validated: %d stages configured", len(cfg.Pipeline.Stages))
+
+	// 验证目标服务器配置
 	if err := cfg.ValidateTargetServers(); err != nil {
 		log.Fatalf("Target servers configuration validation failed: %v", err)
 	}
 	log.Printf("Target servers configuration validated: %d servers configured", len(cfg.TargetServers))
 
-	// 4. 初始化数据库连接
+	// 初始化数据库连接
 	db, err := database.NewPostgres(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -77,35 +122,140 @@ func main() {
 	}()
 	log.Println("Database connection established")
 
-	// 5. 同步目标服务器配置到数据库
+	// 同步目标服务器配置到数据库
 	synchronizer := database.NewTargetSynchronizer(db)
 	if err := synchronizer.SyncTargetServers(cfg.TargetServers); err != nil {
 		log.Fatalf("Failed to sync target servers to database: %v", err)
 	}
 	log.Println("Target servers synchronized to database")
 
-	// 6. 初始化指标系统
+	// 初始化指标系统
 	metrics.Reset()
 	log.Println("Metrics system initialized")
 
-	// 7. 从数据库获取启用的目标服务器配置
+	// 从数据库获取启用的目标服务器配置
 	targets, err := synchronizer.GetEnabledTargetServers()
 	if err != nil {
 		log.Fatalf("Failed to get enabled target servers from database: %v", err)
 	}
 	log.Printf("Loaded %d enabled target servers from database", len(targets))
 
-	// 8. 创建服务实例
-	// tcpServer := tcp.NewServer(&cfg.Server, db) // 暂时注释，使用主动连接模式
+	// 分帧方式和出站代理只在配置文件中维护，不入库
+	for _, target := range targets {
+		target.Framing = cfg.TargetFraming(target.ID)
+		target.Proxy = cfg.TargetProxy(target.ID)
+	}
+
+	// 创建入库队列，接收到的消息批量写入数据库
+	ingestPipeline, err := ingest.NewPipeline(cfg.Ingest, db)
+	if err != nil {
+		log.Fatalf("Failed to create ingest pipeline: %v", err)
+	}
+
+	// 创建处理流水线，数据包经过过滤、改写、附加属性和路由后放入入库队列，不等待数据库写入
+	processing, err := pipeline.New(cfg, func(pkt *pipeline.Packet) error {
+		// 创建消息记录，标记源分组并只投递到路由的目标服务器
+		message := &database.Message{
+			SourceIP:       pkt.Meta.SourceIP,
+			SourceIdentity: pkt.Meta.SourceIdentity,
+			SourceGroup:    pkt.Group,
+			OriginalData:   pkt.Data,
+			DataLength:     len(pkt.Data),
+			CreatedAt:      time.Now(),
+			Status:         database.StatusPending,
+			Targets:        pkt.Targets,
+			Attributes:     pkt.Attributes,
+		}
+
+		// 写入后推进续传检查点
+		if cfg.Sequence.Resume {
+			message.Checkpoint = packetCheckpoint(pkt)
+		}
+
+		if err := ingestPipeline.Enqueue(message); err != nil {
+			log.Printf("Failed to enqueue message: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create processing pipeline: %v", err)
+	}
+	if cfg.Sequence.Resume {
+		// 被过滤或去重丢弃的数据包不入库，按序确认的仍经入库队列推进检查点，
+		// 与之前入队的消息保持先后顺序
+		processing.SetCheckpointSink(func(pkt *pipeline.Packet) error {
+			checkpoint := packetCheckpoint(pkt)
+			if checkpoint == nil {
+				return nil
+			}
+			return ingestPipeline.Enqueue(&database.Message{
+				SourceIP:       pkt.Meta.SourceIP,
+				SourceGroup:    pkt.Group,
+				CreatedAt:      time.Now(),
+				Checkpoint:     checkpoint,
+				CheckpointOnly: true,
+			})
+		})
+	}
+
+	// 创建服务实例
+	// 监听端口接收的数据与主动连接的源服务器共用处理流水线和入库队列
+	var tcpServer *tcp.Server
+	if cfg.Ingest.PassiveEnabled() {
+		tcpServer = tcp.NewServer(&cfg.Server, processing.Handler(&config.SourceGroup{Name: config.ListenerSourceGroup}))
+	}
 	forwarderManager := forwarder.NewManager(&cfg.Forwarder, db, targets)
-	sourceManager := source.NewManager(cfg) // 传递完整配置
-	healthServer := health.NewMinimalServer(cfg.Server.HealthCheckPort, cfg.Server.TCPListenPort, db.DB())
+	// 每个源分组使用独立的源服务器管理器，认证、心跳和分帧配置互不影响
+	sourceManagers := make([]*source.Manager, len(groups))
+	for i := range groups {
+		group := &groups[i]
+		sourceManager, err := source.NewManager(cfg.ForGroup(group))
+		if err != nil {
+			log.Fatalf("Failed to create source server manager for group %s: %v", group.Name, err)
+		}
+		if err := sourceManager.RegisterLayouts(layouts); err != nil {
+			log.Fatalf("Failed to register payload layouts for source group %s: %v", group.Name, err)
+		}
+		if cfg.Sequence.Resume {
+			// 重连或切换后从数据库中分组的检查点请求续传
+			sourceManager.SetCheckpointStore(db.CheckpointsFor(group.Name))
+			sourceManager.SetCheckpointHandler(processing.CheckpointHandler(group))
+		}
+		sourceManagers[i] = sourceManager
+	}
+
+	// 抓包：记录源服务器连接读取的原始数据，可选记录发往目标服务器的数据
+	if cfg.Capture.Enabled {
+		recorder, err := capture.NewRecorder(cfg.Capture)
+		if err != nil {
+			log.Fatalf("Failed to create capture recorder: %v", err)
+		}
+		defer recorder.Close()
+
+		for _, sourceManager := range sourceManagers {
+			sourceManager.SetRecorder(recorder)
+		}
+		if cfg.Capture.Forwarded {
+			forwarderManager.SetRecorder(recorder)
+		}
+		log.Printf("Raw capture enabled: dir=%s, forwarded=%v", cfg.Capture.Dir, cfg.Capture.Forwarded)
+	}
+	// 只在启用监听端口时检查监听状态
+	var listener health.Listener
+	if tcpServer != nil {
+		listener = tcpServer
+	}
+	healthServer := health.NewMinimalServer(cfg.Server.HealthCheckPort, listener, db.DB())
 
-	// 9. 创建上下文和取消函数
+	// 创建上下文和取消函数
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 10. 启动健康检查服务器
+	// 启动入库队列写入器
+	ingestPipeline.Start(ctx)
+
+	// 启动健康检查服务器
 	go func() {
 		log.Printf("Starting health server on port %d", cfg.Server.HealthCheckPort)
 		if err := healthServer.Start(); err != nil && err != http.ErrServerClosed {
@@ -113,52 +263,25 @@ func main() {
 		}
 	}()
 
-	// 11. 启动源服务器管理器（主动连接模式）
-	go func() {
-		log.Println("Starting source server manager in active connection mode...")
-		if err := sourceManager.Start(ctx); err != nil {
-			log.Fatalf("Source server manager failed to start: %v", err)
-		}
-
-		// 设置数据处理回调，将数据存入数据库
-		sourceManager.SetDataHandler(func(data []byte) error {
-			// 创建消息记录
-			message := &database.Message{
-				OriginalData: data,
-				CreatedAt:    time.Now(),
-				Status:       database.StatusPending,
-			}
-
-			// 存入数据库
-			if err := db.SaveMessage(message); err != nil {
-				log.Printf("Failed to save message to database: %v", err)
-				return err
+	// 启动各源分组的源服务器管理器（主动连接模式）
+	for i := range groups {
+		group, sourceManager := groups[i], sourceManagers[i]
+		go func() {
+			log.Printf("Starting source server manager for group %s in active connection mode...", group.Name)
+			if err := sourceManager.Start(ctx); err != nil {
+				log.Fatalf("Source server manager for group %s failed to start: %v", group.Name, err)
 			}
 
-			log.Printf("Saved message to database: ID=%d, Size=%d bytes", message.ID, len(data))
-			return nil
-		})
-
-		// 开始主动连接源服务器
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					if err := sourceManager.ConnectToSource(ctx, func(data []byte) error {
-						// 数据处理回调已在上面设置
-						return nil
-					}); err != nil {
-						log.Printf("Source connection error: %v", err)
-						time.Sleep(5 * time.Second) // 等待5秒后重试
-					}
-				}
+			// 启动重连监督循环：失败后按指数退避加抖动重连，连续失败的服务器熔断
+			// 并发模式下同时连接所有启用的源服务器，跨源去重后合并数据流
+			// 接收到的数据包带上分组名称和分组的目标服务器进入处理流水线
+			if err := sourceManager.Run(ctx, processing.Handler(&group)); err != nil {
+				log.Printf("Source connection supervisor for group %s stopped: %v", group.Name, err)
 			}
 		}()
-	}()
+	}
 
-	// 12. 启动转发器管理器
+	// 启动转发器管理器
 	go func() {
 		log.Println("Starting forwarder manager...")
 		if err := forwarderManager.Start(ctx); err != nil {
@@ -166,16 +289,17 @@ func main() {
 		}
 	}()
 
-	// 13. 启动TCP服务器（暂时注释，使用主动连接模式）
-	// go func() {
-	// 	log.Printf("Starting TCP server on port %d", cfg.Server.TCPListenPort)
-	// 	if err := tcpServer.Start(ctx); err != nil {
-	// 		log.Fatalf("TCP server failed: %v", err)
-	// 	}
-	// }()
-	log.Println("TCP server startup skipped - using active connection mode to source servers")
+	// 启动TCP服务器（被动接收模式）
+	if tcpServer != nil {
+		go func() {
+			log.Printf("Starting TCP server on port %d", cfg.Server.TCPListenPort)
+			if err := tcpServer.Start(ctx); err != nil {
+				log.Fatalf("TCP server failed: %v", err)
+			}
+		}()
+	}
 
-	// 14. 启动指标日志记录
+	// 启动指标日志记录
 	go func() {
 		ticker := time.NewTicker(10 * time.Minute)
 		defer ticker.Stop()
@@ -190,7 +314,7 @@ func main() {
 		}
 	}()
 
-	// 15. 等待服务启动完成
+	// 等待服务启动完成
 	time.Sleep(2 * time.Second)
 
 	// 检查初始健康状态
@@ -202,33 +326,48 @@ func main() {
 
 	log.Println("TCP Proxy Bridge is now running")
 
-	// 16. 设置信号处理，实现优雅关闭
+	// 设置信号处理，实现优雅关闭
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	// 等待终止信号
+	// 等待终止信号，SIGHUP重新加载监听端口访问控制配置
 	sig := <-sigChan
+	for sig == syscall.SIGHUP {
+		reloadAccess(configPath, tcpServer)
+		sig = <-sigChan
+	}
 	log.Printf("Received signal: %v, initiating shutdown...", sig)
 
-	// 17. 优雅关闭
+	// 优雅关闭
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
 	// 记录关闭前的指标
 	metrics.LogMetrics()
+	if len(cfg.Pipeline.Stages) > 0 {
+		log.Printf("Processing pipeline status: %v", processing.GetStatus())
+	}
 
-	// 停止TCP服务器（暂时注释，使用主动连接模式）
-	// log.Println("Stopping TCP server...")
-	// tcpServer.Stop(shutdownCtx)
-	log.Println("TCP server stop skipped - using active connection mode")
+	// 停止TCP服务器
+	if tcpServer != nil {
+		log.Printf("TCP server status: %v", tcpServer.GetStatus())
+		log.Println("Stopping TCP server...")
+		tcpServer.Stop(shutdownCtx)
+	}
 
 	// 停止转发器管理器
 	log.Println("Stopping forwarder manager...")
 	forwarderManager.Stop(shutdownCtx)
 
-	// 停止源服务器管理器
-	log.Println("Stopping source server manager...")
-	sourceManager.Stop(shutdownCtx)
+	// 停止各源分组的源服务器管理器
+	for i, sourceManager := range sourceManagers {
+		log.Printf("Stopping source server manager for group %s...", groups[i].Name)
+		sourceManager.Stop(shutdownCtx)
+	}
+
+	// 停止入库队列，写入剩余消息（需在关闭数据库连接之前）
+	log.Println("Stopping ingest pipeline...")
+	ingestPipeline.Stop(shutdownCtx)
 
 	// 停止健康检查服务器
 	log.Println("Stopping health server...")
@@ -236,3 +375,43 @@ func main() {
 
 	log.Println("TCP Proxy Bridge shutdown completed successfully")
 }
+
+// packetCheckpoint 获取数据包写入后推进的续传检查点
+// 参数: pkt - 数据包
+// 返回: 续传检查点（数据包不推进检查点时为nil）
+func packetCheckpoint(pkt *pipeline.Packet) *database.SourceCheckpoint {
+	meta := pkt.Meta
+	if !meta.Checkpoint || meta.Header == nil {
+		return nil
+	}
+	return &database.SourceCheckpoint{
+		SourceGroup: pkt.Group,
+		SourceInfo:  meta.Header.SourceInfo,
+		HostInfo:    meta.Header.HostInfo,
+		PackageNo:   meta.Header.PackageNo,
+	}
+}
+
+// reloadAccess 重新读取配置文件中的监听端口访问控制配置并替换生效中的规则
+// 参数: configPath - 配置文件路径, tcpServer - TCP服务器（未启用监听端口时为nil）
+func reloadAccess(configPath string, tcpServer *tcp.Server) {
+	if tcpServer == nil {
+		log.Println("Received SIGHUP, listener is not enabled, nothing to reload")
+		return
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Printf("Failed to reload access list from %s, keeping previous rules: %v", configPath, err)
+		return
+	}
+	if err := cfg.Server.Access.Validate(); err != nil {
+		log.Printf("Invalid access list in %s, keeping previous rules: %v", configPath, err)
+		return
+	}
+	if err := tcpServer.ReloadAccess(cfg.Server.Access); err != nil {
+		log.Printf("Failed to reload access list, keeping previous rules: %v", err)
+		return
+	}
+	log.Printf("Received SIGHUP, listener access list reloaded from %s", configPath)
+}
@@ -0,0 +1,74 @@
+// cmd/sourcesim/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// main 源服务器模拟器入口
+// 模拟上游源服务器：校验XFType100认证、应答XFType203心跳、按速率发送BasePackage，
+// 并按场景脚本注入断连、半开连接、垃圾字节、拆包/粘包、序号缺口和认证拒绝等故障
+func main() {
+	scenarioPath := flag.String("config", "", "scenario file (YAML); when set, the other flags are ignored")
+	primary := flag.String("primary", ":9001", "primary source listen address")
+	backup := flag.String("backup", ":9002", "backup source listen address (empty to disable)")
+	token := flag.String("token", "", "expected auth token (empty accepts any token)")
+	rate := flag.Float64("rate", 10, "packages per second per connection")
+	flag.Parse()
+
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+
+	scenario := &Scenario{Token: *token, Rate: *rate}
+	if *scenarioPath != "" {
+		loaded, err := LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Failed to load scenario from %s: %v", *scenarioPath, err)
+		}
+		scenario = loaded
+	} else {
+		scenario.Servers = append(scenario.Servers, ServerScenario{Name: "primary", Listen: *primary})
+		if *backup != "" {
+			scenario.Servers = append(scenario.Servers, ServerScenario{Name: "backup", Listen: *backup})
+		}
+	}
+
+	scenario.normalize()
+	if err := scenario.Validate(); err != nil {
+		log.Fatalf("Invalid scenario: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	servers := make([]*simServer, 0, len(scenario.Servers))
+	for _, cfg := range scenario.Servers {
+		server, err := newSimServer(scenario, cfg)
+		if err != nil {
+			log.Fatalf("Failed to start %s: %v", cfg.Name, err)
+		}
+		servers = append(servers, server)
+	}
+
+	done := make(chan struct{})
+	for _, server := range servers {
+		go func(s *simServer) {
+			s.Serve(ctx)
+			done <- struct{}{}
+		}(server)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutting down source simulator...")
+	cancel()
+	for range servers {
+		<-done
+	}
+}
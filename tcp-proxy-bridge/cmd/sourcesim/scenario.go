@@ -0,0 +1,156 @@
+// cmd/sourcesim/scenario.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// Scenario 模拟场景配置
+type Scenario struct {
+	Token      string                `yaml:"token"`       // 期望的认证令牌（与桥接服务authentication.token一致），为空时接受任意令牌
+	SourceInfo uint32                `yaml:"source_info"` // 数据包信源 (默认1)
+	HostInfo   uint32                `yaml:"host_info"`   // 数据包信宿 (默认0x14)
+	StartNo    uint64                `yaml:"start_no"`    // 起始包序号 (默认1)
+	Rate       float64               `yaml:"rate"`        // 每秒发送的数据包数，0表示不发送 (默认10)
+	Framing    config.FramingConfig  `yaml:"framing"`     // 分帧方式 (默认分隔符7878787888888888)
+	Protocol   config.ProtocolConfig `yaml:"protocol"`    // BasePackage校验和配置
+	Servers    []ServerScenario      `yaml:"servers"`     // 模拟的源服务器
+}
+
+// ServerScenario 单个模拟源服务器
+type ServerScenario struct {
+	Name   string  `yaml:"name"`   // 名称（日志使用）
+	Listen string  `yaml:"listen"` // 监听地址
+	Rate   float64 `yaml:"rate"`   // 覆盖场景的发送速率，0表示使用场景配置
+	Faults []Fault `yaml:"faults"` // 故障脚本
+}
+
+// Fault 故障脚本中的一步
+// 按after（认证通过后经过的时间）或at_packet（本连接发送的第N个数据包）触发，每个连接最多触发一次
+type Fault struct {
+	Action     string        `yaml:"action"`     // 故障类型，见fault*常量
+	Connection int           `yaml:"connection"` // 只对第N个连接生效（从1开始），0表示每个连接
+	After      time.Duration `yaml:"after"`      // 认证通过后多久触发
+	AtPacket   uint64        `yaml:"at_packet"`  // 发送第N个数据包前触发，优先于after
+	Duration   time.Duration `yaml:"duration"`   // half_open持续时间，0表示直到对端关闭
+	Count      int           `yaml:"count"`      // gap跳过的包数 / garbage字节数 / split、coalesce影响的帧数
+	Status     uint8         `yaml:"status"`     // reject_auth应答的状态码 (默认0即认证失败，1为认证通过)
+}
+
+// 故障类型常量定义
+const (
+	faultDisconnect = "disconnect"  // 立即关闭连接
+	faultHalfOpen   = "half_open"   // 连接保持打开但不再读写（模拟对端失联）
+	faultGarbage    = "garbage"     // 发送随机字节
+	faultSplit      = "split"       // 接下来的帧拆成多次小写入
+	faultCoalesce   = "coalesce"    // 接下来的帧合并为一次写入
+	faultGap        = "gap"         // 跳过若干包序号
+	faultRejectAuth = "reject_auth" // 拒绝认证（在认证时生效，忽略after/at_packet）
+)
+
+// defaultDelimiter 默认分隔符，与桥接服务delimiter.separator默认值一致
+const defaultDelimiter = "7878787888888888"
+
+// LoadScenario 从文件加载模拟场景
+// 参数: path - 场景文件路径
+// 返回: 场景配置和错误信息
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// normalize 补全默认配置
+func (s *Scenario) normalize() {
+	if s.SourceInfo == 0 {
+		s.SourceInfo = 1
+	}
+	if s.HostInfo == 0 {
+		s.HostInfo = 0x14
+	}
+	if s.StartNo == 0 {
+		s.StartNo = 1
+	}
+	if s.Framing.Type == "" {
+		s.Framing.Type = config.FramingDelimiter
+		s.Framing.Delimiter = defaultDelimiter
+	}
+	if s.Protocol.Checksum == "" {
+		s.Protocol.Checksum = config.ChecksumNone
+	}
+	if s.Protocol.ChecksumPlacement == "" {
+		switch s.Protocol.Checksum {
+		case config.ChecksumCRC16:
+			s.Protocol.ChecksumPlacement = config.ChecksumReserved
+		case config.ChecksumCRC32:
+			s.Protocol.ChecksumPlacement = config.ChecksumTrailer
+		}
+	}
+
+	for i := range s.Servers {
+		if s.Servers[i].Name == "" {
+			s.Servers[i].Name = s.Servers[i].Listen
+		}
+		if s.Servers[i].Rate == 0 {
+			s.Servers[i].Rate = s.Rate
+		}
+		for j := range s.Servers[i].Faults {
+			fault := &s.Servers[i].Faults[j]
+			if fault.Count == 0 {
+				switch fault.Action {
+				case faultGap:
+					fault.Count = 5
+				case faultGarbage:
+					fault.Count = 64
+				case faultSplit, faultCoalesce:
+					fault.Count = 10
+				}
+			}
+		}
+	}
+}
+
+// Validate 验证模拟场景
+// 返回: 验证错误信息
+func (s *Scenario) Validate() error {
+	if len(s.Servers) == 0 {
+		return fmt.Errorf("at least one server is required")
+	}
+	if err := s.Framing.Validate(); err != nil {
+		return err
+	}
+	if s.Rate < 0 {
+		return fmt.Errorf("rate cannot be negative")
+	}
+
+	for _, server := range s.Servers {
+		if server.Listen == "" {
+			return fmt.Errorf("server %s: listen address is required", server.Name)
+		}
+		for i, fault := range server.Faults {
+			switch fault.Action {
+			case faultDisconnect, faultHalfOpen, faultGarbage, faultSplit, faultCoalesce, faultGap, faultRejectAuth:
+			default:
+				return fmt.Errorf("server %s: fault #%d has unsupported action '%s'", server.Name, i, fault.Action)
+			}
+			if fault.Connection < 0 {
+				return fmt.Errorf("server %s: fault #%d connection cannot be negative", server.Name, i)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,573 @@
+// cmd/sourcesim/server.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tcp-proxy-bridge/internal/framing"
+	"tcp-proxy-bridge/internal/source"
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// simServer 模拟源服务器
+// 同一服务器的所有连接共享包序号，断开重连后从上次的位置继续发送（收到续传请求时从请求的位置发送）
+type simServer struct {
+	cfg      ServerScenario
+	scenario *Scenario
+	codec    *source.PackageCodec
+	registry *xftype.Registry
+	listener net.Listener
+
+	mu          sync.Mutex
+	nextNo      uint64 // 下一个发送的包序号
+	connections int    // 已接受的连接数
+
+	wg sync.WaitGroup
+}
+
+// newSimServer 创建模拟源服务器并开始监听
+// 参数: scenario - 模拟场景, cfg - 服务器配置
+// 返回: 模拟源服务器和错误信息
+func newSimServer(scenario *Scenario, cfg ServerScenario) (*simServer, error) {
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", cfg.Listen, err)
+	}
+
+	return &simServer{
+		cfg:      cfg,
+		scenario: scenario,
+		codec:    source.NewPackageCodec(scenario.Protocol),
+		registry: xftype.NewRegistry(),
+		listener: listener,
+		nextNo:   scenario.StartNo,
+	}, nil
+}
+
+// Serve 接受连接直到上下文取消
+// 参数: ctx - 上下文
+func (s *simServer) Serve(ctx context.Context) {
+	log.Printf("[%s] Listening on %s", s.cfg.Name, s.listener.Addr())
+
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				s.wg.Wait()
+				return
+			default:
+			}
+			log.Printf("[%s] Accept error: %v", s.cfg.Name, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.connections++
+		index := s.connections
+		s.mu.Unlock()
+
+		framer, err := framing.New(s.scenario.Framing)
+		if err != nil {
+			// 分帧配置已在启动时校验
+			log.Printf("[%s] Failed to create framer: %v", s.cfg.Name, err)
+			conn.Close()
+			continue
+		}
+
+		sess := &simSession{
+			srv:    s,
+			conn:   conn,
+			index:  index,
+			framer: framer,
+			authed: make(chan struct{}),
+			closed: make(chan struct{}),
+			fired:  make([]bool, len(s.cfg.Faults)),
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sess.run(ctx)
+		}()
+	}
+}
+
+// takeNo 取出下一个包序号
+// 返回: 包序号
+func (s *simServer) takeNo() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	no := s.nextNo
+	s.nextNo++
+	return no
+}
+
+// skip 跳过若干包序号
+// 参数: n - 跳过的数量
+func (s *simServer) skip(n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextNo += n
+}
+
+// rewind 从指定包序号重新发送（续传请求）
+// 参数: from - 起始包序号
+func (s *simServer) rewind(from uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextNo = from
+}
+
+// buildPackage 生成数据包
+// 数据内容为XFType153短报文，内容由包序号决定，重传时生成相同的数据
+// 参数: no - 包序号
+// 返回: 序列化后的数据包和错误信息
+func (s *simServer) buildPackage(no uint64) ([]byte, error) {
+	content := fmt.Sprintf("sourcesim %s #%d", s.cfg.Name, no)
+	now := time.Now()
+	seg := &xftype.DataSegment{
+		Year:  uint16(now.Year()),
+		Month: uint8(now.Month()),
+		Day:   uint8(now.Day()),
+		Items: []xftype.DataItem{{
+			Type: xftype.TypeShortMessage,
+			Body: &xftype.XFType153{
+				InfoNo:  uint32(no),
+				Sender:  uint64(s.scenario.SourceInfo),
+				Length:  uint16(len(content)),
+				Content: xftype.HexBytes(content),
+			},
+		}},
+	}
+	return s.buildSegmentPackage(no, seg)
+}
+
+// buildSegmentPackage 把数据段封装为BasePackage
+// 参数: no - 包序号, seg - 数据段
+// 返回: 序列化后的数据包和错误信息
+func (s *simServer) buildSegmentPackage(no uint64, seg *xftype.DataSegment) ([]byte, error) {
+	data, err := s.registry.EncodeSegment(seg)
+	if err != nil {
+		return nil, err
+	}
+	return s.codec.Marshal(&source.BasePackage{
+		SourceInfo:      s.scenario.SourceInfo,
+		HostInfo:        s.scenario.HostInfo,
+		PackageNo:       no,
+		CurrentDataItem: uint16(len(seg.Items)),
+		DataSumLength:   uint32(len(data)),
+		Data:            data,
+	})
+}
+
+// expectedToken 按桥接服务的规则把配置的令牌转换为32字节
+// 返回: 令牌字节，未配置令牌时返回nil（接受任意令牌）
+func (s *simServer) expectedToken() []byte {
+	token := s.scenario.Token
+	if token == "" {
+		return nil
+	}
+	if len(token) == 64 {
+		if decoded, err := hex.DecodeString(token); err == nil {
+			return decoded
+		}
+	}
+	raw := make([]byte, 32)
+	copy(raw, token)
+	return raw
+}
+
+// simSession 模拟源服务器上的一个连接
+type simSession struct {
+	srv    *simServer
+	conn   net.Conn
+	index  int // 连接序号（从1开始）
+	framer framing.Framer
+
+	writeMu sync.Mutex
+
+	authOnce   sync.Once
+	authed     chan struct{} // 认证通过时关闭
+	authedAt   time.Time
+	closeOnce  sync.Once
+	closed     chan struct{} // 连接关闭时关闭
+	halfOpen   atomic.Bool   // 半开状态：不再读写
+	sent       uint64        // 本连接已发送的数据包数（仅发送循环使用）
+	fired      []bool        // 故障是否已触发（仅发送循环使用）
+	splitLeft  int           // 剩余需要拆分写入的帧数
+	coalesce   [][]byte      // 等待合并写入的帧
+	coalesceTo int           // 合并写入的帧数
+}
+
+// run 处理连接直到连接关闭或上下文取消
+// 参数: ctx - 上下文
+func (ss *simSession) run(ctx context.Context) {
+	name := ss.srv.cfg.Name
+	log.Printf("[%s] Connection #%d from %s", name, ss.index, ss.conn.RemoteAddr())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-ss.closed:
+		}
+		ss.close()
+	}()
+
+	go ss.stream()
+
+	buffer := make([]byte, 4096)
+	for {
+		n, err := ss.conn.Read(buffer)
+		if err != nil {
+			log.Printf("[%s] Connection #%d closed: %v", name, ss.index, err)
+			ss.close()
+			return
+		}
+		if ss.halfOpen.Load() {
+			// 半开状态下读到的数据直接丢弃，不做任何应答
+			continue
+		}
+
+		frames, err := ss.framer.Decode(buffer[:n])
+		if err != nil {
+			log.Printf("[%s] Frame error on connection #%d: %v", name, ss.index, err)
+		}
+		for _, frame := range frames {
+			if err := ss.handleFrame(frame); err != nil {
+				log.Printf("[%s] Connection #%d: %v", name, ss.index, err)
+				ss.close()
+				return
+			}
+		}
+	}
+}
+
+// close 关闭连接
+func (ss *simSession) close() {
+	ss.closeOnce.Do(func() {
+		close(ss.closed)
+		ss.conn.Close()
+	})
+}
+
+// handleFrame 处理桥接服务发来的一帧
+// 参数: frame - 帧数据
+// 返回: 需要断开连接时返回错误
+func (ss *simSession) handleFrame(frame []byte) error {
+	// 连接保活使用的裸心跳，原样应答
+	if bytes.Equal(frame, source.HeartbeatPacket) {
+		return ss.writeFrame(source.HeartbeatPacket)
+	}
+
+	pkg, err := ss.srv.codec.Unmarshal(frame)
+	if err != nil {
+		log.Printf("[%s] Ignored invalid package: %v", ss.srv.cfg.Name, err)
+		return nil
+	}
+
+	switch pkg.RetransmissionFlag {
+	case source.RetransmissionRequestFlag:
+		return ss.handleRetransmission(pkg)
+	case source.ResumeRequestFlag:
+		return ss.handleResume(pkg)
+	}
+
+	if len(pkg.Data) < 8 {
+		return nil
+	}
+	itemType := binary.BigEndian.Uint16(pkg.Data[4:6])
+	itemLength := int(binary.BigEndian.Uint16(pkg.Data[6:8]))
+	if len(pkg.Data) < 8+itemLength {
+		return nil
+	}
+	content := pkg.Data[8 : 8+itemLength]
+
+	switch itemType {
+	case xftype.TypeAuthRequest:
+		return ss.handleAuth(content)
+	case xftype.TypeHeartbeat:
+		return ss.reply(xftype.TypeHeartbeat, &xftype.XFType203{Content: content})
+	}
+	return nil
+}
+
+// handleAuth 校验认证请求并应答XFType099
+// 参数: token - 认证请求中的32字节令牌
+// 返回: 认证被拒绝时返回错误（应答后断开连接）
+func (ss *simSession) handleAuth(token []byte) error {
+	status := source.AuthStatusAccepted
+	if expected := ss.srv.expectedToken(); expected != nil && !bytes.Equal(token, expected) {
+		status = source.AuthStatusRejected
+	}
+	for _, fault := range ss.srv.cfg.Faults {
+		if fault.Action == faultRejectAuth && ss.applies(fault) {
+			status = fault.Status
+		}
+	}
+
+	resp := &xftype.XFType099{Token: string(bytes.TrimRight(token, "\x00")), Status: status}
+	if err := ss.reply(xftype.TypeAuthResponse, resp); err != nil {
+		return err
+	}
+
+	if status != source.AuthStatusAccepted {
+		log.Printf("[%s] Rejected authentication on connection #%d: status=%d", ss.srv.cfg.Name, ss.index, status)
+		// 给对端留出读取应答的时间
+		time.Sleep(100 * time.Millisecond)
+		return fmt.Errorf("authentication rejected")
+	}
+
+	ss.authOnce.Do(func() {
+		log.Printf("[%s] Accepted authentication on connection #%d", ss.srv.cfg.Name, ss.index)
+		ss.authedAt = time.Now()
+		close(ss.authed)
+	})
+	return nil
+}
+
+// handleRetransmission 重发请求区间内的数据包
+// 参数: pkg - 重传请求包
+// 返回: 错误信息
+func (ss *simSession) handleRetransmission(pkg *source.BasePackage) error {
+	if len(pkg.Data) < 16 {
+		return nil
+	}
+	start := binary.BigEndian.Uint64(pkg.Data[0:8])
+	end := binary.BigEndian.Uint64(pkg.Data[8:16])
+	log.Printf("[%s] Retransmission request on connection #%d: %d-%d", ss.srv.cfg.Name, ss.index, start, end)
+
+	for no := start; no <= end && no-start < 0xFFFF; no++ {
+		packet, err := ss.srv.buildPackage(no)
+		if err != nil {
+			return err
+		}
+		if err := ss.writeFrame(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleResume 从续传请求的包序号开始继续发送
+// 参数: pkg - 续传请求包
+// 返回: 错误信息
+func (ss *simSession) handleResume(pkg *source.BasePackage) error {
+	if len(pkg.Data) < 16 {
+		return nil
+	}
+	sourceInfo := binary.BigEndian.Uint32(pkg.Data[0:4])
+	from := binary.BigEndian.Uint64(pkg.Data[8:16])
+	if sourceInfo != ss.srv.scenario.SourceInfo {
+		return nil
+	}
+
+	log.Printf("[%s] Resume request on connection #%d from %d", ss.srv.cfg.Name, ss.index, from)
+	ss.srv.rewind(from)
+	return nil
+}
+
+// reply 发送只包含一个数据项的应答包
+// 参数: itemType - 信息类型编号, body - 信息内容
+// 返回: 错误信息
+func (ss *simSession) reply(itemType uint16, body interface{}) error {
+	now := time.Now()
+	seg := &xftype.DataSegment{
+		Year:  uint16(now.Year()),
+		Month: uint8(now.Month()),
+		Day:   uint8(now.Day()),
+		Items: []xftype.DataItem{{Type: itemType, Body: body}},
+	}
+	packet, err := ss.srv.buildSegmentPackage(0, seg)
+	if err != nil {
+		return err
+	}
+	return ss.writeFrame(packet)
+}
+
+// writeFrame 添加分帧字节后写入连接
+// 参数: packet - 数据包
+// 返回: 错误信息
+func (ss *simSession) writeFrame(packet []byte) error {
+	frame, err := ss.framer.Encode(packet)
+	if err != nil {
+		return err
+	}
+	return ss.write(frame)
+}
+
+// write 写入连接（半开状态下不写入）
+// 参数: data - 数据
+// 返回: 错误信息
+func (ss *simSession) write(data []byte) error {
+	if ss.halfOpen.Load() {
+		return nil
+	}
+
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+
+	ss.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := ss.conn.Write(data)
+	return err
+}
+
+// stream 认证通过后按速率发送数据包，并按脚本注入故障
+func (ss *simSession) stream() {
+	select {
+	case <-ss.authed:
+	case <-ss.closed:
+		return
+	}
+
+	rate := ss.srv.cfg.Rate
+	if rate <= 0 {
+		// 不发送数据，仍按时间触发故障
+		rate = 10
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ss.closed:
+			return
+		case <-ticker.C:
+		}
+
+		if !ss.injectFaults() {
+			return
+		}
+		if ss.srv.cfg.Rate <= 0 || ss.halfOpen.Load() {
+			continue
+		}
+
+		packet, err := ss.srv.buildPackage(ss.srv.takeNo())
+		if err != nil {
+			log.Printf("[%s] Failed to build package: %v", ss.srv.cfg.Name, err)
+			continue
+		}
+		if err := ss.sendStreamFrame(packet); err != nil {
+			log.Printf("[%s] Write error on connection #%d: %v", ss.srv.cfg.Name, ss.index, err)
+			ss.close()
+			return
+		}
+		ss.sent++
+	}
+}
+
+// applies 故障是否对当前连接生效
+// 参数: fault - 故障
+// 返回: 是否生效
+func (ss *simSession) applies(fault Fault) bool {
+	return fault.Connection == 0 || fault.Connection == ss.index
+}
+
+// injectFaults 触发已到时间或包数的故障
+// 返回: 连接是否仍然可用
+func (ss *simSession) injectFaults() bool {
+	name := ss.srv.cfg.Name
+	for i, fault := range ss.srv.cfg.Faults {
+		if ss.fired[i] || fault.Action == faultRejectAuth || !ss.applies(fault) {
+			continue
+		}
+		if fault.AtPacket > 0 {
+			if ss.sent+1 < fault.AtPacket {
+				continue
+			}
+		} else if time.Since(ss.authedAt) < fault.After {
+			continue
+		}
+		ss.fired[i] = true
+
+		log.Printf("[%s] Injecting fault '%s' on connection #%d after %d packages", name, fault.Action, ss.index, ss.sent)
+		switch fault.Action {
+		case faultDisconnect:
+			ss.close()
+			return false
+		case faultHalfOpen:
+			ss.halfOpen.Store(true)
+			if fault.Duration > 0 {
+				select {
+				case <-time.After(fault.Duration):
+					ss.close()
+				case <-ss.closed:
+				}
+			} else {
+				<-ss.closed
+			}
+			return false
+		case faultGarbage:
+			garbage := make([]byte, fault.Count)
+			rand.Read(garbage)
+			if err := ss.write(garbage); err != nil {
+				ss.close()
+				return false
+			}
+		case faultSplit:
+			ss.splitLeft = fault.Count
+		case faultCoalesce:
+			ss.coalesceTo = fault.Count
+			ss.coalesce = ss.coalesce[:0]
+		case faultGap:
+			ss.srv.skip(uint64(fault.Count))
+		}
+	}
+	return true
+}
+
+// sendStreamFrame 发送数据帧，按当前故障拆分或合并写入
+// 参数: packet - 数据包
+// 返回: 错误信息
+func (ss *simSession) sendStreamFrame(packet []byte) error {
+	frame, err := ss.framer.Encode(packet)
+	if err != nil {
+		return err
+	}
+
+	if ss.coalesceTo > 0 {
+		ss.coalesce = append(ss.coalesce, frame)
+		if len(ss.coalesce) < ss.coalesceTo {
+			return nil
+		}
+		ss.coalesceTo = 0
+		return ss.write(bytes.Join(ss.coalesce, nil))
+	}
+
+	if ss.splitLeft > 0 {
+		ss.splitLeft--
+		// 每次写入3字节，中间短暂停顿，迫使对端分多次读取
+		for len(frame) > 0 {
+			n := 3
+			if n > len(frame) {
+				n = len(frame)
+			}
+			if err := ss.write(frame[:n]); err != nil {
+				return err
+			}
+			frame = frame[n:]
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	}
+
+	return ss.write(frame)
+}
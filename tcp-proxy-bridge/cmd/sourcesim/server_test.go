@@ -0,0 +1,183 @@
+// cmd/sourcesim/server_test.go
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/framing"
+	"tcp-proxy-bridge/internal/source"
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+const testToken = "11111111111111111111111111111111"
+
+// startSimServer 按场景启动一台模拟源服务器
+// 返回: 监听地址
+func startSimServer(t *testing.T, scenario *Scenario) string {
+	t.Helper()
+	scenario.normalize()
+	if err := scenario.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	server, err := newSimServer(scenario, scenario.Servers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Serve(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return server.listener.Addr().String()
+}
+
+// simClient 按桥接服务的方式连接模拟器
+type simClient struct {
+	t        *testing.T
+	conn     net.Conn
+	framer   framing.Framer
+	codec    *source.PackageCodec
+	registry *xftype.Registry
+	frames   [][]byte
+}
+
+func dialSim(t *testing.T, scenario *Scenario, address string) *simClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	framer, err := framing.New(scenario.Framing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &simClient{
+		t:        t,
+		conn:     conn,
+		framer:   framer,
+		codec:    source.NewPackageCodec(scenario.Protocol),
+		registry: xftype.NewRegistry(),
+	}
+}
+
+// authenticate 发送认证包并等待XFType099应答
+// 返回: 应答状态码
+func (c *simClient) authenticate(token string) uint8 {
+	c.t.Helper()
+	packet, err := source.NewAuthManager(token, 802, 20, c.codec).GenerateAuthPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	frame, err := c.framer.Encode(packet)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		pkg := c.next()
+		if pkg == nil {
+			c.t.Fatal("connection closed before auth response")
+		}
+		seg, err := c.registry.DecodeSegment(pkg.Data)
+		if err != nil || len(seg.Items) == 0 {
+			continue
+		}
+		if resp, ok := seg.Items[0].Body.(*xftype.XFType099); ok {
+			return resp.Status
+		}
+	}
+}
+
+// next 读取下一个数据包
+// 返回: 数据包，连接关闭时返回nil
+func (c *simClient) next() *source.BasePackage {
+	c.t.Helper()
+	buffer := make([]byte, 4096)
+	for len(c.frames) == 0 {
+		c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := c.conn.Read(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				c.t.Fatal("timed out waiting for frame")
+			}
+			return nil
+		}
+		frames, _ := c.framer.Decode(buffer[:n])
+		c.frames = append(c.frames, frames...)
+	}
+
+	frame := c.frames[0]
+	c.frames = c.frames[1:]
+	pkg, err := c.codec.Unmarshal(frame)
+	if err != nil {
+		c.t.Fatalf("Unmarshal: %v", err)
+	}
+	return pkg
+}
+
+func TestSimServerAuthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		faults     []Fault
+		wantStatus uint8
+	}{
+		{name: "valid token", token: testToken, wantStatus: source.AuthStatusAccepted},
+		{name: "wrong token", token: "22222222222222222222222222222222", wantStatus: source.AuthStatusRejected},
+		// reject_auth未配置status时以0（认证失败）应答
+		{name: "reject_auth default status", token: testToken, faults: []Fault{{Action: faultRejectAuth}}, wantStatus: source.AuthStatusRejected},
+		// reject_auth配置status为1时应答的是认证通过
+		{name: "reject_auth status 1", token: testToken, faults: []Fault{{Action: faultRejectAuth, Status: 1}}, wantStatus: source.AuthStatusAccepted},
+		// 只对第2个连接生效
+		{name: "reject_auth other connection", token: testToken, faults: []Fault{{Action: faultRejectAuth, Connection: 2}}, wantStatus: source.AuthStatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := &Scenario{
+				Token:   testToken,
+				Rate:    100,
+				Servers: []ServerScenario{{Name: "sim", Listen: "127.0.0.1:0", Faults: tt.faults}},
+			}
+			client := dialSim(t, scenario, startSimServer(t, scenario))
+
+			status := client.authenticate(tt.token)
+			if status != tt.wantStatus {
+				t.Fatalf("auth status = %d, want %d", status, tt.wantStatus)
+			}
+
+			// 应答按桥接服务的认证状态机处理
+			session := source.NewAuthSession(time.Second, 0)
+			session.Begin()
+			accepted := session.HandleResponse(status)
+			if accepted != (tt.wantStatus == source.AuthStatusAccepted) {
+				t.Fatalf("HandleResponse(%d) = %v", status, accepted)
+			}
+
+			pkg := client.next()
+			if accepted {
+				// 认证通过后开始发送数据包
+				if pkg == nil || pkg.PackageNo != scenario.StartNo || pkg.SourceInfo != scenario.SourceInfo {
+					t.Errorf("first data package = %+v, want PackageNo %d from source %d", pkg, scenario.StartNo, scenario.SourceInfo)
+				}
+			} else if pkg != nil {
+				// 认证被拒绝后断开连接，不发送数据
+				t.Errorf("received package %d after rejection", pkg.PackageNo)
+			}
+		})
+	}
+}
@@ -11,44 +11,76 @@ import (
 )
 
 func main() {
-	// 创建源服务器配置
-	sourceConfig := &config.SourceServers{
-		Primary: config.SourceServer{
-			ID:                  "primary-server",
-			Name:                "主源服务器",
-			Address:             "127.0.0.1:8888",
-			Enabled:             true,
-			Timeout:             10 * time.Second,
-			MaxRetries:          3,
-			BatchSize:           100,
-			HealthCheckInterval: 30 * time.Second,
-			HealthCheckTimeout:  5 * time.Second,
-			FailoverThreshold:   3,
+	// 创建源服务器配置（按优先级分组的服务器池）
+	cfg := &config.Config{
+		SourceServers: config.SourceServers{
+			Servers: []config.SourceServer{
+				{
+					ID:                  "primary-server",
+					Name:                "主源服务器",
+					Address:             "127.0.0.1:8888",
+					Enabled:             true,
+					Timeout:             10 * time.Second,
+					MaxRetries:          3,
+					BatchSize:           100,
+					HealthCheckInterval: 30 * time.Second,
+					HealthCheckTimeout:  5 * time.Second,
+					FailoverThreshold:   3,
+					Priority:            0,
+					Weight:              1,
+				},
+				{
+					ID:                  "backup-server-a",
+					Name:                "备用源服务器A",
+					Address:             "127.0.0.1:8889",
+					Enabled:             true,
+					Timeout:             10 * time.Second,
+					MaxRetries:          3,
+					BatchSize:           50,
+					HealthCheckInterval: 30 * time.Second,
+					HealthCheckTimeout:  5 * time.Second,
+					FailoverThreshold:   3,
+					Priority:            1,
+					Weight:              2,
+				},
+				{
+					ID:                  "backup-server-b",
+					Name:                "备用源服务器B",
+					Address:             "127.0.0.1:8890",
+					Enabled:             true,
+					Timeout:             10 * time.Second,
+					MaxRetries:          3,
+					BatchSize:           50,
+					HealthCheckInterval: 30 * time.Second,
+					HealthCheckTimeout:  5 * time.Second,
+					FailoverThreshold:   3,
+					Priority:            1,
+					Weight:              1,
+				},
+			},
 		},
-		Backup: config.SourceServer{
-			ID:                  "backup-server",
-			Name:                "备用源服务器",
-			Address:             "127.0.0.1:8889",
-			Enabled:             true,
-			Timeout:             10 * time.Second,
-			MaxRetries:          3,
-			BatchSize:           50,
-			HealthCheckInterval: 30 * time.Second,
-			HealthCheckTimeout:  5 * time.Second,
-			FailoverThreshold:   3,
+		Authentication: config.AuthConfig{
+			Token:          "11111111111111111111111111111111",
+			SourceID:       802,
+			HostID:         20,
+			ReauthInterval: time.Hour,
+		},
+		Heartbeat: config.HeartbeatConfig{
+			Interval:         60 * time.Second,
+			WriteIdleTimeout: 3 * time.Second,
+			ReadIdleTimeout:  60 * time.Second,
+		},
+		Delimiter: config.DelimiterConfig{
+			Separator:       "7878787888888888",
+			MaxPacketLength: 4096,
 		},
 	}
 
 	// 创建源服务器管理器
-	manager := source.NewManager(sourceConfig)
-
-	// 设置数据处理回调
-	manager.SetDataHandler(func(data []byte) error {
-		log.Printf("Received data: %d bytes", len(data))
-		// 这里可以添加你的数据处理逻辑
-		// 例如：解析数据、存储到数据库、转发到其他服务等
-		return nil
-	})
+	manager, err := source.NewManager(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create source manager: %v", err)
+	}
 
 	// 创建上下文
 	ctx, cancel := context.WithCancel(context.Background())
@@ -68,8 +100,10 @@ func main() {
 			case <-ctx.Done():
 				return
 			default:
-				if err := manager.ConnectToSource(ctx, func(data []byte) error {
-					log.Printf("Processing data from source: %d bytes", len(data))
+				// 数据处理回调：这里可以添加你的数据处理逻辑
+				// 例如：解析数据、存储到数据库、转发到其他服务等
+				if err := manager.ConnectToSource(ctx, func(data []byte, meta *source.PacketMeta) error {
+					log.Printf("Received data from %s: %d bytes", meta.ServerID, len(data))
 					return nil
 				}); err != nil {
 					log.Printf("Connection error: %v", err)
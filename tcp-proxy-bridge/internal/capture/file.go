@@ -0,0 +1,190 @@
+// internal/capture/file.go
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// fileMagic 抓包文件头
+var fileMagic = []byte("TPBCAP01")
+
+// FileExt 抓包文件扩展名
+const FileExt = ".cap"
+
+// maxRecordData 单条记录最大数据长度，读取时用于识别损坏的文件
+const maxRecordData = 16 << 20
+
+// ErrBadMagic 不是抓包文件
+var ErrBadMagic = errors.New("not a capture file")
+
+// Direction 数据方向
+type Direction uint8
+
+// 数据方向常量定义
+const (
+	DirectionIn  Direction = 1 // 从源服务器连接读取的数据
+	DirectionOut Direction = 2 // 发往目标服务器的数据
+)
+
+// String 获取方向名称
+func (d Direction) String() string {
+	switch d {
+	case DirectionIn:
+		return "in"
+	case DirectionOut:
+		return "out"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(d))
+	}
+}
+
+// Record 抓包记录
+// 文件格式: 文件头"TPBCAP01"，之后每条记录为
+// 时间戳(8字节，Unix纳秒) + 方向(1字节) + 流标识长度(1字节) + 流标识 + 对端地址长度(1字节) + 对端地址 + 数据长度(4字节) + 数据，均为大端序
+type Record struct {
+	Time      time.Time // 读取或写入时间
+	Direction Direction // 数据方向
+	Stream    string    // 流标识（源服务器ID或目标服务器ID）
+	Remote    string    // 对端地址
+	Data      []byte    // 原始数据
+}
+
+// encode 编码一条记录
+// 返回: 记录字节
+func (r *Record) encode() []byte {
+	stream := truncate(r.Stream)
+	remote := truncate(r.Remote)
+
+	buf := make([]byte, 0, 8+1+1+len(stream)+1+len(remote)+4+len(r.Data))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.Time.UnixNano()))
+	buf = append(buf, byte(r.Direction), byte(len(stream)))
+	buf = append(buf, stream...)
+	buf = append(buf, byte(len(remote)))
+	buf = append(buf, remote...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Data)))
+	buf = append(buf, r.Data...)
+	return buf
+}
+
+// truncate 截断超过255字节的字符串字段
+func truncate(s string) string {
+	if len(s) > 0xFF {
+		return s[:0xFF]
+	}
+	return s
+}
+
+// Reader 抓包文件读取器
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader 创建抓包文件读取器并校验文件头
+// 参数: r - 数据源
+// 返回: 读取器和错误信息
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("failed to read capture header: %v", err)
+	}
+	if !bytes.Equal(magic, fileMagic) {
+		return nil, ErrBadMagic
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next 读取下一条记录
+// 返回: 记录和错误信息（读完时返回io.EOF；末尾记录不完整时返回io.ErrUnexpectedEOF）
+func (rd *Reader) Next() (*Record, error) {
+	var head [10]byte
+	if _, err := io.ReadFull(rd.r, head[:]); err != nil {
+		return nil, err
+	}
+
+	record := &Record{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(head[0:8]))),
+		Direction: Direction(head[8]),
+	}
+
+	stream, err := rd.readString(int(head[9]))
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	record.Stream = stream
+
+	remoteLen, err := rd.r.ReadByte()
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if record.Remote, err = rd.readString(int(remoteLen)); err != nil {
+		return nil, unexpected(err)
+	}
+
+	var length [4]byte
+	if _, err := io.ReadFull(rd.r, length[:]); err != nil {
+		return nil, unexpected(err)
+	}
+	dataLen := binary.BigEndian.Uint32(length[:])
+	if dataLen > maxRecordData {
+		return nil, fmt.Errorf("corrupt capture record: data length %d", dataLen)
+	}
+	record.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(rd.r, record.Data); err != nil {
+		return nil, unexpected(err)
+	}
+
+	return record, nil
+}
+
+// readString 读取指定长度的字符串字段
+func (rd *Reader) readString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(rd.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// unexpected 记录中途读到文件末尾时视为记录不完整
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ListFiles 列出抓包文件，目录展开为其中的抓包文件
+// 文件名包含创建时间，按文件名排序即为时间顺序
+// 参数: paths - 文件或目录列表
+// 返回: 排序后的文件列表和错误信息
+func ListFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*"+FileExt))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,184 @@
+// internal/capture/recorder.go
+package capture
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// filePrefix 抓包文件名前缀
+const filePrefix = "capture-"
+
+// Recorder 抓包记录器
+// 所有连接共用一个记录器，记录按写入顺序追加到当前文件；文件超过最大字节数后切换新文件，
+// 并删除超出保留数量的最旧文件
+type Recorder struct {
+	mu       sync.Mutex
+	dir      string   // 抓包目录
+	maxBytes int64    // 单个文件最大字节数
+	maxFiles int      // 最多保留的文件数
+	file     *os.File // 当前文件
+	size     int64    // 当前文件字节数
+	files    []string // 现有文件（按创建顺序）
+	closed   bool     // 已关闭
+
+	records  int64 // 已记录的条数
+	bytes    int64 // 已记录的数据字节数
+	failures int64 // 写入失败次数
+	lastErr  string
+}
+
+// NewRecorder 创建抓包记录器并打开新的抓包文件
+// 参数: cfg - 抓包配置
+// 返回: 记录器和错误信息
+func NewRecorder(cfg config.CaptureConfig) (*Recorder, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create capture dir: %v", err)
+	}
+
+	// 上次运行遗留的文件计入保留数量
+	files, err := ListFiles([]string{cfg.Dir})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capture files: %v", err)
+	}
+
+	r := &Recorder{
+		dir:      cfg.Dir,
+		maxBytes: cfg.MaxFileBytes,
+		maxFiles: cfg.MaxFiles,
+		files:    files,
+	}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record 记录一段原始数据
+// 写入失败只记录日志，不影响数据处理
+// 参数: direction - 数据方向, stream - 流标识, remote - 对端地址, data - 数据
+func (r *Recorder) Record(direction Direction, stream, remote string, data []byte) {
+	record := &Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Stream:    stream,
+		Remote:    remote,
+		Data:      data,
+	}
+	buf := record.encode()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	// 上次切换文件失败时重新尝试；超过文件上限的单条记录写入空文件，不再切换
+	full := r.size > int64(len(fileMagic)) && r.size+int64(len(buf)) > r.maxBytes
+	if r.file == nil || full {
+		if err := r.rotate(); err != nil {
+			r.fail(err)
+			return
+		}
+	}
+
+	if _, err := r.file.Write(buf); err != nil {
+		r.fail(fmt.Errorf("failed to write capture file: %v", err))
+		return
+	}
+	r.size += int64(len(buf))
+	r.records++
+	r.bytes += int64(len(data))
+}
+
+// fail 记录写入失败
+// 调用方需持有锁
+func (r *Recorder) fail(err error) {
+	// 连续失败时避免刷屏，只在第一次和每1000次记录日志
+	if r.failures%1000 == 0 {
+		log.Printf("Capture recorder error: %v", err)
+	}
+	r.failures++
+	r.lastErr = err.Error()
+}
+
+// rotate 关闭当前文件并创建新的抓包文件，删除超出保留数量的旧文件
+// 调用方需持有锁
+// 返回: 错误信息
+func (r *Recorder) rotate() error {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+
+	name := filePrefix + time.Now().UTC().Format("20060102T150405.000000000") + FileExt
+	path := filepath.Join(r.dir, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create capture file: %v", err)
+	}
+	if _, err := file.Write(fileMagic); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write capture file: %v", err)
+	}
+
+	r.file = file
+	r.size = int64(len(fileMagic))
+	r.files = append(r.files, path)
+
+	for len(r.files) > r.maxFiles {
+		if err := os.Remove(r.files[0]); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove old capture file %s: %v", r.files[0], err)
+		}
+		r.files = r.files[1:]
+	}
+
+	log.Printf("Capturing raw data to %s", path)
+	return nil
+}
+
+// Close 关闭当前抓包文件
+// 返回: 错误信息
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// GetStatus 获取记录器状态
+// 返回: 状态信息
+func (r *Recorder) GetStatus() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := map[string]interface{}{
+		"dir":      r.dir,
+		"files":    len(r.files),
+		"records":  r.records,
+		"bytes":    r.bytes,
+		"failures": r.failures,
+	}
+	if r.file != nil {
+		status["current_file"] = r.file.Name()
+		status["current_size"] = r.size
+	}
+	if r.lastErr != "" {
+		status["last_error"] = r.lastErr
+	}
+	return status
+}
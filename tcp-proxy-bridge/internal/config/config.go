@@ -2,9 +2,13 @@
 package config
 
 import (
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
@@ -15,21 +19,40 @@ type Config struct {
 	Server         ServerConfig    `yaml:"server"`
 	Database       DatabaseConfig  `yaml:"database"`
 	Forwarder      ForwarderConfig `yaml:"forwarder"`
-	SourceServers  SourceServers   `yaml:"source_servers"` // 源服务器配置（主备）
+	SourceServers  SourceServers   `yaml:"source_servers"` // 源服务器配置（服务器池）
+	SourceGroups   []SourceGroup   `yaml:"source_groups"`  // 源分组配置（多路独立数据源，配置后不能再配置顶层的source_servers.servers）
 	Authentication AuthConfig      `yaml:"authentication"` // 身份认证配置
 	Heartbeat      HeartbeatConfig `yaml:"heartbeat"`      // 心跳配置
 	Delimiter      DelimiterConfig `yaml:"delimiter"`      // 分隔符配置
+	Protocol       ProtocolConfig  `yaml:"protocol"`       // BasePackage编解码配置
+	Sequence       SequenceConfig  `yaml:"sequence"`       // 包序号跟踪配置
+	Payload        PayloadConfig   `yaml:"payload"`        // 数据段解码校验配置
+	Ingest         IngestConfig    `yaml:"ingest"`         // 入库队列配置
+	Capture        CaptureConfig   `yaml:"capture"`        // 原始数据抓包配置
+	Pipeline       PipelineConfig  `yaml:"pipeline"`       // 入库前的处理流水线配置
 	TargetServers  []TargetServer  `yaml:"target_servers"` // 目标服务器配置
 }
 
 // ServerConfig 服务器相关配置
 type ServerConfig struct {
-	TCPListenPort   int           `yaml:"tcp_listen_port"`
-	HealthCheckPort int           `yaml:"health_check_port"`
-	MaxConnections  int           `yaml:"max_connections"`
-	ReadTimeout     time.Duration `yaml:"read_timeout"`
-	WriteTimeout    time.Duration `yaml:"write_timeout"`
-	MaxMessageSize  int           `yaml:"max_message_size"`
+	TCPListenPort       int               `yaml:"tcp_listen_port"`
+	HealthCheckPort     int               `yaml:"health_check_port"`
+	MaxConnections      int               `yaml:"max_connections"`        // 监听端口最大并发连接数（0表示不限制）
+	MaxConnectionsPerIP int               `yaml:"max_connections_per_ip"` // 单个来源IP的最大并发连接数（0表示不限制）
+	ReadTimeout         time.Duration     `yaml:"read_timeout"`
+	WriteTimeout        time.Duration     `yaml:"write_timeout"`
+	MaxMessageSize      int               `yaml:"max_message_size"`
+	Framing             FramingConfig     `yaml:"framing"` // 监听端口分帧方式（默认none，每次读取作为一条消息）
+	Access              AccessConfig      `yaml:"access"`  // 监听端口来源IP访问控制
+	TLS                 ListenerTLSConfig `yaml:"tls"`     // 监听端口TLS配置
+}
+
+// AccessConfig 来源IP访问控制配置
+// 条目为CIDR（10.0.0.0/8）或单个IP；deny优先于allow，allow为空时允许所有未被拒绝的地址
+type AccessConfig struct {
+	Allow []string `yaml:"allow"` // 允许的来源地址
+	Deny  []string `yaml:"deny"`  // 拒绝的来源地址
+	File  string   `yaml:"file"`  // 访问控制列表文件（YAML，包含allow/deny），修改后自动重新加载，与上面的列表合并生效
 }
 
 // DatabaseConfig 数据库连接配置
@@ -53,12 +76,52 @@ type ForwarderConfig struct {
 	MaxRetryInterval     time.Duration `yaml:"max_retry_interval"`
 }
 
-// SourceServers 源服务器配置（主备）
+// SourceServers 源服务器配置（服务器池）
+// 优先使用servers列表；primary/backup为兼容旧配置保留，加载时会被转换为列表
 type SourceServers struct {
-	Primary SourceServer `yaml:"primary"` // 主服务器
-	Backup  SourceServer `yaml:"backup"`  // 备用服务器
+	Mode           string          `yaml:"mode"`            // 接入模式: failover(单连接故障切换) / concurrent(同时连接所有源)
+	DedupWindow    time.Duration   `yaml:"dedup_window"`    // 跨源去重时间窗口
+	DedupCapacity  int             `yaml:"dedup_capacity"`  // 去重记录最大数量
+	Reconnect      ReconnectConfig `yaml:"reconnect"`       // 重连退避和熔断配置
+	WarmStandby    bool            `yaml:"warm_standby"`    // 单连接模式下与备用服务器保持已认证的热备连接
+	SessionHistory int             `yaml:"session_history"` // 状态中保留的最近结束的连接会话数量 (默认20)
+	Servers        []SourceServer  `yaml:"servers"`         // 源服务器列表
+	Primary        SourceServer    `yaml:"primary"`         // 主服务器（旧配置）
+	Backup         SourceServer    `yaml:"backup"`          // 备用服务器（旧配置）
+}
+
+// SourceGroup 源分组配置
+// 每个分组是一路独立的数据源，使用各自的源服务器、认证凭据、分隔符和心跳配置，
+// 收到的消息带有分组名称，只投递到分组的目标服务器
+type SourceGroup struct {
+	Name           string          `yaml:"name"`           // 分组名称（写入message_queue.source_group）
+	SourceServers  SourceServers   `yaml:"source_servers"` // 源服务器配置
+	Authentication AuthConfig      `yaml:"authentication"` // 身份认证配置（reauth_interval和response_timeout未配置时使用顶层配置）
+	Heartbeat      HeartbeatConfig `yaml:"heartbeat"`      // 心跳配置（未配置的字段使用顶层配置）
+	Delimiter      DelimiterConfig `yaml:"delimiter"`      // 分隔符配置（未配置的字段使用顶层配置）
+	Targets        []string        `yaml:"targets"`        // 目标服务器ID列表（为空时投递到所有启用的目标服务器）
+}
+
+// DefaultSourceGroup 未配置source_groups时使用的分组名称
+const DefaultSourceGroup = "default"
+
+// ReconnectConfig 源服务器重连配置
+// 连接失败后按指数退避加随机抖动等待；连续失败达到阈值后熔断，冷却期内不再尝试连接该服务器
+type ReconnectConfig struct {
+	InitialBackoff   time.Duration `yaml:"initial_backoff"`   // 首次重连等待时间 (默认1s)
+	MaxBackoff       time.Duration `yaml:"max_backoff"`       // 最大重连等待时间 (默认60s)
+	Multiplier       float64       `yaml:"multiplier"`        // 退避倍数 (默认2)
+	Jitter           float64       `yaml:"jitter"`            // 随机抖动比例 0~1 (默认0.2)
+	BreakerThreshold int           `yaml:"breaker_threshold"` // 连续失败多少次后熔断 (默认5)
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`  // 熔断后等待多久进入半开状态 (默认30s)
 }
 
+// 源服务器接入模式常量定义
+const (
+	SourceModeFailover   = "failover"   // 单连接模式，故障时切换到下一台服务器
+	SourceModeConcurrent = "concurrent" // 并发模式，同时连接所有启用的服务器并合并数据流
+)
+
 // SourceServer 源服务器配置
 type SourceServer struct {
 	ID                  string        `yaml:"id"`                    // 服务器唯一标识
@@ -71,14 +134,82 @@ type SourceServer struct {
 	HealthCheckInterval time.Duration `yaml:"health_check_interval"` // 健康检查间隔
 	HealthCheckTimeout  time.Duration `yaml:"health_check_timeout"`  // 健康检查超时
 	FailoverThreshold   int           `yaml:"failover_threshold"`    // 故障切换阈值
+	Priority            int           `yaml:"priority"`              // 优先级 (数字越小优先级越高，相同优先级为一组)
+	Weight              int           `yaml:"weight"`                // 组内权重 (默认1)
+	Framing             FramingConfig `yaml:"framing"`               // 分帧方式（默认使用全局分隔符配置）
+	TLS                 TLSConfig     `yaml:"tls"`                   // TLS连接配置
+	Proxy               ProxyConfig   `yaml:"proxy"`                 // 出站代理配置（为空时直连）
+}
+
+// TLSConfig TLS连接配置
+// 证书文件在磁盘上更新后自动重新加载，无需重启
+type TLSConfig struct {
+	Enabled    bool   `yaml:"enabled"`     // 是否使用TLS
+	CAFile     string `yaml:"ca_file"`     // CA证书文件 (PEM)，为空时使用系统根证书
+	CertFile   string `yaml:"cert_file"`   // 客户端证书文件 (PEM)，配置后启用双向TLS
+	KeyFile    string `yaml:"key_file"`    // 客户端私钥文件 (PEM)
+	ServerName string `yaml:"server_name"` // 校验证书使用的服务器名称，为空时使用地址中的主机名
+	MinVersion string `yaml:"min_version"` // 最低TLS版本: 1.0/1.1/1.2/1.3 (默认1.2)
+}
+
+// ProxyConfig 出站代理配置
+// 只能经跳板代理访问源服务器或目标服务器时使用，支持SOCKS5（可选用户名/密码认证）和HTTP CONNECT
+type ProxyConfig struct {
+	Type     string `yaml:"type"`     // 代理类型: socks5/http，为空时直连
+	Address  string `yaml:"address"`  // 代理地址 (host:port)
+	Username string `yaml:"username"` // 用户名（SOCKS5用户名/密码认证或HTTP Basic认证，为空时不认证）
+	Password string `yaml:"password"` // 密码
 }
 
+// 代理类型常量定义
+const (
+	ProxySOCKS5 = "socks5" // SOCKS5代理
+	ProxyHTTP   = "http"   // HTTP CONNECT代理
+)
+
+// TLSVersions 支持的TLS版本
+var TLSVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// DefaultTLSMinVersion 未配置时的最低TLS版本
+const DefaultTLSMinVersion = "1.2"
+
+// ListenerTLSConfig 监听端口TLS配置
+// 要求客户端证书时，证书主题或SAN作为消息的来源身份与来源IP一起入库
+type ListenerTLSConfig struct {
+	Enabled      bool   `yaml:"enabled"`        // 是否使用TLS
+	CertFile     string `yaml:"cert_file"`      // 服务器证书文件 (PEM)
+	KeyFile      string `yaml:"key_file"`       // 服务器私钥文件 (PEM)
+	ClientCAFile string `yaml:"client_ca_file"` // 校验客户端证书的CA证书文件 (PEM)
+	ClientAuth   string `yaml:"client_auth"`    // 客户端证书: none/optional/require (配置client_ca_file时默认require，否则none)
+	Identity     string `yaml:"identity"`       // 来源身份取值: subject(证书主题)/san(第一个SAN) (默认subject)
+	MinVersion   string `yaml:"min_version"`    // 最低TLS版本: 1.0/1.1/1.2/1.3 (默认1.2)
+}
+
+// 客户端证书要求常量定义
+const (
+	ClientAuthNone     = "none"     // 不要求客户端证书
+	ClientAuthOptional = "optional" // 客户端提供证书时校验
+	ClientAuthRequire  = "require"  // 必须提供并通过校验
+)
+
+// 来源身份取值常量定义
+const (
+	IdentitySubject = "subject" // 证书主题 (例如 CN=client-1,O=example)
+	IdentitySAN     = "san"     // 第一个SAN（依次取DNS、URI、邮箱、IP）
+)
+
 // AuthConfig 身份认证配置
 type AuthConfig struct {
-	Token          string        `yaml:"token"`           // 认证令牌
-	SourceID       uint32        `yaml:"source_id"`       // 信源ID
-	HostID         uint32        `yaml:"host_id"`         // 信宿ID
-	ReauthInterval time.Duration `yaml:"reauth_interval"` // 重新认证间隔
+	Token           string        `yaml:"token"`            // 认证令牌
+	SourceID        uint32        `yaml:"source_id"`        // 信源ID
+	HostID          uint32        `yaml:"host_id"`          // 信宿ID
+	ReauthInterval  time.Duration `yaml:"reauth_interval"`  // 重新认证间隔
+	ResponseTimeout time.Duration `yaml:"response_timeout"` // 等待认证应答的超时时间 (默认10s)
 }
 
 // HeartbeatConfig 心跳配置
@@ -94,6 +225,167 @@ type DelimiterConfig struct {
 	MaxPacketLength int    `yaml:"max_packet_length"` // 最大数据包长度
 }
 
+// ProtocolConfig BasePackage编解码配置
+type ProtocolConfig struct {
+	Checksum          string `yaml:"checksum"`           // 校验和算法: none/crc16/crc32 (默认none)
+	ChecksumPlacement string `yaml:"checksum_placement"` // 校验和位置: reserved(包头保留字节)/trailer(数据之后) (crc16默认reserved，crc32默认trailer)
+	MaxDataLength     int    `yaml:"max_data_length"`    // 当前数据段长度上限，超过视为长度错误 (默认使用delimiter.max_packet_length)
+}
+
+// 校验和算法常量定义
+const (
+	ChecksumNone  = "none"  // 不校验
+	ChecksumCRC16 = "crc16" // CRC-16/CCITT-FALSE
+	ChecksumCRC32 = "crc32" // CRC-32/IEEE
+)
+
+// 校验和位置常量定义
+const (
+	ChecksumReserved = "reserved" // 包头第30-31字节（保留字段），只能容纳crc16
+	ChecksumTrailer  = "trailer"  // 数据内容之后，不计入当前数据段长度
+)
+
+// SequenceConfig 包序号跟踪配置
+type SequenceConfig struct {
+	RequestRetransmission bool          `yaml:"request_retransmission"` // 检测到缺口时是否向源服务器请求重传
+	MaxGapSize            uint64        `yaml:"max_gap_size"`           // 单次缺口最大长度，超过视为序号重置
+	MaxMissing            int           `yaml:"max_missing"`            // 每个信源最多跟踪的缺失包数量
+	GapTimeout            time.Duration `yaml:"gap_timeout"`            // 缺失包等待补齐的超时时间
+	Resume                bool          `yaml:"resume"`                 // 重连或切换后从数据库中的检查点请求续传
+}
+
+// PayloadConfig 数据段解码校验配置
+type PayloadConfig struct {
+	Validate    bool     `yaml:"validate"`     // 是否在协议解析后解码校验数据段（配置了布局文件时自动开启）
+	DropInvalid bool     `yaml:"drop_invalid"` // 校验失败时是否丢弃数据包
+	Layouts     []string `yaml:"layouts"`      // 报文布局定义文件列表
+}
+
+// IngestConfig 入库队列配置
+// 接收到的消息先进入有界内存队列，由后台写入器批量写入数据库，读取连接不等待数据库
+type IngestConfig struct {
+	Modes         string        `yaml:"modes"`           // 数据接入方式: active(主动连接源服务器)/passive(监听端口被动接收)/both (默认active)
+	QueueSize     int           `yaml:"queue_size"`      // 内存队列容量 (默认10000)
+	BatchSize     int           `yaml:"batch_size"`      // 单次批量写入的最大消息数 (默认100)
+	FlushInterval time.Duration `yaml:"flush_interval"`  // 不足一批时的最长等待时间 (默认200ms)
+	Backpressure  string        `yaml:"backpressure"`    // 队列满时的处理方式: block/drop_oldest/spill (默认block)
+	SpillDir      string        `yaml:"spill_dir"`       // 溢出文件目录 (spill方式使用)
+	SpillMaxBytes int64         `yaml:"spill_max_bytes"` // 溢出文件最大字节数，超过后丢弃新消息 (默认1GB)
+	RetryInterval time.Duration `yaml:"retry_interval"`  // 数据库写入失败后的重试间隔 (默认1s)
+}
+
+// 入库队列背压方式常量定义
+const (
+	BackpressureBlock      = "block"       // 阻塞入队方，直到队列有空位
+	BackpressureDropOldest = "drop_oldest" // 丢弃队列中最旧的消息
+	BackpressureSpill      = "spill"       // 写入磁盘溢出文件，队列有空位后再读回
+)
+
+// 数据接入方式常量定义
+const (
+	IngestActive  = "active"  // 主动连接源服务器
+	IngestPassive = "passive" // 监听端口，被动接收客户端连接
+	IngestBoth    = "both"    // 同时使用两种方式，写入同一条处理流水线和入库队列
+)
+
+// ListenerSourceGroup 监听端口接收的消息使用的分组名称（也是数据包的源服务器ID）
+const ListenerSourceGroup = "listener"
+
+// MaxIngestBatchSize 单批最大消息数（限制单个事务的大小；超过PostgreSQL单条语句参数个数上限的多行插入会拆分为多条语句）
+const MaxIngestBatchSize = 1000
+
+// CaptureConfig 原始数据抓包配置
+// 记录从源服务器连接读取的原始字节（可选记录发往目标服务器的数据），用于排查和重放
+type CaptureConfig struct {
+	Enabled      bool   `yaml:"enabled"`        // 是否启用抓包
+	Dir          string `yaml:"dir"`            // 抓包文件目录
+	MaxFileBytes int64  `yaml:"max_file_bytes"` // 单个文件最大字节数，超过后切换新文件 (默认64MB)
+	MaxFiles     int    `yaml:"max_files"`      // 最多保留的文件数，超过后删除最旧的文件 (默认24)
+	Forwarded    bool   `yaml:"forwarded"`      // 是否同时记录发往目标服务器的数据
+}
+
+// PipelineConfig 处理流水线配置
+// 源服务器投递的每个数据包在入库前按顺序经过各阶段，未配置阶段时原样入库
+type PipelineConfig struct {
+	Stages []StageConfig `yaml:"stages"` // 处理阶段（按顺序执行）
+}
+
+// StageConfig 处理阶段配置
+// 不同类型的阶段使用不同的字段
+type StageConfig struct {
+	Type string `yaml:"type"` // 阶段类型: filter/transform/enrich/route
+	Name string `yaml:"name"` // 阶段名称（日志和状态使用，默认为阶段类型）
+
+	// filter: 按条件丢弃或保留数据包
+	Match  MatchConfig `yaml:"match"`  // 匹配条件
+	Action string      `yaml:"action"` // 匹配后的动作: drop(丢弃匹配的数据包)/keep(只保留匹配的数据包) (默认drop)
+
+	// transform: 改写入库的数据内容
+	Operation string `yaml:"operation"`  // 操作: full_package(存储完整BasePackage)/prefix/suffix(添加字节)/truncate(截断)
+	Hex       string `yaml:"hex"`        // prefix/suffix添加的字节 (十六进制字符串)
+	MaxLength int    `yaml:"max_length"` // truncate保留的最大字节数
+
+	// enrich: 为消息附加属性（写入message_queue.attributes）
+	Fields []string          `yaml:"fields"` // 从数据包提取的字段，见EnrichFields
+	Labels map[string]string `yaml:"labels"` // 固定标签
+
+	// route: 按条件选择目标服务器
+	Routes  []RouteConfig `yaml:"routes"`  // 路由规则（按顺序匹配第一条）
+	Default []string      `yaml:"default"` // 没有规则匹配时的目标服务器（为空时保持源分组的目标服务器）
+}
+
+// MatchConfig 数据包匹配条件
+// 配置的条件全部满足时匹配，列表条件满足其中一项即可；未配置任何条件时匹配所有数据包
+type MatchConfig struct {
+	Servers    []string `yaml:"servers"`     // 源服务器ID
+	Identities []string `yaml:"identities"`  // 来源身份（监听端口TLS客户端证书）
+	SourceInfo []uint32 `yaml:"source_info"` // 信源
+	HostInfo   []uint32 `yaml:"host_info"`   // 信宿
+	Types      []uint16 `yaml:"types"`       // 信息类型编号（数据段中任一数据项匹配即可）
+	MinLength  int      `yaml:"min_length"`  // 最小数据长度
+	MaxLength  int      `yaml:"max_length"`  // 最大数据长度（0表示不限制）
+}
+
+// RouteConfig 路由规则
+type RouteConfig struct {
+	Match   MatchConfig `yaml:"match"`   // 匹配条件
+	Targets []string    `yaml:"targets"` // 目标服务器ID列表
+}
+
+// 处理阶段类型常量定义
+const (
+	StageFilter    = "filter"    // 过滤
+	StageTransform = "transform" // 改写数据
+	StageEnrich    = "enrich"    // 附加属性
+	StageRoute     = "route"     // 路由
+)
+
+// filter动作常量定义
+const (
+	FilterDrop = "drop" // 丢弃匹配的数据包
+	FilterKeep = "keep" // 只保留匹配的数据包
+)
+
+// transform操作常量定义
+const (
+	TransformFullPackage = "full_package" // 存储完整BasePackage（包头+数据段）
+	TransformPrefix      = "prefix"       // 在数据前添加字节
+	TransformSuffix      = "suffix"       // 在数据后添加字节
+	TransformTruncate    = "truncate"     // 截断到最大字节数
+)
+
+// EnrichFields enrich阶段支持提取的字段
+var EnrichFields = map[string]bool{
+	"server_id":   true, // 源服务器ID
+	"source_info": true, // 信源
+	"host_info":   true, // 信宿
+	"package_no":  true, // 包序号
+	"types":       true, // 数据段中各数据项的信息类型编号
+	"received_at": true, // 接收时间
+
+	"source_identity": true, // 来源身份（监听端口TLS客户端证书）
+}
+
 // TargetServer 目标服务器配置
 type TargetServer struct {
 	ID         string        `yaml:"id"`          // 服务器唯一标识
@@ -104,8 +396,39 @@ type TargetServer struct {
 	MaxRetries int           `yaml:"max_retries"` // 最大重试次数
 	BatchSize  int           `yaml:"batch_size"`  // 批量处理大小
 	Priority   int           `yaml:"priority"`    // 优先级 (数字越小优先级越高)
+	Framing    FramingConfig `yaml:"framing"`     // 发送时的分帧方式（默认none，原样发送）
+	Proxy      ProxyConfig   `yaml:"proxy"`       // 出站代理配置（为空时直连）
 }
 
+// FramingConfig 分帧配置
+// 描述TCP字节流中的帧边界，源服务器、监听端口和目标服务器各自配置
+type FramingConfig struct {
+	Type                 string `yaml:"type"`                   // 分帧方式: none/delimiter/length_prefixed/fixed_header/slip/cobs
+	Delimiter            string `yaml:"delimiter"`              // 分隔符 (十六进制字符串，delimiter方式使用)
+	LengthWidth          int    `yaml:"length_width"`           // 长度字段字节数: 1/2/4/8
+	Endian               string `yaml:"endian"`                 // 长度字段字节序: big/little (默认big)
+	LengthIncludesHeader bool   `yaml:"length_includes_header"` // 长度值是否包含长度字段/包头本身
+	HeaderSize           int    `yaml:"header_size"`            // 包头长度 (fixed_header方式使用)
+	LengthOffset         int    `yaml:"length_offset"`          // 长度字段在包头中的偏移 (fixed_header方式使用)
+	MaxFrameSize         int    `yaml:"max_frame_size"`         // 最大帧长度（不含分帧字节）
+}
+
+// 分帧方式常量定义
+const (
+	FramingNone           = "none"            // 不分帧，每次读取作为一帧
+	FramingDelimiter      = "delimiter"       // 分隔符分帧
+	FramingLengthPrefixed = "length_prefixed" // 长度前缀分帧
+	FramingFixedHeader    = "fixed_header"    // 固定包头分帧，长度字段位于包头内
+	FramingSLIP           = "slip"            // SLIP字节填充
+	FramingCOBS           = "cobs"            // COBS字节填充
+)
+
+// 长度字段字节序常量定义
+const (
+	EndianBig    = "big"    // 大端序
+	EndianLittle = "little" // 小端序
+)
+
 // LoadConfig 从文件加载配置
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -118,9 +441,502 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	config.SourceServers.normalize()
+	config.Authentication.normalize()
+	for i := range config.SourceGroups {
+		config.SourceGroups[i].normalize(&config)
+	}
+	config.Ingest.normalize()
+	config.Server.TLS.normalize()
+	config.Protocol.normalize(config.Delimiter.MaxPacketLength)
+	config.Capture.normalize()
+
 	return &config, nil
 }
 
+// normalize 规范化源服务器列表
+// 将旧的primary/backup配置转换为列表，并补全默认权重
+func (s *SourceServers) normalize() {
+	if len(s.Servers) == 0 {
+		// 兼容旧配置：主服务器优先级0，备用服务器优先级1
+		if s.Primary.ID != "" {
+			primary := s.Primary
+			primary.Priority = 0
+			s.Servers = append(s.Servers, primary)
+		}
+		if s.Backup.ID != "" {
+			backup := s.Backup
+			backup.Priority = 1
+			s.Servers = append(s.Servers, backup)
+		}
+	}
+
+	for i := range s.Servers {
+		if s.Servers[i].Weight == 0 {
+			s.Servers[i].Weight = 1
+		}
+	}
+
+	if s.Mode == "" {
+		s.Mode = SourceModeFailover
+	}
+	if s.DedupWindow == 0 {
+		s.DedupWindow = 5 * time.Minute
+	}
+	if s.DedupCapacity == 0 {
+		s.DedupCapacity = 100000
+	}
+	if s.SessionHistory == 0 {
+		s.SessionHistory = 20
+	}
+
+	s.Reconnect.normalize()
+}
+
+// normalize 补全源分组默认配置
+// 参数: c - 顶层配置（提供认证间隔、心跳和分隔符的默认值）
+func (g *SourceGroup) normalize(c *Config) {
+	g.SourceServers.normalize()
+
+	// 认证凭据按分组配置，重新认证间隔和应答超时未配置时使用顶层配置
+	if g.Authentication.ReauthInterval == 0 {
+		g.Authentication.ReauthInterval = c.Authentication.ReauthInterval
+	}
+	if g.Authentication.ResponseTimeout == 0 {
+		g.Authentication.ResponseTimeout = c.Authentication.ResponseTimeout
+	}
+
+	if g.Heartbeat.Interval == 0 {
+		g.Heartbeat.Interval = c.Heartbeat.Interval
+	}
+	if g.Heartbeat.WriteIdleTimeout == 0 {
+		g.Heartbeat.WriteIdleTimeout = c.Heartbeat.WriteIdleTimeout
+	}
+	if g.Heartbeat.ReadIdleTimeout == 0 {
+		g.Heartbeat.ReadIdleTimeout = c.Heartbeat.ReadIdleTimeout
+	}
+	if g.Delimiter.Separator == "" {
+		g.Delimiter.Separator = c.Delimiter.Separator
+	}
+	if g.Delimiter.MaxPacketLength == 0 {
+		g.Delimiter.MaxPacketLength = c.Delimiter.MaxPacketLength
+	}
+}
+
+// Groups 获取源分组列表
+// 未配置source_groups时返回一个default分组，使用顶层的源服务器、认证、心跳和分隔符配置
+// 返回: 源分组列表
+func (c *Config) Groups() []SourceGroup {
+	if len(c.SourceGroups) > 0 {
+		return c.SourceGroups
+	}
+	return []SourceGroup{{
+		Name:           DefaultSourceGroup,
+		SourceServers:  c.SourceServers,
+		Authentication: c.Authentication,
+		Heartbeat:      c.Heartbeat,
+		Delimiter:      c.Delimiter,
+	}}
+}
+
+// ForGroup 获取源分组使用的配置
+// 返回顶层配置的副本，源服务器、认证、心跳和分隔符配置替换为分组的配置
+// 参数: group - 源分组
+// 返回: 分组配置
+func (c *Config) ForGroup(group *SourceGroup) *Config {
+	groupConfig := *c
+	groupConfig.SourceGroups = nil
+	groupConfig.SourceServers = group.SourceServers
+	groupConfig.Authentication = group.Authentication
+	groupConfig.Heartbeat = group.Heartbeat
+	groupConfig.Delimiter = group.Delimiter
+	return &groupConfig
+}
+
+// normalize 补全重连默认配置
+func (r *ReconnectConfig) normalize() {
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = time.Second
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = time.Minute
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = 2
+	}
+	if r.Jitter == 0 {
+		r.Jitter = 0.2
+	}
+	if r.BreakerThreshold == 0 {
+		r.BreakerThreshold = 5
+	}
+	if r.BreakerCooldown == 0 {
+		r.BreakerCooldown = 30 * time.Second
+	}
+}
+
+// normalize 补全身份认证默认配置
+func (a *AuthConfig) normalize() {
+	if a.ResponseTimeout == 0 {
+		a.ResponseTimeout = 10 * time.Second
+	}
+}
+
+// normalize 补全入库队列默认配置
+func (i *IngestConfig) normalize() {
+	if i.Modes == "" {
+		i.Modes = IngestActive
+	}
+	if i.QueueSize == 0 {
+		i.QueueSize = 10000
+	}
+	if i.BatchSize == 0 {
+		i.BatchSize = 100
+	}
+	if i.FlushInterval == 0 {
+		i.FlushInterval = 200 * time.Millisecond
+	}
+	if i.Backpressure == "" {
+		i.Backpressure = BackpressureBlock
+	}
+	if i.SpillMaxBytes == 0 {
+		i.SpillMaxBytes = 1 << 30
+	}
+	if i.RetryInterval == 0 {
+		i.RetryInterval = time.Second
+	}
+}
+
+// normalize 补全BasePackage编解码默认配置
+// 参数: maxPacketLength - 分隔符配置的最大数据包长度，作为数据段长度上限的默认值
+func (p *ProtocolConfig) normalize(maxPacketLength int) {
+	if p.Checksum == "" {
+		p.Checksum = ChecksumNone
+	}
+	if p.ChecksumPlacement == "" {
+		switch p.Checksum {
+		case ChecksumCRC16:
+			p.ChecksumPlacement = ChecksumReserved
+		case ChecksumCRC32:
+			p.ChecksumPlacement = ChecksumTrailer
+		}
+	}
+	if p.MaxDataLength == 0 {
+		p.MaxDataLength = maxPacketLength
+	}
+}
+
+// normalize 补全抓包默认配置
+func (c *CaptureConfig) normalize() {
+	if c.MaxFileBytes == 0 {
+		c.MaxFileBytes = 64 << 20
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = 24
+	}
+}
+
+// IsConcurrent 是否为并发接入模式
+// 返回: 是否同时连接所有启用的源服务器
+func (s *SourceServers) IsConcurrent() bool {
+	return s.Mode == SourceModeConcurrent
+}
+
+// List 获取按优先级排序的源服务器列表
+// 相同优先级的服务器保持配置顺序
+// 返回: 源服务器配置指针列表
+func (s *SourceServers) List() []*SourceServer {
+	servers := make([]*SourceServer, 0, len(s.Servers))
+	for i := range s.Servers {
+		servers = append(servers, &s.Servers[i])
+	}
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		return servers[i].Priority < servers[j].Priority
+	})
+	return servers
+}
+
+// Validate 验证分帧配置
+// 返回: 验证错误信息
+func (f *FramingConfig) Validate() error {
+	switch f.Endian {
+	case "", EndianBig, EndianLittle:
+	default:
+		return fmt.Errorf("invalid framing endian '%s', expected '%s' or '%s'", f.Endian, EndianBig, EndianLittle)
+	}
+
+	if f.MaxFrameSize < 0 {
+		return fmt.Errorf("framing max_frame_size cannot be negative")
+	}
+
+	switch f.Type {
+	case "", FramingNone, FramingSLIP, FramingCOBS:
+	case FramingDelimiter:
+		delimiter, err := hex.DecodeString(f.Delimiter)
+		if err != nil {
+			return fmt.Errorf("framing delimiter must be a hex string: %v", err)
+		}
+		if len(delimiter) == 0 {
+			return fmt.Errorf("framing delimiter is required")
+		}
+	case FramingLengthPrefixed:
+		if !validLengthWidth(f.LengthWidth) {
+			return fmt.Errorf("framing length_width must be 1, 2, 4 or 8")
+		}
+	case FramingFixedHeader:
+		if !validLengthWidth(f.LengthWidth) {
+			return fmt.Errorf("framing length_width must be 1, 2, 4 or 8")
+		}
+		if f.LengthOffset < 0 || f.LengthOffset+f.LengthWidth > f.HeaderSize {
+			return fmt.Errorf("framing length field (offset %d, width %d) must lie within header_size %d",
+				f.LengthOffset, f.LengthWidth, f.HeaderSize)
+		}
+	default:
+		return fmt.Errorf("unsupported framing type '%s'", f.Type)
+	}
+
+	return nil
+}
+
+// Validate 验证TLS配置
+// 返回: 验证错误信息
+func (t *TLSConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	// 客户端证书和私钥必须同时配置
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+
+	for _, path := range []string{t.CAFile, t.CertFile, t.KeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("tls file %s: %v", path, err)
+		}
+	}
+
+	if t.MinVersion != "" {
+		if _, ok := TLSVersions[t.MinVersion]; !ok {
+			return fmt.Errorf("invalid tls min_version '%s', expected 1.0, 1.1, 1.2 or 1.3", t.MinVersion)
+		}
+	}
+
+	return nil
+}
+
+// normalize 补全监听端口TLS默认配置
+func (t *ListenerTLSConfig) normalize() {
+	if t.ClientAuth == "" {
+		t.ClientAuth = ClientAuthNone
+		if t.ClientCAFile != "" {
+			t.ClientAuth = ClientAuthRequire
+		}
+	}
+	if t.Identity == "" {
+		t.Identity = IdentitySubject
+	}
+}
+
+// Validate 验证监听端口TLS配置
+// 返回: 验证错误信息
+func (t *ListenerTLSConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("tls cert_file and key_file are required")
+	}
+	for _, path := range []string{t.CertFile, t.KeyFile, t.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("tls file %s: %v", path, err)
+		}
+	}
+
+	switch t.ClientAuth {
+	case ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if t.ClientCAFile == "" {
+			return fmt.Errorf("tls client_ca_file is required for client_auth '%s'", t.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("invalid tls client_auth '%s', expected '%s', '%s' or '%s'",
+			t.ClientAuth, ClientAuthNone, ClientAuthOptional, ClientAuthRequire)
+	}
+
+	switch t.Identity {
+	case IdentitySubject, IdentitySAN:
+	default:
+		return fmt.Errorf("invalid tls identity '%s', expected '%s' or '%s'", t.Identity, IdentitySubject, IdentitySAN)
+	}
+
+	if t.MinVersion != "" {
+		if _, ok := TLSVersions[t.MinVersion]; !ok {
+			return fmt.Errorf("invalid tls min_version '%s', expected 1.0, 1.1, 1.2 or 1.3", t.MinVersion)
+		}
+	}
+
+	return nil
+}
+
+// Validate 验证出站代理配置
+// 返回: 验证错误信息
+func (p *ProxyConfig) Validate() error {
+	switch p.Type {
+	case "":
+		return nil
+	case ProxySOCKS5, ProxyHTTP:
+	default:
+		return fmt.Errorf("invalid proxy type '%s', expected '%s' or '%s'", p.Type, ProxySOCKS5, ProxyHTTP)
+	}
+
+	if p.Address == "" {
+		return fmt.Errorf("proxy address is required")
+	}
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return fmt.Errorf("invalid proxy address format '%s', expected 'host:port'", p.Address)
+	}
+
+	if p.Username == "" && p.Password != "" {
+		return fmt.Errorf("proxy password requires username")
+	}
+	// SOCKS5用户名/密码认证中两个字段的长度各占1字节
+	if p.Type == ProxySOCKS5 && (len(p.Username) > 255 || len(p.Password) > 255) {
+		return fmt.Errorf("socks5 proxy username and password cannot exceed 255 bytes")
+	}
+
+	return nil
+}
+
+// Validate 验证来源IP访问控制配置
+// 返回: 验证错误信息
+func (a *AccessConfig) Validate() error {
+	for _, entry := range a.Allow {
+		if _, err := ParseIPNet(entry); err != nil {
+			return fmt.Errorf("access allow: %v", err)
+		}
+	}
+	for _, entry := range a.Deny {
+		if _, err := ParseIPNet(entry); err != nil {
+			return fmt.Errorf("access deny: %v", err)
+		}
+	}
+	return nil
+}
+
+// ParseIPNet 解析访问控制条目
+// 参数: entry - CIDR或单个IP（单个IP视为/32或/128）
+// 返回: 地址范围和错误信息
+func ParseIPNet(entry string) (*net.IPNet, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR '%s'", entry)
+		}
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address '%s'", entry)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// validLengthWidth 检查长度字段字节数是否受支持
+func validLengthWidth(width int) bool {
+	return width == 1 || width == 2 || width == 4 || width == 8
+}
+
+// SourceFraming 获取源服务器使用的分帧配置
+// 未配置时使用全局分隔符配置，与原有的分隔符处理保持一致
+// 参数: server - 源服务器配置
+// 返回: 分帧配置
+func (c *Config) SourceFraming(server *SourceServer) FramingConfig {
+	if server.Framing.Type != "" {
+		return server.Framing
+	}
+	return FramingConfig{
+		Type:         FramingDelimiter,
+		Delimiter:    c.Delimiter.Separator,
+		MaxFrameSize: c.Delimiter.MaxPacketLength,
+	}
+}
+
+// ListenerFraming 获取监听端口使用的分帧配置
+// 未配置时不分帧，最大帧长度默认使用max_message_size
+// 返回: 分帧配置
+func (s *ServerConfig) ListenerFraming() FramingConfig {
+	framing := s.Framing
+	if framing.MaxFrameSize == 0 {
+		framing.MaxFrameSize = s.MaxMessageSize
+	}
+	return framing
+}
+
+// TargetFraming 获取目标服务器使用的分帧配置
+// 参数: id - 目标服务器ID
+// 返回: 分帧配置（未找到时不分帧）
+func (c *Config) TargetFraming(id string) FramingConfig {
+	for _, server := range c.TargetServers {
+		if server.ID == id {
+			return server.Framing
+		}
+	}
+	return FramingConfig{}
+}
+
+// TargetProxy 获取目标服务器使用的出站代理配置
+// 参数: id - 目标服务器ID
+// 返回: 代理配置（未找到时直连）
+func (c *Config) TargetProxy(id string) ProxyConfig {
+	for _, server := range c.TargetServers {
+		if server.ID == id {
+			return server.Proxy
+		}
+	}
+	return ProxyConfig{}
+}
+
+// ValidateFraming 验证源服务器、监听端口和目标服务器的分帧配置
+// 返回: 验证错误信息
+func (c *Config) ValidateFraming() error {
+	for _, group := range c.Groups() {
+		groupConfig := c.ForGroup(&group)
+		for i := range group.SourceServers.Servers {
+			server := &group.SourceServers.Servers[i]
+			framing := groupConfig.SourceFraming(server)
+			if err := framing.Validate(); err != nil {
+				return fmt.Errorf("source server %s: %v", server.ID, err)
+			}
+		}
+	}
+
+	if err := c.Server.Framing.Validate(); err != nil {
+		return fmt.Errorf("server: %v", err)
+	}
+
+	for i := range c.TargetServers {
+		if err := c.TargetServers[i].Framing.Validate(); err != nil {
+			return fmt.Errorf("target server %s: %v", c.TargetServers[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
 // ValidateTargetServers 验证目标服务器配置
 // 返回: 验证错误信息
 func (c *Config) ValidateTargetServers() error {
@@ -167,6 +983,11 @@ func (c *Config) ValidateTargetServers() error {
 		if server.BatchSize <= 0 {
 			return fmt.Errorf("target server %s: batch_size must be positive", server.ID)
 		}
+
+		// 验证出站代理配置
+		if err := server.Proxy.Validate(); err != nil {
+			return fmt.Errorf("target server %s: %v", server.ID, err)
+		}
 	}
 
 	return nil
@@ -175,26 +996,99 @@ func (c *Config) ValidateTargetServers() error {
 // ValidateSourceServers 验证源服务器配置
 // 返回: 验证错误信息
 func (c *Config) ValidateSourceServers() error {
-	// 验证主服务器
-	if err := c.validateSingleSourceServer(&c.SourceServers.Primary, "primary"); err != nil {
-		return err
+	if len(c.SourceServers.Servers) == 0 {
+		return fmt.Errorf("no source servers configured")
 	}
 
-	// 验证备用服务器
-	if err := c.validateSingleSourceServer(&c.SourceServers.Backup, "backup"); err != nil {
+	// 验证接入模式
+	switch c.SourceServers.Mode {
+	case SourceModeFailover, SourceModeConcurrent:
+	default:
+		return fmt.Errorf("invalid source servers mode '%s', expected '%s' or '%s'",
+			c.SourceServers.Mode, SourceModeFailover, SourceModeConcurrent)
+	}
+
+	if c.SourceServers.WarmStandby && c.SourceServers.Mode != SourceModeFailover {
+		return fmt.Errorf("source servers warm_standby only applies to '%s' mode", SourceModeFailover)
+	}
+
+	// 验证去重配置
+	if c.SourceServers.DedupWindow < 0 {
+		return fmt.Errorf("source servers dedup_window cannot be negative")
+	}
+	if c.SourceServers.DedupCapacity < 0 {
+		return fmt.Errorf("source servers dedup_capacity cannot be negative")
+	}
+	if c.SourceServers.SessionHistory < 0 {
+		return fmt.Errorf("source servers session_history cannot be negative")
+	}
+
+	// 验证重连配置
+	if err := c.SourceServers.Reconnect.validate(); err != nil {
 		return err
 	}
 
-	// 检查主备服务器地址不能相同
-	if c.SourceServers.Primary.Address == c.SourceServers.Backup.Address {
-		return fmt.Errorf("primary and backup servers cannot have the same address")
+	seenIDs := make(map[string]bool)
+	seenAddresses := make(map[string]string)
+	enabledCount := 0
+	for i := range c.SourceServers.Servers {
+		server := &c.SourceServers.Servers[i]
+		serverType := fmt.Sprintf("source #%d", i)
+
+		if err := c.validateSingleSourceServer(server, serverType); err != nil {
+			return err
+		}
+
+		// 检查ID是否重复
+		if seenIDs[server.ID] {
+			return fmt.Errorf("duplicate source server ID: %s", server.ID)
+		}
+		seenIDs[server.ID] = true
+
+		// 检查地址是否重复
+		if otherID, exists := seenAddresses[server.Address]; exists {
+			return fmt.Errorf("source servers %s and %s cannot have the same address", otherID, server.ID)
+		}
+		seenAddresses[server.Address] = server.ID
+
+		if server.Enabled {
+			enabledCount++
+		}
+	}
+
+	if enabledCount == 0 {
+		return fmt.Errorf("at least one source server must be enabled")
 	}
 
 	return nil
 }
 
+// validate 验证重连配置
+// 返回: 验证错误信息
+func (r *ReconnectConfig) validate() error {
+	if r.InitialBackoff <= 0 {
+		return fmt.Errorf("reconnect initial_backoff must be positive")
+	}
+	if r.MaxBackoff < r.InitialBackoff {
+		return fmt.Errorf("reconnect max_backoff must not be less than initial_backoff")
+	}
+	if r.Multiplier < 1 {
+		return fmt.Errorf("reconnect multiplier must be at least 1")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("reconnect jitter must be between 0 and 1")
+	}
+	if r.BreakerThreshold <= 0 {
+		return fmt.Errorf("reconnect breaker_threshold must be positive")
+	}
+	if r.BreakerCooldown <= 0 {
+		return fmt.Errorf("reconnect breaker_cooldown must be positive")
+	}
+	return nil
+}
+
 // validateSingleSourceServer 验证单个源服务器配置
-// 参数: server - 源服务器配置, serverType - 服务器类型（primary/backup）
+// 参数: server - 源服务器配置, serverType - 服务器描述（用于错误信息）
 // 返回: 验证错误信息
 func (c *Config) validateSingleSourceServer(server *SourceServer, serverType string) error {
 	// 验证必填字段
@@ -244,6 +1138,81 @@ func (c *Config) validateSingleSourceServer(server *SourceServer, serverType str
 		return fmt.Errorf("%s server: failover_threshold must be positive", serverType)
 	}
 
+	// 验证TLS配置
+	if err := server.TLS.Validate(); err != nil {
+		return fmt.Errorf("%s server: %v", serverType, err)
+	}
+
+	// 验证出站代理配置
+	if err := server.Proxy.Validate(); err != nil {
+		return fmt.Errorf("%s server: %v", serverType, err)
+	}
+
+	// 验证优先级和权重
+	if server.Priority < 0 {
+		return fmt.Errorf("%s server: priority cannot be negative", serverType)
+	}
+	if server.Weight < 0 {
+		return fmt.Errorf("%s server: weight cannot be negative", serverType)
+	}
+
+	return nil
+}
+
+// ValidateSourceGroups 验证源分组配置
+// 逐个分组验证源服务器、认证、心跳和分隔符配置；未配置source_groups时验证顶层配置
+// 返回: 验证错误信息
+func (c *Config) ValidateSourceGroups() error {
+	if len(c.SourceGroups) > 0 && len(c.SourceServers.Servers) > 0 {
+		return fmt.Errorf("source_servers.servers cannot be used together with source_groups")
+	}
+
+	targetIDs := make(map[string]bool, len(c.TargetServers))
+	for _, target := range c.TargetServers {
+		targetIDs[target.ID] = true
+	}
+
+	seenNames := make(map[string]bool)
+	serverGroups := make(map[string]string)
+	for _, group := range c.Groups() {
+		if group.Name == "" {
+			return fmt.Errorf("source group name is required")
+		}
+		if seenNames[group.Name] {
+			return fmt.Errorf("duplicate source group name: %s", group.Name)
+		}
+		if group.Name == ListenerSourceGroup {
+			return fmt.Errorf("source group name %s is reserved for the passive listener", group.Name)
+		}
+		seenNames[group.Name] = true
+
+		// 服务器ID用于连接状态、抓包和日志，跨分组也不能重复
+		for _, server := range group.SourceServers.Servers {
+			if other, exists := serverGroups[server.ID]; exists && other != group.Name {
+				return fmt.Errorf("source server ID %s is used by groups %s and %s", server.ID, other, group.Name)
+			}
+			serverGroups[server.ID] = group.Name
+		}
+
+		for _, id := range group.Targets {
+			if !targetIDs[id] {
+				return fmt.Errorf("source group %s: unknown target server '%s'", group.Name, id)
+			}
+		}
+
+		groupConfig := c.ForGroup(&group)
+		for _, validate := range []func() error{
+			groupConfig.ValidateSourceServers,
+			groupConfig.ValidateAuthentication,
+			groupConfig.ValidateHeartbeat,
+			groupConfig.ValidateDelimiter,
+		} {
+			if err := validate(); err != nil {
+				return fmt.Errorf("source group %s: %v", group.Name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -275,6 +1244,11 @@ func (c *Config) ValidateAuthentication() error {
 		return fmt.Errorf("authentication reauth_interval must be positive")
 	}
 
+	// 验证认证应答超时
+	if c.Authentication.ResponseTimeout <= 0 {
+		return fmt.Errorf("authentication response_timeout must be positive")
+	}
+
 	return nil
 }
 
@@ -319,3 +1293,291 @@ func (c *Config) ValidateDelimiter() error {
 
 	return nil
 }
+
+// ValidateProtocol 验证BasePackage编解码配置
+// 返回: 验证错误信息
+func (c *Config) ValidateProtocol() error {
+	switch c.Protocol.Checksum {
+	case ChecksumNone:
+	case ChecksumCRC16, ChecksumCRC32:
+		switch c.Protocol.ChecksumPlacement {
+		case ChecksumTrailer:
+		case ChecksumReserved:
+			// 保留字段只有2字节
+			if c.Protocol.Checksum == ChecksumCRC32 {
+				return fmt.Errorf("protocol checksum '%s' does not fit in reserved header bytes, use placement '%s'",
+					ChecksumCRC32, ChecksumTrailer)
+			}
+		default:
+			return fmt.Errorf("invalid protocol checksum_placement '%s', expected '%s' or '%s'",
+				c.Protocol.ChecksumPlacement, ChecksumReserved, ChecksumTrailer)
+		}
+	default:
+		return fmt.Errorf("invalid protocol checksum '%s', expected '%s', '%s' or '%s'",
+			c.Protocol.Checksum, ChecksumNone, ChecksumCRC16, ChecksumCRC32)
+	}
+
+	if c.Protocol.MaxDataLength < 0 {
+		return fmt.Errorf("protocol max_data_length cannot be negative")
+	}
+
+	return nil
+}
+
+// ValidateSequence 验证包序号跟踪配置
+// 未配置的参数使用默认值
+// 返回: 验证错误信息
+func (c *Config) ValidateSequence() error {
+	// 验证最多跟踪的缺失包数量
+	if c.Sequence.MaxMissing < 0 {
+		return fmt.Errorf("sequence max_missing cannot be negative")
+	}
+
+	// 验证缺失包超时时间
+	if c.Sequence.GapTimeout < 0 {
+		return fmt.Errorf("sequence gap_timeout cannot be negative")
+	}
+
+	return nil
+}
+
+// ValidatePayload 验证数据段解码校验配置
+// 布局文件内容在启动时由xftype包加载并校验
+// 返回: 验证错误信息
+func (c *Config) ValidatePayload() error {
+	for i, path := range c.Payload.Layouts {
+		if path == "" {
+			return fmt.Errorf("payload layout #%d path cannot be empty", i)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("payload layout file %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateIngest 验证入库队列配置
+// 返回: 验证错误信息
+func (c *Config) ValidateIngest() error {
+	if c.Ingest.QueueSize <= 0 {
+		return fmt.Errorf("ingest queue_size must be positive")
+	}
+	if c.Ingest.BatchSize <= 0 || c.Ingest.BatchSize > MaxIngestBatchSize {
+		return fmt.Errorf("ingest batch_size must be between 1 and %d", MaxIngestBatchSize)
+	}
+	if c.Ingest.FlushInterval <= 0 {
+		return fmt.Errorf("ingest flush_interval must be positive")
+	}
+	if c.Ingest.RetryInterval <= 0 {
+		return fmt.Errorf("ingest retry_interval must be positive")
+	}
+
+	switch c.Ingest.Modes {
+	case IngestActive, IngestPassive, IngestBoth:
+	default:
+		return fmt.Errorf("invalid ingest modes '%s', expected '%s', '%s' or '%s'",
+			c.Ingest.Modes, IngestActive, IngestPassive, IngestBoth)
+	}
+
+	switch c.Ingest.Backpressure {
+	case BackpressureBlock, BackpressureDropOldest:
+	case BackpressureSpill:
+		if c.Ingest.SpillDir == "" {
+			return fmt.Errorf("ingest spill_dir is required for backpressure '%s'", BackpressureSpill)
+		}
+		if c.Ingest.SpillMaxBytes <= 0 {
+			return fmt.Errorf("ingest spill_max_bytes must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid ingest backpressure '%s', expected '%s', '%s' or '%s'",
+			c.Ingest.Backpressure, BackpressureBlock, BackpressureDropOldest, BackpressureSpill)
+	}
+
+	return nil
+}
+
+// ActiveEnabled 是否主动连接源服务器
+// 返回: 接入方式为active或both时为true
+func (i *IngestConfig) ActiveEnabled() bool {
+	return i.Modes == IngestActive || i.Modes == IngestBoth
+}
+
+// PassiveEnabled 是否监听端口被动接收
+// 返回: 接入方式为passive或both时为true
+func (i *IngestConfig) PassiveEnabled() bool {
+	return i.Modes == IngestPassive || i.Modes == IngestBoth
+}
+
+// ValidateListener 验证监听端口配置
+// 未启用被动接收时不检查
+// 返回: 验证错误信息
+func (c *Config) ValidateListener() error {
+	if !c.Ingest.PassiveEnabled() {
+		return nil
+	}
+	if c.Server.TCPListenPort <= 0 || c.Server.TCPListenPort > 65535 {
+		return fmt.Errorf("server tcp_listen_port must be between 1 and 65535")
+	}
+	if c.Server.TCPListenPort == c.Server.HealthCheckPort {
+		return fmt.Errorf("server tcp_listen_port cannot be the same as health_check_port")
+	}
+	if c.Server.ReadTimeout <= 0 {
+		return fmt.Errorf("server read_timeout must be positive")
+	}
+	if c.Server.MaxMessageSize <= 0 {
+		return fmt.Errorf("server max_message_size must be positive")
+	}
+	if c.Server.MaxConnections < 0 || c.Server.MaxConnectionsPerIP < 0 {
+		return fmt.Errorf("server max_connections and max_connections_per_ip cannot be negative")
+	}
+	if c.Server.MaxConnections > 0 && c.Server.MaxConnectionsPerIP > c.Server.MaxConnections {
+		return fmt.Errorf("server max_connections_per_ip cannot exceed max_connections")
+	}
+	if err := c.Server.Access.Validate(); err != nil {
+		return fmt.Errorf("server %v", err)
+	}
+	if err := c.Server.TLS.Validate(); err != nil {
+		return fmt.Errorf("server %v", err)
+	}
+	// 超过max_message_size的帧无论如何分帧都不会入库
+	if c.Server.Framing.MaxFrameSize > c.Server.MaxMessageSize {
+		return fmt.Errorf("server framing max_frame_size (%d) cannot exceed max_message_size (%d)",
+			c.Server.Framing.MaxFrameSize, c.Server.MaxMessageSize)
+	}
+
+	return nil
+}
+
+// ValidateCapture 验证抓包配置
+// 返回: 验证错误信息
+func (c *Config) ValidateCapture() error {
+	if !c.Capture.Enabled {
+		return nil
+	}
+	if c.Capture.Dir == "" {
+		return fmt.Errorf("capture dir is required when capture is enabled")
+	}
+	if c.Capture.MaxFileBytes <= 0 {
+		return fmt.Errorf("capture max_file_bytes must be positive")
+	}
+	if c.Capture.MaxFiles <= 0 {
+		return fmt.Errorf("capture max_files must be positive")
+	}
+
+	return nil
+}
+
+// ValidatePipeline 验证处理流水线配置
+// 返回: 验证错误信息
+func (c *Config) ValidatePipeline() error {
+	targetIDs := make(map[string]bool, len(c.TargetServers))
+	for _, target := range c.TargetServers {
+		targetIDs[target.ID] = true
+	}
+
+	for i := range c.Pipeline.Stages {
+		stage := &c.Pipeline.Stages[i]
+		if err := stage.validate(targetIDs); err != nil {
+			name := stage.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return fmt.Errorf("pipeline stage %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validate 验证单个处理阶段配置
+// 参数: targetIDs - 已配置的目标服务器ID
+// 返回: 验证错误信息
+func (s *StageConfig) validate(targetIDs map[string]bool) error {
+	switch s.Type {
+	case StageFilter:
+		switch s.Action {
+		case "", FilterDrop, FilterKeep:
+		default:
+			return fmt.Errorf("invalid filter action '%s', expected '%s' or '%s'", s.Action, FilterDrop, FilterKeep)
+		}
+		return s.Match.Validate()
+
+	case StageTransform:
+		switch s.Operation {
+		case TransformFullPackage:
+		case TransformPrefix, TransformSuffix:
+			data, err := hex.DecodeString(s.Hex)
+			if err != nil {
+				return fmt.Errorf("transform hex must be a hex string: %v", err)
+			}
+			if len(data) == 0 {
+				return fmt.Errorf("transform hex is required for %s", s.Operation)
+			}
+		case TransformTruncate:
+			if s.MaxLength <= 0 {
+				return fmt.Errorf("transform max_length must be positive")
+			}
+		default:
+			return fmt.Errorf("invalid transform operation '%s'", s.Operation)
+		}
+		return nil
+
+	case StageEnrich:
+		if len(s.Fields) == 0 && len(s.Labels) == 0 {
+			return fmt.Errorf("enrich requires fields or labels")
+		}
+		for _, field := range s.Fields {
+			if !EnrichFields[field] {
+				return fmt.Errorf("unknown enrich field '%s'", field)
+			}
+		}
+		return nil
+
+	case StageRoute:
+		if len(s.Routes) == 0 {
+			return fmt.Errorf("route requires at least one rule")
+		}
+		for i := range s.Routes {
+			route := &s.Routes[i]
+			if len(route.Targets) == 0 {
+				return fmt.Errorf("route rule %d: targets are required", i)
+			}
+			if err := route.Match.Validate(); err != nil {
+				return fmt.Errorf("route rule %d: %v", i, err)
+			}
+			for _, id := range route.Targets {
+				if !targetIDs[id] {
+					return fmt.Errorf("route rule %d: unknown target server '%s'", i, id)
+				}
+			}
+		}
+		for _, id := range s.Default {
+			if !targetIDs[id] {
+				return fmt.Errorf("route default: unknown target server '%s'", id)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("invalid stage type '%s', expected filter, transform, enrich or route", s.Type)
+	}
+}
+
+// Validate 验证匹配条件
+// 返回: 验证错误信息
+func (m *MatchConfig) Validate() error {
+	if m.MinLength < 0 || m.MaxLength < 0 {
+		return fmt.Errorf("match min_length and max_length cannot be negative")
+	}
+	if m.MaxLength > 0 && m.MinLength > m.MaxLength {
+		return fmt.Errorf("match min_length cannot exceed max_length")
+	}
+	return nil
+}
+
+// PayloadEnabled 是否启用数据段解码校验
+// 返回: 显式开启或配置了布局文件时为true
+func (c *Config) PayloadEnabled() bool {
+	return c.Payload.Validate || len(c.Payload.Layouts) > 0
+}
@@ -1,18 +1,41 @@
 // internal/database/models.go
 package database
 
-import "time"
+import (
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
 
 // Message 消息数据模型
 // 对应message_queue表，存储接收到的TCP消息
 type Message struct {
-	ID           int64      `db:"id"`            // 消息ID，主键
-	SourceIP     string     `db:"source_ip"`     // 来源IP地址
-	OriginalData []byte     `db:"original_data"` // 原始消息数据
-	DataLength   int        `db:"data_length"`   // 数据长度
-	CreatedAt    time.Time  `db:"created_at"`    // 创建时间
-	ProcessedAt  *time.Time `db:"processed_at"`  // 处理完成时间
-	Status       string     `db:"status"`        // 消息状态
+	ID             int64      `db:"id"`              // 消息ID，主键
+	SourceIP       string     `db:"source_ip"`       // 来源IP地址
+	SourceIdentity string     `db:"source_identity"` // 来源身份（监听端口TLS客户端证书，为空时不入库）
+	OriginalData   []byte     `db:"original_data"`   // 原始消息数据
+	DataLength     int        `db:"data_length"`     // 数据长度
+	CreatedAt      time.Time  `db:"created_at"`      // 创建时间
+	ProcessedAt    *time.Time `db:"processed_at"`    // 处理完成时间
+	Status         string     `db:"status"`          // 消息状态
+	SourceGroup    string     `db:"source_group"`    // 源分组名称（为空时记为default）
+
+	Attributes map[string]string `db:"attributes"` // 处理流水线附加的属性（为空时不入库）
+
+	Targets    []string          // 投递的目标服务器ID（为空时投递到所有启用的目标服务器，不入库）
+	Checkpoint *SourceCheckpoint // 写入后推进的续传检查点（为nil时不更新）
+
+	CheckpointOnly bool // 只推进续传检查点，不保存消息（被丢弃但已按序确认的数据包）
+}
+
+// SourceCheckpoint 续传检查点模型
+// 对应source_checkpoints表，记录每个源分组内各信源/信宿已写入数据库的最后一个包序号
+type SourceCheckpoint struct {
+	SourceGroup string    `db:"source_group"` // 源分组名称
+	SourceInfo  uint32    `db:"source_info"`  // 信源
+	HostInfo    uint32    `db:"host_info"`    // 信宿
+	PackageNo   uint64    `db:"package_no"`   // 已写入的最后一个包序号
+	UpdatedAt   time.Time `db:"updated_at"`   // 更新时间
 }
 
 // TargetDeliveryStatus 目标投递状态模型
@@ -38,19 +61,21 @@ type TargetDeliveryStatus struct {
 // TargetServer 目标服务器配置模型
 // 对应target_servers表，存储目标服务器信息
 type TargetServer struct {
-	ID                string        `db:"id"`                // 服务器ID
-	Name              string        `db:"name"`              // 服务器名称
-	Address           string        `db:"address"`           // 服务器地址
-	Enabled           bool          `db:"enabled"`           // 是否启用
-	IsOnline          bool          `db:"is_online"`         // 是否在线
-	LastHealthCheck   *time.Time    `db:"last_health_check"` // 最后健康检查时间
-	Timeout           time.Duration // 连接超时时间
-	MaxRetries        int           `db:"max_retries"`         // 最大重试次数
-	BatchSize         int           `db:"batch_size"`          // 批量大小
-	Priority          int           `db:"priority"`            // 新增优先级字段
-	TotalMessagesSent int64         `db:"total_messages_sent"` // 总发送消息数
-	TotalErrors       int64         `db:"total_errors"`        // 总错误数
-	LastSuccessAt     *time.Time    `db:"last_success_at"`     // 最后成功时间
+	ID                string               `db:"id"`                // 服务器ID
+	Name              string               `db:"name"`              // 服务器名称
+	Address           string               `db:"address"`           // 服务器地址
+	Enabled           bool                 `db:"enabled"`           // 是否启用
+	IsOnline          bool                 `db:"is_online"`         // 是否在线
+	LastHealthCheck   *time.Time           `db:"last_health_check"` // 最后健康检查时间
+	Timeout           time.Duration        // 连接超时时间
+	MaxRetries        int                  `db:"max_retries"`         // 最大重试次数
+	BatchSize         int                  `db:"batch_size"`          // 批量大小
+	Priority          int                  `db:"priority"`            // 新增优先级字段
+	TotalMessagesSent int64                `db:"total_messages_sent"` // 总发送消息数
+	TotalErrors       int64                `db:"total_errors"`        // 总错误数
+	LastSuccessAt     *time.Time           `db:"last_success_at"`     // 最后成功时间
+	Framing           config.FramingConfig // 发送时的分帧方式（来自配置文件，不入库）
+	Proxy             config.ProxyConfig   // 出站代理配置（来自配置文件，不入库）
 }
 
 // 消息状态常量定义
@@ -4,8 +4,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"tcp-proxy-bridge/internal/config"
@@ -52,51 +54,361 @@ func NewPostgres(cfg config.DatabaseConfig) (*Postgres, error) {
 	return &Postgres{db: db}, nil
 }
 
-// SaveMessage 保存接收到的消息到数据库
-// 同时为每个启用的目标服务器创建投递状态记录
-// 参数: msg - 要保存的消息对象
+// SaveMessage 在一个事务中保存接收到的消息
+// 同时为消息路由到的每个启用的目标服务器创建投递状态记录，最后推进续传检查点；任一语句失败时整体回滚
+// 参数: msg - 要保存的消息对象（只推进检查点的消息不保存）
 // 返回: 错误信息
 func (p *Postgres) SaveMessage(msg *Message) error {
-	// SQL插入语句，返回生成的ID和创建时间
-	query := `INSERT INTO message_queue (source_ip, original_data, data_length, status) 
-              VALUES ($1, $2, $3, $4) RETURNING id, created_at`
-
-	// 执行插入操作
-	err := p.db.QueryRow(query, msg.SourceIP, msg.OriginalData, msg.DataLength, msg.Status).
-		Scan(&msg.ID, &msg.CreatedAt)
+	var targets []*TargetServer
+	if !msg.CheckpointOnly {
+		// 获取所有启用的目标服务器
+		var err error
+		if targets, err = p.GetEnabledTargetServers(); err != nil {
+			return fmt.Errorf("failed to get target servers: %v", err)
+		}
+	}
 
+	tx, err := p.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to save message: %v", err)
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if !msg.CheckpointOnly {
+		// SQL插入语句，返回生成的ID和创建时间
+		query := `INSERT INTO message_queue (source_ip, source_identity, original_data, data_length, status, source_group, attributes) 
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+
+		attributes, err := messageAttributes(msg)
+		if err != nil {
+			return err
+		}
+
+		// 执行插入操作
+		err = tx.QueryRow(query, msg.SourceIP, messageIdentity(msg), msg.OriginalData, msg.DataLength, msg.Status, messageGroup(msg), attributes).
+			Scan(&msg.ID, &msg.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save message: %v", err)
+		}
+
+		// 为消息路由到的每个目标服务器创建投递状态记录
+		for _, target := range routeTargets(targets, msg) {
+			delivery := &TargetDeliveryStatus{
+				MessageID:        msg.ID,
+				TargetServerID:   target.ID,
+				TargetServerName: target.Name,
+				TargetAddress:    target.Address,
+				Status:           StatusPending,
+				MaxAttempts:      target.MaxRetries,
+				DataSize:         msg.DataLength,
+			}
+
+			if err := createDeliveryStatus(tx, delivery); err != nil {
+				return fmt.Errorf("failed to create delivery status for target %s: %v", target.ID, err)
+			}
+		}
 	}
 
-	log.Printf("Successfully saved message %d from %s", msg.ID, msg.SourceIP)
+	// 最后推进续传检查点，与消息在同一事务中提交
+	if err := saveCheckpoints(tx, []*Message{msg}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit message: %v", err)
+	}
+
+	if !msg.CheckpointOnly {
+		log.Printf("Successfully saved message %d from %s", msg.ID, msg.SourceIP)
+	}
+	return nil
+}
 
-	// 获取所有启用的目标服务器
+// SaveMessages 在一个事务中批量保存消息
+// 同时为消息路由到的每个启用的目标服务器创建投递状态记录；任一语句失败时整批回滚
+// 参数: msgs - 要保存的消息列表（成功后回填ID和创建时间；只推进检查点的消息不保存）
+// 返回: 错误信息
+func (p *Postgres) SaveMessages(msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	// 获取所有启用的目标服务器（整批只查询一次）
 	targets, err := p.GetEnabledTargetServers()
 	if err != nil {
 		return fmt.Errorf("failed to get target servers: %v", err)
 	}
 
-	// 为每个目标服务器创建投递状态记录
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 多行插入消息（只推进检查点的消息不保存），RETURNING的顺序与VALUES一致
+	stored := make([]*Message, 0, len(msgs))
+	rows := make([][]interface{}, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.CheckpointOnly {
+			continue
+		}
+		attributes, err := messageAttributes(msg)
+		if err != nil {
+			return err
+		}
+		stored = append(stored, msg)
+		rows = append(rows, []interface{}{msg.SourceIP, messageIdentity(msg), msg.OriginalData, msg.DataLength, msg.Status, messageGroup(msg), attributes})
+	}
+	i := 0
+	for _, stmt := range buildValuesStatements(`INSERT INTO message_queue (source_ip, source_identity, original_data, data_length, status, source_group, attributes) VALUES `,
+		" RETURNING id, created_at", rows) {
+		result, err := tx.Query(stmt.query, stmt.args...)
+		if err != nil {
+			return fmt.Errorf("failed to save messages: %v", err)
+		}
+		for result.Next() {
+			if i >= len(stored) {
+				break
+			}
+			if err := result.Scan(&stored[i].ID, &stored[i].CreatedAt); err != nil {
+				result.Close()
+				return fmt.Errorf("failed to scan saved message: %v", err)
+			}
+			i++
+		}
+		result.Close()
+		if err := result.Err(); err != nil {
+			return fmt.Errorf("failed to save messages: %v", err)
+		}
+	}
+	if i != len(stored) {
+		return fmt.Errorf("saved %d of %d messages", i, len(stored))
+	}
+
+	// 多行插入投递状态，每条消息 × 路由到的目标服务器各一行
+	rows = rows[:0]
+	for _, msg := range stored {
+		for _, target := range routeTargets(targets, msg) {
+			rows = append(rows, []interface{}{msg.ID, target.ID, target.Name, target.Address,
+				StatusPending, target.MaxRetries, msg.DataLength})
+		}
+	}
+	for _, stmt := range buildValuesStatements(`INSERT INTO target_delivery_status 
+              (message_id, target_server_id, target_server_name, target_address, 
+               status, max_attempts, data_size) VALUES `, "", rows) {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to create delivery status: %v", err)
+		}
+	}
+
+	// 推进续传检查点，与消息在同一事务中提交
+	if err := saveCheckpoints(tx, msgs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit messages: %v", err)
+	}
+	return nil
+}
+
+// maxQueryParams PostgreSQL单条语句可绑定的最大参数个数
+const maxQueryParams = 65535
+
+// valuesStatement 一条多行插入语句及其参数
+type valuesStatement struct {
+	query string
+	args  []interface{}
+}
+
+// buildValuesStatements 构建多行插入语句
+// 参数个数超过PostgreSQL单条语句上限时按行拆分为多条，在同一事务中依次执行
+// 参数: prefix - VALUES及之前的语句, suffix - VALUES之后的子句（如RETURNING）, rows - 每行的参数（各行列数相同）
+// 返回: 按行顺序排列的语句列表
+func buildValuesStatements(prefix, suffix string, rows [][]interface{}) []valuesStatement {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	perStatement := maxQueryParams / len(rows[0])
+	statements := make([]valuesStatement, 0, (len(rows)+perStatement-1)/perStatement)
+	for start := 0; start < len(rows); start += perStatement {
+		end := start + perStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var query strings.Builder
+		query.WriteString(prefix)
+		args := make([]interface{}, 0, (end-start)*len(rows[0]))
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j, arg := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, arg)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteString(")")
+		}
+		query.WriteString(suffix)
+		statements = append(statements, valuesStatement{query: query.String(), args: args})
+	}
+	return statements
+}
+
+// messageGroup 获取消息的源分组名称
+// 参数: msg - 消息
+// 返回: 源分组名称（未设置时为default）
+func messageGroup(msg *Message) string {
+	if msg.SourceGroup == "" {
+		return config.DefaultSourceGroup
+	}
+	return msg.SourceGroup
+}
+
+// messageIdentity 获取消息来源身份的入库值
+// 参数: msg - 消息
+// 返回: 来源身份（未设置时为nil，入库为NULL）
+func messageIdentity(msg *Message) interface{} {
+	if msg.SourceIdentity == "" {
+		return nil
+	}
+	return msg.SourceIdentity
+}
+
+// messageAttributes 获取消息附加属性的入库值
+// 参数: msg - 消息
+// 返回: JSON字符串（没有附加属性时为nil，入库为NULL）和错误信息
+func messageAttributes(msg *Message) (interface{}, error) {
+	if len(msg.Attributes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(msg.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message attributes: %v", err)
+	}
+	return string(data), nil
+}
+
+// routeTargets 获取消息路由到的目标服务器
+// 参数: targets - 启用的目标服务器, msg - 消息
+// 返回: 目标服务器列表（消息未指定目标时为全部启用的目标服务器）
+func routeTargets(targets []*TargetServer, msg *Message) []*TargetServer {
+	if len(msg.Targets) == 0 {
+		return targets
+	}
+
+	routed := make([]*TargetServer, 0, len(msg.Targets))
 	for _, target := range targets {
-		delivery := &TargetDeliveryStatus{
-			MessageID:        msg.ID,
-			TargetServerID:   target.ID,
-			TargetServerName: target.Name,
-			TargetAddress:    target.Address,
-			Status:           StatusPending,
-			MaxAttempts:      target.MaxRetries,
-			DataSize:         msg.DataLength,
+		for _, id := range msg.Targets {
+			if target.ID == id {
+				routed = append(routed, target)
+				break
+			}
 		}
+	}
+	return routed
+}
+
+// execer 可执行SQL语句的对象（*sql.DB或*sql.Tx）
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
 
-		if err := p.CreateDeliveryStatus(delivery); err != nil {
-			log.Printf("Failed to create delivery status for target %s: %v", target.ID, err)
+// saveCheckpoints 按消息顺序更新各源分组内信源/信宿的续传检查点
+// 同一信源/信宿以最后一条消息的包序号为准（包序号重置后检查点随之回退）
+// 参数: ex - 数据库或事务, msgs - 消息列表
+// 返回: 错误信息
+func saveCheckpoints(ex execer, msgs []*Message) error {
+	type pair struct {
+		group        string
+		source, host uint32
+	}
+	latest := make(map[pair]uint64)
+	var order []pair
+	for _, msg := range msgs {
+		if msg.Checkpoint == nil {
+			continue
 		}
+		key := pair{messageGroup(msg), msg.Checkpoint.SourceInfo, msg.Checkpoint.HostInfo}
+		if _, exists := latest[key]; !exists {
+			order = append(order, key)
+		}
+		latest[key] = msg.Checkpoint.PackageNo
 	}
 
+	query := `INSERT INTO source_checkpoints (source_group, source_info, host_info, package_no, updated_at) 
+              VALUES ($1, $2, $3, $4, NOW()) 
+              ON CONFLICT (source_group, source_info, host_info) 
+              DO UPDATE SET package_no = EXCLUDED.package_no, updated_at = EXCLUDED.updated_at`
+	for _, key := range order {
+		// BIGINT为有符号数，包序号按位转换保存
+		if _, err := ex.Exec(query, key.group, int64(key.source), int64(key.host), int64(latest[key])); err != nil {
+			return fmt.Errorf("failed to save checkpoint for group %s source %d host %d: %v",
+				key.group, key.source, key.host, err)
+		}
+	}
 	return nil
 }
 
+// GroupCheckpoints 单个源分组的续传检查点存储
+type GroupCheckpoints struct {
+	db    *Postgres
+	group string
+}
+
+// CheckpointsFor 获取源分组的续传检查点存储
+// 参数: group - 源分组名称
+// 返回: 检查点存储
+func (p *Postgres) CheckpointsFor(group string) *GroupCheckpoints {
+	return &GroupCheckpoints{db: p, group: group}
+}
+
+// LoadCheckpoints 获取分组内所有信源/信宿的续传检查点
+// 返回: 检查点列表和错误信息
+func (g *GroupCheckpoints) LoadCheckpoints() ([]*SourceCheckpoint, error) {
+	return g.db.LoadCheckpoints(g.group)
+}
+
+// LoadCheckpoints 获取源分组内所有信源/信宿的续传检查点
+// 参数: group - 源分组名称
+// 返回: 检查点列表和错误信息
+func (p *Postgres) LoadCheckpoints(group string) ([]*SourceCheckpoint, error) {
+	query := `SELECT source_info, host_info, package_no, updated_at 
+              FROM source_checkpoints 
+              WHERE source_group = $1 
+              ORDER BY source_info, host_info`
+
+	rows, err := p.db.Query(query, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoints: %v", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*SourceCheckpoint
+	for rows.Next() {
+		var sourceInfo, hostInfo, packageNo int64
+		checkpoint := &SourceCheckpoint{SourceGroup: group}
+		if err := rows.Scan(&sourceInfo, &hostInfo, &packageNo, &checkpoint.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %v", err)
+		}
+		checkpoint.SourceInfo = uint32(sourceInfo)
+		checkpoint.HostInfo = uint32(hostInfo)
+		checkpoint.PackageNo = uint64(packageNo)
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
 // GetPendingMessagesForTarget 获取指定目标服务器的待处理消息
 // 参数: targetID - 目标服务器ID, limit - 最大返回数量
 // 返回: 消息列表和错误信息
@@ -231,12 +543,19 @@ func (p *Postgres) GetEnabledTargetServers() ([]*TargetServer, error) {
 // 参数: delivery - 投递状态信息
 // 返回: 错误信息
 func (p *Postgres) CreateDeliveryStatus(delivery *TargetDeliveryStatus) error {
+	return createDeliveryStatus(p.db, delivery)
+}
+
+// createDeliveryStatus 在数据库或事务中创建投递状态记录
+// 参数: ex - 数据库或事务, delivery - 投递状态信息
+// 返回: 错误信息
+func createDeliveryStatus(ex execer, delivery *TargetDeliveryStatus) error {
 	query := `INSERT INTO target_delivery_status 
               (message_id, target_server_id, target_server_name, target_address, 
                status, max_attempts, data_size) 
               VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
-	_, err := p.db.Exec(
+	_, err := ex.Exec(
 		query,
 		delivery.MessageID,
 		delivery.TargetServerID,
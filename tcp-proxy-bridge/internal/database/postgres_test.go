@@ -0,0 +1,69 @@
+// internal/database/postgres_test.go
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildValuesStatements(t *testing.T) {
+	const columns = 7
+	tests := []struct {
+		name           string
+		rows           int
+		wantStatements int
+	}{
+		{name: "empty", rows: 0, wantStatements: 0},
+		{name: "single row", rows: 1, wantStatements: 1},
+		{name: "at limit", rows: maxQueryParams / columns, wantStatements: 1},
+		{name: "over limit", rows: maxQueryParams/columns + 1, wantStatements: 2},
+		// 单批最大消息数 × 10个目标服务器
+		{name: "max batch with 10 targets", rows: 1000 * 10, wantStatements: 2},
+		{name: "max batch with 50 targets", rows: 1000 * 50, wantStatements: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := make([][]interface{}, tt.rows)
+			for i := range rows {
+				rows[i] = make([]interface{}, columns)
+				for j := range rows[i] {
+					rows[i][j] = i*columns + j
+				}
+			}
+
+			statements := buildValuesStatements("INSERT INTO t VALUES ", " RETURNING id", rows)
+			if len(statements) != tt.wantStatements {
+				t.Fatalf("got %d statements, want %d", len(statements), tt.wantStatements)
+			}
+
+			next := 0
+			for i, stmt := range statements {
+				if len(stmt.args) > maxQueryParams || len(stmt.args)%columns != 0 {
+					t.Fatalf("statement %d binds %d params", i, len(stmt.args))
+				}
+				if !strings.HasPrefix(stmt.query, "INSERT INTO t VALUES ($1, $2, ") || !strings.HasSuffix(stmt.query, " RETURNING id") {
+					t.Errorf("statement %d query = %.40q...", i, stmt.query)
+				}
+				// 每条语句的占位符从$1开始编号，最后一个占位符与参数个数一致
+				if last := fmt.Sprintf("$%d)", len(stmt.args)); !strings.HasSuffix(strings.TrimSuffix(stmt.query, " RETURNING id"), last) {
+					t.Errorf("statement %d does not end with %s", i, last)
+				}
+				if rowCount := strings.Count(stmt.query, "("); rowCount != len(stmt.args)/columns {
+					t.Errorf("statement %d has %d value rows for %d params", i, rowCount, len(stmt.args))
+				}
+				// 参数按行顺序排列
+				for _, arg := range stmt.args {
+					if arg != next {
+						t.Fatalf("statement %d arg = %v, want %d", i, arg, next)
+					}
+					next++
+				}
+			}
+			if next != tt.rows*columns {
+				t.Errorf("bound %d params, want %d", next, tt.rows*columns)
+			}
+		})
+	}
+}
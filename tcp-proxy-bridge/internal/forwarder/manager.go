@@ -5,13 +5,15 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net"
 	"sync"
 	"time"
 
+	"tcp-proxy-bridge/internal/capture"
 	"tcp-proxy-bridge/internal/config"
 	"tcp-proxy-bridge/internal/database"
+	"tcp-proxy-bridge/internal/framing"
 	"tcp-proxy-bridge/internal/metrics"
+	"tcp-proxy-bridge/internal/proxy"
 )
 
 // Manager 转发器管理器
@@ -21,6 +23,8 @@ type Manager struct {
 	db      *database.Postgres       // 数据库实例
 	targets []*database.TargetServer // 目标服务器列表
 
+	recorder *capture.Recorder // 抓包记录器，记录发往目标服务器的数据（未启用时为nil）
+
 	workers   map[string]*Worker // 工作器映射表
 	mu        sync.RWMutex       // 读写锁
 	isRunning bool               // 运行状态
@@ -35,6 +39,9 @@ type Worker struct {
 	target       *database.TargetServer  // 目标服务器
 	db           *database.Postgres      // 数据库实例
 	config       *config.ForwarderConfig // 转发配置
+	framer       framing.Framer          // 分帧器（只用于编码）
+	dialer       *proxy.Dialer           // 拨号器（直连或经出站代理）
+	recorder     *capture.Recorder       // 抓包记录器（未启用时为nil）
 	isRunning    bool                    // 运行状态
 	shutdownChan chan struct{}           // 关闭信号通道
 	wg           sync.WaitGroup          // 等待组
@@ -53,6 +60,16 @@ func NewManager(cfg *config.ForwarderConfig, db *database.Postgres, targets []*d
 	}
 }
 
+// SetRecorder 设置抓包记录器，记录发往目标服务器的每次写入
+// 需要在Start之前调用
+// 参数: recorder - 抓包记录器
+func (m *Manager) SetRecorder(recorder *capture.Recorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recorder = recorder
+}
+
 // Start 启动转发器管理器
 // 参数: ctx - 上下文
 // 返回: 错误信息
@@ -70,6 +87,7 @@ func (m *Manager) Start(ctx context.Context) error {
 	for _, target := range m.targets {
 		if target.Enabled {
 			worker := NewWorker(target, m.db, m.config)
+			worker.recorder = m.recorder
 			m.workers[target.ID] = worker
 			go worker.Start(ctx)
 			log.Printf("Started worker for target server: %s (%s)", target.Name, target.Address)
@@ -121,10 +139,19 @@ func (m *Manager) Stop(ctx context.Context) error {
 // 参数: target - 目标服务器, db - 数据库实例, cfg - 转发配置
 // 返回: 工作器实例
 func NewWorker(target *database.TargetServer, db *database.Postgres, cfg *config.ForwarderConfig) *Worker {
+	framer, err := framing.New(target.Framing)
+	if err != nil {
+		// 分帧配置已在启动时校验，这里仅作兜底
+		log.Printf("Failed to create framer for target %s, sending raw data: %v", target.Name, err)
+		framer = framing.NewRawFramer(framing.DefaultMaxFrameSize)
+	}
+
 	return &Worker{
 		target:       target,
 		db:           db,
 		config:       cfg,
+		framer:       framer,
+		dialer:       proxy.NewDialer(target.Proxy),
 		shutdownChan: make(chan struct{}),
 	}
 }
@@ -263,8 +290,14 @@ func (w *Worker) processSingleMessage(ctx context.Context, message *database.Mes
 // 参数: message - 要发送的消息
 // 返回: 错误信息
 func (w *Worker) sendToTarget(message *database.Message) error {
-	// 建立TCP连接到目标服务器
-	conn, err := net.DialTimeout("tcp", w.target.Address, w.target.Timeout)
+	// 按目标服务器的分帧方式编码
+	frame, err := w.framer.Encode(message.OriginalData)
+	if err != nil {
+		return fmt.Errorf("failed to frame message for target server %s: %v", w.target.Address, err)
+	}
+
+	// 建立TCP连接到目标服务器（配置出站代理时经代理建立隧道）
+	conn, err := w.dialer.DialContext(context.Background(), w.target.Timeout, w.target.Address)
 	if err != nil {
 		return fmt.Errorf("failed to connect to target server %s: %v", w.target.Address, err)
 	}
@@ -274,11 +307,15 @@ func (w *Worker) sendToTarget(message *database.Message) error {
 	conn.SetWriteDeadline(time.Now().Add(w.target.Timeout))
 
 	// 发送消息数据
-	_, err = conn.Write(message.OriginalData)
+	_, err = conn.Write(frame)
 	if err != nil {
 		return fmt.Errorf("failed to send data to target server %s: %v", w.target.Address, err)
 	}
 
+	if w.recorder != nil {
+		w.recorder.Record(capture.DirectionOut, w.target.ID, w.target.Address, frame)
+	}
+
 	return nil
 }
 
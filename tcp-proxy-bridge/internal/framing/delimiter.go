@@ -0,0 +1,77 @@
+// internal/framing/delimiter.go
+package framing
+
+import (
+	"bytes"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// DelimiterFramer 分隔符分帧器
+// 帧之间以固定的分隔符分割，帧内容不能包含分隔符
+type DelimiterFramer struct {
+	streamBuffer
+	delimiter []byte // 分隔符
+}
+
+// NewDelimiterFramer 创建分隔符分帧器
+// 参数: delimiter - 分隔符, maxFrameSize - 最大帧长度
+// 返回: 分帧器实例
+func NewDelimiterFramer(delimiter []byte, maxFrameSize int) *DelimiterFramer {
+	return &DelimiterFramer{
+		streamBuffer: streamBuffer{buf: make([]byte, 0, 4096), maxFrameSize: maxFrameSize},
+		delimiter:    delimiter,
+	}
+}
+
+// Name 分帧方式名称
+func (f *DelimiterFramer) Name() string { return config.FramingDelimiter }
+
+// Decode 追加数据并按分隔符切分出完整的帧
+// 缓冲区超过最大帧长度仍未找到分隔符时丢弃缓冲区并返回ErrFrameTooLarge，
+// 该帧剩余的数据在下一个分隔符之前全部跳过
+func (f *DelimiterFramer) Decode(data []byte) ([][]byte, error) {
+	f.buf = append(f.buf, data...)
+
+	var frames [][]byte
+	var err error
+	for {
+		index := bytes.Index(f.buf, f.delimiter)
+		if index == -1 {
+			if f.discarding {
+				f.discard(len(f.delimiter) - 1)
+			} else if len(f.buf) > f.maxFrameSize+len(f.delimiter) {
+				err = frameTooLarge(len(f.buf), f.maxFrameSize)
+				f.discard(len(f.delimiter) - 1)
+			}
+			return frames, err
+		}
+
+		// 空帧（连续的分隔符）直接跳过，超长帧丢弃
+		switch {
+		case f.discarding:
+			// 已丢弃的超长帧到此结束
+			f.discarding = false
+		case index > f.maxFrameSize:
+			err = frameTooLarge(index, f.maxFrameSize)
+		case index > 0:
+			frame := make([]byte, index)
+			copy(frame, f.buf[:index])
+			frames = append(frames, frame)
+		}
+		f.buf = f.buf[index+len(f.delimiter):]
+	}
+}
+
+// Encode 在数据末尾添加分隔符
+func (f *DelimiterFramer) Encode(frame []byte) ([]byte, error) {
+	out := make([]byte, len(frame)+len(f.delimiter))
+	copy(out, frame)
+	copy(out[len(frame):], f.delimiter)
+	return out, nil
+}
+
+// Delimiter 获取分隔符
+func (f *DelimiterFramer) Delimiter() []byte {
+	return f.delimiter
+}
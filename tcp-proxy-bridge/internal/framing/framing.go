@@ -0,0 +1,148 @@
+// internal/framing/framing.go
+package framing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// DefaultMaxFrameSize 未配置时的最大帧长度
+const DefaultMaxFrameSize = 65536
+
+// ErrFrameTooLarge 帧长度超过上限
+var ErrFrameTooLarge = errors.New("frame exceeds max frame size")
+
+// ErrOutOfSync 帧边界丢失
+// 长度字段类分帧方式遇到非法或超长的长度字段后无法在字节流中重新找到帧边界，调用方应断开连接
+var ErrOutOfSync = errors.New("frame boundary lost")
+
+// Framer 分帧器
+// 负责在TCP字节流中识别帧边界，以及为待发送的数据添加分帧字节。
+// Decode带有内部缓冲区，每个连接需要使用独立的实例；Encode不修改内部状态。
+type Framer interface {
+	Name() string                         // 分帧方式名称
+	Decode(data []byte) ([][]byte, error) // 追加接收到的数据，返回已完整的帧（不含分帧字节）
+	Encode(frame []byte) ([]byte, error)  // 为一帧数据添加分帧字节
+	Buffered() int                        // 缓冲区中尚未成帧的字节数
+	Reset()                               // 清空缓冲区
+}
+
+// New 根据配置创建分帧器
+// 参数: cfg - 分帧配置
+// 返回: 分帧器实例和错误信息
+func New(cfg config.FramingConfig) (Framer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	maxFrameSize := cfg.MaxFrameSize
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if cfg.Endian == config.EndianLittle {
+		order = binary.LittleEndian
+	}
+
+	switch cfg.Type {
+	case "", config.FramingNone:
+		return NewRawFramer(maxFrameSize), nil
+	case config.FramingDelimiter:
+		delimiter, err := hex.DecodeString(cfg.Delimiter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delimiter: %v", err)
+		}
+		return NewDelimiterFramer(delimiter, maxFrameSize), nil
+	case config.FramingLengthPrefixed:
+		return NewLengthPrefixedFramer(cfg.LengthWidth, order, cfg.LengthIncludesHeader, maxFrameSize), nil
+	case config.FramingFixedHeader:
+		return NewFixedHeaderFramer(cfg.HeaderSize, cfg.LengthOffset, cfg.LengthWidth, order,
+			cfg.LengthIncludesHeader, maxFrameSize), nil
+	case config.FramingSLIP:
+		return NewSLIPFramer(maxFrameSize), nil
+	case config.FramingCOBS:
+		return NewCOBSFramer(maxFrameSize), nil
+	default:
+		return nil, fmt.Errorf("unsupported framing type: %s", cfg.Type)
+	}
+}
+
+// streamBuffer 分帧器共用的接收缓冲区
+type streamBuffer struct {
+	buf          []byte // 尚未成帧的数据
+	maxFrameSize int    // 最大帧长度
+	discarding   bool   // 是否正在跳过超长帧的剩余数据（直到下一个帧边界）
+}
+
+// Buffered 缓冲区中尚未成帧的字节数（跳过超长帧时为0）
+func (b *streamBuffer) Buffered() int {
+	if b.discarding {
+		return 0
+	}
+	return len(b.buf)
+}
+
+// Reset 清空缓冲区
+func (b *streamBuffer) Reset() {
+	b.buf = b.buf[:0]
+	b.discarding = false
+}
+
+// discard 丢弃缓冲区中的数据，并跳过之后的数据直到下一个帧边界
+// 避免超长帧被截断后剩余的部分被当作一个新帧
+// 参数: keep - 保留缓冲区末尾的字节数（帧边界可能被拆分到两次读取中）
+func (b *streamBuffer) discard(keep int) {
+	if len(b.buf) > keep {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-keep:]...)
+	}
+	b.discarding = true
+}
+
+// frameTooLarge 生成帧超长错误
+func frameTooLarge(size, max int) error {
+	return fmt.Errorf("%w: %d bytes, max %d", ErrFrameTooLarge, size, max)
+}
+
+// RawFramer 不分帧，每次读取的数据作为一帧（兼容旧的被动接收行为）
+type RawFramer struct {
+	maxFrameSize int
+}
+
+// NewRawFramer 创建不分帧的分帧器
+// 参数: maxFrameSize - 最大帧长度
+// 返回: 分帧器实例
+func NewRawFramer(maxFrameSize int) *RawFramer {
+	return &RawFramer{maxFrameSize: maxFrameSize}
+}
+
+// Name 分帧方式名称
+func (f *RawFramer) Name() string { return config.FramingNone }
+
+// Decode 将本次数据整体作为一帧
+func (f *RawFramer) Decode(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) > f.maxFrameSize {
+		return nil, frameTooLarge(len(data), f.maxFrameSize)
+	}
+	frame := make([]byte, len(data))
+	copy(frame, data)
+	return [][]byte{frame}, nil
+}
+
+// Encode 原样返回数据
+func (f *RawFramer) Encode(frame []byte) ([]byte, error) {
+	return frame, nil
+}
+
+// Buffered 不缓冲数据
+func (f *RawFramer) Buffered() int { return 0 }
+
+// Reset 无缓冲区
+func (f *RawFramer) Reset() {}
@@ -0,0 +1,292 @@
+// internal/framing/framing_test.go
+package framing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// newTestFramers 创建各分帧方式的分帧器（最大帧长度16字节）
+func newTestFramers(t *testing.T) map[string]func() Framer {
+	t.Helper()
+	return map[string]func() Framer{
+		"delimiter": func() Framer { return NewDelimiterFramer([]byte{0x0D, 0x0A}, 16) },
+		"length_prefixed_2": func() Framer {
+			return NewLengthPrefixedFramer(2, binary.BigEndian, false, 16)
+		},
+		"length_prefixed_4_le_inclusive": func() Framer {
+			return NewLengthPrefixedFramer(4, binary.LittleEndian, true, 16)
+		},
+		"length_prefixed_8": func() Framer {
+			return NewLengthPrefixedFramer(8, binary.BigEndian, false, 16)
+		},
+		"slip": func() Framer { return NewSLIPFramer(16) },
+		"cobs": func() Framer { return NewCOBSFramer(16) },
+	}
+}
+
+func TestFramerRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("hello"),
+		{0x00, 0xC0, 0xDB, 0xDC, 0xDD, 0x00},
+		bytes.Repeat([]byte{0x7F}, 16),
+		{0x01},
+	}
+
+	for name, newFramer := range newTestFramers(t) {
+		t.Run(name, func(t *testing.T) {
+			encoder := newFramer()
+			var stream []byte
+			for _, payload := range payloads {
+				if name == "delimiter" && bytes.Contains(payload, []byte{0x0D, 0x0A}) {
+					continue
+				}
+				encoded, err := encoder.Encode(payload)
+				if err != nil {
+					t.Fatalf("Encode(%x): %v", payload, err)
+				}
+				stream = append(stream, encoded...)
+			}
+
+			// 逐字节送入，验证帧被拆分到多次读取时仍能正确切分
+			decoder := newFramer()
+			var frames [][]byte
+			for i := range stream {
+				got, err := decoder.Decode(stream[i : i+1])
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				frames = append(frames, got...)
+			}
+
+			if len(frames) != len(payloads) {
+				t.Fatalf("got %d frames, want %d", len(frames), len(payloads))
+			}
+			for i, frame := range frames {
+				if !bytes.Equal(frame, payloads[i]) {
+					t.Errorf("frame %d = %x, want %x", i, frame, payloads[i])
+				}
+			}
+			if decoder.Buffered() != 0 {
+				t.Errorf("Buffered() = %d after complete frames", decoder.Buffered())
+			}
+		})
+	}
+}
+
+func TestFixedHeaderFramer(t *testing.T) {
+	// BasePackage风格：8字节包头，长度字段位于偏移4，2字节大端，长度不含包头
+	header := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0x00, 0x00, 0x01, 0x02}
+	body := []byte("payload")
+
+	f := NewFixedHeaderFramer(8, 4, 2, binary.BigEndian, false, 16)
+	encoded, err := f.Encode(append(append([]byte{}, header...), body...))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if got := binary.BigEndian.Uint16(encoded[4:6]); got != uint16(len(body)) {
+		t.Fatalf("length field = %d, want %d", got, len(body))
+	}
+
+	frames, err := f.Decode(append(encoded, encoded[:3]...))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(frames) != 1 || !bytes.Equal(frames[0], encoded) {
+		t.Fatalf("frames = %x, want [%x]", frames, encoded)
+	}
+	if f.Buffered() != 3 {
+		t.Errorf("Buffered() = %d, want 3", f.Buffered())
+	}
+
+	if _, err := f.Encode(header[:4]); err == nil {
+		t.Error("Encode accepted frame shorter than header")
+	}
+}
+
+func TestRawFramer(t *testing.T) {
+	f := NewRawFramer(4)
+	frames, err := f.Decode([]byte("abcd"))
+	if err != nil || len(frames) != 1 || string(frames[0]) != "abcd" {
+		t.Fatalf("Decode = %q, %v", frames, err)
+	}
+	if frames, err := f.Decode(nil); err != nil || frames != nil {
+		t.Errorf("Decode(nil) = %q, %v", frames, err)
+	}
+	if _, err := f.Decode([]byte("abcde")); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Decode oversized error = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestLengthFramerOutOfSync(t *testing.T) {
+	tests := []struct {
+		name   string
+		framer *LengthFramer
+		header []byte
+		large  bool // 是否为超长错误
+	}{
+		{
+			name:   "too large",
+			framer: NewLengthPrefixedFramer(2, binary.BigEndian, false, 16),
+			header: []byte{0x00, 0x11},
+			large:  true,
+		},
+		{
+			name:   "shorter than header",
+			framer: NewLengthPrefixedFramer(4, binary.BigEndian, true, 16),
+			header: []byte{0x00, 0x00, 0x00, 0x03},
+		},
+		{
+			// 长度加上包头后溢出为2，不能被当作合法的短帧
+			name:   "8 byte length overflow",
+			framer: NewLengthPrefixedFramer(8, binary.BigEndian, false, 16),
+			header: binary.BigEndian.AppendUint64(nil, ^uint64(0)-5),
+			large:  true,
+		},
+		{
+			name:   "8 byte length overflow including header",
+			framer: NewLengthPrefixedFramer(8, binary.BigEndian, true, 16),
+			header: binary.BigEndian.AppendUint64(nil, ^uint64(0)),
+			large:  true,
+		},
+		{
+			name:   "fixed header 8 byte length overflow",
+			framer: NewFixedHeaderFramer(12, 2, 8, binary.LittleEndian, false, 16),
+			header: append(append([]byte{0x01, 0x02}, binary.LittleEndian.AppendUint64(nil, ^uint64(0)-11)...), 0x00, 0x00),
+			large:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := tt.framer.Decode(append(tt.header, 0x01, 0x02))
+			if len(frames) != 0 {
+				t.Errorf("got %d frames, want none", len(frames))
+			}
+			if !errors.Is(err, ErrOutOfSync) {
+				t.Fatalf("error = %v, want ErrOutOfSync", err)
+			}
+			if errors.Is(err, ErrFrameTooLarge) != tt.large {
+				t.Errorf("errors.Is(err, ErrFrameTooLarge) = %v, want %v", !tt.large, tt.large)
+			}
+			if tt.framer.Buffered() != 0 {
+				t.Errorf("Buffered() = %d after out of sync, want 0", tt.framer.Buffered())
+			}
+		})
+	}
+}
+
+func TestLengthFramerEncodeTooLarge(t *testing.T) {
+	f := NewLengthPrefixedFramer(1, binary.BigEndian, false, 1024)
+	if _, err := f.Encode(make([]byte, 256)); err == nil {
+		t.Error("Encode accepted frame whose length does not fit in 1 byte")
+	}
+}
+
+func TestStuffedFramerOversizedResync(t *testing.T) {
+	tests := []struct {
+		name      string
+		framer    Framer
+		oversized []byte
+	}{
+		{
+			name:      "delimiter",
+			framer:    NewDelimiterFramer([]byte{0x0D, 0x0A}, 4),
+			oversized: []byte("0123456789"),
+		},
+		{
+			name:      "slip",
+			framer:    NewSLIPFramer(4),
+			oversized: []byte("0123456789"),
+		},
+		{
+			name:      "cobs",
+			framer:    NewCOBSFramer(4),
+			oversized: []byte("0123456789"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			big, _ := tt.framer.Encode(tt.oversized)
+			small, _ := tt.framer.Encode([]byte("ok"))
+
+			// 超长帧分两次到达，剩余部分不能被当作新帧
+			var frames [][]byte
+			var oversized int
+			for _, chunk := range [][]byte{big[:len(big)/2], append(big[len(big)/2:], small...)} {
+				got, err := tt.framer.Decode(chunk)
+				if errors.Is(err, ErrFrameTooLarge) {
+					oversized++
+				} else if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				frames = append(frames, got...)
+			}
+
+			if oversized == 0 {
+				t.Error("oversized frame not reported")
+			}
+			if len(frames) != 1 || string(frames[0]) != "ok" {
+				t.Errorf("frames = %q, want [\"ok\"]", frames)
+			}
+		})
+	}
+}
+
+func TestStuffedFramerMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		framer Framer
+		data   []byte
+	}{
+		{name: "slip invalid escape", framer: NewSLIPFramer(16), data: []byte{slipEnd, 0x01, slipEsc, 0x01, slipEnd}},
+		{name: "slip truncated escape", framer: NewSLIPFramer(16), data: []byte{slipEnd, 0x01, slipEsc, slipEnd}},
+		{name: "cobs code past end", framer: NewCOBSFramer(16), data: []byte{0x05, 0x01, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := tt.framer.Decode(tt.data)
+			if err == nil || errors.Is(err, ErrFrameTooLarge) {
+				t.Errorf("error = %v, want malformed frame error", err)
+			}
+			if len(frames) != 0 {
+				t.Errorf("got %d frames, want none", len(frames))
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg  config.FramingConfig
+		name string
+	}{
+		{config.FramingConfig{}, config.FramingNone},
+		{config.FramingConfig{Type: config.FramingDelimiter, Delimiter: "0d0a"}, config.FramingDelimiter},
+		{config.FramingConfig{Type: config.FramingLengthPrefixed, LengthWidth: 4}, config.FramingLengthPrefixed},
+		{config.FramingConfig{Type: config.FramingFixedHeader, HeaderSize: 32, LengthOffset: 18, LengthWidth: 4}, config.FramingFixedHeader},
+		{config.FramingConfig{Type: config.FramingSLIP}, config.FramingSLIP},
+		{config.FramingConfig{Type: config.FramingCOBS}, config.FramingCOBS},
+	}
+
+	for _, tt := range tests {
+		f, err := New(tt.cfg)
+		if err != nil {
+			t.Errorf("New(%+v): %v", tt.cfg, err)
+			continue
+		}
+		if f.Name() != tt.name {
+			t.Errorf("New(%+v).Name() = %s, want %s", tt.cfg, f.Name(), tt.name)
+		}
+	}
+
+	if _, err := New(config.FramingConfig{Type: "bogus"}); err == nil {
+		t.Error("New accepted unknown framing type")
+	}
+}
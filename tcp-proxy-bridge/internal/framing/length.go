@@ -0,0 +1,161 @@
+// internal/framing/length.go
+package framing
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// LengthFramer 基于长度字段的分帧器
+// length_prefixed: 帧前附加长度字段，解码后去掉长度字段；
+// fixed_header: 帧以固定长度的包头开始，长度字段位于包头内，解码后保留包头
+type LengthFramer struct {
+	streamBuffer
+	name           string           // 分帧方式名称
+	headerSize     int              // 包头长度（length_prefixed时等于长度字段宽度）
+	lengthOffset   int              // 长度字段在包头中的偏移
+	lengthWidth    int              // 长度字段字节数: 1/2/4/8
+	order          binary.ByteOrder // 长度字段字节序
+	includesHeader bool             // 长度值是否包含包头本身
+	stripHeader    bool             // 解码时是否去掉包头
+}
+
+// NewLengthPrefixedFramer 创建长度前缀分帧器
+// 参数: width - 长度字段字节数, order - 字节序, includesHeader - 长度值是否包含长度字段本身, maxFrameSize - 最大帧长度
+// 返回: 分帧器实例
+func NewLengthPrefixedFramer(width int, order binary.ByteOrder, includesHeader bool, maxFrameSize int) *LengthFramer {
+	return &LengthFramer{
+		streamBuffer:   streamBuffer{buf: make([]byte, 0, 4096), maxFrameSize: maxFrameSize},
+		name:           config.FramingLengthPrefixed,
+		headerSize:     width,
+		lengthWidth:    width,
+		order:          order,
+		includesHeader: includesHeader,
+		stripHeader:    true,
+	}
+}
+
+// NewFixedHeaderFramer 创建固定包头分帧器
+// 例如BasePackage: 包头32字节，长度字段位于第18字节，4字节大端，长度不含包头
+// 参数: headerSize - 包头长度, offset - 长度字段偏移, width - 长度字段字节数, order - 字节序,
+//
+//	includesHeader - 长度值是否包含包头, maxFrameSize - 最大帧长度
+//
+// 返回: 分帧器实例
+func NewFixedHeaderFramer(headerSize, offset, width int, order binary.ByteOrder, includesHeader bool, maxFrameSize int) *LengthFramer {
+	return &LengthFramer{
+		streamBuffer:   streamBuffer{buf: make([]byte, 0, 4096), maxFrameSize: maxFrameSize},
+		name:           config.FramingFixedHeader,
+		headerSize:     headerSize,
+		lengthOffset:   offset,
+		lengthWidth:    width,
+		order:          order,
+		includesHeader: includesHeader,
+	}
+}
+
+// Name 分帧方式名称
+func (f *LengthFramer) Name() string { return f.name }
+
+// Decode 追加数据并按长度字段切分出完整的帧
+// 长度字段非法或超过最大帧长度时无法重新同步，丢弃缓冲区并返回包装了ErrOutOfSync的错误
+func (f *LengthFramer) Decode(data []byte) ([][]byte, error) {
+	f.buf = append(f.buf, data...)
+
+	var frames [][]byte
+	for len(f.buf) >= f.headerSize {
+		length := f.readLength(f.buf[f.lengthOffset : f.lengthOffset+f.lengthWidth])
+
+		// 先按不含包头的长度检查上限，再计算帧总长度，避免8字节长度字段加上包头后溢出
+		body := length
+		if f.includesHeader {
+			if length < uint64(f.headerSize) {
+				f.Reset()
+				return frames, fmt.Errorf("%w: invalid frame length %d, shorter than header %d", ErrOutOfSync, length, f.headerSize)
+			}
+			body -= uint64(f.headerSize)
+		}
+		if body > uint64(f.maxFrameSize) {
+			f.Reset()
+			return frames, fmt.Errorf("%w: %w: %d bytes, max %d", ErrOutOfSync, ErrFrameTooLarge, body, f.maxFrameSize)
+		}
+		total := body + uint64(f.headerSize)
+
+		start := 0
+		if f.stripHeader {
+			start = f.headerSize
+		}
+		if total < uint64(start) {
+			f.Reset()
+			return frames, fmt.Errorf("%w: invalid frame length %d", ErrOutOfSync, length)
+		}
+		if uint64(len(f.buf)) < total {
+			break
+		}
+
+		frame := make([]byte, int(total)-start)
+		copy(frame, f.buf[start:total])
+		frames = append(frames, frame)
+
+		f.buf = f.buf[total:]
+	}
+
+	return frames, nil
+}
+
+// Encode 写入长度字段
+// length_prefixed在数据前添加长度字段；fixed_header要求数据已包含包头，只回填长度字段
+func (f *LengthFramer) Encode(frame []byte) ([]byte, error) {
+	var out []byte
+	if f.stripHeader {
+		out = make([]byte, f.headerSize+len(frame))
+		copy(out[f.headerSize:], frame)
+	} else {
+		if len(frame) < f.headerSize {
+			return nil, fmt.Errorf("frame shorter than header: %d < %d", len(frame), f.headerSize)
+		}
+		out = make([]byte, len(frame))
+		copy(out, frame)
+	}
+
+	length := uint64(len(out))
+	if !f.includesHeader {
+		length -= uint64(f.headerSize)
+	}
+	if f.lengthWidth < 8 && length>>(uint(f.lengthWidth)*8) != 0 {
+		return nil, fmt.Errorf("frame length %d does not fit in %d bytes", length, f.lengthWidth)
+	}
+
+	f.writeLength(out[f.lengthOffset:f.lengthOffset+f.lengthWidth], length)
+	return out, nil
+}
+
+// readLength 读取长度字段
+func (f *LengthFramer) readLength(b []byte) uint64 {
+	switch f.lengthWidth {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(f.order.Uint16(b))
+	case 4:
+		return uint64(f.order.Uint32(b))
+	default:
+		return f.order.Uint64(b)
+	}
+}
+
+// writeLength 写入长度字段
+func (f *LengthFramer) writeLength(b []byte, length uint64) {
+	switch f.lengthWidth {
+	case 1:
+		b[0] = byte(length)
+	case 2:
+		f.order.PutUint16(b, uint16(length))
+	case 4:
+		f.order.PutUint32(b, uint32(length))
+	default:
+		f.order.PutUint64(b, length)
+	}
+}
@@ -0,0 +1,177 @@
+// internal/framing/stuffing.go
+package framing
+
+import (
+	"bytes"
+	"fmt"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// SLIP特殊字节 (RFC 1055)
+const (
+	slipEnd    byte = 0xC0 // 帧结束
+	slipEsc    byte = 0xDB // 转义
+	slipEscEnd byte = 0xDC // 转义后的END
+	slipEscEsc byte = 0xDD // 转义后的ESC
+)
+
+// SLIPFramer SLIP字节填充分帧器
+type SLIPFramer struct {
+	streamBuffer
+}
+
+// NewSLIPFramer 创建SLIP分帧器
+// 参数: maxFrameSize - 最大帧长度
+// 返回: 分帧器实例
+func NewSLIPFramer(maxFrameSize int) *SLIPFramer {
+	return &SLIPFramer{streamBuffer: streamBuffer{buf: make([]byte, 0, 4096), maxFrameSize: maxFrameSize}}
+}
+
+// Name 分帧方式名称
+func (f *SLIPFramer) Name() string { return config.FramingSLIP }
+
+// Decode 按END字节切分并还原转义字节
+func (f *SLIPFramer) Decode(data []byte) ([][]byte, error) {
+	return splitStuffed(&f.streamBuffer, data, slipEnd, slipDecode)
+}
+
+// Encode 转义特殊字节，并在前后添加END
+func (f *SLIPFramer) Encode(frame []byte) ([]byte, error) {
+	out := make([]byte, 0, len(frame)+2)
+	out = append(out, slipEnd)
+	for _, b := range frame {
+		switch b {
+		case slipEnd:
+			out = append(out, slipEsc, slipEscEnd)
+		case slipEsc:
+			out = append(out, slipEsc, slipEscEsc)
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, slipEnd), nil
+}
+
+// slipDecode 还原SLIP转义
+func slipDecode(encoded []byte) ([]byte, error) {
+	out := make([]byte, 0, len(encoded))
+	for i := 0; i < len(encoded); i++ {
+		if encoded[i] != slipEsc {
+			out = append(out, encoded[i])
+			continue
+		}
+		if i+1 >= len(encoded) {
+			return nil, fmt.Errorf("truncated SLIP escape sequence")
+		}
+		i++
+		switch encoded[i] {
+		case slipEscEnd:
+			out = append(out, slipEnd)
+		case slipEscEsc:
+			out = append(out, slipEsc)
+		default:
+			return nil, fmt.Errorf("invalid SLIP escape byte 0x%02X", encoded[i])
+		}
+	}
+	return out, nil
+}
+
+// COBSFramer COBS字节填充分帧器，帧之间以0x00分割
+type COBSFramer struct {
+	streamBuffer
+}
+
+// NewCOBSFramer 创建COBS分帧器
+// 参数: maxFrameSize - 最大帧长度
+// 返回: 分帧器实例
+func NewCOBSFramer(maxFrameSize int) *COBSFramer {
+	return &COBSFramer{streamBuffer: streamBuffer{buf: make([]byte, 0, 4096), maxFrameSize: maxFrameSize}}
+}
+
+// Name 分帧方式名称
+func (f *COBSFramer) Name() string { return config.FramingCOBS }
+
+// Decode 按0x00切分并进行COBS解码
+func (f *COBSFramer) Decode(data []byte) ([][]byte, error) {
+	return splitStuffed(&f.streamBuffer, data, 0x00, cobsDecode)
+}
+
+// Encode 进行COBS编码并在末尾添加0x00
+func (f *COBSFramer) Encode(frame []byte) ([]byte, error) {
+	out := make([]byte, 1, len(frame)+len(frame)/254+2)
+	codeIndex, code := 0, byte(1)
+	for _, b := range frame {
+		if b == 0 {
+			out[codeIndex] = code
+			codeIndex, code = len(out), 1
+			out = append(out, 0)
+			continue
+		}
+		out = append(out, b)
+		code++
+		if code == 0xFF {
+			out[codeIndex] = code
+			codeIndex, code = len(out), 1
+			out = append(out, 0)
+		}
+	}
+	out[codeIndex] = code
+	return append(out, 0x00), nil
+}
+
+// cobsDecode COBS解码
+func cobsDecode(encoded []byte) ([]byte, error) {
+	out := make([]byte, 0, len(encoded))
+	for i := 0; i < len(encoded); {
+		code := int(encoded[i])
+		if code == 0 || i+code > len(encoded) {
+			return nil, fmt.Errorf("invalid COBS code %d at offset %d", code, i)
+		}
+		out = append(out, encoded[i+1:i+code]...)
+		i += code
+		if code < 0xFF && i < len(encoded) {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// splitStuffed 按结束字节切分字节填充的帧并解码
+// 参数: b - 接收缓冲区, data - 新数据, end - 结束字节, decode - 解码函数
+// 返回: 解码后的帧列表和错误信息（超长或解码失败的帧被丢弃）
+func splitStuffed(b *streamBuffer, data []byte, end byte, decode func([]byte) ([]byte, error)) ([][]byte, error) {
+	b.buf = append(b.buf, data...)
+
+	var frames [][]byte
+	var err error
+	for {
+		index := bytes.IndexByte(b.buf, end)
+		if index == -1 {
+			if b.discarding {
+				b.discard(0)
+			} else if len(b.buf) > b.maxFrameSize+b.maxFrameSize/254+2 {
+				// 编码后的长度最多比原始数据多约1/254
+				err = frameTooLarge(len(b.buf), b.maxFrameSize)
+				b.discard(0)
+			}
+			return frames, err
+		}
+
+		if b.discarding {
+			// 已丢弃的超长帧到此结束
+			b.discarding = false
+		} else if index > 0 {
+			frame, decodeErr := decode(b.buf[:index])
+			switch {
+			case decodeErr != nil:
+				err = decodeErr
+			case len(frame) > b.maxFrameSize:
+				err = frameTooLarge(len(frame), b.maxFrameSize)
+			case len(frame) > 0:
+				frames = append(frames, frame)
+			}
+		}
+		b.buf = b.buf[index+1:]
+	}
+}
@@ -6,23 +6,28 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
 	"time"
 )
 
+// Listener 被检查的监听端口服务
+type Listener interface {
+	IsRunning() bool // 是否正在监听
+}
+
 // MinimalServer 最小化健康检查服务器
 // 只检查数据库连接和TCP端口监听状态
 type MinimalServer struct {
-	server  *http.Server // HTTP服务器实例
-	db      *sql.DB      // 数据库连接
-	tcpPort int          // TCP服务端口
+	server   *http.Server // HTTP服务器实例
+	db       *sql.DB      // 数据库连接
+	listener Listener     // TCP监听服务
 }
 
 // NewMinimalServer 创建最小化健康检查服务器
-// 参数: healthPort - 健康检查服务端口, tcpPort - TCP服务端口, db - 数据库连接
+// 监听状态直接从TCP服务获取，不连接监听端口，避免健康检查被计入访问控制和TLS握手的拒绝统计
+// 参数: healthPort - 健康检查服务端口, listener - TCP监听服务（为nil时不检查，未启用监听端口时使用）, db - 数据库连接
 // 返回: 健康检查服务器实例
-func NewMinimalServer(healthPort, tcpPort int, db *sql.DB) *MinimalServer {
+func NewMinimalServer(healthPort int, listener Listener, db *sql.DB) *MinimalServer {
 	// 创建HTTP路由
 	mux := http.NewServeMux()
 
@@ -31,8 +36,8 @@ func NewMinimalServer(healthPort, tcpPort int, db *sql.DB) *MinimalServer {
 			Addr:    fmt.Sprintf(":%d", healthPort),
 			Handler: mux,
 		},
-		db:      db,
-		tcpPort: tcpPort,
+		db:       db,
+		listener: listener,
 	}
 
 	// 注册健康检查端点
@@ -72,7 +77,7 @@ func (s *MinimalServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 2. 检查TCP服务端口是否在监听
 	if !s.checkTCPPort() {
-		log.Printf("Health check failed: TCP port %d not listening", s.tcpPort)
+		log.Printf("Health check failed: TCP listener not running")
 		http.Error(w, "TCP port not listening", http.StatusServiceUnavailable)
 		return
 	}
@@ -91,14 +96,12 @@ func (s *MinimalServer) readyHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // checkTCPPort 检查TCP端口是否在监听
-// 返回: 端口是否可连接
+// 返回: 是否在监听（未启用监听端口时为true）
 func (s *MinimalServer) checkTCPPort() bool {
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf(":%d", s.tcpPort), 2*time.Second)
-	if err != nil {
-		return false
+	if s.listener == nil {
+		return true
 	}
-	conn.Close()
-	return true
+	return s.listener.IsRunning()
 }
 
 // GetHealthStatus 获取健康状态（供程序内部使用）
@@ -114,7 +117,7 @@ func (s *MinimalServer) GetHealthStatus() (bool, error) {
 
 	// 检查TCP端口
 	if !s.checkTCPPort() {
-		return false, fmt.Errorf("TCP port %d not listening", s.tcpPort)
+		return false, fmt.Errorf("TCP listener not running")
 	}
 
 	return true, nil
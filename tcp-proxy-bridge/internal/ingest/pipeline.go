@@ -0,0 +1,444 @@
+// internal/ingest/pipeline.go
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/database"
+	"tcp-proxy-bridge/internal/metrics"
+)
+
+// ErrClosed 入库队列已关闭
+var ErrClosed = errors.New("ingest pipeline is closed")
+
+// Store 消息存储接口
+type Store interface {
+	// SaveMessages 在一个事务中批量保存消息
+	SaveMessages(msgs []*database.Message) error
+	// SaveMessage 保存单条消息
+	SaveMessage(msg *database.Message) error
+	// PingContext 检查数据库连接状态
+	PingContext(ctx context.Context) error
+}
+
+// Pipeline 入库队列
+// 接收到的消息先进入有界内存队列，由后台写入器按批写入数据库，
+// 接收方只在队列满且背压方式为block时等待，不会等待数据库往返
+type Pipeline struct {
+	cfg   config.IngestConfig // 入库队列配置
+	store Store               // 消息存储
+
+	mu      sync.Mutex
+	notFull *sync.Cond          // block方式下等待队列空位
+	queue   []*database.Message // 内存队列（队头为最旧的消息）
+	spill   *spillFile          // 磁盘溢出队列（仅spill方式）
+	closed  bool                // 是否已关闭入队
+	retryAt time.Time           // 数据库不可用时下一次重试写入的时间
+
+	wake     chan struct{} // 通知写入器有新消息
+	stop     chan struct{} // 关闭信号
+	done     chan struct{} // 写入器退出信号
+	stopOnce sync.Once
+
+	enqueued    int64         // 入队消息数
+	dropped     int64         // 丢弃消息数（队列满或溢出文件满）
+	spilled     int64         // 写入溢出文件的消息数
+	written     int64         // 已写入数据库的消息数
+	batches     int64         // 已写入的批次数
+	writeErrors int64         // 批量写入失败次数
+	poison      int64         // 单条重试仍失败而丢弃的消息数
+	lastBatch   int           // 最近一批的消息数
+	lastLatency time.Duration // 最近一批的写入耗时
+	lastError   string        // 最近一次写入错误
+	lastWriteAt time.Time     // 最近一次成功写入时间
+}
+
+// NewPipeline 创建入库队列
+// spill方式下打开溢出文件，上次运行遗留的记录会在启动后重新写入数据库
+// 参数: cfg - 入库队列配置, store - 消息存储
+// 返回: 入库队列实例和错误信息
+func NewPipeline(cfg config.IngestConfig, store Store) (*Pipeline, error) {
+	p := &Pipeline{
+		cfg:   cfg,
+		store: store,
+		queue: make([]*database.Message, 0, cfg.QueueSize),
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	p.notFull = sync.NewCond(&p.mu)
+
+	if cfg.Backpressure == config.BackpressureSpill {
+		spill, err := openSpillFile(cfg.SpillDir, cfg.SpillMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		if spill.pending > 0 {
+			log.Printf("Recovered %d spilled messages from %s", spill.pending, spill.path)
+		}
+		p.spill = spill
+		p.updateDepth()
+	}
+
+	return p, nil
+}
+
+// Start 启动后台写入器
+// 参数: ctx - 上下文，取消时写入剩余消息后退出
+func (p *Pipeline) Start(ctx context.Context) {
+	log.Printf("Starting ingest pipeline: queue_size=%d, batch_size=%d, flush_interval=%v, backpressure=%s",
+		p.cfg.QueueSize, p.cfg.BatchSize, p.cfg.FlushInterval, p.cfg.Backpressure)
+	go p.run(ctx)
+}
+
+// Enqueue 消息入队
+// 队列满时按背压方式处理: block等待空位, drop_oldest丢弃最旧的消息, spill写入溢出文件
+// 参数: msg - 消息
+// 返回: 错误信息（队列已关闭或溢出文件已满）
+func (p *Pipeline) Enqueue(msg *database.Message) error {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	if msg.DataLength == 0 {
+		msg.DataLength = len(msg.OriginalData)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+
+	switch p.cfg.Backpressure {
+	case config.BackpressureDropOldest:
+		for len(p.queue) >= p.cfg.QueueSize {
+			p.queue[0] = nil
+			p.queue = p.queue[1:]
+			p.dropped++
+			metrics.IncIngestDropped()
+		}
+		p.queue = append(p.queue, msg)
+
+	case config.BackpressureSpill:
+		// 溢出文件中还有消息时新消息也写入文件，保证先进先出
+		if p.spill.pending > 0 || len(p.queue) >= p.cfg.QueueSize {
+			if err := p.spill.append(msg); err != nil {
+				p.dropped++
+				p.mu.Unlock()
+				metrics.IncIngestDropped()
+				return err
+			}
+			p.spilled++
+		} else {
+			p.queue = append(p.queue, msg)
+		}
+
+	default:
+		for len(p.queue) >= p.cfg.QueueSize && !p.closed {
+			p.notFull.Wait()
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return ErrClosed
+		}
+		p.queue = append(p.queue, msg)
+	}
+
+	p.enqueued++
+	full := len(p.queue) >= p.cfg.BatchSize
+	p.updateDepth()
+	p.mu.Unlock()
+
+	// 凑够一批时立即唤醒写入器，否则等待刷新间隔
+	if full {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Stop 停止入队并写入剩余消息
+// 数据库不可用时，spill方式下剩余消息写入溢出文件，其他方式下丢弃并记录日志
+// 参数: ctx - 上下文，控制最长等待时间
+func (p *Pipeline) Stop(ctx context.Context) {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.notFull.Broadcast()
+		p.mu.Unlock()
+		close(p.stop)
+	})
+
+	select {
+	case <-p.done:
+		log.Println("Ingest pipeline stopped")
+	case <-ctx.Done():
+		log.Printf("Ingest pipeline stop timed out with %d messages queued", p.Depth())
+	}
+}
+
+// Depth 获取队列深度（内存队列与溢出文件中的消息总数）
+// 返回: 消息数
+func (p *Pipeline) Depth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.depth()
+}
+
+// GetStatus 获取入库队列状态
+// 返回: 状态信息
+func (p *Pipeline) GetStatus() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := map[string]interface{}{
+		"backpressure":     p.cfg.Backpressure,
+		"queue_size":       p.cfg.QueueSize,
+		"batch_size":       p.cfg.BatchSize,
+		"depth":            p.depth(),
+		"memory_depth":     len(p.queue),
+		"enqueued":         p.enqueued,
+		"dropped":          p.dropped,
+		"written":          p.written,
+		"batches":          p.batches,
+		"write_errors":     p.writeErrors,
+		"poison_dropped":   p.poison,
+		"last_batch_size":  p.lastBatch,
+		"last_batch_ms":    p.lastLatency.Milliseconds(),
+		"last_write_at":    p.lastWriteAt,
+		"last_write_error": p.lastError,
+		"closed":           p.closed,
+	}
+	if p.spill != nil {
+		status["spilled"] = p.spilled
+		status["spill_depth"] = p.spill.pending
+		status["spill_bytes"] = p.spill.size - p.spill.readOff
+	}
+	if !p.retryAt.IsZero() {
+		status["retry_at"] = p.retryAt
+	}
+	return status
+}
+
+// run 后台写入循环
+// 凑够一批时立即写入，不足一批的消息每隔刷新间隔写入一次
+func (p *Pipeline) run(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.drain()
+			return
+		case <-p.stop:
+			p.drain()
+			return
+		case <-p.wake:
+			p.flush(false)
+		case <-ticker.C:
+			p.flush(true)
+		}
+	}
+}
+
+// flush 按批写入队列中的消息
+// 参数: partial - 是否写入不足一批的消息
+// 返回: 是否写完（数据库不可用时返回false）
+func (p *Pipeline) flush(partial bool) bool {
+	for {
+		p.mu.Lock()
+		if time.Now().Before(p.retryAt) {
+			p.mu.Unlock()
+			return false
+		}
+		batch := p.takeBatch(partial)
+		p.mu.Unlock()
+
+		if len(batch) == 0 {
+			return true
+		}
+		if !p.write(batch) {
+			return false
+		}
+	}
+}
+
+// drain 关闭时写入剩余消息
+func (p *Pipeline) drain() {
+	p.mu.Lock()
+	p.closed = true
+	p.notFull.Broadcast()
+	p.retryAt = time.Time{}
+	p.mu.Unlock()
+
+	if !p.flush(true) {
+		p.mu.Lock()
+		p.spillRemaining()
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.spill != nil {
+		if err := p.spill.close(); err != nil {
+			log.Printf("Failed to close spill file: %v", err)
+		}
+	}
+}
+
+// spillRemaining 数据库不可用时保存内存队列中剩余的消息
+// 调用方需持有锁
+func (p *Pipeline) spillRemaining() {
+	if len(p.queue) == 0 {
+		return
+	}
+
+	if p.spill == nil {
+		log.Printf("Database unavailable during shutdown, %d queued messages discarded", len(p.queue))
+		p.dropped += int64(len(p.queue))
+		p.queue = nil
+		p.updateDepth()
+		return
+	}
+
+	// 内存队列中的消息比溢出文件中的旧，写在文件开头
+	if err := p.spill.prepend(p.queue); err != nil {
+		log.Printf("Failed to save %d queued messages to spill file: %v", len(p.queue), err)
+		p.dropped += int64(len(p.queue))
+		p.queue = nil
+		p.updateDepth()
+		return
+	}
+	saved := len(p.queue)
+	log.Printf("Database unavailable during shutdown, %d messages saved to %s", saved, p.spill.path)
+	p.queue = nil
+	p.updateDepth()
+}
+
+// takeBatch 从队头取出一批消息
+// 内存队列有空位时先从溢出文件读回消息
+// 调用方需持有锁
+// 参数: partial - 是否允许不足一批
+// 返回: 消息列表
+func (p *Pipeline) takeBatch(partial bool) []*database.Message {
+	if p.spill != nil && p.spill.pending > 0 && len(p.queue) < p.cfg.QueueSize {
+		msgs, err := p.spill.read(p.cfg.QueueSize - len(p.queue))
+		if err != nil {
+			log.Printf("Failed to read spill file: %v", err)
+		}
+		p.queue = append(p.queue, msgs...)
+	}
+
+	n := len(p.queue)
+	if n == 0 || (n < p.cfg.BatchSize && !partial) {
+		return nil
+	}
+	if n > p.cfg.BatchSize {
+		n = p.cfg.BatchSize
+	}
+
+	batch := make([]*database.Message, n)
+	copy(batch, p.queue)
+	for i := 0; i < n; i++ {
+		p.queue[i] = nil
+	}
+	p.queue = p.queue[n:]
+	if len(p.queue) == 0 {
+		p.queue = p.queue[:0:0]
+	}
+
+	p.notFull.Broadcast()
+	p.updateDepth()
+	return batch
+}
+
+// write 写入一批消息
+// 批量写入失败时检查数据库连接：数据库不可用则把整批放回队头，等待重试间隔后重写；
+// 数据库可用则逐条重写，仍然失败的消息记录日志后丢弃，避免一条坏数据阻塞整个队列
+// 参数: batch - 消息列表
+// 返回: 是否继续写入
+func (p *Pipeline) write(batch []*database.Message) bool {
+	start := time.Now()
+	err := p.store.SaveMessages(batch)
+	latency := time.Since(start)
+
+	if err == nil {
+		p.mu.Lock()
+		p.written += int64(len(batch))
+		p.batches++
+		p.lastBatch = len(batch)
+		p.lastLatency = latency
+		p.lastWriteAt = time.Now()
+		p.lastError = ""
+		p.mu.Unlock()
+		return true
+	}
+
+	log.Printf("Failed to save batch of %d messages: %v", len(batch), err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.RetryInterval)
+	pingErr := p.store.PingContext(ctx)
+	cancel()
+
+	p.mu.Lock()
+	p.writeErrors++
+	p.lastError = err.Error()
+	if pingErr != nil {
+		// 数据库不可用，整批放回队头
+		p.queue = append(batch, p.queue...)
+		p.retryAt = time.Now().Add(p.cfg.RetryInterval)
+		p.updateDepth()
+		p.mu.Unlock()
+		log.Printf("Database unavailable, retrying ingest in %v: %v", p.cfg.RetryInterval, pingErr)
+		return false
+	}
+	p.mu.Unlock()
+
+	// 数据库可用，逐条重写找出无法写入的消息
+	written := 0
+	for _, msg := range batch {
+		if err := p.store.SaveMessage(msg); err != nil {
+			log.Printf("Dropping message from %s (%d bytes) after write failure: %v",
+				msg.SourceIP, msg.DataLength, err)
+			metrics.IncMessageErrors()
+			p.mu.Lock()
+			p.poison++
+			p.mu.Unlock()
+			continue
+		}
+		written++
+	}
+
+	p.mu.Lock()
+	p.written += int64(written)
+	p.mu.Unlock()
+	return true
+}
+
+// depth 获取队列深度
+// 调用方需持有锁
+func (p *Pipeline) depth() int {
+	depth := len(p.queue)
+	if p.spill != nil {
+		depth += p.spill.pending
+	}
+	return depth
+}
+
+// updateDepth 更新队列深度指标
+// 调用方需持有锁
+func (p *Pipeline) updateDepth() {
+	metrics.SetIngestQueueDepth(int64(p.depth()))
+}
@@ -0,0 +1,321 @@
+// internal/ingest/pipeline_test.go
+package ingest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/database"
+)
+
+// fakeStore 记录写入内容的消息存储
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]string // 每次批量写入成功的消息内容
+	singles []string   // 逐条写入成功的消息内容
+	down    bool       // 数据库不可用：写入和连接检查都失败
+	poison  string     // 包含该内容的消息写入失败
+}
+
+func (s *fakeStore) SaveMessages(msgs []*database.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		return errors.New("connection refused")
+	}
+	batch := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if string(msg.OriginalData) == s.poison {
+			return errors.New("invalid message")
+		}
+		batch = append(batch, string(msg.OriginalData))
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeStore) SaveMessage(msg *database.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		return errors.New("connection refused")
+	}
+	if string(msg.OriginalData) == s.poison {
+		return errors.New("invalid message")
+	}
+	s.singles = append(s.singles, string(msg.OriginalData))
+	return nil
+}
+
+func (s *fakeStore) PingContext(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (s *fakeStore) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// saved 获取已写入的全部消息内容（先批量后逐条）
+func (s *fakeStore) saved() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var all []string
+	for _, batch := range s.batches {
+		all = append(all, batch...)
+	}
+	return append(all, s.singles...)
+}
+
+// batchSizes 获取每次批量写入的消息数
+func (s *fakeStore) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, 0, len(s.batches))
+	for _, batch := range s.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+// testIngestConfig 测试使用的入库队列配置
+func testIngestConfig(queueSize, batchSize int, backpressure string) config.IngestConfig {
+	return config.IngestConfig{
+		QueueSize:     queueSize,
+		BatchSize:     batchSize,
+		FlushInterval: 20 * time.Millisecond,
+		Backpressure:  backpressure,
+		SpillMaxBytes: 1 << 20,
+		RetryInterval: 20 * time.Millisecond,
+	}
+}
+
+// enqueue 依次入队内容为names的消息
+func enqueue(t *testing.T, p *Pipeline, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := p.Enqueue(&database.Message{SourceIP: "10.0.0.1", OriginalData: []byte(name)}); err != nil {
+			t.Fatalf("Enqueue(%s): %v", name, err)
+		}
+	}
+}
+
+// waitFor 等待条件满足
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// stop 停止入库队列
+func stop(p *Pipeline) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	p.Stop(ctx)
+}
+
+func TestPipelineBatching(t *testing.T) {
+	store := &fakeStore{}
+	p, err := NewPipeline(testIngestConfig(100, 3, config.BackpressureBlock), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 凑够一批立即写入，不足一批的在刷新间隔后写入
+	enqueue(t, p, "1", "2", "3", "4", "5", "6", "7")
+	p.Start(context.Background())
+	defer stop(p)
+	waitFor(t, "7 messages written", func() bool { return len(store.saved()) == 7 })
+
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"1", "2", "3", "4", "5", "6", "7"}) {
+		t.Errorf("saved %v, want messages in order", got)
+	}
+	if sizes := store.batchSizes(); !reflect.DeepEqual(sizes, []int{3, 3, 1}) {
+		t.Errorf("batch sizes %v, want [3 3 1]", sizes)
+	}
+	if depth := p.Depth(); depth != 0 {
+		t.Errorf("Depth() = %d after flush", depth)
+	}
+}
+
+func TestPipelineBackpressureBlock(t *testing.T) {
+	store := &fakeStore{}
+	p, err := NewPipeline(testIngestConfig(2, 2, config.BackpressureBlock), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enqueue(t, p, "1", "2")
+
+	// 队列满时入队方等待写入器腾出空位
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Enqueue(&database.Message{OriginalData: []byte("3")})
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Enqueue returned %v while queue was full", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	p.Start(context.Background())
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Enqueue still blocked after writer started")
+	}
+	stop(p)
+
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("saved %v, want [1 2 3]", got)
+	}
+	if err := p.Enqueue(&database.Message{OriginalData: []byte("4")}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Enqueue after Stop: %v, want ErrClosed", err)
+	}
+}
+
+func TestPipelineBackpressureDropOldest(t *testing.T) {
+	store := &fakeStore{}
+	p, err := NewPipeline(testIngestConfig(3, 10, config.BackpressureDropOldest), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enqueue(t, p, "1", "2", "3", "4", "5")
+	if depth := p.Depth(); depth != 3 {
+		t.Errorf("Depth() = %d, want 3", depth)
+	}
+	if dropped := p.GetStatus()["dropped"]; dropped != int64(2) {
+		t.Errorf("dropped = %v, want 2", dropped)
+	}
+
+	p.Start(context.Background())
+	stop(p)
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"3", "4", "5"}) {
+		t.Errorf("saved %v, want [3 4 5]", got)
+	}
+}
+
+func TestPipelineBackpressureSpill(t *testing.T) {
+	store := &fakeStore{}
+	cfg := testIngestConfig(2, 10, config.BackpressureSpill)
+	cfg.SpillDir = t.TempDir()
+	p, err := NewPipeline(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 队列满后写入溢出文件，溢出文件中有消息时新消息也写入文件，保证先进先出
+	enqueue(t, p, "1", "2", "3", "4", "5")
+	status := p.GetStatus()
+	if status["memory_depth"] != 2 || status["spill_depth"] != 3 || p.Depth() != 5 {
+		t.Errorf("memory_depth = %v, spill_depth = %v, Depth() = %d", status["memory_depth"], status["spill_depth"], p.Depth())
+	}
+
+	p.Start(context.Background())
+	waitFor(t, "5 messages written", func() bool { return len(store.saved()) == 5 })
+	stop(p)
+
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"1", "2", "3", "4", "5"}) {
+		t.Errorf("saved %v, want messages in order", got)
+	}
+}
+
+func TestPipelineSpillOnShutdown(t *testing.T) {
+	store := &fakeStore{down: true}
+	cfg := testIngestConfig(10, 10, config.BackpressureSpill)
+	cfg.SpillDir = t.TempDir()
+	p, err := NewPipeline(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start(context.Background())
+	enqueue(t, p, "1", "2")
+
+	// 关闭时数据库不可用，剩余消息保存到溢出文件
+	stop(p)
+	if got := store.saved(); len(got) != 0 {
+		t.Fatalf("saved %v while database was down", got)
+	}
+
+	// 重启后读回溢出文件中的消息
+	store.setDown(false)
+	p, err = NewPipeline(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth := p.Depth(); depth != 2 {
+		t.Errorf("recovered Depth() = %d, want 2", depth)
+	}
+	p.Start(context.Background())
+	stop(p)
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("saved %v after restart, want [1 2]", got)
+	}
+}
+
+func TestPipelineDatabaseUnavailable(t *testing.T) {
+	store := &fakeStore{down: true}
+	p, err := NewPipeline(testIngestConfig(100, 2, config.BackpressureBlock), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Start(context.Background())
+	defer stop(p)
+
+	enqueue(t, p, "1", "2", "3")
+	waitFor(t, "write error", func() bool { return p.GetStatus()["write_errors"].(int64) > 0 })
+	if depth := p.Depth(); depth != 3 {
+		t.Errorf("Depth() = %d while database down, want 3", depth)
+	}
+
+	// 数据库恢复后按原顺序重写
+	store.setDown(false)
+	waitFor(t, "3 messages written", func() bool { return len(store.saved()) == 3 })
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("saved %v, want [1 2 3]", got)
+	}
+}
+
+func TestPipelinePoisonMessage(t *testing.T) {
+	store := &fakeStore{poison: "bad"}
+	p, err := NewPipeline(testIngestConfig(100, 3, config.BackpressureBlock), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 批量写入失败但数据库可用时逐条重写，只丢弃写不进去的消息
+	enqueue(t, p, "1", "bad", "3", "4")
+	p.Start(context.Background())
+	waitFor(t, "3 messages written", func() bool { return len(store.saved()) == 3 })
+	stop(p)
+
+	if got := store.saved(); !reflect.DeepEqual(got, []string{"4", "1", "3"}) {
+		t.Errorf("saved %v, want batch [4] and singles [1 3]", got)
+	}
+	status := p.GetStatus()
+	if status["poison_dropped"] != int64(1) || status["written"] != int64(3) {
+		t.Errorf("poison_dropped = %v, written = %v, want 1 and 3", status["poison_dropped"], status["written"])
+	}
+}
@@ -0,0 +1,238 @@
+// internal/ingest/spill.go
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tcp-proxy-bridge/internal/database"
+)
+
+// ErrSpillFull 溢出文件已达到最大字节数
+var ErrSpillFull = errors.New("ingest spill file is full")
+
+// spillFileName 溢出文件名
+const spillFileName = "ingest.spill"
+
+// spillRecord 溢出文件中的一条记录（每行一个JSON对象）
+type spillRecord struct {
+	SourceIP       string    `json:"source_ip"`
+	SourceIdentity string    `json:"source_identity,omitempty"`
+	SourceGroup    string    `json:"source_group,omitempty"`
+	Data           []byte    `json:"data"`
+	DataLength     int       `json:"data_length"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	Targets        []string  `json:"targets,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	Checkpoint     *database.SourceCheckpoint `json:"checkpoint,omitempty"`
+	CheckpointOnly bool                       `json:"checkpoint_only,omitempty"`
+}
+
+// spillFile 磁盘溢出队列
+// 记录追加写入文件末尾，从读取偏移处按顺序读回；全部读完后截断文件。
+// 读取偏移不持久化，进程异常退出后重启会重新读回已写入数据库的记录（至少一次）
+type spillFile struct {
+	path     string   // 文件路径
+	maxBytes int64    // 最大字节数
+	file     *os.File // 文件句柄
+	size     int64    // 写入偏移（文件有效长度）
+	readOff  int64    // 读取偏移
+	pending  int      // 未读回的记录数
+}
+
+// openSpillFile 打开溢出文件，并统计上次运行遗留的记录
+// 参数: dir - 溢出目录, maxBytes - 最大字节数
+// 返回: 溢出文件实例和错误信息
+func openSpillFile(dir string, maxBytes int64) (*spillFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill dir: %v", err)
+	}
+
+	path := filepath.Join(dir, spillFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %v", err)
+	}
+
+	s := &spillFile{path: path, maxBytes: maxBytes, file: file}
+	if err := s.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover 统计文件中完整的记录，截掉末尾写了一半的记录
+func (s *spillFile) recover() error {
+	reader := bufio.NewReader(s.file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spill file: %v", err)
+		}
+		s.size += int64(len(line))
+		s.pending++
+	}
+
+	if err := s.file.Truncate(s.size); err != nil {
+		return fmt.Errorf("failed to truncate spill file: %v", err)
+	}
+	return nil
+}
+
+// append 追加一条记录
+// 参数: msg - 消息
+// 返回: 错误信息（超过最大字节数时返回ErrSpillFull）
+func (s *spillFile) append(msg *database.Message) error {
+	line, err := json.Marshal(spillRecord{
+		SourceIP:       msg.SourceIP,
+		SourceIdentity: msg.SourceIdentity,
+		SourceGroup:    msg.SourceGroup,
+		Data:           msg.OriginalData,
+		DataLength:     msg.DataLength,
+		Status:         msg.Status,
+		CreatedAt:      msg.CreatedAt,
+		Targets:        msg.Targets,
+		Attributes:     msg.Attributes,
+		Checkpoint:     msg.Checkpoint,
+		CheckpointOnly: msg.CheckpointOnly,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode spill record: %v", err)
+	}
+	line = append(line, '\n')
+
+	if s.size+int64(len(line)) > s.maxBytes {
+		return ErrSpillFull
+	}
+	if _, err := s.file.WriteAt(line, s.size); err != nil {
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+
+	s.size += int64(len(line))
+	s.pending++
+	return nil
+}
+
+// read 按写入顺序读回最多n条记录
+// 参数: n - 最大记录数
+// 返回: 消息列表和错误信息
+func (s *spillFile) read(n int) ([]*database.Message, error) {
+	if n <= 0 || s.pending == 0 {
+		return nil, nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.readOff, s.size-s.readOff))
+	var msgs []*database.Message
+	for len(msgs) < n && s.pending > 0 {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return msgs, fmt.Errorf("failed to read spill file: %v", err)
+		}
+		s.readOff += int64(len(line))
+		s.pending--
+
+		var record spillRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// 损坏的记录无法恢复，跳过
+			continue
+		}
+		msgs = append(msgs, &database.Message{
+			SourceIP:       record.SourceIP,
+			SourceIdentity: record.SourceIdentity,
+			SourceGroup:    record.SourceGroup,
+			OriginalData:   record.Data,
+			DataLength:     record.DataLength,
+			Status:         record.Status,
+			CreatedAt:      record.CreatedAt,
+			Targets:        record.Targets,
+			Attributes:     record.Attributes,
+			Checkpoint:     record.Checkpoint,
+			CheckpointOnly: record.CheckpointOnly,
+		})
+	}
+
+	if s.pending == 0 {
+		if err := s.reset(); err != nil {
+			return msgs, err
+		}
+	}
+	return msgs, nil
+}
+
+// prepend 把消息写到未读记录之前（关闭时保存内存队列使用，不受最大字节数限制）
+// 参数: msgs - 消息列表
+// 返回: 错误信息
+func (s *spillFile) prepend(msgs []*database.Message) error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+
+	head := &spillFile{path: tmpPath, maxBytes: math.MaxInt64, file: tmp}
+	for _, msg := range msgs {
+		if err := head.append(msg); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	rest := io.NewSectionReader(s.file, s.readOff, s.size-s.readOff)
+	n, err := io.Copy(io.NewOffsetWriter(tmp, head.size), rest)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy spill file: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace spill file: %v", err)
+	}
+
+	s.file.Close()
+	s.file = tmp
+	s.size = head.size + n
+	s.readOff = 0
+	s.pending += head.pending
+	return nil
+}
+
+// reset 所有记录读完后清空文件
+func (s *spillFile) reset() error {
+	s.size = 0
+	s.readOff = 0
+	s.pending = 0
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate spill file: %v", err)
+	}
+	return nil
+}
+
+// close 关闭溢出文件
+// 已读回的部分从文件中移除（未读记录复制到新文件），避免下次启动重复写入
+// 返回: 错误信息
+func (s *spillFile) close() error {
+	if s.readOff > 0 && s.pending > 0 {
+		if err := s.prepend(nil); err != nil {
+			s.file.Close()
+			return err
+		}
+	}
+	return s.file.Close()
+}
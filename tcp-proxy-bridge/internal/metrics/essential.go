@@ -28,8 +28,50 @@ type EssentialMetrics struct {
 	// ActiveConnections 当前活跃的TCP连接数
 	// 用途：监控系统并发负载，防止连接泄漏
 	ActiveConnections atomic.Int32
+
+	// IngestQueueDepth 入库队列中等待写入数据库的消息数（内存+溢出文件）
+	// 用途：监控数据库写入是否跟得上接收速度
+	IngestQueueDepth atomic.Int64
+
+	// IngestDropped 因入库队列已满被丢弃的消息总数
+	// 用途：背压告警
+	IngestDropped atomic.Int64
+
+	// ChecksumMismatches 校验和不一致被丢弃的数据包总数
+	// 用途：发现链路或上游编码问题
+	ChecksumMismatches atomic.Int64
+
+	// LengthMismatches 当前数据段长度与实际长度不一致（或超过上限）的数据包总数
+	// 用途：发现分帧错位或上游编码问题
+	LengthMismatches atomic.Int64
+
+	// FramesOversized 监听端口接收的超过最大帧长度被丢弃的帧总数
+	// 用途：发现客户端分帧配置不一致或异常大的消息
+	FramesOversized atomic.Int64
+
+	// FramesMalformed 监听端口接收的格式错误（长度字段非法、解码失败、连接关闭时不完整）被丢弃的帧总数
+	// 用途：发现客户端分帧配置不一致或链路问题
+	FramesMalformed atomic.Int64
+
+	// RejectedAccessDenied 来源IP不在访问控制允许范围内被拒绝的连接总数
+	// RejectedMaxConnections 超过监听端口最大并发连接数被拒绝的连接总数
+	// RejectedMaxConnectionsPerIP 超过单个来源IP最大并发连接数被拒绝的连接总数
+	// RejectedTLSHandshake TLS握手失败（包括客户端证书校验失败）被拒绝的连接总数
+	// 用途：发现扫描、攻击或连接数配置过小
+	RejectedAccessDenied        atomic.Int64
+	RejectedMaxConnections      atomic.Int64
+	RejectedMaxConnectionsPerIP atomic.Int64
+	RejectedTLSHandshake        atomic.Int64
 }
 
+// 连接被拒绝的原因常量定义
+const (
+	RejectAccessDenied        = "access_denied"          // 来源IP被访问控制拒绝
+	RejectMaxConnections      = "max_connections"        // 超过最大并发连接数
+	RejectMaxConnectionsPerIP = "max_connections_per_ip" // 超过单个来源IP的最大并发连接数
+	RejectTLSHandshake        = "tls_handshake"          // TLS握手或客户端证书校验失败
+)
+
 // 全局指标实例
 var globalMetrics = &EssentialMetrics{}
 
@@ -63,6 +105,58 @@ func DecActiveConnections() {
 	globalMetrics.ActiveConnections.Add(-1)
 }
 
+// SetIngestQueueDepth 设置入库队列深度
+// 在入库队列入队和批量写入后调用
+func SetIngestQueueDepth(depth int64) {
+	globalMetrics.IngestQueueDepth.Store(depth)
+}
+
+// IncIngestDropped 增加入库队列丢弃计数
+// 在队列已满丢弃消息时调用
+func IncIngestDropped() {
+	globalMetrics.IngestDropped.Add(1)
+}
+
+// IncChecksumMismatches 增加校验和不一致计数
+// 在解析数据包校验失败时调用
+func IncChecksumMismatches() {
+	globalMetrics.ChecksumMismatches.Add(1)
+}
+
+// IncLengthMismatches 增加数据段长度不一致计数
+// 在解析数据包长度检查失败时调用
+func IncLengthMismatches() {
+	globalMetrics.LengthMismatches.Add(1)
+}
+
+// IncFramesOversized 增加超长帧计数
+// 在监听端口分帧发现超过最大帧长度的帧时调用
+func IncFramesOversized() {
+	globalMetrics.FramesOversized.Add(1)
+}
+
+// IncFramesMalformed 增加格式错误帧计数
+// 在监听端口分帧失败或连接关闭时仍有不完整的帧时调用
+func IncFramesMalformed() {
+	globalMetrics.FramesMalformed.Add(1)
+}
+
+// IncConnectionsRejected 增加被拒绝的连接计数
+// 在监听端口拒绝新连接时调用
+// 参数: reason - 拒绝原因（Reject*常量）
+func IncConnectionsRejected(reason string) {
+	switch reason {
+	case RejectAccessDenied:
+		globalMetrics.RejectedAccessDenied.Add(1)
+	case RejectMaxConnections:
+		globalMetrics.RejectedMaxConnections.Add(1)
+	case RejectMaxConnectionsPerIP:
+		globalMetrics.RejectedMaxConnectionsPerIP.Add(1)
+	case RejectTLSHandshake:
+		globalMetrics.RejectedTLSHandshake.Add(1)
+	}
+}
+
 // GetMetricsSnapshot 获取指标快照
 // 返回: 包含所有当前指标值的map
 // 用途：定期日志记录、健康检查、调试信息
@@ -72,7 +166,21 @@ func GetMetricsSnapshot() map[string]interface{} {
 		"messages_forwarded": globalMetrics.MessagesForwarded.Load(),
 		"message_errors":     globalMetrics.MessageErrors.Load(),
 		"active_connections": globalMetrics.ActiveConnections.Load(),
+		"ingest_queue_depth": globalMetrics.IngestQueueDepth.Load(),
+		"ingest_dropped":     globalMetrics.IngestDropped.Load(),
+		"checksum_mismatch":  globalMetrics.ChecksumMismatches.Load(),
+		"length_mismatch":    globalMetrics.LengthMismatches.Load(),
+		"frames_oversized":   globalMetrics.FramesOversized.Load(),
+		"frames_malformed":   globalMetrics.FramesMalformed.Load(),
 		"timestamp":          time.Now().Format(time.RFC3339),
+
+		// 按原因统计的被拒绝连接数
+		"connections_rejected": map[string]int64{
+			RejectAccessDenied:        globalMetrics.RejectedAccessDenied.Load(),
+			RejectMaxConnections:      globalMetrics.RejectedMaxConnections.Load(),
+			RejectMaxConnectionsPerIP: globalMetrics.RejectedMaxConnectionsPerIP.Load(),
+			RejectTLSHandshake:        globalMetrics.RejectedTLSHandshake.Load(),
+		},
 	}
 }
 
@@ -81,11 +189,18 @@ func GetMetricsSnapshot() map[string]interface{} {
 // 用途：日志输出、状态显示
 func GetMetricsSummary() string {
 	snapshot := GetMetricsSnapshot()
-	return fmt.Sprintf("Received: %d, Forwarded: %d, Errors: %d, Connections: %d",
+	return fmt.Sprintf("Received: %d, Forwarded: %d, Errors: %d, Connections: %d, IngestQueue: %d, IngestDropped: %d, ChecksumMismatch: %d, LengthMismatch: %d, FramesOversized: %d, FramesMalformed: %d, Rejected: %v",
 		snapshot["messages_received"],
 		snapshot["messages_forwarded"],
 		snapshot["message_errors"],
-		snapshot["active_connections"])
+		snapshot["active_connections"],
+		snapshot["ingest_queue_depth"],
+		snapshot["ingest_dropped"],
+		snapshot["checksum_mismatch"],
+		snapshot["length_mismatch"],
+		snapshot["frames_oversized"],
+		snapshot["frames_malformed"],
+		snapshot["connections_rejected"])
 }
 
 // LogMetrics 记录指标到日志
@@ -115,6 +230,16 @@ func Reset() {
 	globalMetrics.MessagesForwarded.Store(0)
 	globalMetrics.MessageErrors.Store(0)
 	globalMetrics.ActiveConnections.Store(0)
+	globalMetrics.IngestQueueDepth.Store(0)
+	globalMetrics.IngestDropped.Store(0)
+	globalMetrics.ChecksumMismatches.Store(0)
+	globalMetrics.LengthMismatches.Store(0)
+	globalMetrics.FramesOversized.Store(0)
+	globalMetrics.FramesMalformed.Store(0)
+	globalMetrics.RejectedAccessDenied.Store(0)
+	globalMetrics.RejectedMaxConnections.Store(0)
+	globalMetrics.RejectedMaxConnectionsPerIP.Store(0)
+	globalMetrics.RejectedTLSHandshake.Store(0)
 }
 
 // GetConnectionCount 获取当前连接数
@@ -0,0 +1,189 @@
+// internal/pipeline/pipeline.go
+package pipeline
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/source"
+)
+
+// Packet 流经处理流水线的数据包
+type Packet struct {
+	Data       []byte             // 入库的数据内容（transform阶段可以改写）
+	Meta       *source.PacketMeta // 数据包元信息（源服务器、解析后的包头）
+	Group      string             // 源分组名称
+	Targets    []string           // 投递的目标服务器ID（为空时投递到所有启用的目标服务器）
+	Attributes map[string]string  // 附加属性（enrich阶段设置）
+}
+
+// Stage 处理阶段
+type Stage interface {
+	// Name 获取阶段名称
+	Name() string
+	// Process 处理数据包
+	// 返回: 是否继续传递（false表示丢弃）和错误信息（出错的数据包被丢弃）
+	Process(pkt *Packet) (bool, error)
+}
+
+// Sink 流水线终点，接收通过所有阶段的数据包
+type Sink func(pkt *Packet) error
+
+// stageStats 单个阶段的统计
+type stageStats struct {
+	processed atomic.Int64 // 处理的数据包数
+	dropped   atomic.Int64 // 丢弃的数据包数
+	errors    atomic.Int64 // 处理出错的数据包数
+}
+
+// Pipeline 处理流水线
+// 源服务器投递的每个数据包按顺序经过各阶段，通过所有阶段后交给终点（入库队列）。
+// 各阶段只读配置，可以被多个连接并发调用
+type Pipeline struct {
+	stages     []Stage
+	stats      []*stageStats
+	sink       Sink
+	checkpoint Sink // 被丢弃数据包的检查点终点（未设置时为nil）
+
+	received  atomic.Int64 // 进入流水线的数据包数
+	delivered atomic.Int64 // 交给终点的数据包数
+	failed    atomic.Int64 // 终点处理失败的数据包数
+}
+
+// New 按配置创建处理流水线
+// 参数: cfg - 完整配置（流水线阶段和BasePackage编解码配置）, sink - 流水线终点
+// 返回: 流水线实例和错误信息
+func New(cfg *config.Config, sink Sink) (*Pipeline, error) {
+	p := &Pipeline{sink: sink}
+	for i := range cfg.Pipeline.Stages {
+		stage, err := NewStage(&cfg.Pipeline.Stages[i], cfg.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		p.stages = append(p.stages, stage)
+		p.stats = append(p.stats, &stageStats{})
+	}
+	return p, nil
+}
+
+// NewStage 按配置创建处理阶段
+// 参数: cfg - 阶段配置, protocol - BasePackage编解码配置（full_package改写使用）
+// 返回: 处理阶段和错误信息
+func NewStage(cfg *config.StageConfig, protocol config.ProtocolConfig) (Stage, error) {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Type
+	}
+
+	switch cfg.Type {
+	case config.StageFilter:
+		return newFilterStage(name, cfg), nil
+	case config.StageTransform:
+		return newTransformStage(name, cfg, protocol)
+	case config.StageEnrich:
+		return newEnrichStage(name, cfg), nil
+	case config.StageRoute:
+		return newRouteStage(name, cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown pipeline stage type '%s'", cfg.Type)
+	}
+}
+
+// Handler 获取源分组的数据处理函数
+// 数据包带上分组名称和分组的目标服务器后进入流水线
+// 参数: group - 源分组
+// 返回: 数据处理函数
+func (p *Pipeline) Handler(group *config.SourceGroup) source.DataHandler {
+	name, targets := group.Name, group.Targets
+	return func(data []byte, meta *source.PacketMeta) error {
+		return p.Process(&Packet{
+			Data:    data,
+			Meta:    meta,
+			Group:   name,
+			Targets: targets,
+		})
+	}
+}
+
+// SetCheckpointSink 设置被丢弃数据包的检查点终点
+// 被阶段丢弃或处理出错的数据包不交给终点，但需要推进续传检查点时交给该终点
+// 参数: sink - 检查点终点
+func (p *Pipeline) SetCheckpointSink(sink Sink) {
+	p.checkpoint = sink
+}
+
+// CheckpointHandler 获取源分组的检查点处理函数
+// 源服务器管理器丢弃的数据包带上分组名称后交给检查点终点
+// 参数: group - 源分组
+// 返回: 检查点处理函数
+func (p *Pipeline) CheckpointHandler(group *config.SourceGroup) source.CheckpointHandler {
+	name := group.Name
+	return func(meta *source.PacketMeta) {
+		p.settle(&Packet{Meta: meta, Group: name})
+	}
+}
+
+// settle 推进未交给终点的数据包的续传检查点
+// 参数: pkt - 数据包
+func (p *Pipeline) settle(pkt *Packet) {
+	if p.checkpoint == nil || pkt.Meta == nil || !pkt.Meta.Checkpoint {
+		return
+	}
+	if err := p.checkpoint(pkt); err != nil {
+		log.Printf("Failed to advance checkpoint for dropped packet: %v", err)
+	}
+}
+
+// Process 数据包依次经过各阶段，通过后交给终点
+// 参数: pkt - 数据包
+// 返回: 阶段或终点的错误信息（被过滤的数据包返回nil）
+func (p *Pipeline) Process(pkt *Packet) error {
+	p.received.Add(1)
+
+	for i, stage := range p.stages {
+		stats := p.stats[i]
+		stats.processed.Add(1)
+
+		keep, err := stage.Process(pkt)
+		if err != nil {
+			stats.errors.Add(1)
+			p.settle(pkt)
+			return fmt.Errorf("pipeline stage %s: %v", stage.Name(), err)
+		}
+		if !keep {
+			stats.dropped.Add(1)
+			p.settle(pkt)
+			return nil
+		}
+	}
+
+	if err := p.sink(pkt); err != nil {
+		p.failed.Add(1)
+		return err
+	}
+	p.delivered.Add(1)
+	return nil
+}
+
+// GetStatus 获取流水线状态
+// 返回: 状态信息
+func (p *Pipeline) GetStatus() map[string]interface{} {
+	stages := make([]map[string]interface{}, 0, len(p.stages))
+	for i, stage := range p.stages {
+		stages = append(stages, map[string]interface{}{
+			"name":      stage.Name(),
+			"processed": p.stats[i].processed.Load(),
+			"dropped":   p.stats[i].dropped.Load(),
+			"errors":    p.stats[i].errors.Load(),
+		})
+	}
+
+	return map[string]interface{}{
+		"received":  p.received.Load(),
+		"delivered": p.delivered.Load(),
+		"failed":    p.failed.Load(),
+		"stages":    stages,
+	}
+}
@@ -0,0 +1,89 @@
+// internal/pipeline/pipeline_test.go
+package pipeline
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/source"
+)
+
+// failStage 对指定包序号的数据包返回错误的阶段
+type failStage struct {
+	packageNo uint64
+}
+
+func (s *failStage) Name() string { return "fail" }
+
+func (s *failStage) Process(pkt *Packet) (bool, error) {
+	if pkt.Meta.Header != nil && pkt.Meta.Header.PackageNo == s.packageNo {
+		return false, errors.New("stage failure")
+	}
+	return true, nil
+}
+
+// testPacket 构建来自信源7的数据包
+// 参数: no - 包序号, checkpoint - 是否推进续传检查点
+func testPacket(no uint64, checkpoint bool) *Packet {
+	return &Packet{
+		Data: []byte("payload"),
+		Meta: &source.PacketMeta{
+			ServerID:   "src-1",
+			Header:     &source.BasePackage{SourceInfo: 7, HostInfo: 20, PackageNo: no},
+			Checkpoint: checkpoint,
+		},
+		Group: "default",
+	}
+}
+
+func TestPipelineCheckpointSink(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Pipeline.Stages = []config.StageConfig{{
+		Type:   config.StageFilter,
+		Match:  config.MatchConfig{MaxLength: 4},
+		Action: config.FilterKeep,
+	}}
+
+	var delivered, settled []uint64
+	p, err := New(cfg, func(pkt *Packet) error {
+		delivered = append(delivered, pkt.Meta.Header.PackageNo)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.stages = append(p.stages, &failStage{packageNo: 4})
+	p.stats = append(p.stats, &stageStats{})
+	p.SetCheckpointSink(func(pkt *Packet) error {
+		if pkt.Group != "default" {
+			t.Errorf("checkpoint packet group = %q", pkt.Group)
+		}
+		settled = append(settled, pkt.Meta.Header.PackageNo)
+		return nil
+	})
+
+	// 1: 被过滤，推进检查点；2: 被过滤但不推进检查点；3: 通过所有阶段
+	short := testPacket(3, true)
+	short.Data = []byte("ok")
+	// 4: 阶段出错丢弃，推进检查点
+	errored := testPacket(4, true)
+	errored.Data = []byte("ok")
+
+	for _, pkt := range []*Packet{testPacket(1, true), testPacket(2, false), short, errored} {
+		p.Process(pkt)
+	}
+
+	// 源服务器管理器丢弃的数据包带上分组名称交给检查点终点
+	handler := p.CheckpointHandler(&config.SourceGroup{Name: "default"})
+	handler(testPacket(5, true).Meta)
+	handler(testPacket(6, false).Meta)
+
+	if !reflect.DeepEqual(delivered, []uint64{3}) {
+		t.Errorf("delivered %v, want [3]", delivered)
+	}
+	if !reflect.DeepEqual(settled, []uint64{1, 4, 5}) {
+		t.Errorf("checkpoints %v, want [1 4 5]", settled)
+	}
+}
@@ -0,0 +1,278 @@
+// internal/pipeline/stages.go
+package pipeline
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/source"
+)
+
+// matcher 数据包匹配条件
+type matcher struct {
+	servers    map[string]bool
+	identities map[string]bool
+	sources    map[uint32]bool
+	hosts      map[uint32]bool
+	types      map[uint16]bool
+	minLength  int
+	maxLength  int
+}
+
+// newMatcher 创建匹配条件
+// 参数: cfg - 匹配条件配置
+// 返回: 匹配条件实例
+func newMatcher(cfg config.MatchConfig) *matcher {
+	m := &matcher{minLength: cfg.MinLength, maxLength: cfg.MaxLength}
+	if len(cfg.Servers) > 0 {
+		m.servers = make(map[string]bool, len(cfg.Servers))
+		for _, id := range cfg.Servers {
+			m.servers[id] = true
+		}
+	}
+	if len(cfg.Identities) > 0 {
+		m.identities = make(map[string]bool, len(cfg.Identities))
+		for _, identity := range cfg.Identities {
+			m.identities[identity] = true
+		}
+	}
+	if len(cfg.SourceInfo) > 0 {
+		m.sources = make(map[uint32]bool, len(cfg.SourceInfo))
+		for _, v := range cfg.SourceInfo {
+			m.sources[v] = true
+		}
+	}
+	if len(cfg.HostInfo) > 0 {
+		m.hosts = make(map[uint32]bool, len(cfg.HostInfo))
+		for _, v := range cfg.HostInfo {
+			m.hosts[v] = true
+		}
+	}
+	if len(cfg.Types) > 0 {
+		m.types = make(map[uint16]bool, len(cfg.Types))
+		for _, v := range cfg.Types {
+			m.types[v] = true
+		}
+	}
+	return m
+}
+
+// match 检查数据包是否满足全部条件
+// 协议解析失败的数据包没有包头，不满足信源、信宿和信息类型条件
+// 参数: pkt - 数据包
+// 返回: 是否匹配
+func (m *matcher) match(pkt *Packet) bool {
+	if m.servers != nil && !m.servers[pkt.Meta.ServerID] {
+		return false
+	}
+	if m.identities != nil && !m.identities[pkt.Meta.SourceIdentity] {
+		return false
+	}
+	if len(pkt.Data) < m.minLength || (m.maxLength > 0 && len(pkt.Data) > m.maxLength) {
+		return false
+	}
+
+	header := pkt.Meta.Header
+	if m.sources != nil && (header == nil || !m.sources[header.SourceInfo]) {
+		return false
+	}
+	if m.hosts != nil && (header == nil || !m.hosts[header.HostInfo]) {
+		return false
+	}
+	if m.types != nil {
+		if header == nil {
+			return false
+		}
+		for _, t := range segmentTypes(header.Data) {
+			if m.types[t] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// segmentTypes 获取数据段中各数据项的信息类型编号
+// 只遍历数据项头，不解码信息内容；数据段不完整时返回已读到的类型
+// 参数: data - 数据段（BasePackage.Data）
+// 返回: 信息类型编号列表
+func segmentTypes(data []byte) []uint16 {
+	var types []uint16
+	// 数据段以4字节日期开始，之后每个数据项为类型(2字节) + 长度(2字节) + 内容
+	for offset := 4; len(data)-offset >= 4; {
+		types = append(types, binary.BigEndian.Uint16(data[offset:offset+2]))
+		offset += 4 + int(binary.BigEndian.Uint16(data[offset+2:offset+4]))
+	}
+	return types
+}
+
+// filterStage 过滤阶段
+// drop动作丢弃匹配的数据包，keep动作只保留匹配的数据包
+type filterStage struct {
+	name    string
+	matcher *matcher
+	keep    bool
+}
+
+func newFilterStage(name string, cfg *config.StageConfig) *filterStage {
+	return &filterStage{
+		name:    name,
+		matcher: newMatcher(cfg.Match),
+		keep:    cfg.Action == config.FilterKeep,
+	}
+}
+
+func (s *filterStage) Name() string { return s.name }
+
+func (s *filterStage) Process(pkt *Packet) (bool, error) {
+	return s.matcher.match(pkt) == s.keep, nil
+}
+
+// transformStage 改写数据阶段
+type transformStage struct {
+	name      string
+	operation string
+	bytes     []byte               // prefix/suffix添加的字节
+	maxLength int                  // truncate保留的最大字节数
+	codec     *source.PackageCodec // full_package使用的编解码器
+}
+
+func newTransformStage(name string, cfg *config.StageConfig, protocol config.ProtocolConfig) (*transformStage, error) {
+	s := &transformStage{name: name, operation: cfg.Operation, maxLength: cfg.MaxLength}
+	switch cfg.Operation {
+	case config.TransformFullPackage:
+		s.codec = source.NewPackageCodec(protocol)
+	case config.TransformPrefix, config.TransformSuffix:
+		data, err := hex.DecodeString(cfg.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %s: invalid hex: %v", name, err)
+		}
+		s.bytes = data
+	}
+	return s, nil
+}
+
+func (s *transformStage) Name() string { return s.name }
+
+func (s *transformStage) Process(pkt *Packet) (bool, error) {
+	switch s.operation {
+	case config.TransformFullPackage:
+		// 协议解析失败的数据包本身就是原始数据，不需要改写
+		if pkt.Meta.Header == nil {
+			return true, nil
+		}
+		data, err := s.codec.Marshal(pkt.Meta.Header)
+		if err != nil {
+			return false, err
+		}
+		pkt.Data = data
+	case config.TransformPrefix:
+		pkt.Data = append(append(make([]byte, 0, len(s.bytes)+len(pkt.Data)), s.bytes...), pkt.Data...)
+	case config.TransformSuffix:
+		pkt.Data = append(append(make([]byte, 0, len(pkt.Data)+len(s.bytes)), pkt.Data...), s.bytes...)
+	case config.TransformTruncate:
+		if len(pkt.Data) > s.maxLength {
+			pkt.Data = pkt.Data[:s.maxLength]
+		}
+	}
+	return true, nil
+}
+
+// enrichStage 附加属性阶段
+// 从数据包提取字段并附加固定标签；包头相关字段在协议解析失败时不附加
+type enrichStage struct {
+	name   string
+	fields []string
+	labels map[string]string
+}
+
+func newEnrichStage(name string, cfg *config.StageConfig) *enrichStage {
+	return &enrichStage{name: name, fields: cfg.Fields, labels: cfg.Labels}
+}
+
+func (s *enrichStage) Name() string { return s.name }
+
+func (s *enrichStage) Process(pkt *Packet) (bool, error) {
+	if pkt.Attributes == nil {
+		pkt.Attributes = make(map[string]string, len(s.fields)+len(s.labels))
+	}
+	for key, value := range s.labels {
+		pkt.Attributes[key] = value
+	}
+
+	header := pkt.Meta.Header
+	for _, field := range s.fields {
+		switch field {
+		case "server_id":
+			pkt.Attributes[field] = pkt.Meta.ServerID
+		case "received_at":
+			received := time.Now()
+			if header != nil && !header.Timestamp.IsZero() {
+				received = header.Timestamp
+			}
+			pkt.Attributes[field] = received.Format(time.RFC3339Nano)
+		case "source_identity":
+			if pkt.Meta.SourceIdentity != "" {
+				pkt.Attributes[field] = pkt.Meta.SourceIdentity
+			}
+		}
+		if header == nil {
+			continue
+		}
+		switch field {
+		case "source_info":
+			pkt.Attributes[field] = strconv.FormatUint(uint64(header.SourceInfo), 10)
+		case "host_info":
+			pkt.Attributes[field] = strconv.FormatUint(uint64(header.HostInfo), 10)
+		case "package_no":
+			pkt.Attributes[field] = strconv.FormatUint(header.PackageNo, 10)
+		case "types":
+			types := segmentTypes(header.Data)
+			names := make([]string, len(types))
+			for i, t := range types {
+				names[i] = strconv.Itoa(int(t))
+			}
+			pkt.Attributes[field] = strings.Join(names, ",")
+		}
+	}
+	return true, nil
+}
+
+// routeStage 路由阶段
+// 按顺序匹配规则，第一条匹配的规则决定目标服务器；没有规则匹配时使用默认目标服务器
+type routeStage struct {
+	name     string
+	matchers []*matcher
+	targets  [][]string
+	fallback []string
+}
+
+func newRouteStage(name string, cfg *config.StageConfig) *routeStage {
+	s := &routeStage{name: name, fallback: cfg.Default}
+	for _, route := range cfg.Routes {
+		s.matchers = append(s.matchers, newMatcher(route.Match))
+		s.targets = append(s.targets, route.Targets)
+	}
+	return s
+}
+
+func (s *routeStage) Name() string { return s.name }
+
+func (s *routeStage) Process(pkt *Packet) (bool, error) {
+	for i, m := range s.matchers {
+		if m.match(pkt) {
+			pkt.Targets = s.targets[i]
+			return true, nil
+		}
+	}
+	if len(s.fallback) > 0 {
+		pkt.Targets = s.fallback
+	}
+	return true, nil
+}
@@ -0,0 +1,294 @@
+// internal/pipeline/stages_test.go
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/source"
+)
+
+// testSegment 数据段：日期 + 信息类型99（1字节内容）+ 信息类型5（无内容）
+var testSegment = []byte{0x07, 0xea, 10, 17, 0x00, 0x63, 0x00, 0x01, 0xff, 0x00, 0x05, 0x00, 0x00}
+
+// headerPacket 构建带包头的数据包
+// 参数: serverID - 源服务器ID, sourceInfo - 信源, hostInfo - 信宿
+func headerPacket(serverID string, sourceInfo, hostInfo uint32) *Packet {
+	header := &source.BasePackage{
+		SourceInfo:    sourceInfo,
+		HostInfo:      hostInfo,
+		PackageNo:     42,
+		DataSumLength: uint32(len(testSegment)),
+		Data:          testSegment,
+		Timestamp:     time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+	}
+	return &Packet{
+		Data:  append([]byte(nil), testSegment...),
+		Meta:  &source.PacketMeta{ServerID: serverID, Header: header},
+		Group: "default",
+	}
+}
+
+// rawPacket 构建协议解析失败的数据包（没有包头）
+func rawPacket(serverID string, data string) *Packet {
+	return &Packet{
+		Data:  []byte(data),
+		Meta:  &source.PacketMeta{ServerID: serverID},
+		Group: "default",
+	}
+}
+
+// newTestStage 按配置创建处理阶段
+func newTestStage(t *testing.T, cfg config.StageConfig) Stage {
+	t.Helper()
+	stage, err := NewStage(&cfg, config.ProtocolConfig{})
+	if err != nil {
+		t.Fatalf("NewStage: %v", err)
+	}
+	return stage
+}
+
+func TestFilterStage(t *testing.T) {
+	identity := rawPacket("src-1", "raw")
+	identity.Meta.SourceIdentity = "CN=client-a"
+
+	tests := []struct {
+		name  string
+		match config.MatchConfig
+		pkt   *Packet
+		want  bool // drop动作下是否匹配（匹配时丢弃）
+	}{
+		{name: "empty match", pkt: rawPacket("src-1", "raw"), want: true},
+		{name: "server", match: config.MatchConfig{Servers: []string{"src-2", "src-1"}}, pkt: rawPacket("src-1", "raw"), want: true},
+		{name: "other server", match: config.MatchConfig{Servers: []string{"src-2"}}, pkt: rawPacket("src-1", "raw")},
+		{name: "identity", match: config.MatchConfig{Identities: []string{"CN=client-a"}}, pkt: identity, want: true},
+		{name: "source info", match: config.MatchConfig{SourceInfo: []uint32{7}}, pkt: headerPacket("src-1", 7, 20), want: true},
+		{name: "other source info", match: config.MatchConfig{SourceInfo: []uint32{8}}, pkt: headerPacket("src-1", 7, 20)},
+		{name: "host info", match: config.MatchConfig{HostInfo: []uint32{20}}, pkt: headerPacket("src-1", 7, 20), want: true},
+		// 没有包头的数据包不满足包头相关的条件
+		{name: "source info without header", match: config.MatchConfig{SourceInfo: []uint32{7}}, pkt: rawPacket("src-1", "raw")},
+		{name: "type", match: config.MatchConfig{Types: []uint16{5}}, pkt: headerPacket("src-1", 7, 20), want: true},
+		{name: "other type", match: config.MatchConfig{Types: []uint16{6}}, pkt: headerPacket("src-1", 7, 20)},
+		{name: "type without header", match: config.MatchConfig{Types: []uint16{5}}, pkt: rawPacket("src-1", "raw")},
+		{name: "min length", match: config.MatchConfig{MinLength: 4}, pkt: rawPacket("src-1", "raw")},
+		{name: "max length", match: config.MatchConfig{MaxLength: 3}, pkt: rawPacket("src-1", "raw"), want: true},
+		{name: "over max length", match: config.MatchConfig{MaxLength: 2}, pkt: rawPacket("src-1", "raw")},
+		// 全部条件满足时才匹配
+		{name: "all conditions", match: config.MatchConfig{Servers: []string{"src-1"}, SourceInfo: []uint32{8}}, pkt: headerPacket("src-1", 7, 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drop := newTestStage(t, config.StageConfig{Type: config.StageFilter, Match: tt.match})
+			if keep, err := drop.Process(tt.pkt); err != nil || keep == tt.want {
+				t.Errorf("drop: Process() = %v, %v, want %v", keep, err, !tt.want)
+			}
+
+			keep := newTestStage(t, config.StageConfig{Type: config.StageFilter, Match: tt.match, Action: config.FilterKeep})
+			if kept, err := keep.Process(tt.pkt); err != nil || kept != tt.want {
+				t.Errorf("keep: Process() = %v, %v, want %v", kept, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformStage(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.StageConfig
+		pkt  *Packet
+		want []byte
+	}{
+		{name: "prefix", cfg: config.StageConfig{Operation: config.TransformPrefix, Hex: "aabb"}, pkt: rawPacket("src-1", "raw"), want: []byte("\xaa\xbbraw")},
+		{name: "suffix", cfg: config.StageConfig{Operation: config.TransformSuffix, Hex: "0d0a"}, pkt: rawPacket("src-1", "raw"), want: []byte("raw\r\n")},
+		{name: "truncate", cfg: config.StageConfig{Operation: config.TransformTruncate, MaxLength: 2}, pkt: rawPacket("src-1", "raw"), want: []byte("ra")},
+		{name: "truncate short", cfg: config.StageConfig{Operation: config.TransformTruncate, MaxLength: 8}, pkt: rawPacket("src-1", "raw"), want: []byte("raw")},
+		// 没有包头的数据包保持原始数据
+		{name: "full package without header", cfg: config.StageConfig{Operation: config.TransformFullPackage}, pkt: rawPacket("src-1", "raw"), want: []byte("raw")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Type = config.StageTransform
+			stage := newTestStage(t, tt.cfg)
+			if keep, err := stage.Process(tt.pkt); err != nil || !keep {
+				t.Fatalf("Process() = %v, %v", keep, err)
+			}
+			if !reflect.DeepEqual(tt.pkt.Data, tt.want) {
+				t.Errorf("data = %q, want %q", tt.pkt.Data, tt.want)
+			}
+		})
+	}
+
+	t.Run("full package", func(t *testing.T) {
+		stage := newTestStage(t, config.StageConfig{Type: config.StageTransform, Operation: config.TransformFullPackage})
+		pkt := headerPacket("src-1", 7, 20)
+		if keep, err := stage.Process(pkt); err != nil || !keep {
+			t.Fatalf("Process() = %v, %v", keep, err)
+		}
+
+		// 存储的是包头和数据段序列化后的完整BasePackage
+		decoded, err := source.NewPackageCodec(config.ProtocolConfig{}).Unmarshal(pkt.Data)
+		if err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if decoded.SourceInfo != 7 || decoded.HostInfo != 20 || decoded.PackageNo != 42 || !reflect.DeepEqual(decoded.Data, testSegment) {
+			t.Errorf("decoded package = %+v", decoded)
+		}
+	})
+
+	t.Run("invalid hex", func(t *testing.T) {
+		cfg := config.StageConfig{Type: config.StageTransform, Operation: config.TransformPrefix, Hex: "zz"}
+		if _, err := NewStage(&cfg, config.ProtocolConfig{}); err == nil {
+			t.Error("NewStage accepted invalid hex")
+		}
+	})
+}
+
+func TestEnrichStage(t *testing.T) {
+	fields := []string{"server_id", "source_info", "host_info", "package_no", "types", "received_at", "source_identity"}
+	stage := newTestStage(t, config.StageConfig{
+		Type:   config.StageEnrich,
+		Fields: fields,
+		Labels: map[string]string{"site": "north"},
+	})
+
+	pkt := headerPacket("src-1", 7, 20)
+	if keep, err := stage.Process(pkt); err != nil || !keep {
+		t.Fatalf("Process() = %v, %v", keep, err)
+	}
+	want := map[string]string{
+		"site":        "north",
+		"server_id":   "src-1",
+		"source_info": "7",
+		"host_info":   "20",
+		"package_no":  "42",
+		"types":       "99,5",
+		"received_at": "2026-10-17T08:00:00Z",
+	}
+	if !reflect.DeepEqual(pkt.Attributes, want) {
+		t.Errorf("attributes = %v, want %v", pkt.Attributes, want)
+	}
+
+	// 没有包头的数据包只附加与包头无关的字段
+	raw := rawPacket("src-2", "raw")
+	raw.Meta.SourceIdentity = "CN=client-a"
+	if _, err := stage.Process(raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, raw.Attributes["received_at"]); err != nil {
+		t.Errorf("received_at = %q: %v", raw.Attributes["received_at"], err)
+	}
+	delete(raw.Attributes, "received_at")
+	want = map[string]string{"site": "north", "server_id": "src-2", "source_identity": "CN=client-a"}
+	if !reflect.DeepEqual(raw.Attributes, want) {
+		t.Errorf("attributes without header = %v, want %v", raw.Attributes, want)
+	}
+}
+
+func TestRouteStage(t *testing.T) {
+	cfg := config.StageConfig{
+		Type: config.StageRoute,
+		Routes: []config.RouteConfig{
+			{Match: config.MatchConfig{SourceInfo: []uint32{7}}, Targets: []string{"target-a"}},
+			{Match: config.MatchConfig{Servers: []string{"src-1"}}, Targets: []string{"target-b", "target-c"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		fallback []string
+		pkt      *Packet
+		want     []string
+	}{
+		// 按顺序匹配第一条规则
+		{name: "first route", pkt: headerPacket("src-1", 7, 20), want: []string{"target-a"}},
+		{name: "second route", pkt: headerPacket("src-1", 8, 20), want: []string{"target-b", "target-c"}},
+		// 没有规则匹配且未配置默认目标时保持源分组的目标服务器
+		{name: "no match", pkt: headerPacket("src-2", 8, 20), want: []string{"group-target"}},
+		{name: "default", fallback: []string{"target-d"}, pkt: headerPacket("src-2", 8, 20), want: []string{"target-d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Default = tt.fallback
+			stage := newTestStage(t, cfg)
+			tt.pkt.Targets = []string{"group-target"}
+			if keep, err := stage.Process(tt.pkt); err != nil || !keep {
+				t.Fatalf("Process() = %v, %v", keep, err)
+			}
+			if !reflect.DeepEqual(tt.pkt.Targets, tt.want) {
+				t.Errorf("targets = %v, want %v", tt.pkt.Targets, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStage(t *testing.T) {
+	stage := newTestStage(t, config.StageConfig{Type: config.StageEnrich})
+	if stage.Name() != config.StageEnrich {
+		t.Errorf("default name = %q, want stage type", stage.Name())
+	}
+	stage = newTestStage(t, config.StageConfig{Type: config.StageFilter, Name: "drop-heartbeat"})
+	if stage.Name() != "drop-heartbeat" {
+		t.Errorf("name = %q", stage.Name())
+	}
+
+	cfg := config.StageConfig{Type: "unknown"}
+	if _, err := NewStage(&cfg, config.ProtocolConfig{}); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("NewStage(unknown) error = %v", err)
+	}
+}
+
+func TestPipelineProcess(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Pipeline.Stages = []config.StageConfig{
+		{Type: config.StageFilter, Name: "drop-src-2", Match: config.MatchConfig{Servers: []string{"src-2"}}},
+		{Type: config.StageTransform, Operation: config.TransformPrefix, Hex: "ff"},
+		{Type: config.StageEnrich, Labels: map[string]string{"site": "north"}},
+		{Type: config.StageRoute, Routes: []config.RouteConfig{{Match: config.MatchConfig{SourceInfo: []uint32{7}}, Targets: []string{"target-a"}}}},
+	}
+
+	var delivered []*Packet
+	p, err := New(cfg, func(pkt *Packet) error {
+		delivered = append(delivered, pkt)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 源分组的处理函数带上分组名称和目标服务器
+	handler := p.Handler(&config.SourceGroup{Name: "north", Targets: []string{"group-target"}})
+	for _, serverID := range []string{"src-1", "src-2"} {
+		pkt := headerPacket(serverID, 7, 20)
+		if err := handler(pkt.Data, pkt.Meta); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+
+	// 各阶段按顺序处理，被过滤的数据包不交给终点
+	if len(delivered) != 1 {
+		t.Fatalf("delivered %d packets, want 1", len(delivered))
+	}
+	pkt := delivered[0]
+	if pkt.Group != "north" || pkt.Meta.ServerID != "src-1" || pkt.Data[0] != 0xff ||
+		pkt.Attributes["site"] != "north" || !reflect.DeepEqual(pkt.Targets, []string{"target-a"}) {
+		t.Errorf("delivered packet = %+v", pkt)
+	}
+
+	status := p.GetStatus()
+	if status["received"] != int64(2) || status["delivered"] != int64(1) || status["failed"] != int64(0) {
+		t.Errorf("status = %v", status)
+	}
+	stages := status["stages"].([]map[string]interface{})
+	if stages[0]["name"] != "drop-src-2" || stages[0]["processed"] != int64(2) || stages[0]["dropped"] != int64(1) {
+		t.Errorf("filter stage status = %v", stages[0])
+	}
+	if stages[3]["name"] != config.StageRoute || stages[3]["processed"] != int64(1) {
+		t.Errorf("route stage status = %v", stages[3])
+	}
+}
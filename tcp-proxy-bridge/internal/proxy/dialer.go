@@ -0,0 +1,288 @@
+// internal/proxy/dialer.go
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// SOCKS5协议常量定义（RFC 1928 / RFC 1929）
+const (
+	socksVersion       = 0x05 // 协议版本
+	socksAuthVersion   = 0x01 // 用户名/密码认证子协议版本
+	socksMethodNone    = 0x00 // 无需认证
+	socksMethodUserPwd = 0x02 // 用户名/密码认证
+	socksMethodNoMatch = 0xFF // 没有可接受的认证方式
+	socksCmdConnect    = 0x01 // CONNECT命令
+	socksAddrIPv4      = 0x01 // IPv4地址
+	socksAddrDomain    = 0x03 // 域名
+	socksAddrIPv6      = 0x04 // IPv6地址
+)
+
+// socksReplies SOCKS5应答码说明
+var socksReplies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// Dialer 出站连接拨号器
+// 未配置代理时直接建立TCP连接；配置代理时先连接代理，再通过SOCKS5 CONNECT或HTTP CONNECT
+// 建立到目标地址的隧道。目标地址中的主机名交给代理解析
+type Dialer struct {
+	cfg config.ProxyConfig // 代理配置
+}
+
+// NewDialer 创建出站连接拨号器
+// 参数: cfg - 代理配置
+// 返回: 拨号器实例
+func NewDialer(cfg config.ProxyConfig) *Dialer {
+	return &Dialer{cfg: cfg}
+}
+
+// DialContext 建立到目标地址的连接
+// 经代理时超时时间同时覆盖到代理的TCP连接和隧道建立
+// 参数: ctx - 上下文, timeout - 超时时间, address - 目标地址 (host:port)
+// 返回: 连接和错误信息
+func (d *Dialer) DialContext(ctx context.Context, timeout time.Duration, address string) (net.Conn, error) {
+	netDialer := &net.Dialer{Timeout: timeout}
+	if d.cfg.Type == "" {
+		return netDialer.DialContext(ctx, "tcp", address)
+	}
+
+	conn, err := netDialer.DialContext(ctx, "tcp", d.cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%s proxy %s: %v", d.cfg.Type, d.cfg.Address, err)
+	}
+
+	// 隧道建立期间的读写受超时和上下文取消约束
+	conn.SetDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	tunnel, err := d.handshake(conn, address)
+	if !stop() {
+		// 上下文已取消，隧道即使建立也不再使用
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s proxy %s: %v", d.cfg.Type, d.cfg.Address, err)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// handshake 按代理类型建立隧道
+// 参数: conn - 到代理的连接, address - 目标地址
+// 返回: 隧道连接和错误信息
+func (d *Dialer) handshake(conn net.Conn, address string) (net.Conn, error) {
+	switch d.cfg.Type {
+	case config.ProxySOCKS5:
+		if err := d.socks5Connect(conn, address); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	case config.ProxyHTTP:
+		return d.httpConnect(conn, address)
+	default:
+		return nil, fmt.Errorf("unsupported proxy type '%s'", d.cfg.Type)
+	}
+}
+
+// socks5Connect 通过SOCKS5代理连接目标地址
+// 参数: conn - 到代理的连接, address - 目标地址
+// 返回: 错误信息
+func (d *Dialer) socks5Connect(conn net.Conn, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port in %s", address)
+	}
+
+	// 协商认证方式
+	methods := []byte{socksMethodNone}
+	if d.cfg.Username != "" {
+		methods = append(methods, socksMethodUserPwd)
+	}
+	greeting := append([]byte{socksVersion, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return fmt.Errorf("socks5 greeting: %v", err)
+	}
+
+	var choice [2]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil {
+		return fmt.Errorf("socks5 greeting: %v", err)
+	}
+	if choice[0] != socksVersion {
+		return fmt.Errorf("unexpected socks version %d", choice[0])
+	}
+
+	switch choice[1] {
+	case socksMethodNone:
+	case socksMethodUserPwd:
+		if d.cfg.Username == "" {
+			return fmt.Errorf("socks5 proxy requires username/password authentication")
+		}
+		if err := d.socks5Auth(conn); err != nil {
+			return err
+		}
+	case socksMethodNoMatch:
+		return fmt.Errorf("socks5 proxy rejected all authentication methods")
+	default:
+		return fmt.Errorf("socks5 proxy selected unsupported authentication method %d", choice[1])
+	}
+
+	// 发送CONNECT请求
+	req := []byte{socksVersion, socksCmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socksAddrIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socksAddrIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(req, socksAddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("socks5 connect: %v", err)
+	}
+
+	// 读取应答，绑定地址不使用
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return fmt.Errorf("socks5 connect: %v", err)
+	}
+	if head[0] != socksVersion {
+		return fmt.Errorf("unexpected socks version %d", head[0])
+	}
+	if head[1] != 0x00 {
+		if reason, ok := socksReplies[head[1]]; ok {
+			return fmt.Errorf("socks5 connect to %s failed: %s", address, reason)
+		}
+		return fmt.Errorf("socks5 connect to %s failed: reply %d", address, head[1])
+	}
+
+	var addrLen int
+	switch head[3] {
+	case socksAddrIPv4:
+		addrLen = net.IPv4len
+	case socksAddrIPv6:
+		addrLen = net.IPv6len
+	case socksAddrDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return fmt.Errorf("socks5 connect: %v", err)
+		}
+		addrLen = int(n[0])
+	default:
+		return fmt.Errorf("socks5 reply has unknown address type %d", head[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return fmt.Errorf("socks5 connect: %v", err)
+	}
+
+	return nil
+}
+
+// socks5Auth SOCKS5用户名/密码认证
+// 参数: conn - 到代理的连接
+// 返回: 错误信息
+func (d *Dialer) socks5Auth(conn net.Conn) error {
+	req := []byte{socksAuthVersion, byte(len(d.cfg.Username))}
+	req = append(req, d.cfg.Username...)
+	req = append(req, byte(len(d.cfg.Password)))
+	req = append(req, d.cfg.Password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("socks5 auth: %v", err)
+	}
+
+	var resp [2]byte
+	if _, err := io.ReadFull(conn, resp[:]); err != nil {
+		return fmt.Errorf("socks5 auth: %v", err)
+	}
+	if resp[1] != 0x00 {
+		return fmt.Errorf("socks5 proxy rejected username/password")
+	}
+	return nil
+}
+
+// httpConnect 通过HTTP CONNECT代理连接目标地址
+// 参数: conn - 到代理的连接, address - 目标地址
+// 返回: 隧道连接和错误信息（代理在应答后紧接着发送的数据保留在隧道连接中）
+func (d *Dialer) httpConnect(conn net.Conn, address string) (net.Conn, error) {
+	req := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if d.cfg.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.cfg.Username + ":" + d.cfg.Password))
+		req += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	req += "\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		return nil, fmt.Errorf("http connect: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, fmt.Errorf("http connect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http connect to %s failed: %s", address, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// GetStatus 获取拨号器状态
+// 返回: 状态信息（不含密码），直连时返回nil
+func (d *Dialer) GetStatus() map[string]interface{} {
+	if d.cfg.Type == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"type":     d.cfg.Type,
+		"address":  d.cfg.Address,
+		"username": d.cfg.Username,
+	}
+}
@@ -0,0 +1,302 @@
+// internal/proxy/dialer_test.go
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// startListener 在本地随机端口启动监听，每个连接交给handle处理
+func startListener(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// startEcho 启动回显服务器，作为隧道的目标地址
+func startEcho(t *testing.T) string {
+	return startListener(t, func(conn net.Conn) {
+		io.Copy(conn, conn)
+	})
+}
+
+// tunnel 在客户端和目标地址之间双向转发
+func tunnel(client io.Reader, clientConn net.Conn, address string) {
+	upstream, err := net.Dial("tcp", address)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	go io.Copy(upstream, client)
+	io.Copy(clientConn, upstream)
+}
+
+// startSOCKS5 启动SOCKS5代理替身
+// 参数: username/password - 要求的用户名和密码（为空时不认证）, reply - CONNECT应答码
+func startSOCKS5(t *testing.T, username, password string, reply byte) string {
+	return startListener(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+
+		var head [2]byte
+		if _, err := io.ReadFull(br, head[:]); err != nil {
+			return
+		}
+		methods := make([]byte, head[1])
+		if _, err := io.ReadFull(br, methods); err != nil {
+			return
+		}
+		want := byte(socksMethodNone)
+		if username != "" {
+			want = socksMethodUserPwd
+		}
+		if bytes.IndexByte(methods, want) < 0 {
+			conn.Write([]byte{socksVersion, socksMethodNoMatch})
+			return
+		}
+		conn.Write([]byte{socksVersion, want})
+
+		if want == socksMethodUserPwd {
+			var fields [2]string
+			br.ReadByte()
+			for i := range fields {
+				n, _ := br.ReadByte()
+				buf := make([]byte, n)
+				io.ReadFull(br, buf)
+				fields[i] = string(buf)
+			}
+			if fields[0] != username || fields[1] != password {
+				conn.Write([]byte{socksAuthVersion, 0x01})
+				return
+			}
+			conn.Write([]byte{socksAuthVersion, 0x00})
+		}
+
+		var req [4]byte
+		if _, err := io.ReadFull(br, req[:]); err != nil {
+			return
+		}
+		var host string
+		switch req[3] {
+		case socksAddrIPv4:
+			ip := make([]byte, net.IPv4len)
+			io.ReadFull(br, ip)
+			host = net.IP(ip).String()
+		case socksAddrDomain:
+			n, _ := br.ReadByte()
+			name := make([]byte, n)
+			io.ReadFull(br, name)
+			host = string(name)
+		default:
+			return
+		}
+		var port [2]byte
+		io.ReadFull(br, port[:])
+
+		conn.Write([]byte{socksVersion, reply, 0x00, socksAddrIPv4, 127, 0, 0, 1, 0, 0})
+		if reply != 0x00 {
+			return
+		}
+		if host == "localhost" {
+			host = "127.0.0.1"
+		}
+		tunnel(br, conn, net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))))
+	})
+}
+
+// startHTTPConnect 启动HTTP CONNECT代理替身
+// 参数: username/password - 要求的Basic认证（为空时不认证）, status - CONNECT应答状态码, greeting - 应答后紧接着发送的数据
+func startHTTPConnect(t *testing.T, username, password string, status int, greeting string) string {
+	return startListener(t, func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		req, err := http.ReadRequest(br)
+		if err != nil || req.Method != http.MethodConnect {
+			return
+		}
+
+		if username != "" {
+			expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+			if req.Header.Get("Proxy-Authorization") != expected {
+				io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+				return
+			}
+		}
+		if status != http.StatusOK {
+			io.WriteString(conn, "HTTP/1.1 "+strconv.Itoa(status)+" "+http.StatusText(status)+"\r\nContent-Length: 0\r\n\r\n")
+			return
+		}
+
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"+greeting)
+		tunnel(br, conn, req.Host)
+	})
+}
+
+func TestDialerTunnel(t *testing.T) {
+	echo := startEcho(t)
+	_, echoPort, _ := net.SplitHostPort(echo)
+
+	tests := []struct {
+		name     string
+		cfg      config.ProxyConfig
+		target   string
+		greeting string // 代理在应答后紧接着发送的数据
+		wantErr  string
+	}{
+		{
+			name:   "direct",
+			cfg:    config.ProxyConfig{},
+			target: echo,
+		},
+		{
+			name:   "socks5 no auth",
+			cfg:    config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "", "", 0x00)},
+			target: echo,
+		},
+		{
+			name:   "socks5 username/password",
+			cfg:    config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "bridge", "secret", 0x00), Username: "bridge", Password: "secret"},
+			target: echo,
+		},
+		{
+			name:   "socks5 domain target",
+			cfg:    config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "", "", 0x00)},
+			target: net.JoinHostPort("localhost", echoPort),
+		},
+		{
+			name:    "socks5 wrong password",
+			cfg:     config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "bridge", "secret", 0x00), Username: "bridge", Password: "wrong"},
+			target:  echo,
+			wantErr: "rejected username/password",
+		},
+		{
+			name:    "socks5 requires auth",
+			cfg:     config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "bridge", "secret", 0x00)},
+			target:  echo,
+			wantErr: "rejected all authentication methods",
+		},
+		{
+			name:    "socks5 connect refused",
+			cfg:     config.ProxyConfig{Type: config.ProxySOCKS5, Address: startSOCKS5(t, "", "", 0x05)},
+			target:  echo,
+			wantErr: "connection refused",
+		},
+		{
+			name:   "http connect",
+			cfg:    config.ProxyConfig{Type: config.ProxyHTTP, Address: startHTTPConnect(t, "", "", http.StatusOK, "")},
+			target: echo,
+		},
+		{
+			name:   "http connect basic auth",
+			cfg:    config.ProxyConfig{Type: config.ProxyHTTP, Address: startHTTPConnect(t, "bridge", "secret", http.StatusOK, ""), Username: "bridge", Password: "secret"},
+			target: echo,
+		},
+		{
+			name:     "http connect data after response",
+			cfg:      config.ProxyConfig{Type: config.ProxyHTTP, Address: startHTTPConnect(t, "", "", http.StatusOK, "hi")},
+			target:   echo,
+			greeting: "hi",
+		},
+		{
+			name:    "http connect auth required",
+			cfg:     config.ProxyConfig{Type: config.ProxyHTTP, Address: startHTTPConnect(t, "bridge", "secret", http.StatusOK, "")},
+			target:  echo,
+			wantErr: "407",
+		},
+		{
+			name:    "http connect forbidden",
+			cfg:     config.ProxyConfig{Type: config.ProxyHTTP, Address: startHTTPConnect(t, "", "", http.StatusForbidden, "")},
+			target:  echo,
+			wantErr: "403",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := NewDialer(tt.cfg).DialContext(context.Background(), 2*time.Second, tt.target)
+			if tt.wantErr != "" {
+				if err == nil {
+					conn.Close()
+					t.Fatalf("DialContext succeeded, want error containing %q", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DialContext: %v", err)
+			}
+			defer conn.Close()
+
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			want := tt.greeting + "ping"
+			got := make([]byte, len(want))
+			if _, err := io.ReadFull(conn, got); err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("read %q through tunnel, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDialerContextCancel(t *testing.T) {
+	// 代理接受连接但不应答，取消上下文后拨号应立即返回
+	silent := startListener(t, func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := NewDialer(config.ProxyConfig{Type: config.ProxySOCKS5, Address: silent}).DialContext(ctx, 5*time.Second, "127.0.0.1:1")
+	if err == nil {
+		t.Fatal("DialContext succeeded against silent proxy")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DialContext returned after %v, want prompt return on cancel", elapsed)
+	}
+}
+
+func TestDialerGetStatus(t *testing.T) {
+	if status := NewDialer(config.ProxyConfig{}).GetStatus(); status != nil {
+		t.Errorf("direct dialer status = %v, want nil", status)
+	}
+	status := NewDialer(config.ProxyConfig{Type: config.ProxyHTTP, Address: "proxy:3128", Username: "u", Password: "p"}).GetStatus()
+	if _, ok := status["password"]; ok {
+		t.Error("status exposes password")
+	}
+}
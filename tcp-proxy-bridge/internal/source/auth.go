@@ -3,49 +3,61 @@ package source
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"sync"
 	"time"
+
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// 请求包重复标志常量定义
+const (
+	RetransmissionRequestFlag uint16 = 1 // 重传请求
+	ResumeRequestFlag         uint16 = 2 // 续传请求
 )
 
 // AuthManager 身份认证管理器
 // 负责处理与源服务器的身份认证
 type AuthManager struct {
-	token        string    // 认证令牌
-	sourceID     uint32    // 信源ID
-	hostID       uint32    // 信宿ID
-	packageNo    uint64    // 包序号
-	lastAuthTime time.Time // 最后认证时间
+	mu        sync.Mutex    // 保护包序号，允许多个连接并发认证
+	token     string        // 认证令牌
+	sourceID  uint32        // 信源ID
+	hostID    uint32        // 信宿ID
+	packageNo uint64        // 包序号
+	codec     *PackageCodec // BasePackage编解码器
 }
 
 // NewAuthManager 创建身份认证管理器
-// 参数: token - 认证令牌, sourceID - 信源ID, hostID - 信宿ID
+// 参数: token - 认证令牌, sourceID - 信源ID, hostID - 信宿ID, codec - BasePackage编解码器（为nil时不带校验和）
 // 返回: 身份认证管理器实例
-func NewAuthManager(token string, sourceID, hostID uint32) *AuthManager {
+func NewAuthManager(token string, sourceID, hostID uint32, codec *PackageCodec) *AuthManager {
+	if codec == nil {
+		codec = DefaultCodec
+	}
 	return &AuthManager{
 		token:     token,
 		sourceID:  sourceID,
 		hostID:    hostID,
 		packageNo: 1,
+		codec:     codec,
 	}
 }
 
+// ForConnection 为新连接创建请求包生成器
+// 新的生成器与原管理器使用相同的令牌、信源、信宿和编解码器，包序号从1开始独立计数
+// 返回: 身份认证管理器实例
+func (am *AuthManager) ForConnection() *AuthManager {
+	return NewAuthManager(am.token, am.sourceID, am.hostID, am.codec)
+}
+
 // GenerateAuthPacket 生成身份认证包 (XFType100)
 // 返回: 认证包数据和错误信息
 func (am *AuthManager) GenerateAuthPacket() ([]byte, error) {
-	// 创建基础包结构
-	basePackage := &BasePackage{
-		SourceInfo:              am.sourceID,  // 信源 (0x322)
-		HostInfo:                am.hostID,    // 信宿 (0x0014)
-		PackageNo:               am.packageNo, // 包序号
-		CurrentDataItem:         1,            // 当前数据项
-		DataSumLength:           0x0028,       // 当前数据段长度 (40字节)
-		RetransmissionFlag:      0x00,         // 重复标志
-		RetransmissionData:      0x00,         // 重发数据项
-		RetransmissionSumLength: 0x0000,       // 重发数据段长度
-		Timestamp:               time.Now(),
-	}
+	am.mu.Lock()
+	defer am.mu.Unlock()
 
 	// 生成32字节的token数据
 	tokenBytes, err := am.generateTokenBytes()
@@ -53,8 +65,23 @@ func (am *AuthManager) GenerateAuthPacket() ([]byte, error) {
 		return nil, fmt.Errorf("failed to generate token: %v", err)
 	}
 
-	// 设置数据内容为token
-	basePackage.Data = tokenBytes
+	// 数据段: 年(2) + 月(1) + 日(1) + 信息类型编号(2) + 信息内容长度(2) + token(32)
+	now := time.Now()
+	data := buildSegment(now, xftype.TypeAuthRequest, tokenBytes)
+
+	// 创建基础包结构
+	basePackage := &BasePackage{
+		SourceInfo:              am.sourceID,       // 信源 (0x322)
+		HostInfo:                am.hostID,         // 信宿 (0x0014)
+		PackageNo:               am.packageNo,      // 包序号
+		CurrentDataItem:         1,                 // 当前数据项
+		DataSumLength:           uint32(len(data)), // 当前数据段长度 (40字节)
+		RetransmissionFlag:      0x00,              // 重复标志
+		RetransmissionData:      0x00,              // 重发数据项
+		RetransmissionSumLength: 0x0000,            // 重发数据段长度
+		Data:                    data,
+		Timestamp:               now,
+	}
 
 	// 序列化包
 	packetData, err := am.serializeBasePackage(basePackage)
@@ -64,7 +91,6 @@ func (am *AuthManager) GenerateAuthPacket() ([]byte, error) {
 
 	// 增加包序号
 	am.packageNo++
-	am.lastAuthTime = time.Now()
 
 	log.Printf("Generated auth packet: SourceID=0x%X, HostID=0x%X, PackageNo=%d, TokenLength=%d",
 		am.sourceID, am.hostID, am.packageNo-1, len(tokenBytes))
@@ -72,6 +98,130 @@ func (am *AuthManager) GenerateAuthPacket() ([]byte, error) {
 	return packetData, nil
 }
 
+// GenerateHeartbeatRequest 生成协议心跳包 (XFType203)
+// 与连接保活使用的裸心跳字节不同，该心跳包使用BasePackage封装，用于健康探测测量往返时延
+// 返回: 心跳包数据和错误信息
+func (am *AuthManager) GenerateHeartbeatRequest() ([]byte, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	now := time.Now()
+	data := buildSegment(now, xftype.TypeHeartbeat, HeartbeatPacket)
+
+	basePackage := &BasePackage{
+		SourceInfo:      am.sourceID,
+		HostInfo:        am.hostID,
+		PackageNo:       am.packageNo,
+		CurrentDataItem: 1,
+		DataSumLength:   uint32(len(data)),
+		Data:            data,
+		Timestamp:       now,
+	}
+
+	packetData, err := am.serializeBasePackage(basePackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize heartbeat packet: %v", err)
+	}
+
+	am.packageNo++
+	return packetData, nil
+}
+
+// GenerateRetransmissionRequest 生成重传请求包
+// 请求包复用BasePackage包头：重复标志置1，重发数据项为请求的包数量（最大0xFFFF），
+// 重发数据段长度为缺失包总数，数据内容为缺失区间的起止包序号（各8字节，大端序）
+// 参数: gapStart - 缺失起始包序号, gapEnd - 缺失结束包序号（包含）
+// 返回: 请求包数据和错误信息
+func (am *AuthManager) GenerateRetransmissionRequest(gapStart, gapEnd uint64) ([]byte, error) {
+	if gapEnd < gapStart {
+		return nil, fmt.Errorf("invalid retransmission range: %d-%d", gapStart, gapEnd)
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	count := gapEnd - gapStart + 1
+	items := count
+	if items > 0xFFFF {
+		items = 0xFFFF
+	}
+
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[0:8], gapStart)
+	binary.BigEndian.PutUint64(data[8:16], gapEnd)
+
+	basePackage := &BasePackage{
+		SourceInfo:              am.sourceID,
+		HostInfo:                am.hostID,
+		PackageNo:               am.packageNo,
+		CurrentDataItem:         1,
+		DataSumLength:           uint32(len(data)),
+		RetransmissionFlag:      RetransmissionRequestFlag,
+		RetransmissionData:      uint16(items),
+		RetransmissionSumLength: uint32(count),
+		Data:                    data,
+		Timestamp:               time.Now(),
+	}
+
+	packetData, err := am.serializeBasePackage(basePackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize retransmission request: %v", err)
+	}
+
+	am.packageNo++
+
+	log.Printf("Generated retransmission request: PackageNo=%d, Range=%d-%d", am.packageNo-1, gapStart, gapEnd)
+	return packetData, nil
+}
+
+// GenerateResumeRequest 生成续传请求包
+// 请求包复用BasePackage包头：重复标志置2，数据内容为续传的信源、信宿（各4字节）
+// 和起始包序号（8字节），均为大端序
+// 参数: sourceInfo - 信源, hostInfo - 信宿, from - 起始包序号（包含）
+// 返回: 请求包数据和错误信息
+func (am *AuthManager) GenerateResumeRequest(sourceInfo, hostInfo uint32, from uint64) ([]byte, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint32(data[0:4], sourceInfo)
+	binary.BigEndian.PutUint32(data[4:8], hostInfo)
+	binary.BigEndian.PutUint64(data[8:16], from)
+
+	basePackage := &BasePackage{
+		SourceInfo:         am.sourceID,
+		HostInfo:           am.hostID,
+		PackageNo:          am.packageNo,
+		CurrentDataItem:    1,
+		DataSumLength:      uint32(len(data)),
+		RetransmissionFlag: ResumeRequestFlag,
+		Data:               data,
+		Timestamp:          time.Now(),
+	}
+
+	packetData, err := am.serializeBasePackage(basePackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize resume request: %v", err)
+	}
+
+	am.packageNo++
+	return packetData, nil
+}
+
+// buildSegment 构建只包含一个数据项的数据段
+// 参数: now - 数据段日期, itemType - 信息类型编号, content - 信息内容
+// 返回: 数据段字节
+func buildSegment(now time.Time, itemType uint16, content []byte) []byte {
+	data := make([]byte, 8+len(content))
+	binary.BigEndian.PutUint16(data[0:2], uint16(now.Year()))
+	data[2] = byte(now.Month())
+	data[3] = byte(now.Day())
+	binary.BigEndian.PutUint16(data[4:6], itemType)
+	binary.BigEndian.PutUint16(data[6:8], uint16(len(content)))
+	copy(data[8:], content)
+	return data
+}
+
 // generateTokenBytes 生成token字节数组
 // 返回: token字节数组和错误信息
 func (am *AuthManager) generateTokenBytes() ([]byte, error) {
@@ -103,78 +253,7 @@ func (am *AuthManager) generateTokenBytes() ([]byte, error) {
 // 参数: pkg - 基础包
 // 返回: 序列化后的字节数组和错误信息
 func (am *AuthManager) serializeBasePackage(pkg *BasePackage) ([]byte, error) {
-	// 计算总长度：32字节包头 + 数据长度
-	totalLength := 32 + len(pkg.Data)
-	data := make([]byte, totalLength)
-
-	// 序列化包头 (大端序)
-	offset := 0
-
-	// 信源 (4字节)
-	data[offset] = byte(pkg.SourceInfo >> 24)
-	data[offset+1] = byte(pkg.SourceInfo >> 16)
-	data[offset+2] = byte(pkg.SourceInfo >> 8)
-	data[offset+3] = byte(pkg.SourceInfo)
-	offset += 4
-
-	// 信宿 (4字节)
-	data[offset] = byte(pkg.HostInfo >> 24)
-	data[offset+1] = byte(pkg.HostInfo >> 16)
-	data[offset+2] = byte(pkg.HostInfo >> 8)
-	data[offset+3] = byte(pkg.HostInfo)
-	offset += 4
-
-	// 包序号 (8字节)
-	data[offset] = byte(pkg.PackageNo >> 56)
-	data[offset+1] = byte(pkg.PackageNo >> 48)
-	data[offset+2] = byte(pkg.PackageNo >> 40)
-	data[offset+3] = byte(pkg.PackageNo >> 32)
-	data[offset+4] = byte(pkg.PackageNo >> 24)
-	data[offset+5] = byte(pkg.PackageNo >> 16)
-	data[offset+6] = byte(pkg.PackageNo >> 8)
-	data[offset+7] = byte(pkg.PackageNo)
-	offset += 8
-
-	// 当前数据项 (2字节)
-	data[offset] = byte(pkg.CurrentDataItem >> 8)
-	data[offset+1] = byte(pkg.CurrentDataItem)
-	offset += 2
-
-	// 当前数据段长度 (4字节)
-	data[offset] = byte(pkg.DataSumLength >> 24)
-	data[offset+1] = byte(pkg.DataSumLength >> 16)
-	data[offset+2] = byte(pkg.DataSumLength >> 8)
-	data[offset+3] = byte(pkg.DataSumLength)
-	offset += 4
-
-	// 重复标志 (2字节)
-	data[offset] = byte(pkg.RetransmissionFlag >> 8)
-	data[offset+1] = byte(pkg.RetransmissionFlag)
-	offset += 2
-
-	// 重发数据项 (2字节)
-	data[offset] = byte(pkg.RetransmissionData >> 8)
-	data[offset+1] = byte(pkg.RetransmissionData)
-	offset += 2
-
-	// 重发数据段长度 (4字节)
-	data[offset] = byte(pkg.RetransmissionSumLength >> 24)
-	data[offset+1] = byte(pkg.RetransmissionSumLength >> 16)
-	data[offset+2] = byte(pkg.RetransmissionSumLength >> 8)
-	data[offset+3] = byte(pkg.RetransmissionSumLength)
-	offset += 4
-
-	// 数据内容
-	copy(data[offset:], pkg.Data)
-
-	return data, nil
-}
-
-// ShouldReauth 检查是否需要重新认证
-// 参数: interval - 认证间隔
-// 返回: 是否需要重新认证
-func (am *AuthManager) ShouldReauth(interval time.Duration) bool {
-	return time.Since(am.lastAuthTime) >= interval
+	return am.codec.Marshal(pkg)
 }
 
 // GetToken 获取当前token
@@ -198,5 +277,8 @@ func (am *AuthManager) GetHostID() uint32 {
 // GetPackageNo 获取当前包序号
 // 返回: 包序号
 func (am *AuthManager) GetPackageNo() uint64 {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
 	return am.packageNo
 }
@@ -0,0 +1,207 @@
+// internal/source/auth_session.go
+package source
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// AuthState 连接认证状态
+type AuthState int
+
+const (
+	AuthStateNone          AuthState = iota // 尚未发送认证包
+	AuthStatePending                        // 已发送认证包，等待应答
+	AuthStateAuthenticated                  // 认证通过
+	AuthStateRejected                       // 认证被拒绝（不可重试）
+	AuthStateTimeout                        // 等待应答超时
+)
+
+// String 获取认证状态名称
+func (s AuthState) String() string {
+	switch s {
+	case AuthStateNone:
+		return "none"
+	case AuthStatePending:
+		return "pending"
+	case AuthStateAuthenticated:
+		return "authenticated"
+	case AuthStateRejected:
+		return "rejected"
+	case AuthStateTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// 认证应答 (XFType099) 状态码，与参考客户端一致：1为成功，0为失败
+// 除AuthStatusAccepted以外的状态码都按拒绝处理
+const (
+	AuthStatusRejected uint8 = 0 // 认证失败
+	AuthStatusAccepted uint8 = 1 // 认证通过
+)
+
+// AuthRejectedError 认证被拒绝错误
+// 源服务器明确拒绝认证时返回，重连不会改变结果，因此不可重试
+type AuthRejectedError struct {
+	Server string // 服务器名称
+	Status uint8  // 应答状态码
+}
+
+// Error 实现error接口
+func (e *AuthRejectedError) Error() string {
+	return fmt.Sprintf("authentication rejected by %s: status=%d", e.Server, e.Status)
+}
+
+// AuthSession 单个连接的认证状态机
+//
+//	none --发送认证包--> pending --应答通过--> authenticated --重新认证--> pending
+//	                        |--应答拒绝--> rejected
+//	                        |--超时------> timeout
+type AuthSession struct {
+	mu              sync.Mutex
+	state           AuthState     // 当前状态
+	responseTimeout time.Duration // 等待应答超时时间
+	reauthInterval  time.Duration // 重新认证间隔
+	sentAt          time.Time     // 最近一次发送认证包的时间
+	authenticatedAt time.Time     // 最近一次认证通过的时间
+	everAccepted    bool          // 当前连接是否曾认证通过
+	attempts        int           // 发送认证包次数
+	lastStatus      uint8         // 最近一次应答状态码
+}
+
+// NewAuthSession 创建认证状态机
+// 参数: responseTimeout - 等待应答超时时间, reauthInterval - 重新认证间隔（0表示不重新认证）
+// 返回: 认证状态机实例
+func NewAuthSession(responseTimeout, reauthInterval time.Duration) *AuthSession {
+	return &AuthSession{
+		responseTimeout: responseTimeout,
+		reauthInterval:  reauthInterval,
+	}
+}
+
+// Begin 记录已发送认证包，进入等待应答状态
+func (s *AuthSession) Begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = AuthStatePending
+	s.sentAt = time.Now()
+	s.attempts++
+}
+
+// HandleResponse 处理认证应答
+// 参数: status - 应答状态码（AuthStatusAccepted为通过，其余均为拒绝）
+// 返回: 认证是否通过
+func (s *AuthSession) HandleResponse(status uint8) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastStatus = status
+	if status != AuthStatusAccepted {
+		s.state = AuthStateRejected
+		return false
+	}
+
+	s.state = AuthStateAuthenticated
+	s.authenticatedAt = time.Now()
+	s.everAccepted = true
+	return true
+}
+
+// CheckTimeout 检查等待应答是否超时，超时则进入timeout状态
+// 返回: 是否超时
+func (s *AuthSession) CheckTimeout() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != AuthStatePending || time.Since(s.sentAt) < s.responseTimeout {
+		return false
+	}
+	s.state = AuthStateTimeout
+	return true
+}
+
+// Deadline 获取等待应答的截止时间
+// 返回: 截止时间和是否处于等待状态
+func (s *AuthSession) Deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != AuthStatePending {
+		return time.Time{}, false
+	}
+	return s.sentAt.Add(s.responseTimeout), true
+}
+
+// ShouldReauth 检查是否需要重新认证
+// 返回: 已认证且距上次认证通过超过重新认证间隔时为true
+func (s *AuthSession) ShouldReauth() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state == AuthStateAuthenticated && s.reauthInterval > 0 &&
+		time.Since(s.authenticatedAt) >= s.reauthInterval
+}
+
+// CanDeliver 当前连接的数据是否可以投递
+// 首次认证通过前收到的数据不投递；重新认证期间沿用已建立的认证
+// 返回: 是否可以投递
+func (s *AuthSession) CanDeliver() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.everAccepted && s.state != AuthStateRejected && s.state != AuthStateTimeout
+}
+
+// State 获取当前状态
+// 返回: 认证状态
+func (s *AuthSession) State() AuthState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// GetStatus 获取认证状态信息
+// 返回: 状态信息
+func (s *AuthSession) GetStatus() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := map[string]interface{}{
+		"state":       s.state.String(),
+		"attempts":    s.attempts,
+		"last_status": s.lastStatus,
+	}
+	if !s.authenticatedAt.IsZero() {
+		status["authenticated_at"] = s.authenticatedAt
+	}
+	return status
+}
+
+// parseAuthResponse 从数据包中提取认证应答 (XFType099)
+// 参数: registry - 报文编解码器注册表, pkg - 解析后的数据包
+// 返回: 认证应答和是否为认证应答包
+func parseAuthResponse(registry *xftype.Registry, pkg *BasePackage) (*xftype.XFType099, bool) {
+	// 快速判断第一个数据项的类型，避免对每个数据包做完整解码
+	if len(pkg.Data) < 8 || binary.BigEndian.Uint16(pkg.Data[4:6]) != xftype.TypeAuthResponse {
+		return nil, false
+	}
+
+	seg, err := registry.DecodeSegment(pkg.Data)
+	if err != nil {
+		return nil, false
+	}
+	for _, item := range seg.Items {
+		if resp, ok := item.Body.(*xftype.XFType099); ok {
+			return resp, true
+		}
+	}
+	return nil, false
+}
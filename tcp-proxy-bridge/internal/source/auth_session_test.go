@@ -0,0 +1,162 @@
+// internal/source/auth_session_test.go
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuthSessionResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      uint8
+		wantOK      bool
+		wantState   AuthState
+		wantDeliver bool
+	}{
+		{name: "accepted", status: AuthStatusAccepted, wantOK: true, wantState: AuthStateAuthenticated, wantDeliver: true},
+		{name: "rejected", status: AuthStatusRejected, wantState: AuthStateRejected},
+		// 未定义的状态码按拒绝处理
+		{name: "unknown status", status: 2, wantState: AuthStateRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAuthSession(time.Minute, 0)
+			if s.State() != AuthStateNone || s.CanDeliver() {
+				t.Fatalf("new session: state = %v, CanDeliver = %v", s.State(), s.CanDeliver())
+			}
+
+			s.Begin()
+			if s.State() != AuthStatePending || s.CanDeliver() {
+				t.Fatalf("after Begin: state = %v, CanDeliver = %v", s.State(), s.CanDeliver())
+			}
+
+			if ok := s.HandleResponse(tt.status); ok != tt.wantOK {
+				t.Errorf("HandleResponse(%d) = %v, want %v", tt.status, ok, tt.wantOK)
+			}
+			if s.State() != tt.wantState {
+				t.Errorf("state = %v, want %v", s.State(), tt.wantState)
+			}
+			if s.CanDeliver() != tt.wantDeliver {
+				t.Errorf("CanDeliver() = %v, want %v", s.CanDeliver(), tt.wantDeliver)
+			}
+			if got := s.GetStatus()["last_status"]; got != tt.status {
+				t.Errorf("last_status = %v, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthSessionTimeout(t *testing.T) {
+	s := NewAuthSession(20*time.Millisecond, 0)
+	if s.CheckTimeout() {
+		t.Fatal("CheckTimeout() = true before Begin")
+	}
+
+	s.Begin()
+	deadline, pending := s.Deadline()
+	if !pending || deadline.IsZero() {
+		t.Fatalf("Deadline() = %v, %v, want pending deadline", deadline, pending)
+	}
+	if s.CheckTimeout() {
+		t.Fatal("CheckTimeout() = true before response timeout")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !s.CheckTimeout() {
+		t.Fatal("CheckTimeout() = false after response timeout")
+	}
+	if s.State() != AuthStateTimeout || s.CanDeliver() {
+		t.Errorf("after timeout: state = %v, CanDeliver = %v", s.State(), s.CanDeliver())
+	}
+	if _, pending := s.Deadline(); pending {
+		t.Error("Deadline() still pending after timeout")
+	}
+}
+
+func TestAuthSessionReauth(t *testing.T) {
+	s := NewAuthSession(time.Minute, 20*time.Millisecond)
+	s.Begin()
+	s.HandleResponse(AuthStatusAccepted)
+	if s.ShouldReauth() {
+		t.Fatal("ShouldReauth() = true right after authentication")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !s.ShouldReauth() {
+		t.Fatal("ShouldReauth() = false after reauth interval")
+	}
+
+	// 重新认证等待应答期间沿用已建立的认证
+	s.Begin()
+	if s.ShouldReauth() || !s.CanDeliver() {
+		t.Errorf("during reauth: ShouldReauth = %v, CanDeliver = %v", s.ShouldReauth(), s.CanDeliver())
+	}
+
+	// 重新认证被拒绝后停止投递
+	s.HandleResponse(AuthStatusRejected)
+	if s.CanDeliver() {
+		t.Error("CanDeliver() = true after reauth rejected")
+	}
+
+	// 不重新认证
+	s = NewAuthSession(time.Minute, 0)
+	s.Begin()
+	s.HandleResponse(AuthStatusAccepted)
+	if s.ShouldReauth() {
+		t.Error("ShouldReauth() = true with reauth disabled")
+	}
+}
+
+func TestManagerAuthResponse(t *testing.T) {
+	t.Run("accepted", func(t *testing.T) {
+		m := newTestManager(t, nil)
+		l := newTestLink(t, m, "src-1")
+
+		// 认证通过前的数据包不投递
+		if err := l.m.sendAuthPacket(l.conn, l.h); err != nil {
+			t.Fatal(err)
+		}
+		l.receive(l.dataPacket(7, 1))
+		if err := l.receive(l.authResponse(AuthStatusAccepted)); err != nil {
+			t.Fatalf("auth response: %v", err)
+		}
+		l.receive(l.dataPacket(7, 2))
+
+		if nos := l.deliveredNos(); len(nos) != 1 || nos[0] != 2 {
+			t.Errorf("delivered %v, want [2]", nos)
+		}
+		if _, rejected := m.authRejected["src-1"]; rejected {
+			t.Error("server marked as rejected")
+		}
+
+		// 未请求的认证应答被忽略，不影响已建立的认证
+		if err := l.receive(l.authResponse(AuthStatusRejected)); err != nil {
+			t.Errorf("unsolicited auth response: %v", err)
+		}
+		if !l.h.auth.CanDeliver() {
+			t.Error("unsolicited rejection revoked authentication")
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		m := newTestManager(t, nil)
+		l := newTestLink(t, m, "src-1")
+
+		err := l.authenticate(AuthStatusRejected)
+		var rejected *AuthRejectedError
+		if !errors.As(err, &rejected) || rejected.Status != AuthStatusRejected {
+			t.Fatalf("authenticate error = %v, want AuthRejectedError with status 0", err)
+		}
+		if status, exists := m.authRejected["src-1"]; !exists || status != AuthStatusRejected {
+			t.Errorf("authRejected = %v, want src-1 with status 0", m.authRejected)
+		}
+
+		l.receive(l.dataPacket(7, 1))
+		if nos := l.deliveredNos(); len(nos) != 0 {
+			t.Errorf("delivered %v after rejection", nos)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+// internal/source/checkpoint.go
+package source
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"tcp-proxy-bridge/internal/database"
+)
+
+// PacketMeta 数据包元信息，随数据一起交给数据处理函数
+type PacketMeta struct {
+	ServerID       string       // 接收该包的源服务器ID
+	SourceIP       string       // 源服务器IP地址
+	SourceIdentity string       // 来源身份（监听端口TLS客户端证书的主题或SAN，未使用客户端证书时为空）
+	Header         *BasePackage // 解析后的数据包（协议解析失败时为nil）
+	Checkpoint     bool         // 写入后是否推进续传检查点（按序到达的新数据包）
+}
+
+// DataHandler 数据处理函数
+// 参数: data - 数据内容, meta - 数据包元信息
+// 返回: 错误信息
+type DataHandler func(data []byte, meta *PacketMeta) error
+
+// CheckpointHandler 被丢弃数据包的检查点处理函数
+// 去重或数据段校验丢弃的数据包不交给数据处理函数，但已按序确认时仍需推进续传检查点，
+// 否则续传会从这些数据包之前重新请求
+// 参数: meta - 数据包元信息（Checkpoint为true）
+type CheckpointHandler func(meta *PacketMeta)
+
+// CheckpointStore 续传检查点存储
+type CheckpointStore interface {
+	// LoadCheckpoints 获取所有信源/信宿已写入数据库的最后一个包序号
+	LoadCheckpoints() ([]*database.SourceCheckpoint, error)
+}
+
+// SetCheckpointStore 设置续传检查点存储
+// 设置后每个连接首次认证通过时从检查点请求续传
+// 参数: store - 检查点存储
+func (m *Manager) SetCheckpointStore(store CheckpointStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpointStore = store
+}
+
+// SetCheckpointHandler 设置被丢弃数据包的检查点处理函数
+// 参数: handler - 检查点处理函数
+func (m *Manager) SetCheckpointHandler(handler CheckpointHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpointDrop = handler
+}
+
+// checkpointDropped 推进被丢弃数据包的续传检查点
+// 参数: meta - 数据包元信息
+func (m *Manager) checkpointDropped(meta *PacketMeta) {
+	if !meta.Checkpoint {
+		return
+	}
+
+	m.mu.RLock()
+	handler := m.checkpointDrop
+	m.mu.RUnlock()
+	if handler != nil {
+		handler(meta)
+	}
+}
+
+// resumeOnce 连接可以投递数据后从续传检查点请求续传，每个连接只续传一次
+// 只由连接的读取循环调用：热备连接认证通过时不续传，提升后由读取循环在投递数据之前续传，
+// 提升前丢弃的数据包未写入数据库，由续传重新请求
+// 参数: conn - 连接, h - 连接会话
+// 返回: 发送失败时返回错误
+func (m *Manager) resumeOnce(conn net.Conn, h *connSession) error {
+	if h.resumed || h.standby.Load() || !h.auth.CanDeliver() {
+		return nil
+	}
+	h.resumed = true
+	return m.resumeFromCheckpoints(conn, h)
+}
+
+// resumeFromCheckpoints 从数据库中的检查点请求续传
+// 先清除该服务器之前推进的包序号状态，以检查点重新建立基线，使已写入数据库的数据包按重复包丢弃，
+// 再逐个信源/信宿发送续传请求；未启用续传时由新连接的第一个数据包建立基线
+// 参数: conn - 连接, h - 连接会话
+// 返回: 发送失败时返回错误
+func (m *Manager) resumeFromCheckpoints(conn net.Conn, h *connSession) error {
+	if cleared := m.sequenceTracker.Rebaseline(h.server.ID); cleared > 0 {
+		log.Printf("Sequence baseline cleared for %d sources after %s authenticated", cleared, h.server.Name)
+	}
+
+	m.mu.RLock()
+	store := m.checkpointStore
+	m.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	checkpoints, err := store.LoadCheckpoints()
+	if err != nil {
+		// 数据库不可用时不续传，缺口由包序号跟踪按重传处理
+		log.Printf("Failed to load checkpoints, skipping resume for %s: %v", h.server.Name, err)
+		return nil
+	}
+
+	// 同一信源存在多个信宿时以最小的检查点为基线，宁可重复也不丢包
+	baseline := make(map[uint32]uint64)
+	for _, checkpoint := range checkpoints {
+		if last, exists := baseline[checkpoint.SourceInfo]; !exists || checkpoint.PackageNo < last {
+			baseline[checkpoint.SourceInfo] = checkpoint.PackageNo
+		}
+	}
+	for sourceInfo, last := range baseline {
+		if m.sequenceTracker.Seed(sourceInfo, last) {
+			log.Printf("Sequence baseline for source %d restored from checkpoint: %d", sourceInfo, last)
+		}
+	}
+
+	for _, checkpoint := range checkpoints {
+		if err := m.sendResumeRequest(conn, h, checkpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendResumeRequest 向源服务器发送续传请求
+// 参数: conn - 连接, h - 连接会话, checkpoint - 续传检查点
+// 返回: 错误信息
+func (m *Manager) sendResumeRequest(conn net.Conn, h *connSession, checkpoint *database.SourceCheckpoint) error {
+	from := checkpoint.PackageNo + 1
+	request, err := h.requests.GenerateResumeRequest(checkpoint.SourceInfo, checkpoint.HostInfo, from)
+	if err != nil {
+		return err
+	}
+
+	// 添加分帧字节
+	frame, err := h.framer.Encode(request)
+	if err != nil {
+		return fmt.Errorf("failed to frame resume request: %v", err)
+	}
+
+	if err := m.writeFrame(conn, h, frame); err != nil {
+		return fmt.Errorf("failed to send resume request: %v", err)
+	}
+
+	log.Printf("Sent resume request to %s: Source=%d, Host=%d, From=%d",
+		h.server.Name, checkpoint.SourceInfo, checkpoint.HostInfo, from)
+	return nil
+}
+
+// remoteIP 获取连接对端的IP地址
+// 参数: conn - 连接
+// 返回: IP地址
+func remoteIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,175 @@
+// internal/source/checkpoint_test.go
+package source
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/database"
+)
+
+// fakeCheckpointStore 返回固定检查点的检查点存储
+type fakeCheckpointStore struct {
+	checkpoints []*database.SourceCheckpoint
+	err         error
+	loads       int
+}
+
+func (s *fakeCheckpointStore) LoadCheckpoints() ([]*database.SourceCheckpoint, error) {
+	s.loads++
+	return s.checkpoints, s.err
+}
+
+// resumeRequest 续传请求的内容
+type resumeRequest struct {
+	source, host uint32
+	from         uint64
+}
+
+// resumeRequests 解析连接上已发送的续传请求
+func (l *testLink) resumeRequests() []resumeRequest {
+	var requests []resumeRequest
+	for _, pkg := range l.sent() {
+		if pkg.RetransmissionFlag != ResumeRequestFlag {
+			continue
+		}
+		requests = append(requests, resumeRequest{
+			source: binary.BigEndian.Uint32(pkg.Data[0:4]),
+			host:   binary.BigEndian.Uint32(pkg.Data[4:8]),
+			from:   binary.BigEndian.Uint64(pkg.Data[8:16]),
+		})
+	}
+	return requests
+}
+
+func TestManagerResumeFromCheckpoints(t *testing.T) {
+	m := newTestManager(t, nil)
+	store := &fakeCheckpointStore{checkpoints: []*database.SourceCheckpoint{
+		{SourceInfo: 7, HostInfo: 20, PackageNo: 5},
+		{SourceInfo: 7, HostInfo: 21, PackageNo: 3},
+		{SourceInfo: 9, HostInfo: 20, PackageNo: 10},
+	}}
+	m.SetCheckpointStore(store)
+
+	l := newTestLink(t, m, "src-1")
+	if err := l.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+
+	// 认证通过后逐个信源/信宿从检查点之后请求续传
+	want := []resumeRequest{{7, 20, 6}, {7, 21, 4}, {9, 20, 11}}
+	if got := l.resumeRequests(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("resume requests %+v, want %+v", got, want)
+	}
+
+	// 同一信源以最小的检查点为基线，不大于基线的数据包按重复包丢弃
+	l.receive(l.dataPacket(7, 3), l.dataPacket(7, 4), l.dataPacket(9, 10), l.dataPacket(9, 11))
+	if nos := l.deliveredNos(); !reflect.DeepEqual(nos, []uint64{4, 11}) {
+		t.Errorf("delivered %v, want [4 11]", nos)
+	}
+	for _, meta := range l.delivered {
+		if !meta.Checkpoint {
+			t.Errorf("package %d from source %d does not advance checkpoint", meta.Header.PackageNo, meta.Header.SourceInfo)
+		}
+	}
+
+	// 重新认证不再续传
+	if err := l.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+	if store.loads != 1 || len(l.resumeRequests()) != len(want) {
+		t.Errorf("loads = %d, resume requests = %d after reauth", store.loads, len(l.resumeRequests()))
+	}
+}
+
+func TestManagerResumeLoadFailure(t *testing.T) {
+	m := newTestManager(t, nil)
+	m.SetCheckpointStore(&fakeCheckpointStore{err: errors.New("connection refused")})
+
+	// 读取检查点失败时跳过续传，由第一个数据包建立基线
+	l := newTestLink(t, m, "src-1")
+	if err := l.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if requests := l.resumeRequests(); len(requests) != 0 {
+		t.Errorf("sent resume requests %+v", requests)
+	}
+	l.receive(l.dataPacket(7, 3))
+	if nos := l.deliveredNos(); !reflect.DeepEqual(nos, []uint64{3}) {
+		t.Errorf("delivered %v, want [3]", nos)
+	}
+}
+
+// checkpointRecorder 记录被丢弃数据包的检查点
+type checkpointRecorder struct {
+	mu  sync.Mutex
+	nos []uint64
+}
+
+func (r *checkpointRecorder) handle(meta *PacketMeta) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nos = append(r.nos, meta.Header.PackageNo)
+}
+
+func (r *checkpointRecorder) packageNos() []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint64(nil), r.nos...)
+}
+
+func TestManagerCheckpointDropped(t *testing.T) {
+	t.Run("dedup", func(t *testing.T) {
+		m := newTestManager(t, nil)
+		recorder := &checkpointRecorder{}
+		m.SetCheckpointHandler(recorder.handle)
+
+		first := newTestLink(t, m, "src-1")
+		if err := first.authenticate(AuthStatusAccepted); err != nil {
+			t.Fatal(err)
+		}
+		first.receiveRange(7, 1, 3)
+
+		// 重连后源端重发的数据包由跨源去重丢弃，仍按序推进检查点
+		reconnected := newTestLink(t, m, "src-1")
+		if err := reconnected.authenticate(AuthStatusAccepted); err != nil {
+			t.Fatal(err)
+		}
+		reconnected.receiveRange(7, 2, 4)
+
+		if nos := reconnected.deliveredNos(); !reflect.DeepEqual(nos, []uint64{4}) {
+			t.Errorf("delivered %v, want [4]", nos)
+		}
+		if nos := recorder.packageNos(); !reflect.DeepEqual(nos, []uint64{2, 3}) {
+			t.Errorf("dropped checkpoints %v, want [2 3]", nos)
+		}
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		m := newTestManager(t, func(cfg *config.Config) {
+			cfg.Payload.Validate = true
+			cfg.Payload.DropInvalid = true
+			cfg.Sequence.MaxGapSize = 10
+		})
+		recorder := &checkpointRecorder{}
+		m.SetCheckpointHandler(recorder.handle)
+
+		l := newTestLink(t, m, "src-1")
+		if err := l.authenticate(AuthStatusAccepted); err != nil {
+			t.Fatal(err)
+		}
+
+		// 校验失败丢弃的数据包按序到达时推进检查点；缺口之后的不推进
+		l.receive(l.dataPacket(7, 1), l.dataPacket(7, 2), l.dataPacket(7, 4))
+		if nos := l.deliveredNos(); len(nos) != 0 {
+			t.Errorf("delivered %v, want none", nos)
+		}
+		if nos := recorder.packageNos(); !reflect.DeepEqual(nos, []uint64{1, 2}) {
+			t.Errorf("dropped checkpoints %v, want [1 2]", nos)
+		}
+	})
+}
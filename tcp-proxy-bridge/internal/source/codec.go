@@ -0,0 +1,218 @@
+// internal/source/codec.go
+package source
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// BasePackageHeaderSize BasePackage包头长度
+const BasePackageHeaderSize = 32
+
+// 包头保留字段偏移（2字节）
+const reservedOffset = 30
+
+// ErrLengthMismatch 当前数据段长度与实际数据长度不一致
+var ErrLengthMismatch = errors.New("base package data length mismatch")
+
+// ErrChecksumMismatch 校验和不一致
+var ErrChecksumMismatch = errors.New("base package checksum mismatch")
+
+// PackageCodec BasePackage编解码器
+// Marshal和Unmarshal互为逆操作；配置了校验和时写入包头保留字段或数据之后，解码时校验
+type PackageCodec struct {
+	checksum      string // 校验和算法
+	placement     string // 校验和位置
+	maxDataLength uint32 // 当前数据段长度上限（0为不限制）
+}
+
+// DefaultCodec 不带校验和的编解码器
+var DefaultCodec = &PackageCodec{checksum: config.ChecksumNone}
+
+// NewPackageCodec 创建BasePackage编解码器
+// 参数: cfg - 编解码配置（已规范化）
+// 返回: 编解码器实例
+func NewPackageCodec(cfg config.ProtocolConfig) *PackageCodec {
+	codec := &PackageCodec{
+		checksum:  cfg.Checksum,
+		placement: cfg.ChecksumPlacement,
+	}
+	if codec.checksum == "" {
+		codec.checksum = config.ChecksumNone
+	}
+	if cfg.MaxDataLength > 0 {
+		codec.maxDataLength = uint32(cfg.MaxDataLength)
+	}
+	return codec
+}
+
+// trailerSize 数据之后的校验和字节数
+// 返回: 字节数（校验和不在数据之后时为0）
+func (c *PackageCodec) trailerSize() int {
+	if c.placement != config.ChecksumTrailer {
+		return 0
+	}
+	switch c.checksum {
+	case config.ChecksumCRC16:
+		return 2
+	case config.ChecksumCRC32:
+		return 4
+	}
+	return 0
+}
+
+// PacketLength 根据包头计算完整数据包长度
+// 参数: header - 至少32字节的包头
+// 返回: 包头+数据+校验和的总长度和错误信息（数据段长度超过上限时返回ErrLengthMismatch）
+func (c *PackageCodec) PacketLength(header []byte) (int, error) {
+	if len(header) < BasePackageHeaderSize {
+		return 0, fmt.Errorf("insufficient data for header")
+	}
+
+	dataLength := binary.BigEndian.Uint32(header[18:22])
+	if c.maxDataLength > 0 && dataLength > c.maxDataLength {
+		return 0, fmt.Errorf("%w: declared %d bytes, max %d", ErrLengthMismatch, dataLength, c.maxDataLength)
+	}
+	return BasePackageHeaderSize + int(dataLength) + c.trailerSize(), nil
+}
+
+// Marshal 序列化BasePackage
+// 参数: pkg - 数据包（DataSumLength必须与数据长度一致）
+// 返回: 序列化后的字节数组和错误信息
+func (c *PackageCodec) Marshal(pkg *BasePackage) ([]byte, error) {
+	if int(pkg.DataSumLength) != len(pkg.Data) {
+		return nil, fmt.Errorf("%w: DataSumLength=%d, data %d bytes", ErrLengthMismatch, pkg.DataSumLength, len(pkg.Data))
+	}
+
+	data := make([]byte, BasePackageHeaderSize+len(pkg.Data)+c.trailerSize())
+
+	// 序列化包头 (大端序)，保留字段置0
+	binary.BigEndian.PutUint32(data[0:4], pkg.SourceInfo)                // 信源
+	binary.BigEndian.PutUint32(data[4:8], pkg.HostInfo)                  // 信宿
+	binary.BigEndian.PutUint64(data[8:16], pkg.PackageNo)                // 包序号
+	binary.BigEndian.PutUint16(data[16:18], pkg.CurrentDataItem)         // 当前数据项
+	binary.BigEndian.PutUint32(data[18:22], pkg.DataSumLength)           // 当前数据段长度
+	binary.BigEndian.PutUint16(data[22:24], pkg.RetransmissionFlag)      // 重复标志
+	binary.BigEndian.PutUint16(data[24:26], pkg.RetransmissionData)      // 重发数据项
+	binary.BigEndian.PutUint32(data[26:30], pkg.RetransmissionSumLength) // 重发数据段长度
+
+	// 数据内容从第32字节开始
+	end := BasePackageHeaderSize + copy(data[BasePackageHeaderSize:], pkg.Data)
+
+	// 校验和覆盖包头（保留字段为0）和数据内容
+	switch {
+	case c.checksum == config.ChecksumNone:
+	case c.placement == config.ChecksumReserved:
+		binary.BigEndian.PutUint16(data[reservedOffset:reservedOffset+2], crc16(data[:end]))
+	case c.checksum == config.ChecksumCRC16:
+		binary.BigEndian.PutUint16(data[end:], crc16(data[:end]))
+	case c.checksum == config.ChecksumCRC32:
+		binary.BigEndian.PutUint32(data[end:], crc32.ChecksumIEEE(data[:end]))
+	}
+
+	return data, nil
+}
+
+// Unmarshal 解析完整的BasePackage
+// 参数: data - 一个完整数据包（长度必须等于包头声明的长度）
+// 返回: 数据包和错误信息（长度不一致返回ErrLengthMismatch，校验失败返回ErrChecksumMismatch）
+func (c *PackageCodec) Unmarshal(data []byte) (*BasePackage, error) {
+	if len(data) < BasePackageHeaderSize {
+		return nil, fmt.Errorf("insufficient data for package header")
+	}
+
+	dataLength := binary.BigEndian.Uint32(data[18:22])
+	end := BasePackageHeaderSize + int(dataLength)
+	if len(data) != end+c.trailerSize() {
+		return nil, fmt.Errorf("%w: DataSumLength=%d, got %d bytes", ErrLengthMismatch,
+			dataLength, len(data)-BasePackageHeaderSize-c.trailerSize())
+	}
+
+	if err := c.verify(data, end); err != nil {
+		return nil, err
+	}
+
+	pkg := &BasePackage{
+		SourceInfo:              binary.BigEndian.Uint32(data[0:4]),   // 信源
+		HostInfo:                binary.BigEndian.Uint32(data[4:8]),   // 信宿
+		PackageNo:               binary.BigEndian.Uint64(data[8:16]),  // 包序号
+		CurrentDataItem:         binary.BigEndian.Uint16(data[16:18]), // 当前数据项
+		DataSumLength:           dataLength,                           // 当前数据段长度
+		RetransmissionFlag:      binary.BigEndian.Uint16(data[22:24]), // 重复标志
+		RetransmissionData:      binary.BigEndian.Uint16(data[24:26]), // 重发数据项
+		RetransmissionSumLength: binary.BigEndian.Uint32(data[26:30]), // 重发数据段长度
+	}
+
+	// 数据内容
+	if dataLength > 0 {
+		pkg.Data = make([]byte, dataLength)
+		copy(pkg.Data, data[BasePackageHeaderSize:end])
+	}
+
+	return pkg, nil
+}
+
+// verify 校验数据包的校验和
+// 参数: data - 完整数据包, end - 数据内容结束偏移
+// 返回: 校验失败时返回ErrChecksumMismatch
+func (c *PackageCodec) verify(data []byte, end int) error {
+	var expected, actual uint32
+	switch {
+	case c.checksum == config.ChecksumNone:
+		return nil
+	case c.placement == config.ChecksumReserved:
+		// 计算时保留字段按0处理
+		covered := make([]byte, end)
+		copy(covered, data[:end])
+		covered[reservedOffset], covered[reservedOffset+1] = 0, 0
+		expected = uint32(binary.BigEndian.Uint16(data[reservedOffset : reservedOffset+2]))
+		actual = uint32(crc16(covered))
+	case c.checksum == config.ChecksumCRC16:
+		expected = uint32(binary.BigEndian.Uint16(data[end:]))
+		actual = uint32(crc16(data[:end]))
+	case c.checksum == config.ChecksumCRC32:
+		expected = binary.BigEndian.Uint32(data[end:])
+		actual = crc32.ChecksumIEEE(data[:end])
+	}
+
+	if expected != actual {
+		return fmt.Errorf("%w: %s expected 0x%X, got 0x%X", ErrChecksumMismatch, c.checksum, expected, actual)
+	}
+	return nil
+}
+
+// MarshalBasePackage 使用不带校验和的编解码器序列化BasePackage
+// 参数: pkg - 数据包
+// 返回: 序列化后的字节数组和错误信息
+func MarshalBasePackage(pkg *BasePackage) ([]byte, error) {
+	return DefaultCodec.Marshal(pkg)
+}
+
+// UnmarshalBasePackage 使用不带校验和的编解码器解析BasePackage
+// 参数: data - 一个完整数据包
+// 返回: 数据包和错误信息
+func UnmarshalBasePackage(data []byte) (*BasePackage, error) {
+	return DefaultCodec.Unmarshal(data)
+}
+
+// crc16 计算CRC-16/CCITT-FALSE (多项式0x1021，初始值0xFFFF)
+// 参数: data - 数据
+// 返回: 校验值
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
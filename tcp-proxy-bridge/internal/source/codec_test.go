@@ -0,0 +1,190 @@
+// internal/source/codec_test.go
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// testPackage 包头各字段取不同值，便于发现字段偏移错误
+func testPackage(data []byte) *BasePackage {
+	return &BasePackage{
+		SourceInfo:              0x01020304,
+		HostInfo:                0x05060708,
+		PackageNo:               0x090A0B0C0D0E0F10,
+		CurrentDataItem:         0x1112,
+		DataSumLength:           uint32(len(data)),
+		RetransmissionFlag:      0x1314,
+		RetransmissionData:      0x1516,
+		RetransmissionSumLength: 0x1718191A,
+		Data:                    data,
+	}
+}
+
+// testCodecs 各校验和组合的编解码配置
+var testCodecs = []struct {
+	name    string
+	cfg     config.ProtocolConfig
+	trailer int // 数据之后的校验和字节数
+}{
+	{name: "none", cfg: config.ProtocolConfig{Checksum: config.ChecksumNone}},
+	{name: "crc16 reserved", cfg: config.ProtocolConfig{Checksum: config.ChecksumCRC16, ChecksumPlacement: config.ChecksumReserved}},
+	{name: "crc16 trailer", cfg: config.ProtocolConfig{Checksum: config.ChecksumCRC16, ChecksumPlacement: config.ChecksumTrailer}, trailer: 2},
+	{name: "crc32 trailer", cfg: config.ProtocolConfig{Checksum: config.ChecksumCRC32, ChecksumPlacement: config.ChecksumTrailer}, trailer: 4},
+}
+
+func TestPackageCodecRoundTrip(t *testing.T) {
+	for _, tc := range testCodecs {
+		for _, data := range [][]byte{nil, []byte("payload"), bytes.Repeat([]byte{0xFF}, 300)} {
+			t.Run(tc.name, func(t *testing.T) {
+				codec := NewPackageCodec(tc.cfg)
+				pkg := testPackage(data)
+
+				encoded, err := codec.Marshal(pkg)
+				if err != nil {
+					t.Fatalf("Marshal: %v", err)
+				}
+				if want := BasePackageHeaderSize + len(data) + tc.trailer; len(encoded) != want {
+					t.Fatalf("encoded %d bytes, want %d", len(encoded), want)
+				}
+				if length, err := codec.PacketLength(encoded); err != nil || length != len(encoded) {
+					t.Errorf("PacketLength = %d, %v, want %d", length, err, len(encoded))
+				}
+
+				decoded, err := codec.Unmarshal(encoded)
+				if err != nil {
+					t.Fatalf("Unmarshal: %v", err)
+				}
+				if !reflect.DeepEqual(decoded, pkg) {
+					t.Errorf("Unmarshal = %+v, want %+v", decoded, pkg)
+				}
+			})
+		}
+	}
+}
+
+func TestPackageCodecLayout(t *testing.T) {
+	encoded, err := MarshalBasePackage(testPackage([]byte{0xAB}))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	// 包头为大端序，保留字段为0，数据从第32字节开始
+	want := []byte{
+		0x01, 0x02, 0x03, 0x04, // 信源
+		0x05, 0x06, 0x07, 0x08, // 信宿
+		0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10, // 包序号
+		0x11, 0x12, // 当前数据项
+		0x00, 0x00, 0x00, 0x01, // 当前数据段长度
+		0x13, 0x14, // 重复标志
+		0x15, 0x16, // 重发数据项
+		0x17, 0x18, 0x19, 0x1A, // 重发数据段长度
+		0x00, 0x00, // 保留
+		0xAB,
+	}
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("encoded = %x, want %x", encoded, want)
+	}
+}
+
+func TestPackageCodecChecksum(t *testing.T) {
+	for _, tc := range testCodecs {
+		if tc.cfg.Checksum == config.ChecksumNone {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			codec := NewPackageCodec(tc.cfg)
+			encoded, err := codec.Marshal(testPackage([]byte("payload")))
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			// 包头、数据和校验和本身任一字节被篡改都应校验失败
+			for _, offset := range []int{0, 17, reservedOffset, BasePackageHeaderSize + 3, len(encoded) - 1} {
+				corrupted := append([]byte{}, encoded...)
+				corrupted[offset] ^= 0x40
+				if _, err := codec.Unmarshal(corrupted); !errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("byte %d corrupted: error = %v, want ErrChecksumMismatch", offset, err)
+				}
+			}
+
+			// 不带校验和的编解码器不校验
+			if tc.trailer == 0 {
+				if _, err := DefaultCodec.Unmarshal(encoded); err != nil {
+					t.Errorf("DefaultCodec.Unmarshal: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// CRC-16/CCITT-FALSE标准校验值
+	if got := crc16([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("crc16(\"123456789\") = 0x%04X, want 0x29B1", got)
+	}
+}
+
+func TestPackageCodecLengthMismatch(t *testing.T) {
+	codec := NewPackageCodec(config.ProtocolConfig{Checksum: config.ChecksumCRC32, ChecksumPlacement: config.ChecksumTrailer, MaxDataLength: 16})
+
+	pkg := testPackage([]byte("abc"))
+	pkg.DataSumLength = 4
+	if _, err := codec.Marshal(pkg); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("Marshal with wrong DataSumLength: error = %v, want ErrLengthMismatch", err)
+	}
+
+	encoded, err := codec.Marshal(testPackage([]byte("abc")))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if _, err := codec.Unmarshal(encoded[:len(encoded)-1]); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("Unmarshal truncated: error = %v, want ErrLengthMismatch", err)
+	}
+	if _, err := codec.Unmarshal(encoded[:BasePackageHeaderSize-1]); err == nil {
+		t.Error("Unmarshal accepted short header")
+	}
+
+	header := make([]byte, BasePackageHeaderSize)
+	binary.BigEndian.PutUint32(header[18:22], 17)
+	if _, err := codec.PacketLength(header); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("PacketLength over max: error = %v, want ErrLengthMismatch", err)
+	}
+}
+
+func TestProtocolHandlerProcessData(t *testing.T) {
+	codec := NewPackageCodec(config.ProtocolConfig{Checksum: config.ChecksumCRC16, ChecksumPlacement: config.ChecksumReserved})
+	first, _ := codec.Marshal(testPackage([]byte("first")))
+	second, _ := codec.Marshal(testPackage([]byte("second")))
+	corrupted := append([]byte{}, second...)
+	corrupted[BasePackageHeaderSize] ^= 0x01
+
+	// 心跳包、粘包、半包和校验失败的包混合到达
+	stream := append(append(append(append([]byte{}, HeartbeatPacket...), first...), corrupted...), second...)
+
+	ph := NewProtocolHandler(time.Minute, codec)
+	var packages []*BasePackage
+	for _, chunk := range [][]byte{stream[:20], stream[20:50], stream[50:]} {
+		got, err := ph.ProcessData(chunk)
+		if err != nil {
+			t.Fatalf("ProcessData: %v", err)
+		}
+		packages = append(packages, got...)
+	}
+
+	if len(packages) != 2 || string(packages[0].Data) != "first" || string(packages[1].Data) != "second" {
+		t.Fatalf("got %d packages, want first and second", len(packages))
+	}
+	if errs := ph.TakeErrors(); errs != 1 {
+		t.Errorf("TakeErrors() = %d, want 1", errs)
+	}
+	if ph.GetBufferSize() != 0 {
+		t.Errorf("GetBufferSize() = %d after complete packages", ph.GetBufferSize())
+	}
+}
@@ -0,0 +1,113 @@
+// internal/source/dedup.go
+package source
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// dedupKey 去重键（信源 + 包序号）
+type dedupKey struct {
+	sourceInfo uint32
+	packageNo  uint64
+}
+
+// dedupEntry 去重记录
+type dedupEntry struct {
+	key    dedupKey
+	seenAt time.Time
+}
+
+// Deduplicator 跨源去重器
+// 以BasePackage的信源和包序号作为键，在时间窗口内过滤多个源服务器重复投递的数据包
+type Deduplicator struct {
+	mu         sync.Mutex
+	window     time.Duration              // 去重时间窗口
+	capacity   int                        // 最大记录数
+	seen       map[dedupKey]*list.Element // 已见数据包索引
+	order      *list.List                 // 按到达顺序排列的记录，用于淘汰
+	duplicates int64                      // 已过滤的重复包数量
+}
+
+// NewDeduplicator 创建去重器
+// 参数: window - 去重时间窗口, capacity - 最大记录数
+// 返回: 去重器实例
+func NewDeduplicator(window time.Duration, capacity int) *Deduplicator {
+	return &Deduplicator{
+		window:   window,
+		capacity: capacity,
+		seen:     make(map[dedupKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// IsDuplicate 检查数据包是否已在窗口内出现过，未出现过则记录
+// 参数: pkg - 解析后的数据包
+// 返回: 是否为重复数据包
+func (d *Deduplicator) IsDuplicate(pkg *BasePackage) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.evict(now)
+
+	key := dedupKey{sourceInfo: pkg.SourceInfo, packageNo: pkg.PackageNo}
+	if _, exists := d.seen[key]; exists {
+		d.duplicates++
+		return true
+	}
+
+	d.seen[key] = d.order.PushBack(&dedupEntry{key: key, seenAt: now})
+	return false
+}
+
+// Forget 清除指定信源的全部去重记录
+// 源端序号重置（例如重启后重新从1开始编号）后调用，避免新编号的数据包与重置前的记录冲突而被当作重复包丢弃
+// 参数: sourceInfo - 信源
+// 返回: 清除的记录数
+func (d *Deduplicator) Forget(sourceInfo uint32) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	removed := 0
+	for element := d.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*dedupEntry)
+		if entry.key.sourceInfo == sourceInfo {
+			delete(d.seen, entry.key)
+			d.order.Remove(element)
+			removed++
+		}
+		element = next
+	}
+	return removed
+}
+
+// evict 淘汰过期或超出容量的记录
+// 调用方需持有锁
+// 参数: now - 当前时间
+func (d *Deduplicator) evict(now time.Time) {
+	for front := d.order.Front(); front != nil; front = d.order.Front() {
+		entry := front.Value.(*dedupEntry)
+		if now.Sub(entry.seenAt) < d.window && d.order.Len() < d.capacity {
+			break
+		}
+		delete(d.seen, entry.key)
+		d.order.Remove(front)
+	}
+}
+
+// GetStatus 获取去重器状态
+// 返回: 状态信息
+func (d *Deduplicator) GetStatus() map[string]interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return map[string]interface{}{
+		"window":     d.window.String(),
+		"capacity":   d.capacity,
+		"entries":    d.order.Len(),
+		"duplicates": d.duplicates,
+	}
+}
@@ -0,0 +1,142 @@
+// internal/source/dedup_test.go
+package source
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(time.Minute, 100)
+	steps := []struct {
+		sourceInfo uint32
+		no         uint64
+		want       bool
+	}{
+		{sourceInfo: 1, no: 1},
+		{sourceInfo: 1, no: 2},
+		{sourceInfo: 1, no: 1, want: true},
+		// 不同信源的相同包序号不是重复包
+		{sourceInfo: 2, no: 1},
+		{sourceInfo: 2, no: 1, want: true},
+	}
+	for i, step := range steps {
+		if got := d.IsDuplicate(&BasePackage{SourceInfo: step.sourceInfo, PackageNo: step.no}); got != step.want {
+			t.Errorf("step %d (%d/%d): IsDuplicate = %v, want %v", i, step.sourceInfo, step.no, got, step.want)
+		}
+	}
+
+	// 只清除指定信源的记录
+	if removed := d.Forget(1); removed != 2 {
+		t.Errorf("Forget(1) = %d, want 2", removed)
+	}
+	if d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: 1}) {
+		t.Error("source 1 still deduplicated after Forget")
+	}
+	if !d.IsDuplicate(&BasePackage{SourceInfo: 2, PackageNo: 1}) {
+		t.Error("source 2 entries removed by Forget(1)")
+	}
+	if status := d.GetStatus(); status["duplicates"] != int64(3) {
+		t.Errorf("duplicates = %v, want 3", status["duplicates"])
+	}
+}
+
+func TestDeduplicatorEviction(t *testing.T) {
+	// 超出时间窗口的记录被淘汰
+	d := NewDeduplicator(20*time.Millisecond, 100)
+	d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: 1})
+	time.Sleep(30 * time.Millisecond)
+	if d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: 1}) {
+		t.Error("entry outside window still deduplicated")
+	}
+
+	// 超出容量时淘汰最早的记录
+	d = NewDeduplicator(time.Minute, 2)
+	for no := uint64(1); no <= 3; no++ {
+		d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: no})
+	}
+	if !d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: 3}) {
+		t.Error("newest entry evicted")
+	}
+	if d.IsDuplicate(&BasePackage{SourceInfo: 1, PackageNo: 1}) {
+		t.Error("oldest entry not evicted at capacity")
+	}
+}
+
+// receiveRange 依次接收信源的一段连续包序号
+func (l *testLink) receiveRange(sourceInfo uint32, from, to uint64) {
+	l.t.Helper()
+	for no := from; no <= to; no++ {
+		if err := l.receive(l.dataPacket(sourceInfo, no)); err != nil {
+			l.t.Fatal(err)
+		}
+	}
+}
+
+func TestManagerRenumberAfterRestart(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.Sequence.MaxGapSize = 10
+	})
+	l := newTestLink(t, m, "src-1")
+	if err := l.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+
+	l.receiveRange(7, 1, 20)
+	l.receiveRange(8, 1, 2)
+
+	// 源端重启后信源7重新从1开始编号，仍在去重时间窗口内
+	l.receiveRange(7, 1, 3)
+	// 未重置的信源8仍然去重
+	l.receiveRange(8, 2, 2)
+
+	var renumbered []uint64
+	for _, meta := range l.delivered[22:] {
+		renumbered = append(renumbered, meta.Header.PackageNo)
+	}
+	if want := []uint64{1, 2, 3}; !reflect.DeepEqual(renumbered, want) {
+		t.Errorf("delivered after restart %v, want %v", renumbered, want)
+	}
+	if status := m.sequenceTracker.GetStatus(); status["resets"] != int64(1) {
+		t.Errorf("sequence resets = %v, want 1", status["resets"])
+	}
+}
+
+func TestManagerDedupAcrossConnections(t *testing.T) {
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.SourceServers.Mode = config.SourceModeConcurrent
+	})
+
+	primary := newTestLink(t, m, "src-1")
+	if err := primary.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+	primary.receiveRange(7, 1, 3)
+
+	// 另一台源服务器投递相同的数据包
+	backup := newTestLink(t, m, "src-2")
+	if err := backup.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+	backup.receiveRange(7, 1, 4)
+
+	// 重连后序号状态由新连接重新建立基线，源端重发的数据包由跨源去重丢弃
+	reconnected := newTestLink(t, m, "src-1")
+	if err := reconnected.authenticate(AuthStatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+	reconnected.receiveRange(7, 2, 5)
+
+	if nos := primary.deliveredNos(); !reflect.DeepEqual(nos, []uint64{1, 2, 3}) {
+		t.Errorf("primary delivered %v, want [1 2 3]", nos)
+	}
+	if nos := backup.deliveredNos(); !reflect.DeepEqual(nos, []uint64{4}) {
+		t.Errorf("backup delivered %v, want [4]", nos)
+	}
+	if nos := reconnected.deliveredNos(); !reflect.DeepEqual(nos, []uint64{5}) {
+		t.Errorf("reconnected delivered %v, want [5]", nos)
+	}
+}
@@ -0,0 +1,86 @@
+// internal/source/dialer.go
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/proxy"
+	"tcp-proxy-bridge/internal/tlsutil"
+)
+
+// Dialer 源服务器拨号器
+// 按服务器配置直连或经出站代理建立明文TCP或TLS连接，数据连接和健康探测共用
+type Dialer struct {
+	server *config.SourceServer // 服务器配置
+	tls    *tlsutil.Reloader    // TLS配置（未启用TLS时为nil）
+	proxy  *proxy.Dialer        // TCP连接拨号器（直连或经代理）
+}
+
+// NewDialer 创建源服务器拨号器
+// 参数: server - 服务器配置
+// 返回: 拨号器实例和错误信息（证书加载失败）
+func NewDialer(server *config.SourceServer) (*Dialer, error) {
+	d := &Dialer{server: server, proxy: proxy.NewDialer(server.Proxy)}
+	if server.TLS.Enabled {
+		reloader, err := tlsutil.NewClientReloader(server.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS configuration for %s: %v", server.Name, err)
+		}
+		d.tls = reloader
+	}
+	return d, nil
+}
+
+// DialContext 建立到源服务器的连接
+// 启用TLS时超时时间同时覆盖TCP连接（经代理时包括隧道建立）和TLS握手，TLS在代理隧道内端到端建立
+// 参数: ctx - 上下文, timeout - 超时时间
+// 返回: 连接和错误信息
+func (d *Dialer) DialContext(ctx context.Context, timeout time.Duration) (net.Conn, error) {
+	if d.tls == nil {
+		return d.proxy.DialContext(ctx, timeout, d.server.Address)
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := d.proxy.DialContext(dialCtx, timeout, d.server.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未配置server_name时按地址中的主机名校验证书
+	tlsConfig := d.tls.ClientConfig()
+	if tlsConfig.ServerName == "" {
+		if host, _, err := net.SplitHostPort(d.server.Address); err == nil {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = host
+		}
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("tls dial: %v", err)
+	}
+	return tlsConn, nil
+}
+
+// GetStatus 获取拨号器状态
+// 返回: 状态信息，未启用TLS时返回nil
+func (d *Dialer) GetStatus() map[string]interface{} {
+	if d.tls == nil {
+		return nil
+	}
+	return d.tls.GetStatus()
+}
+
+// ProxyStatus 获取出站代理状态
+// 返回: 状态信息，直连时返回nil
+func (d *Dialer) ProxyStatus() map[string]interface{} {
+	return d.proxy.GetStatus()
+}
@@ -2,25 +2,82 @@
 package source
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"net"
+	"sync"
 	"time"
 
 	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/framing"
+	"tcp-proxy-bridge/internal/xftype"
 )
 
+// 健康探测阶段
+const (
+	ProbeStageConnect   = "connect"   // 建立TCP连接
+	ProbeStageAuth      = "auth"      // 身份认证
+	ProbeStageHeartbeat = "heartbeat" // 心跳往返
+	ProbeStageDone      = "done"      // 探测完成
+	ProbeStageSession   = "session"   // 已有健康的连接，未另建探测连接
+)
+
+// ProbeResult 单次健康探测结果
+type ProbeResult struct {
+	Healthy        bool          // 是否健康
+	Stage          string        // 探测结束时所处阶段（失败时为失败阶段）
+	ConnectLatency time.Duration // TCP连接耗时
+	AuthLatency    time.Duration // 认证应答耗时
+	RTT            time.Duration // 心跳往返时延
+	AuthStatus     uint8         // 认证应答状态码
+	Error          string        // 失败原因
+	CheckedAt      time.Time     // 探测时间
+}
+
+// GetStatus 获取探测结果信息
+// 返回: 状态信息
+func (r *ProbeResult) GetStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"healthy":            r.Healthy,
+		"stage":              r.Stage,
+		"connect_latency_ms": r.ConnectLatency.Milliseconds(),
+		"auth_latency_ms":    r.AuthLatency.Milliseconds(),
+		"rtt_ms":             r.RTT.Milliseconds(),
+		"auth_status":        r.AuthStatus,
+		"error":              r.Error,
+		"checked_at":         r.CheckedAt,
+	}
+}
+
 // HealthChecker 健康检查器
-// 负责检查源服务器的健康状态
+// 使用独立连接对源服务器进行协议级探测：完成身份认证后交换一次XFType203心跳并测量往返时延，
+// 只接受连接但不应答的服务器视为不健康
 type HealthChecker struct {
-	// 可以添加更多配置，如检查间隔、超时时间等
+	mu          sync.Mutex                                       // 保护探测结果
+	authManager *AuthManager                                     // 生成认证包和心跳包
+	registry    *xftype.Registry                                 // 解析认证应答和心跳应答
+	newFramer   func(server *config.SourceServer) framing.Framer // 为探测连接创建分帧器
+	dial        DialFunc                                         // 建立探测连接（明文TCP或TLS，可经出站代理）
+	results     map[string]*ProbeResult                          // 各服务器最近一次探测结果
 }
 
+// DialFunc 建立到源服务器的连接
+type DialFunc func(ctx context.Context, server *config.SourceServer, timeout time.Duration) (net.Conn, error)
+
 // NewHealthChecker 创建健康检查器
+// 参数: authManager - 身份认证管理器, registry - 报文编解码器注册表, newFramer - 分帧器工厂, dial - 拨号函数
 // 返回: 健康检查器实例
-func NewHealthChecker() *HealthChecker {
-	return &HealthChecker{}
+func NewHealthChecker(authManager *AuthManager, registry *xftype.Registry,
+	newFramer func(server *config.SourceServer) framing.Framer, dial DialFunc) *HealthChecker {
+	return &HealthChecker{
+		authManager: authManager,
+		registry:    registry,
+		newFramer:   newFramer,
+		dial:        dial,
+		results:     make(map[string]*ProbeResult),
+	}
 }
 
 // IsHealthy 检查服务器是否健康
@@ -30,109 +87,183 @@ func (hc *HealthChecker) IsHealthy(ctx context.Context, server *config.SourceSer
 	if !server.Enabled {
 		return false
 	}
+	return hc.Probe(ctx, server).Healthy
+}
+
+// RecordSession 记录由已有连接判定的健康结果
+// 服务器已有认证通过且仍在收到数据的连接时不另建探测连接，避免每次检查都向源服务器发起第二个认证会话
+// 参数: serverID - 服务器ID
+// 返回: 探测结果
+func (hc *HealthChecker) RecordSession(serverID string) *ProbeResult {
+	result := &ProbeResult{Healthy: true, Stage: ProbeStageSession, CheckedAt: time.Now()}
+	hc.record(serverID, result)
+	return result
+}
+
+// Probe 对服务器执行一次协议级探测
+// 整个探测（连接、认证、心跳往返）必须在HealthCheckTimeout内完成
+// 参数: ctx - 上下文, server - 服务器配置
+// 返回: 探测结果
+func (hc *HealthChecker) Probe(ctx context.Context, server *config.SourceServer) *ProbeResult {
+	result := &ProbeResult{Stage: ProbeStageConnect, CheckedAt: time.Now()}
+	defer hc.record(server.ID, result)
+
+	probeCtx, cancel := context.WithTimeout(ctx, server.HealthCheckTimeout)
+	defer cancel()
+	deadline, _ := probeCtx.Deadline()
 
-	// 尝试建立TCP连接
-	conn, err := net.DialTimeout("tcp", server.Address, server.HealthCheckTimeout)
+	// 建立连接（启用TLS时包含握手）
+	start := time.Now()
+	conn, err := hc.dial(probeCtx, server, server.HealthCheckTimeout)
 	if err != nil {
-		log.Printf("Health check failed for %s (%s): %v", server.Name, server.Address, err)
-		return false
+		return hc.fail(server, result, err)
 	}
 	defer conn.Close()
+	conn.SetDeadline(deadline)
+	result.ConnectLatency = time.Since(start)
 
-	// 可以在这里添加更复杂的健康检查逻辑
-	// 例如发送ping消息、检查响应等
-
-	// 简单的连接测试通过
-	log.Printf("Health check passed for %s (%s)", server.Name, server.Address)
-	return true
-}
+	// 探测连接与数据连接一样使用独立的分帧器、协议缓冲区和请求包序号
+	framer := hc.newFramer(server)
+	requests := hc.authManager.ForConnection()
+	reader := &probeReader{conn: conn, framer: framer, protocol: NewProtocolHandler(0, requests.codec)}
 
-// CheckAllServers 检查所有服务器的健康状态
-// 参数: ctx - 上下文, servers - 服务器配置列表
-// 返回: 健康状态映射
-func (hc *HealthChecker) CheckAllServers(ctx context.Context, servers []*config.SourceServer) map[string]bool {
-	results := make(map[string]bool)
+	// 身份认证
+	result.Stage = ProbeStageAuth
+	authPacket, err := requests.GenerateAuthPacket()
+	if err != nil {
+		return hc.fail(server, result, err)
+	}
+	start = time.Now()
+	if err := writeFrame(conn, framer, authPacket); err != nil {
+		return hc.fail(server, result, err)
+	}
 
-	for _, server := range servers {
-		results[server.ID] = hc.IsHealthy(ctx, server)
+	var authResp *xftype.XFType099
+	err = reader.readUntil(func(pkg *BasePackage) bool {
+		authResp, _ = parseAuthResponse(hc.registry, pkg)
+		return authResp != nil
+	})
+	if err != nil {
+		return hc.fail(server, result, fmt.Errorf("no auth response: %v", err))
+	}
+	result.AuthLatency = time.Since(start)
+	result.AuthStatus = authResp.Status
+	if authResp.Status != AuthStatusAccepted {
+		return hc.fail(server, result, &AuthRejectedError{Server: server.Name, Status: authResp.Status})
 	}
 
-	return results
-}
+	// 心跳往返
+	result.Stage = ProbeStageHeartbeat
+	heartbeat, err := requests.GenerateHeartbeatRequest()
+	if err != nil {
+		return hc.fail(server, result, err)
+	}
+	start = time.Now()
+	if err := writeFrame(conn, framer, heartbeat); err != nil {
+		return hc.fail(server, result, err)
+	}
 
-// StartPeriodicCheck 启动定期健康检查
-// 参数: ctx - 上下文, servers - 服务器配置列表, interval - 检查间隔, callback - 结果回调
-func (hc *HealthChecker) StartPeriodicCheck(ctx context.Context, servers []*config.SourceServer,
-	interval time.Duration, callback func(map[string]bool)) {
+	err = reader.readUntil(hc.isHeartbeatResponse)
+	if err != nil {
+		return hc.fail(server, result, fmt.Errorf("no heartbeat response: %v", err))
+	}
+	result.RTT = time.Since(start)
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	result.Stage = ProbeStageDone
+	result.Healthy = true
+	log.Printf("Health probe passed for %s (%s): rtt=%v", server.Name, server.Address, result.RTT)
+	return result
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			results := hc.CheckAllServers(ctx, servers)
-			if callback != nil {
-				callback(results)
-			}
+// isHeartbeatResponse 判断数据包是否包含XFType203心跳
+// 参数: pkg - 解析后的数据包
+// 返回: 是否为心跳应答
+func (hc *HealthChecker) isHeartbeatResponse(pkg *BasePackage) bool {
+	seg, err := hc.registry.DecodeSegment(pkg.Data)
+	if err != nil {
+		return false
+	}
+	for _, item := range seg.Items {
+		if item.Type == xftype.TypeHeartbeat {
+			return true
 		}
 	}
+	return false
 }
 
-// PingServer 向服务器发送ping消息
-// 参数: ctx - 上下文, server - 服务器配置, message - ping消息
-// 返回: 响应和错误信息
-func (hc *HealthChecker) PingServer(ctx context.Context, server *config.SourceServer, message []byte) ([]byte, error) {
-	// 创建带超时的上下文
-	pingCtx, cancel := context.WithTimeout(ctx, server.HealthCheckTimeout)
-	defer cancel()
+// fail 记录探测失败
+// 参数: server - 服务器配置, result - 探测结果, err - 失败原因
+// 返回: 探测结果
+func (hc *HealthChecker) fail(server *config.SourceServer, result *ProbeResult, err error) *ProbeResult {
+	result.Error = err.Error()
+	log.Printf("Health probe failed for %s (%s) at %s: %v", server.Name, server.Address, result.Stage, err)
+	return result
+}
 
-	// 建立连接
-	conn, err := net.DialTimeout("tcp", server.Address, server.HealthCheckTimeout)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %v", server.Address, err)
-	}
-	defer conn.Close()
+// record 保存探测结果
+// 参数: serverID - 服务器ID, result - 探测结果
+func (hc *HealthChecker) record(serverID string, result *ProbeResult) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
 
-	// 发送ping消息
-	if _, err := conn.Write(message); err != nil {
-		return nil, fmt.Errorf("failed to send ping to %s: %v", server.Address, err)
-	}
+	hc.results[serverID] = result
+}
 
-	// 读取响应
-	response := make([]byte, 1024)
-	conn.SetReadDeadline(time.Now().Add(server.HealthCheckTimeout))
+// LastResult 获取服务器最近一次探测结果
+// 参数: serverID - 服务器ID
+// 返回: 探测结果，尚未探测时返回nil
+func (hc *HealthChecker) LastResult(serverID string) *ProbeResult {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
 
-	n, err := conn.Read(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read ping response from %s: %v", server.Address, err)
-	}
+	return hc.results[serverID]
+}
 
-	// 检查上下文是否被取消
-	select {
-	case <-pingCtx.Done():
-		return nil, pingCtx.Err()
-	default:
+// writeFrame 按分帧方式编码并发送数据
+// 参数: conn - 连接, framer - 分帧器, data - 数据包
+// 返回: 错误信息
+func writeFrame(conn net.Conn, framer framing.Framer, data []byte) error {
+	frame, err := framer.Encode(data)
+	if err != nil {
+		return err
 	}
+	_, err = conn.Write(frame)
+	return err
+}
 
-	return response[:n], nil
+// probeReader 探测连接的读取器
+type probeReader struct {
+	conn     net.Conn
+	framer   framing.Framer
+	protocol *ProtocolHandler
+	buffer   [4096]byte
 }
 
-// GetServerLatency 获取服务器延迟
-// 参数: ctx - 上下文, server - 服务器配置
-// 返回: 延迟时间和错误信息
-func (hc *HealthChecker) GetServerLatency(ctx context.Context, server *config.SourceServer) (time.Duration, error) {
-	start := time.Now()
+// readUntil 持续读取直到收到满足条件的数据包或连接超时
+// 参数: matchPacket - 数据包匹配函数
+// 返回: 错误信息
+func (r *probeReader) readUntil(matchPacket func(*BasePackage) bool) error {
+	for {
+		n, err := r.conn.Read(r.buffer[:])
+		if err != nil {
+			return err
+		}
 
-	// 简单的连接测试
-	conn, err := net.DialTimeout("tcp", server.Address, server.HealthCheckTimeout)
-	if err != nil {
-		return 0, err
+		frames, _ := r.framer.Decode(r.buffer[:n])
+		for _, frame := range frames {
+			// 连接保活的心跳帧不是数据包
+			if bytes.Equal(frame, HeartbeatPacket) {
+				continue
+			}
+			packages, err := r.protocol.ProcessData(frame)
+			if err != nil {
+				continue
+			}
+			for _, pkg := range packages {
+				if matchPacket(pkg) {
+					return nil
+				}
+			}
+		}
 	}
-	defer conn.Close()
-
-	latency := time.Since(start)
-	return latency, nil
 }
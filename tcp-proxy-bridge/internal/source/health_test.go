@@ -0,0 +1,78 @@
+// internal/source/health_test.go
+package source
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+func TestPerformHealthCheckSkipsProbeWithHealthySession(t *testing.T) {
+	tests := []struct {
+		name      string
+		session   bool          // 是否有连接
+		accepted  bool          // 连接是否认证通过（否则仍在等待认证应答）
+		read      bool          // 连接是否读取到过数据
+		idle      time.Duration // 最后一次读取距今的时间
+		wantProbe bool
+	}{
+		{name: "no session", wantProbe: true},
+		{name: "authenticated session", session: true, accepted: true, read: true},
+		{name: "pending session", session: true, read: true, wantProbe: true},
+		{name: "session without reads", session: true, accepted: true, wantProbe: true},
+		{name: "idle session", session: true, accepted: true, read: true, idle: 2 * time.Minute, wantProbe: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, func(cfg *config.Config) {
+				cfg.SourceServers.Servers[0].HealthCheckInterval = 30 * time.Second
+				cfg.SourceServers.Servers[0].HealthCheckTimeout = time.Second
+			})
+			server := m.serverIndex["src-1"]
+
+			probes := 0
+			m.healthChecker.dial = func(ctx context.Context, server *config.SourceServer, timeout time.Duration) (net.Conn, error) {
+				probes++
+				return nil, errors.New("connection refused")
+			}
+
+			if tt.session {
+				l := newTestLink(t, m, "src-1")
+				l.h.stats = m.sessions.open(l.h, "10.0.0.1:8888")
+				if tt.accepted {
+					l.authenticate(AuthStatusAccepted)
+				} else {
+					l.m.sendAuthPacket(l.conn, l.h)
+				}
+				if tt.read {
+					l.h.stats.recordRead(1)
+					l.h.stats.lastRead.Store(time.Now().Add(-tt.idle).UnixNano())
+				}
+			}
+
+			m.performHealthCheck(context.Background(), server)
+
+			if (probes > 0) != tt.wantProbe {
+				t.Fatalf("probes = %d, want probe %v", probes, tt.wantProbe)
+			}
+			result := m.healthChecker.results["src-1"]
+			if tt.wantProbe {
+				if result == nil || result.Healthy || m.failureCounts["src-1"] != 1 {
+					t.Errorf("probe result = %+v, failures = %d, want failed probe", result, m.failureCounts["src-1"])
+				}
+				return
+			}
+			if result == nil || !result.Healthy || result.Stage != ProbeStageSession {
+				t.Errorf("result = %+v, want healthy from session", result)
+			}
+			if m.failureCounts["src-1"] != 0 {
+				t.Errorf("failures = %d, want 0", m.failureCounts["src-1"])
+			}
+		})
+	}
+}
@@ -10,12 +10,11 @@ import (
 // HeartbeatManager 心跳管理器
 // 负责处理与源服务器的心跳机制
 type HeartbeatManager struct {
-	interval           time.Duration // 心跳间隔
-	lastHeartbeat      time.Time     // 最后心跳时间
-	lastHeartbeatRecv  time.Time     // 最后接收心跳时间
-	writeIdleTimeout   time.Duration // 写空闲超时
-	readIdleTimeout    time.Duration // 读空闲超时
-	separatorCharacter []byte        // 分隔符
+	interval          time.Duration // 心跳间隔
+	lastHeartbeat     time.Time     // 最后心跳时间
+	lastHeartbeatRecv time.Time     // 最后接收心跳时间
+	writeIdleTimeout  time.Duration // 写空闲超时
+	readIdleTimeout   time.Duration // 读空闲超时
 }
 
 // NewHeartbeatManager 创建心跳管理器
@@ -23,26 +22,21 @@ type HeartbeatManager struct {
 // 返回: 心跳管理器实例
 func NewHeartbeatManager(interval, writeIdle, readIdle time.Duration) *HeartbeatManager {
 	return &HeartbeatManager{
-		interval:           interval,
-		writeIdleTimeout:   writeIdle,
-		readIdleTimeout:    readIdle,
-		separatorCharacter: []byte{0x78, 0x78, 0x78, 0x78, 0x88, 0x88, 0x88, 0x88}, // "7878787888888888"
+		interval:         interval,
+		writeIdleTimeout: writeIdle,
+		readIdleTimeout:  readIdle,
 	}
 }
 
-// GenerateHeartbeatPacket 生成心跳包 (XFType203)
+// GenerateHeartbeatPacket 生成心跳包
+// 只包含心跳内容，分帧字节由连接的分帧器添加
 // 返回: 心跳包数据
 func (hm *HeartbeatManager) GenerateHeartbeatPacket() []byte {
-	// 心跳包内容: "心跳"的UTF-8编码 + 分隔符
-	heartbeatContent := []byte{0xE5, 0xBF, 0x83, 0xE8, 0xB7, 0xB3} // "心跳"
-	
-	// 组合心跳内容和分隔符
-	packet := make([]byte, len(heartbeatContent)+len(hm.separatorCharacter))
-	copy(packet, heartbeatContent)
-	copy(packet[len(heartbeatContent):], hm.separatorCharacter)
-	
+	packet := make([]byte, len(HeartbeatPacket))
+	copy(packet, HeartbeatPacket)
+
 	hm.lastHeartbeat = time.Now()
-	
+
 	log.Printf("Generated heartbeat packet: %d bytes", len(packet))
 	return packet
 }
@@ -75,26 +69,11 @@ func (hm *HeartbeatManager) UpdateHeartbeatReceived() {
 	hm.lastHeartbeatRecv = time.Now()
 }
 
-// IsHeartbeatPacket 检查是否是心跳包
-// 参数: data - 数据
+// IsHeartbeatPacket 检查分帧后的一帧是否是心跳包
+// 参数: frame - 分帧器切分出的一帧
 // 返回: 是否是心跳包
-func (hm *HeartbeatManager) IsHeartbeatPacket(data []byte) bool {
-	// 检查是否包含心跳内容
-	heartbeatContent := []byte{0xE5, 0xBF, 0x83, 0xE8, 0xB7, 0xB3}
-	return bytes.Contains(data, heartbeatContent)
-}
-
-// IsSeparatorPacket 检查是否是分隔符包
-// 参数: data - 数据
-// 返回: 是否是分隔符包
-func (hm *HeartbeatManager) IsSeparatorPacket(data []byte) bool {
-	return bytes.Contains(data, hm.separatorCharacter)
-}
-
-// GetSeparatorCharacter 获取分隔符
-// 返回: 分隔符字节数组
-func (hm *HeartbeatManager) GetSeparatorCharacter() []byte {
-	return hm.separatorCharacter
+func (hm *HeartbeatManager) IsHeartbeatPacket(frame []byte) bool {
+	return bytes.Equal(frame, HeartbeatPacket)
 }
 
 // GetLastHeartbeatTime 获取最后心跳时间
@@ -0,0 +1,99 @@
+// internal/source/heartbeat_test.go
+package source
+
+import (
+	"bytes"
+	"testing"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// testFramings 源服务器使用的各种分帧方式（空类型表示使用全局分隔符）
+var testFramings = []struct {
+	name    string
+	framing config.FramingConfig
+}{
+	{name: "delimiter"},
+	{name: "length_prefixed", framing: config.FramingConfig{Type: config.FramingLengthPrefixed, LengthWidth: 2}},
+	{name: "slip", framing: config.FramingConfig{Type: config.FramingSLIP}},
+	{name: "cobs", framing: config.FramingConfig{Type: config.FramingCOBS}},
+}
+
+// newFramingManager 创建第一台源服务器使用指定分帧方式的管理器
+func newFramingManager(t *testing.T, framing config.FramingConfig) *Manager {
+	return newTestManager(t, func(cfg *config.Config) {
+		cfg.SourceServers.Servers[0].Framing = framing
+	})
+}
+
+func TestSendHeartbeatFramed(t *testing.T) {
+	for _, tf := range testFramings {
+		t.Run(tf.name, func(t *testing.T) {
+			l := newTestLink(t, newFramingManager(t, tf.framing), "src-1")
+			if err := l.m.sendHeartbeat(l.conn, l.h); err != nil {
+				t.Fatalf("sendHeartbeat: %v", err)
+			}
+
+			frames := l.sentFrames()
+			if len(frames) != 1 || !bytes.Equal(frames[0], HeartbeatPacket) {
+				t.Fatalf("sent frames %x, want one heartbeat frame", frames)
+			}
+			if tf.framing.Type == "" {
+				// 分隔符分帧的线上字节与原有心跳包一致
+				want := append(append([]byte{}, HeartbeatPacket...), 0x78, 0x78, 0x78, 0x78, 0x88, 0x88, 0x88, 0x88)
+				if !bytes.Equal(l.conn.written, want) {
+					t.Errorf("written %x, want %x", l.conn.written, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHeartbeatMixedWithData(t *testing.T) {
+	for _, tf := range testFramings {
+		t.Run(tf.name, func(t *testing.T) {
+			l := newTestLink(t, newFramingManager(t, tf.framing), "src-1")
+			if err := l.authenticate(AuthStatusAccepted); err != nil {
+				t.Fatal(err)
+			}
+
+			// 心跳帧和数据包在同一次读取中到达，数据包不能被丢弃
+			if err := l.receive(HeartbeatPacket, l.dataPacket(7, 1), HeartbeatPacket, l.dataPacket(7, 2)); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.receive(l.dataPacket(7, 3), HeartbeatPacket); err != nil {
+				t.Fatal(err)
+			}
+
+			if nos := l.deliveredNos(); len(nos) != 3 || nos[0] != 1 || nos[1] != 2 || nos[2] != 3 {
+				t.Errorf("delivered %v, want [1 2 3]", nos)
+			}
+			if l.h.stats.heartbeatRecv.Load() == 0 {
+				t.Error("heartbeat not recorded")
+			}
+			if l.h.stats.parseErrors.Load() != 0 {
+				t.Errorf("parse errors = %d, want 0", l.h.stats.parseErrors.Load())
+			}
+		})
+	}
+}
+
+func TestIsHeartbeatPacket(t *testing.T) {
+	hm := NewHeartbeatManager(0, 0, 0)
+	tests := []struct {
+		name  string
+		frame []byte
+		want  bool
+	}{
+		{name: "heartbeat", frame: HeartbeatPacket, want: true},
+		{name: "generated", frame: hm.GenerateHeartbeatPacket(), want: true},
+		// 数据包中恰好包含心跳内容时不是心跳包
+		{name: "data containing heartbeat", frame: append([]byte("data"), HeartbeatPacket...)},
+		{name: "empty", frame: nil},
+	}
+	for _, tt := range tests {
+		if got := hm.IsHeartbeatPacket(tt.frame); got != tt.want {
+			t.Errorf("%s: IsHeartbeatPacket = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
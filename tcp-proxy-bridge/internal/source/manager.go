@@ -3,23 +3,30 @@ package source
 
 import (
 	"context"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"tcp-proxy-bridge/internal/capture"
 	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/framing"
+	"tcp-proxy-bridge/internal/xftype"
 )
 
 // Manager 源服务器管理器
-// 负责管理主备源服务器的连接和故障切换
+// 负责管理源服务器池的连接和故障切换
 type Manager struct {
-	config        *config.SourceServers // 源服务器配置
-	currentServer *config.SourceServer  // 当前使用的服务器
-	mu            sync.RWMutex          // 读写锁
-	isRunning     bool                  // 运行状态
+	config        *config.SourceServers           // 源服务器配置
+	servers       []*config.SourceServer          // 按优先级排序的服务器列表
+	serverIndex   map[string]*config.SourceServer // 服务器ID到配置的映射
+	currentServer *config.SourceServer            // 当前使用的服务器
+	mu            sync.RWMutex                    // 读写锁
+	isRunning     bool                            // 运行状态
 
 	// 健康检查相关
 	healthChecker *HealthChecker
@@ -27,52 +34,123 @@ type Manager struct {
 	wg            sync.WaitGroup // 等待组
 
 	// 故障统计
-	failureCounts map[string]int       // 各服务器失败次数统计
-	lastFailTime  map[string]time.Time // 各服务器最后失败时间
+	failureCounts map[string]int          // 各服务器失败次数统计
+	lastFailTime  map[string]time.Time    // 各服务器最后失败时间
+	connectedAt   map[string]time.Time    // 当前已连接的服务器及连接时间
+	authRejected  map[string]uint8        // 认证被拒绝的服务器及拒绝状态码（不再重试）
+	authSessions  map[string]*AuthSession // 各服务器最近一次连接的认证状态
+	sessions      *sessionRegistry        // 连接会话统计（当前连接和最近结束的连接）
 
 	// 协议处理相关
-	protocolHandler  *ProtocolHandler   // 协议处理器
-	delimiterHandler *DelimiterHandler  // 分隔符处理器
-	authManager      *AuthManager       // 身份认证管理器
-	heartbeatManager *HeartbeatManager  // 心跳管理器
-	dataHandler      func([]byte) error // 数据处理回调函数
+	framings         map[string]config.FramingConfig // 各服务器的分帧配置
+	dialers          map[string]*Dialer              // 各服务器的拨号器（明文TCP或TLS）
+	reconnects       map[string]*reconnectState      // 各服务器的重连退避和熔断状态
+	heartbeatConfig  config.HeartbeatConfig          // 心跳配置
+	authManager      *AuthManager                    // 身份认证管理器（各连接由此创建独立的请求包生成器）
+	authConfig       config.AuthConfig               // 身份认证配置
+	deduplicator     *Deduplicator                   // 跨源去重器
+	sequenceTracker  *SequenceTracker                // 包序号跟踪器
+	sequenceConfig   config.SequenceConfig           // 包序号跟踪配置
+	payloadRegistry  *xftype.Registry                // 报文编解码器注册表
+	payloadInspector *PayloadInspector               // 数据段解码校验器（未启用时为nil）
+	checkpointStore  CheckpointStore                 // 续传检查点存储（未启用续传时为nil）
+	checkpointDrop   CheckpointHandler               // 被丢弃数据包的检查点处理函数（未设置时为nil）
+	recorder         *capture.Recorder               // 原始数据抓包记录器（未启用抓包时为nil）
+
+	// 热备连接相关
+	standby           *standbyConn // 当前的热备连接（未启用热备或未连接时为nil）
+	standbyPromotions int64        // 热备连接被提升的次数
+}
+
+// connSession 单个源连接的会话状态
+// 每次建立连接（包括重连、切换和热备连接）都创建新的会话，分帧和协议缓冲区、心跳计时、
+// 请求包序号和认证状态都不跨连接保留。会话只由该连接的读取循环使用，不同连接之间不共享
+type connSession struct {
+	server           *config.SourceServer // 连接对应的服务器
+	exclusive        bool                 // 单连接模式：当前服务器切换后断开连接
+	standby          atomic.Bool          // 热备连接：保持认证和心跳，但不投递数据
+	framer           framing.Framer       // 分帧器
+	protocolHandler  *ProtocolHandler     // 协议处理器
+	heartbeatManager *HeartbeatManager    // 心跳管理器
+	requests         *AuthManager         // 认证、重传和续传请求包生成器（包序号从1开始）
+	auth             *AuthSession         // 认证状态机
+	stats            *Session             // 连接会话统计（读取循环开始时创建）
+	resumed          bool                 // 已从续传检查点请求续传（只由读取循环访问）
 }
 
 // NewManager 创建源服务器管理器
 // 参数: cfg - 完整配置
-// 返回: 源服务器管理器实例
-func NewManager(cfg *config.Config) *Manager {
-	// 解析分隔符
-	delimiterBytes, err := hex.DecodeString(cfg.Delimiter.Separator)
-	if err != nil {
-		log.Fatalf("Failed to decode delimiter: %v", err)
+// 返回: 源服务器管理器实例和错误信息（拨号器或分帧器创建失败时返回错误）
+func NewManager(cfg *config.Config) (*Manager, error) {
+	// 创建身份认证管理器
+	codec := NewPackageCodec(cfg.Protocol)
+	authManager := NewAuthManager(cfg.Authentication.Token, cfg.Authentication.SourceID, cfg.Authentication.HostID, codec)
+
+	servers := cfg.SourceServers.List()
+	serverIndex := make(map[string]*config.SourceServer, len(servers))
+	framings := make(map[string]config.FramingConfig, len(servers))
+	dialers := make(map[string]*Dialer, len(servers))
+	reconnects := make(map[string]*reconnectState, len(servers))
+	for _, server := range servers {
+		serverIndex[server.ID] = server
+		reconnects[server.ID] = &reconnectState{
+			backoff: NewBackoff(cfg.SourceServers.Reconnect),
+			breaker: NewCircuitBreaker(cfg.SourceServers.Reconnect.BreakerThreshold, cfg.SourceServers.Reconnect.BreakerCooldown),
+		}
+
+		// 创建拨号器，加载TLS证书
+		dialer, err := NewDialer(server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dialer for %s: %v", server.Name, err)
+		}
+		dialers[server.ID] = dialer
+
+		// 解析分帧配置
+		framings[server.ID] = cfg.SourceFraming(server)
+		if _, err := framing.New(framings[server.ID]); err != nil {
+			return nil, fmt.Errorf("failed to create framer for %s: %v", server.Name, err)
+		}
 	}
 
-	// 创建分隔符处理器
-	delimiterHandler := NewDelimiterHandler(delimiterBytes, cfg.Delimiter.MaxPacketLength)
+	m := &Manager{
+		config:          &cfg.SourceServers,
+		servers:         servers,
+		serverIndex:     serverIndex,
+		shutdownChan:    make(chan struct{}),
+		failureCounts:   make(map[string]int),
+		lastFailTime:    make(map[string]time.Time),
+		connectedAt:     make(map[string]time.Time),
+		authRejected:    make(map[string]uint8),
+		authSessions:    make(map[string]*AuthSession),
+		sessions:        newSessionRegistry(cfg.SourceServers.SessionHistory),
+		framings:        framings,
+		dialers:         dialers,
+		reconnects:      reconnects,
+		heartbeatConfig: cfg.Heartbeat,
+		authManager:     authManager,
+		authConfig:      cfg.Authentication,
+		deduplicator:    NewDeduplicator(cfg.SourceServers.DedupWindow, cfg.SourceServers.DedupCapacity),
+		sequenceTracker: NewSequenceTracker(cfg.Sequence.MaxGapSize, cfg.Sequence.MaxMissing, cfg.Sequence.GapTimeout),
+		sequenceConfig:  cfg.Sequence,
+		payloadRegistry: xftype.NewRegistry(),
+	}
 
-	// 创建身份认证管理器
-	authManager := NewAuthManager(cfg.Authentication.Token, cfg.Authentication.SourceID, cfg.Authentication.HostID)
+	m.healthChecker = NewHealthChecker(authManager, m.payloadRegistry, m.newFramer,
+		func(ctx context.Context, server *config.SourceServer, timeout time.Duration) (net.Conn, error) {
+			return m.dialers[server.ID].DialContext(ctx, timeout)
+		})
 
-	// 创建心跳管理器
-	heartbeatManager := NewHeartbeatManager(
-		cfg.Heartbeat.Interval,
-		cfg.Heartbeat.WriteIdleTimeout,
-		cfg.Heartbeat.ReadIdleTimeout,
-	)
+	if cfg.PayloadEnabled() {
+		m.payloadInspector = NewPayloadInspector(m.payloadRegistry, cfg.Payload.DropInvalid)
+	}
 
-	return &Manager{
-		config:           &cfg.SourceServers,
-		currentServer:    &cfg.SourceServers.Primary, // 默认使用主服务器
-		healthChecker:    NewHealthChecker(),
-		shutdownChan:     make(chan struct{}),
-		failureCounts:    make(map[string]int),
-		lastFailTime:     make(map[string]time.Time),
-		protocolHandler:  NewProtocolHandler(cfg.Heartbeat.Interval), // 使用配置的心跳间隔
-		delimiterHandler: delimiterHandler,
-		authManager:      authManager,
-		heartbeatManager: heartbeatManager,
+	// 默认使用优先级最高的可用服务器
+	m.currentServer = m.selectServer("")
+	if m.currentServer == nil && len(servers) > 0 {
+		m.currentServer = servers[0]
 	}
+
+	return m, nil
 }
 
 // Start 启动源服务器管理器
@@ -86,12 +164,20 @@ func (m *Manager) Start(ctx context.Context) error {
 		return fmt.Errorf("source manager is already running")
 	}
 
-	log.Printf("Starting source server manager with primary: %s, backup: %s",
-		m.config.Primary.Name, m.config.Backup.Name)
+	names := make([]string, 0, len(m.servers))
+	for _, server := range m.servers {
+		names = append(names, fmt.Sprintf("%s(p%d)", server.Name, server.Priority))
+	}
+	log.Printf("Starting source server manager with %d servers: %v", len(m.servers), names)
 
-	// 启动健康检查
-	m.wg.Add(1)
-	go m.healthCheckLoop(ctx)
+	// 每台启用的服务器按各自的检查间隔进行健康探测
+	for _, server := range m.servers {
+		if !server.Enabled {
+			continue
+		}
+		m.wg.Add(1)
+		go m.healthCheckLoop(ctx, server)
+	}
 
 	m.isRunning = true
 	log.Printf("Source server manager started successfully")
@@ -130,21 +216,45 @@ func (m *Manager) GetCurrentServer() *config.SourceServer {
 	return m.currentServer
 }
 
-// SetDataHandler 设置数据处理回调函数
-// 参数: handler - 数据处理函数
-func (m *Manager) SetDataHandler(handler func([]byte) error) {
-	m.dataHandler = handler
+// SetRecorder 设置原始数据抓包记录器
+// 设置后从源服务器连接读取的每段原始数据都写入抓包文件
+// 参数: recorder - 抓包记录器
+func (m *Manager) SetRecorder(recorder *capture.Recorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recorder = recorder
+}
+
+// RegisterLayouts 注册报文布局定义，用于数据段解码校验
+// 参数: layouts - 布局定义列表
+// 返回: 错误信息（类型编号与已注册类型冲突等）
+func (m *Manager) RegisterLayouts(layouts []*xftype.Layout) error {
+	return m.payloadRegistry.RegisterLayouts(layouts)
+}
+
+// PayloadRegistry 获取报文编解码器注册表
+// 返回: 注册表实例
+func (m *Manager) PayloadRegistry() *xftype.Registry {
+	return m.payloadRegistry
 }
 
 // ConnectToSource 连接到源服务器获取数据
 // 参数: ctx - 上下文, dataHandler - 数据处理函数
 // 返回: 错误信息
-func (m *Manager) ConnectToSource(ctx context.Context, dataHandler func([]byte) error) error {
+func (m *Manager) ConnectToSource(ctx context.Context, dataHandler DataHandler) error {
 	server := m.GetCurrentServer()
 	if server == nil {
 		return fmt.Errorf("no available source server")
 	}
 
+	m.mu.RLock()
+	usable := m.isUsable(server)
+	m.mu.RUnlock()
+	if !usable {
+		return fmt.Errorf("no usable source server: %s was rejected", server.Name)
+	}
+
 	log.Printf("Connecting to source server: %s (%s)", server.Name, server.Address)
 
 	// 尝试连接当前服务器
@@ -152,12 +262,10 @@ func (m *Manager) ConnectToSource(ctx context.Context, dataHandler func([]byte)
 	if err != nil {
 		log.Printf("Failed to connect to current server %s: %v", server.Name, err)
 
-		// 记录失败并尝试切换
+		// 记录失败，达到阈值或已熔断时切换，由调用方（重连监督循环）决定何时连接新服务器
 		m.recordFailure(server.ID)
-		if m.shouldFailover(server.ID) {
+		if errors.Is(err, ErrCircuitOpen) || m.shouldFailover(server.ID) {
 			m.performFailover()
-			// 使用新服务器重试
-			return m.ConnectToSource(ctx, dataHandler)
 		}
 		return err
 	}
@@ -168,49 +276,323 @@ func (m *Manager) ConnectToSource(ctx context.Context, dataHandler func([]byte)
 
 	log.Printf("Successfully connected to source server: %s", server.Name)
 
+	m.markConnected(server.ID, true)
+	defer m.markConnected(server.ID, false)
+
 	// 开始读取数据
-	return m.readDataFromSource(ctx, conn, dataHandler)
+	err = m.readDataFromSource(ctx, conn, m.newConnSession(server, true), dataHandler)
+	if _, rejected := err.(*AuthRejectedError); rejected {
+		// 认证被拒绝的服务器不再可用，立即切换
+		m.performFailover()
+	}
+	return err
+}
+
+// Run 重连监督循环，维持与源服务器的连接直到上下文取消或管理器关闭
+// 单连接模式下连接当前服务器，失败后按退避时间等待；若已切换到另一台可用服务器则立即连接。
+// 并发模式下为每台服务器启动独立的重连循环
+// 参数: ctx - 上下文, dataHandler - 数据处理函数
+// 返回: 上下文取消或管理器关闭时返回错误信息
+func (m *Manager) Run(ctx context.Context, dataHandler DataHandler) error {
+	if m.config.IsConcurrent() {
+		return m.ConnectToAllSources(ctx, dataHandler)
+	}
+
+	if m.config.WarmStandby {
+		go m.standbyLoop(ctx, dataHandler)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.shutdownChan:
+			return fmt.Errorf("manager shutdown")
+		default:
+		}
+
+		server := m.GetCurrentServer()
+		var err error
+		if sc := m.takeStandby(serverID(server)); sc != nil {
+			// 切换到热备服务器：直接使用已认证的热备连接
+			err = m.runPromoted(sc)
+		} else {
+			err = m.ConnectToSource(ctx, dataHandler)
+		}
+		if err != nil {
+			log.Printf("Source connection error: %v", err)
+		}
+
+		// 当前连接断开且热备连接已认证时立即切换，不等待故障切换阈值
+		if err != nil && server != nil && m.config.WarmStandby && ctx.Err() == nil {
+			if m.switchToStandby(server) {
+				m.recordFailure(server.ID)
+				continue
+			}
+		}
+
+		// 已切换到另一台可用服务器时不等待
+		if next := m.GetCurrentServer(); server != nil && next != nil && next.ID != server.ID {
+			m.mu.RLock()
+			available := m.isAvailable(next)
+			m.mu.RUnlock()
+			if available {
+				continue
+			}
+		}
+
+		if !m.waitReconnect(ctx, server) {
+			return ctx.Err()
+		}
+	}
+}
+
+// runPromoted 等待被提升的热备连接结束
+// 参数: sc - 热备连接
+// 返回: 连接结束原因
+func (m *Manager) runPromoted(sc *standbyConn) error {
+	m.resetFailureCount(sc.server.ID)
+	<-sc.done
+
+	if _, rejected := sc.err.(*AuthRejectedError); rejected {
+		// 认证被拒绝的服务器不再可用，立即切换
+		m.performFailover()
+	}
+	return sc.err
+}
+
+// serverID 获取服务器ID
+// 参数: server - 服务器配置（可为nil）
+// 返回: 服务器ID
+func serverID(server *config.SourceServer) string {
+	if server == nil {
+		return ""
+	}
+	return server.ID
+}
+
+// waitReconnect 按服务器的退避时间等待重连
+// 参数: ctx - 上下文, server - 服务器配置（为nil时使用初始退避时间）
+// 返回: 是否继续重连（上下文取消或管理器关闭时为false）
+func (m *Manager) waitReconnect(ctx context.Context, server *config.SourceServer) bool {
+	delay := time.Second
+	if server != nil {
+		delay = m.reconnects[server.ID].backoff.Next()
+		log.Printf("Reconnecting to %s in %v", server.Name, delay)
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-m.shutdownChan:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// ConnectToAllSources 并发连接所有启用的源服务器并合并数据流
+// 每台服务器由独立的协程负责连接和重连，数据包按信源+包序号跨源去重
+// 参数: ctx - 上下文, dataHandler - 数据处理函数
+// 返回: 上下文取消或管理器关闭时返回错误信息
+func (m *Manager) ConnectToAllSources(ctx context.Context, dataHandler DataHandler) error {
+	var wg sync.WaitGroup
+
+	for _, server := range m.servers {
+		if !server.Enabled {
+			continue
+		}
+
+		wg.Add(1)
+		go func(server *config.SourceServer) {
+			defer wg.Done()
+			m.runSourceLoop(ctx, server, dataHandler)
+		}(server)
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("manager shutdown")
+}
+
+// runSourceLoop 维持与单台源服务器的连接，断开后自动重连
+// 参数: ctx - 上下文, server - 服务器配置, dataHandler - 数据处理函数
+func (m *Manager) runSourceLoop(ctx context.Context, server *config.SourceServer, dataHandler DataHandler) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.shutdownChan:
+			return
+		default:
+		}
+
+		if err := m.connectAndRead(ctx, server, dataHandler); err != nil {
+			log.Printf("Source connection to %s ended: %v", server.Name, err)
+			if _, rejected := err.(*AuthRejectedError); rejected {
+				// 认证被拒绝不可重试
+				return
+			}
+		}
+
+		// 按退避时间等待后重连
+		if !m.waitReconnect(ctx, server) {
+			return
+		}
+	}
+}
+
+// connectAndRead 连接单台源服务器并读取数据，每个连接使用独立的协议处理器
+// 参数: ctx - 上下文, server - 服务器配置, dataHandler - 数据处理函数
+// 返回: 错误信息
+func (m *Manager) connectAndRead(ctx context.Context, server *config.SourceServer, dataHandler DataHandler) error {
+	log.Printf("Connecting to source server: %s (%s)", server.Name, server.Address)
+
+	conn, err := m.connectWithRetry(ctx, server)
+	if err != nil {
+		m.recordFailure(server.ID)
+		return err
+	}
+	defer conn.Close()
+
+	m.resetFailureCount(server.ID)
+	log.Printf("Successfully connected to source server: %s", server.Name)
+
+	m.markConnected(server.ID, true)
+	defer m.markConnected(server.ID, false)
+
+	return m.readDataFromSource(ctx, conn, m.newConnSession(server, false), dataHandler)
+}
+
+// newFramer 为连接创建分帧器
+// 参数: server - 服务器配置
+// 返回: 分帧器实例
+func (m *Manager) newFramer(server *config.SourceServer) framing.Framer {
+	framer, err := framing.New(m.framings[server.ID])
+	if err != nil {
+		// 分帧配置已在创建管理器时校验，这里仅作兜底
+		log.Printf("Failed to create framer for %s, falling back to raw: %v", server.Name, err)
+		return framing.NewRawFramer(framing.DefaultMaxFrameSize)
+	}
+	return framer
+}
+
+// newConnSession 为新连接创建会话
+// 参数: server - 服务器配置, exclusive - 是否为单连接模式的连接（当前服务器切换后断开）
+// 返回: 连接会话
+func (m *Manager) newConnSession(server *config.SourceServer, exclusive bool) *connSession {
+	heartbeatManager := NewHeartbeatManager(
+		m.heartbeatConfig.Interval,
+		m.heartbeatConfig.WriteIdleTimeout,
+		m.heartbeatConfig.ReadIdleTimeout,
+	)
+	// 心跳发送和读空闲从连接建立时开始计时
+	heartbeatManager.Reset()
+
+	return &connSession{
+		server:           server,
+		exclusive:        exclusive,
+		framer:           m.newFramer(server),
+		protocolHandler:  NewProtocolHandler(m.heartbeatConfig.Interval, m.authManager.codec),
+		heartbeatManager: heartbeatManager,
+		requests:         m.authManager.ForConnection(),
+		auth:             m.newAuthSession(server),
+	}
+}
+
+// newAuthSession 为新连接创建认证状态机，并记录为该服务器的当前认证状态
+// 参数: server - 服务器配置
+// 返回: 认证状态机
+func (m *Manager) newAuthSession(server *config.SourceServer) *AuthSession {
+	session := NewAuthSession(m.authConfig.ResponseTimeout, m.authConfig.ReauthInterval)
+
+	m.mu.Lock()
+	m.authSessions[server.ID] = session
+	m.mu.Unlock()
+
+	return session
+}
+
+// markConnected 记录服务器连接状态
+// 参数: serverID - 服务器ID, connected - 是否已连接
+func (m *Manager) markConnected(serverID string, connected bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if connected {
+		m.connectedAt[serverID] = time.Now()
+	} else {
+		delete(m.connectedAt, serverID)
+	}
 }
 
 // connectWithRetry 带重试的连接
+// 重试间隔按指数退避加随机抖动计算；熔断器打开时不再尝试
 // 参数: ctx - 上下文, server - 服务器配置
-// 返回: 连接对象和错误信息
+// 返回: 连接对象和错误信息（熔断时包装ErrCircuitOpen）
 func (m *Manager) connectWithRetry(ctx context.Context, server *config.SourceServer) (net.Conn, error) {
+	rs := m.reconnects[server.ID]
 	var lastErr error
 
 	for attempt := 0; attempt <= server.MaxRetries; attempt++ {
+		if !rs.breaker.Allow() {
+			if lastErr == nil {
+				return nil, fmt.Errorf("%s: %w", server.Name, ErrCircuitOpen)
+			}
+			return nil, fmt.Errorf("%s: %w after %d attempts: %v", server.Name, ErrCircuitOpen, attempt, lastErr)
+		}
+
 		if attempt > 0 {
-			// 重试前等待
+			// 重试前按退避时间等待
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-time.After(rs.backoff.Next()):
 			}
 		}
 
-		// 尝试连接
-		conn, err := net.DialTimeout("tcp", server.Address, server.Timeout)
+		// 尝试连接（启用TLS时完成握手）
+		conn, err := m.dialers[server.ID].DialContext(ctx, server.Timeout)
 		if err == nil {
+			rs.breaker.RecordSuccess()
+			rs.backoff.Reset()
 			return conn, nil
 		}
 
 		lastErr = err
 		log.Printf("Connection attempt %d to %s failed: %v", attempt+1, server.Address, err)
+		if rs.breaker.RecordFailure() {
+			log.Printf("Circuit breaker opened for %s", server.Name)
+		}
 	}
 
 	return nil, fmt.Errorf("failed to connect after %d attempts: %v", server.MaxRetries+1, lastErr)
 }
 
 // readDataFromSource 从源服务器读取数据
-// 参数: ctx - 上下文, conn - 连接, dataHandler - 数据处理函数
-// 返回: 错误信息
-func (m *Manager) readDataFromSource(ctx context.Context, conn net.Conn, dataHandler func([]byte) error) error {
+// 连接建立后先完成认证握手，之后按重新认证间隔定期认证
+// 参数: ctx - 上下文, conn - 连接, h - 连接会话, dataHandler - 数据处理函数
+// 返回: 错误信息（认证被拒绝时为*AuthRejectedError）
+func (m *Manager) readDataFromSource(ctx context.Context, conn net.Conn, h *connSession, dataHandler DataHandler) (err error) {
+	remote := conn.RemoteAddr().String()
+	h.stats = m.sessions.open(h, remote)
+	defer func() {
+		m.sessions.close(h.stats, err)
+	}()
+
 	buffer := make([]byte, 4096)
 	heartbeatTicker := time.NewTicker(3 * time.Second) // 3秒检查一次心跳
 	defer heartbeatTicker.Stop()
 
-	// 连接建立后立即发送身份认证包
-	if err := m.sendAuthPacket(conn); err != nil {
+	m.mu.RLock()
+	recorder := m.recorder
+	m.mu.RUnlock()
+
+	// 连接建立后立即发送身份认证包，等待源服务器应答
+	if err := m.sendAuthPacket(conn, h); err != nil {
 		log.Printf("Failed to send auth packet: %v", err)
 		return err
 	}
@@ -223,26 +605,53 @@ func (m *Manager) readDataFromSource(ctx context.Context, conn net.Conn, dataHan
 			return fmt.Errorf("manager shutdown")
 		case <-heartbeatTicker.C:
 			// 检查是否需要发送心跳
-			if m.heartbeatManager.ShouldSendHeartbeat() {
-				if err := m.sendHeartbeat(conn); err != nil {
+			if h.heartbeatManager.ShouldSendHeartbeat() {
+				if err := m.sendHeartbeat(conn, h); err != nil {
 					log.Printf("Failed to send heartbeat: %v", err)
 					return err
 				}
 			}
 
 			// 检查读空闲超时
-			if m.heartbeatManager.IsReadIdle() {
+			if h.heartbeatManager.IsReadIdle() {
 				log.Printf("Read idle timeout, closing connection")
 				return fmt.Errorf("read idle timeout")
 			}
+
+			// 检查认证应答超时和重新认证
+			if err := m.checkAuth(conn, h); err != nil {
+				return err
+			}
+
+			// 热备连接提升后没有数据到达时也续传
+			if err := m.resumeOnce(conn, h); err != nil {
+				return err
+			}
+
+			// 单连接模式下健康探测触发了切换，断开当前连接以连接新的服务器（热备连接除外）
+			if h.exclusive && !h.standby.Load() {
+				if current := m.GetCurrentServer(); current != nil && current.ID != h.server.ID {
+					return fmt.Errorf("current source server switched to %s", current.Name)
+				}
+			}
 		default:
-			// 设置读取超时
-			conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+			// 设置读取超时，等待认证应答期间不超过应答截止时间
+			readDeadline := time.Now().Add(30 * time.Second)
+			if deadline, pending := h.auth.Deadline(); pending && deadline.Before(readDeadline) {
+				readDeadline = deadline
+			}
+			conn.SetReadDeadline(readDeadline)
 
 			n, err := conn.Read(buffer)
 			if err != nil {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					// 超时，继续循环
+					// 超时（或热备连接提升时被唤醒），检查认证状态和续传后继续循环
+					if err := m.checkAuth(conn, h); err != nil {
+						return err
+					}
+					if err := m.resumeOnce(conn, h); err != nil {
+						return err
+					}
 					continue
 				}
 				return fmt.Errorf("failed to read from source: %v", err)
@@ -252,61 +661,230 @@ func (m *Manager) readDataFromSource(ctx context.Context, conn net.Conn, dataHan
 				// 处理接收到的数据
 				data := make([]byte, n)
 				copy(data, buffer[:n])
+				h.stats.recordRead(n)
 
-				// 更新心跳接收时间
-				m.heartbeatManager.UpdateHeartbeatReceived()
-
-				// 检查是否是心跳包
-				if m.heartbeatManager.IsHeartbeatPacket(data) {
-					log.Printf("Received heartbeat packet")
-					continue
+				// 记录原始数据（包括心跳和认证应答），用于排查和重放
+				if recorder != nil {
+					recorder.Record(capture.DirectionIn, h.server.ID, remote, data)
 				}
 
-				// 使用分隔符处理器处理数据，防粘包
-				packets, err := m.delimiterHandler.ProcessData(data)
-				if err != nil {
-					log.Printf("Error processing data with delimiter: %v", err)
-					continue
-				}
+				// 更新心跳接收时间
+				h.heartbeatManager.UpdateHeartbeatReceived()
+
+				// 使用分帧器处理数据，防粘包；心跳包在分帧后逐帧识别
+				packets, frameErr := m.decodeFrames(h, data)
 
 				// 处理每个完整的数据包
 				for _, packet := range packets {
-					// 验证数据包
-					if !m.delimiterHandler.ValidatePacket(packet) {
-						log.Printf("Invalid packet received: %d bytes", len(packet))
-						continue
+					if err := m.handlePacket(conn, h, packet, dataHandler); err != nil {
+						return err
 					}
+				}
 
-					// 使用协议处理器进一步解析
-					basePackages, err := m.protocolHandler.ProcessData(packet)
-					if err != nil {
-						log.Printf("Error parsing base package: %v", err)
-						// 如果协议解析失败，直接处理原始数据
-						if err := dataHandler(packet); err != nil {
-							log.Printf("Error handling raw data: %v", err)
-						}
-						continue
-					}
+				// 帧边界丢失后无法重新同步，断开连接后重连
+				if frameErr != nil {
+					return frameErr
+				}
+			}
+		}
+	}
+}
 
-					// 处理每个解析后的数据包
-					for _, pkg := range basePackages {
-						if err := dataHandler(pkg.Data); err != nil {
-							log.Printf("Error handling data from source: %v", err)
-							// 继续处理，不中断连接
-						}
-					}
+// decodeFrames 使用连接的分帧器切分数据，返回已完整的帧
+// 超长或格式错误的帧只记录并丢弃；长度字段类分帧方式丢失帧边界时返回错误，由调用方断开连接
+// 参数: h - 连接会话, data - 读取到的原始数据
+// 返回: 完整的帧列表和错误信息（包装了framing.ErrOutOfSync）
+func (m *Manager) decodeFrames(h *connSession, data []byte) ([][]byte, error) {
+	packets, err := h.framer.Decode(data)
+	if err != nil {
+		log.Printf("Error decoding %s frames from %s: %v", h.framer.Name(), h.server.Name, err)
+		if errors.Is(err, framing.ErrFrameTooLarge) {
+			h.stats.frameOverflows.Add(1)
+		} else {
+			h.stats.parseErrors.Add(1)
+		}
+	}
+	h.stats.framesIn.Add(int64(len(packets)))
+
+	if errors.Is(err, framing.ErrOutOfSync) {
+		return packets, fmt.Errorf("%s frame boundary lost: %v", h.framer.Name(), err)
+	}
+	return packets, nil
+}
+
+// handlePacket 处理一个完整的数据包
+// 参数: conn - 连接, h - 连接会话, packet - 分隔后的数据包, dataHandler - 数据处理函数
+// 返回: 需要断开连接时返回错误（认证被拒绝）
+func (m *Manager) handlePacket(conn net.Conn, h *connSession, packet []byte, dataHandler DataHandler) error {
+	// 分帧器已丢弃超长帧，这里只需过滤空包
+	if len(packet) == 0 {
+		return nil
+	}
+
+	// 心跳包只更新统计，不进入协议解析
+	if h.heartbeatManager.IsHeartbeatPacket(packet) {
+		log.Printf("Received heartbeat packet from %s", h.server.Name)
+		h.stats.recordHeartbeatReceived()
+		return nil
+	}
+
+	// 使用协议处理器进一步解析
+	basePackages, err := h.protocolHandler.ProcessData(packet)
+	h.stats.parseErrors.Add(int64(h.protocolHandler.TakeErrors()))
+	h.stats.packages.Add(int64(len(basePackages)))
+	if err != nil {
+		log.Printf("Error parsing base package: %v", err)
+		// 如果协议解析失败，直接处理原始数据（热备连接不投递）
+		if h.auth.CanDeliver() && !h.standby.Load() {
+			meta := &PacketMeta{ServerID: h.server.ID, SourceIP: remoteIP(conn)}
+			h.stats.delivered.Add(1)
+			if err := dataHandler(packet, meta); err != nil {
+				log.Printf("Error handling raw data: %v", err)
+			}
+		}
+		return nil
+	}
+
+	// 处理每个解析后的数据包
+	for _, pkg := range basePackages {
+		// 认证应答
+		if resp, ok := parseAuthResponse(m.payloadRegistry, pkg); ok {
+			if err := m.handleAuthResponse(conn, h, resp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 热备连接只保持认证和心跳，不投递数据
+		if h.standby.Load() {
+			continue
+		}
+
+		// 热备连接提升后，在投递第一个数据包之前续传
+		if err := m.resumeOnce(conn, h); err != nil {
+			return err
+		}
+
+		// 首次认证通过前的数据不投递
+		if !h.auth.CanDeliver() {
+			log.Printf("Dropped package from %s before authentication: Source=%d, PackageNo=%d",
+				h.server.Name, pkg.SourceInfo, pkg.PackageNo)
+			continue
+		}
+
+		// 包序号检查：丢弃重复包，检测到缺口时请求重传
+		check := m.sequenceTracker.Check(pkg, h.server.ID)
+		switch check.Result {
+		case SequenceDuplicate:
+			log.Printf("Dropped duplicate package from %s by sequence: Source=%d, PackageNo=%d",
+				h.server.Name, pkg.SourceInfo, pkg.PackageNo)
+			continue
+		case SequenceGap:
+			if m.sequenceConfig.RequestRetransmission {
+				if err := m.sendRetransmissionRequest(conn, h, check.GapStart, check.GapEnd); err != nil {
+					log.Printf("Failed to request retransmission: %v", err)
+				}
+			}
+		case SequenceFilled:
+			log.Printf("Retransmitted package filled gap: Source=%d, PackageNo=%d",
+				pkg.SourceInfo, pkg.PackageNo)
+		case SequenceReset:
+			// 源端重新编号后，重置前的去重记录不再对应同一批数据包
+			if removed := m.deduplicator.Forget(pkg.SourceInfo); removed > 0 {
+				log.Printf("Cleared %d dedup entries for source %d after sequence reset", removed, pkg.SourceInfo)
+			}
+		}
+
+		meta := &PacketMeta{
+			ServerID: h.server.ID,
+			SourceIP: remoteIP(conn),
+			Header:   pkg,
+			// 只有推进期望序号且之前没有缺失包的数据包才推进检查点：存在缺口时推进会使重启后
+			// 从缺口之后续传，缺失的数据包永久丢失；补齐缺口的重传包不回退检查点
+			Checkpoint: (check.Result == SequenceInOrder || check.Result == SequenceReset) && check.Settled,
+		}
+
+		// 跨源去重，同一数据包只处理一次（在序号检查之后，以便先识别源端序号重置）
+		if m.deduplicator.IsDuplicate(pkg) {
+			log.Printf("Dropped duplicate package from %s: Source=%d, PackageNo=%d",
+				h.server.Name, pkg.SourceInfo, pkg.PackageNo)
+			m.checkpointDropped(meta)
+			continue
+		}
+
+		// 解码校验数据段
+		if m.payloadInspector != nil {
+			if _, err := m.payloadInspector.Inspect(pkg); err != nil {
+				log.Printf("Invalid payload from %s: Source=%d, PackageNo=%d: %v",
+					h.server.Name, pkg.SourceInfo, pkg.PackageNo, err)
+				h.stats.invalid.Add(1)
+				if m.payloadInspector.DropInvalid() {
+					h.stats.invalidDropped.Add(1)
+					m.checkpointDropped(meta)
+					continue
 				}
 			}
 		}
+
+		h.stats.delivered.Add(1)
+		if err := dataHandler(pkg.Data, meta); err != nil {
+			log.Printf("Error handling data from source: %v", err)
+			// 继续处理，不中断连接
+		}
 	}
+
+	return nil
 }
 
-// healthCheckLoop 健康检查循环
-// 参数: ctx - 上下文
-func (m *Manager) healthCheckLoop(ctx context.Context) {
+// handleAuthResponse 处理认证应答
+// 连接首次认证通过后从续传检查点请求续传（热备连接在提升后续传）
+// 参数: conn - 连接, h - 连接会话, resp - 认证应答
+// 返回: 认证被拒绝时返回*AuthRejectedError
+func (m *Manager) handleAuthResponse(conn net.Conn, h *connSession, resp *xftype.XFType099) error {
+	if h.auth.State() != AuthStatePending {
+		log.Printf("Ignored unsolicited auth response from %s: status=%d", h.server.Name, resp.Status)
+		return nil
+	}
+
+	if h.auth.HandleResponse(resp.Status) {
+		log.Printf("Authentication accepted by %s", h.server.Name)
+		return m.resumeOnce(conn, h)
+	}
+
+	// 认证被拒绝：标记服务器不可用，不再重试
+	m.mu.Lock()
+	m.authRejected[h.server.ID] = resp.Status
+	m.mu.Unlock()
+
+	log.Printf("Authentication rejected by %s: status=%d, server will not be retried", h.server.Name, resp.Status)
+	return &AuthRejectedError{Server: h.server.Name, Status: resp.Status}
+}
+
+// checkAuth 检查认证应答超时，并按间隔重新认证
+// 参数: conn - 连接, h - 连接会话
+// 返回: 应答超时或发送失败时返回错误
+func (m *Manager) checkAuth(conn net.Conn, h *connSession) error {
+	if h.auth.CheckTimeout() {
+		log.Printf("Authentication response timeout from %s", h.server.Name)
+		return fmt.Errorf("authentication response timeout after %v", m.authConfig.ResponseTimeout)
+	}
+
+	if h.auth.ShouldReauth() {
+		log.Printf("Re-authenticating with %s", h.server.Name)
+		if err := m.sendAuthPacket(conn, h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// healthCheckLoop 单台服务器的健康检查循环
+// 参数: ctx - 上下文, server - 服务器配置
+func (m *Manager) healthCheckLoop(ctx context.Context, server *config.SourceServer) {
 	defer m.wg.Done()
 
-	ticker := time.NewTicker(30 * time.Second) // 默认30秒检查一次
+	ticker := time.NewTicker(server.HealthCheckInterval)
 	defer ticker.Stop()
 
 	for {
@@ -316,38 +894,52 @@ func (m *Manager) healthCheckLoop(ctx context.Context) {
 		case <-m.shutdownChan:
 			return
 		case <-ticker.C:
-			m.performHealthCheck(ctx)
+			m.performHealthCheck(ctx, server)
 		}
 	}
 }
 
-// performHealthCheck 执行健康检查
-// 参数: ctx - 上下文
-func (m *Manager) performHealthCheck(ctx context.Context) {
-	// 检查主服务器
-	if m.config.Primary.Enabled {
-		if !m.healthChecker.IsHealthy(ctx, &m.config.Primary) {
-			log.Printf("Primary server %s is unhealthy", m.config.Primary.Name)
-			m.recordFailure(m.config.Primary.ID)
-		} else {
-			m.resetFailureCount(m.config.Primary.ID)
-		}
+// performHealthCheck 对单台服务器执行协议级健康探测，并根据结果决定是否切换
+// 参数: ctx - 上下文, server - 服务器配置
+func (m *Manager) performHealthCheck(ctx context.Context, server *config.SourceServer) {
+	m.mu.RLock()
+	usable := m.isUsable(server)
+	m.mu.RUnlock()
+	if !usable {
+		// 认证被拒绝的服务器不再探测
+		return
 	}
 
-	// 检查备用服务器
-	if m.config.Backup.Enabled {
-		if !m.healthChecker.IsHealthy(ctx, &m.config.Backup) {
-			log.Printf("Backup server %s is unhealthy", m.config.Backup.Name)
-			m.recordFailure(m.config.Backup.ID)
-		} else {
-			m.resetFailureCount(m.config.Backup.ID)
-		}
+	var result *ProbeResult
+	if m.hasHealthySession(server) {
+		// 已有健康的连接，不再另建认证会话探测
+		result = m.healthChecker.RecordSession(server.ID)
+	} else {
+		result = m.healthChecker.Probe(ctx, server)
+	}
+	if !result.Healthy {
+		log.Printf("Source server %s is unhealthy: %s", server.Name, result.Error)
+		m.recordFailure(server.ID)
+	} else {
+		m.resetFailureCount(server.ID)
 	}
 
 	// 检查是否需要故障切换
 	m.checkAndPerformFailover()
 }
 
+// hasHealthySession 服务器是否已有健康的连接
+// 认证通过且在最近一个检查间隔（不短于心跳间隔）内收到过数据的数据连接或热备连接视为健康
+// 参数: server - 服务器配置
+// 返回: 是否已有健康的连接
+func (m *Manager) hasHealthySession(server *config.SourceServer) bool {
+	maxIdle := server.HealthCheckInterval
+	if m.heartbeatConfig.Interval > maxIdle {
+		maxIdle = m.heartbeatConfig.Interval
+	}
+	return m.sessions.healthy(server.ID, maxIdle)
+}
+
 // recordFailure 记录服务器失败
 // 参数: serverID - 服务器ID
 func (m *Manager) recordFailure(serverID string) {
@@ -381,12 +973,8 @@ func (m *Manager) shouldFailover(serverID string) bool {
 	defer m.mu.RUnlock()
 
 	// 获取服务器配置
-	var server *config.SourceServer
-	if serverID == m.config.Primary.ID {
-		server = &m.config.Primary
-	} else if serverID == m.config.Backup.ID {
-		server = &m.config.Backup
-	} else {
+	server, exists := m.serverIndex[serverID]
+	if !exists {
 		return false
 	}
 
@@ -399,85 +987,262 @@ func (m *Manager) checkAndPerformFailover() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// 如果当前是主服务器且主服务器失败，切换到备用服务器
-	if m.currentServer.ID == m.config.Primary.ID &&
-		m.failureCounts[m.config.Primary.ID] >= m.config.Primary.FailoverThreshold &&
-		m.config.Backup.Enabled {
-
-		log.Printf("Failing over from primary to backup server")
-		m.currentServer = &m.config.Backup
+	if m.currentServer == nil {
+		m.currentServer = m.selectServer("")
 		return
 	}
 
-	// 如果当前是备用服务器且主服务器恢复，切换回主服务器
-	if m.currentServer.ID == m.config.Backup.ID &&
-		m.failureCounts[m.config.Primary.ID] == 0 &&
-		m.config.Primary.Enabled {
-
-		log.Printf("Failing back from backup to primary server")
-		m.currentServer = &m.config.Primary
+	// 如果当前服务器不可用，切换到下一个可用服务器
+	if !m.isAvailable(m.currentServer) {
+		if next := m.selectServer(m.currentServer.ID); next != nil {
+			log.Printf("Failing over from %s to %s", m.currentServer.Name, next.Name)
+			m.currentServer = next
+		}
 		return
 	}
+
+	// 如果存在更高优先级的健康服务器，切换回该服务器
+	if best := m.selectFailback(); best != nil {
+		log.Printf("Failing back from %s to %s", m.currentServer.Name, best.Name)
+		m.currentServer = best
+	}
 }
 
 // performFailover 执行故障切换
+// 沿优先级列表向下选择下一个可用服务器，全部不可用时按顺序轮换
 func (m *Manager) performFailover() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.currentServer.ID == m.config.Primary.ID && m.config.Backup.Enabled {
-		log.Printf("Performing failover: %s -> %s",
-			m.config.Primary.Name, m.config.Backup.Name)
-		m.currentServer = &m.config.Backup
-	} else if m.currentServer.ID == m.config.Backup.ID && m.config.Primary.Enabled {
-		log.Printf("Performing failover: %s -> %s",
-			m.config.Backup.Name, m.config.Primary.Name)
-		m.currentServer = &m.config.Primary
+	if m.currentServer == nil {
+		m.currentServer = m.selectServer("")
+		return
+	}
+
+	next := m.selectServer(m.currentServer.ID)
+	if next == nil {
+		next = m.nextEnabledServer(m.currentServer.ID)
+	}
+	if next == nil || next.ID == m.currentServer.ID {
+		return
+	}
+
+	log.Printf("Performing failover: %s -> %s", m.currentServer.Name, next.Name)
+	m.currentServer = next
+}
+
+// isAvailable 判断服务器是否可用（可使用且失败次数未达到阈值）
+// 调用方需持有锁
+// 参数: server - 服务器配置
+// 返回: 是否可用
+func (m *Manager) isAvailable(server *config.SourceServer) bool {
+	return m.isUsable(server) && m.failureCounts[server.ID] < server.FailoverThreshold &&
+		m.reconnects[server.ID].breaker.Available()
+}
+
+// isUsable 判断服务器是否可使用（已启用且未被拒绝认证）
+// 调用方需持有锁
+// 参数: server - 服务器配置
+// 返回: 是否可使用
+func (m *Manager) isUsable(server *config.SourceServer) bool {
+	if server == nil || !server.Enabled {
+		return false
+	}
+	_, rejected := m.authRejected[server.ID]
+	return !rejected
+}
+
+// selectServer 按优先级分组选择可用服务器
+// 从优先级最高的组开始查找，组内按权重随机选择
+// 调用方需持有锁
+// 参数: excludeID - 需要排除的服务器ID
+// 返回: 选中的服务器，没有可用服务器时返回nil
+func (m *Manager) selectServer(excludeID string) *config.SourceServer {
+	for _, group := range m.priorityGroups() {
+		var candidates []*config.SourceServer
+		for _, server := range group {
+			if server.ID != excludeID && m.isAvailable(server) {
+				candidates = append(candidates, server)
+			}
+		}
+		if len(candidates) > 0 {
+			return pickWeighted(candidates)
+		}
+	}
+	return nil
+}
+
+// selectFailback 选择可回切的更高优先级服务器
+// 只有失败计数已清零的服务器才会被视为恢复
+// 调用方需持有锁
+// 返回: 可回切的服务器，无需回切时返回nil
+func (m *Manager) selectFailback() *config.SourceServer {
+	for _, group := range m.priorityGroups() {
+		if group[0].Priority >= m.currentServer.Priority {
+			return nil
+		}
+
+		var candidates []*config.SourceServer
+		for _, server := range group {
+			if m.isUsable(server) && m.failureCounts[server.ID] == 0 {
+				candidates = append(candidates, server)
+			}
+		}
+		if len(candidates) > 0 {
+			return pickWeighted(candidates)
+		}
+	}
+	return nil
+}
+
+// nextEnabledServer 获取列表中位于指定服务器之后的下一个可使用的服务器（循环）
+// 调用方需持有锁
+// 参数: serverID - 当前服务器ID
+// 返回: 下一个可使用的服务器，没有时返回nil
+func (m *Manager) nextEnabledServer(serverID string) *config.SourceServer {
+	start := 0
+	for i, server := range m.servers {
+		if server.ID == serverID {
+			start = i + 1
+			break
+		}
+	}
+
+	for i := 0; i < len(m.servers); i++ {
+		server := m.servers[(start+i)%len(m.servers)]
+		if m.isUsable(server) {
+			return server
+		}
+	}
+	return nil
+}
+
+// priorityGroups 将服务器列表按优先级分组
+// 返回: 按优先级从高到低排列的服务器组
+func (m *Manager) priorityGroups() [][]*config.SourceServer {
+	var groups [][]*config.SourceServer
+	for start := 0; start < len(m.servers); {
+		end := start + 1
+		for end < len(m.servers) && m.servers[end].Priority == m.servers[start].Priority {
+			end++
+		}
+		groups = append(groups, m.servers[start:end])
+		start = end
+	}
+	return groups
+}
+
+// pickWeighted 按权重随机选择服务器
+// 参数: candidates - 候选服务器列表
+// 返回: 选中的服务器
+func pickWeighted(candidates []*config.SourceServer) *config.SourceServer {
+	total := 0
+	for _, server := range candidates {
+		total += serverWeight(server)
+	}
+
+	n := rand.Intn(total)
+	for _, server := range candidates {
+		n -= serverWeight(server)
+		if n < 0 {
+			return server
+		}
 	}
+	return candidates[len(candidates)-1]
+}
+
+// serverWeight 获取服务器权重，未配置时默认为1
+// 参数: server - 服务器配置
+// 返回: 权重
+func serverWeight(server *config.SourceServer) int {
+	if server.Weight <= 0 {
+		return 1
+	}
+	return server.Weight
 }
 
 // sendAuthPacket 发送身份认证包
-// 参数: conn - 连接
+// 参数: conn - 连接, h - 连接会话
 // 返回: 错误信息
-func (m *Manager) sendAuthPacket(conn net.Conn) error {
-	authPacket, err := m.authManager.GenerateAuthPacket()
+func (m *Manager) sendAuthPacket(conn net.Conn, h *connSession) error {
+	authPacket, err := h.requests.GenerateAuthPacket()
 	if err != nil {
 		return fmt.Errorf("failed to generate auth packet: %v", err)
 	}
 
-	// 添加分隔符
-	packetWithDelimiter := m.delimiterHandler.AddDelimiter(authPacket)
+	// 添加分帧字节
+	frame, err := h.framer.Encode(authPacket)
+	if err != nil {
+		return fmt.Errorf("failed to frame auth packet: %v", err)
+	}
 
-	// 设置写超时
-	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := m.writeFrame(conn, h, frame); err != nil {
+		return fmt.Errorf("failed to send auth packet: %v", err)
+	}
+	h.auth.Begin()
+
+	log.Printf("Sent auth packet to %s: %d bytes", h.server.Name, len(frame))
+	return nil
+}
 
-	_, err = conn.Write(packetWithDelimiter)
+// sendRetransmissionRequest 向源服务器发送重传请求
+// 参数: conn - 连接, h - 连接会话, gapStart - 缺失起始包序号, gapEnd - 缺失结束包序号
+// 返回: 错误信息
+func (m *Manager) sendRetransmissionRequest(conn net.Conn, h *connSession, gapStart, gapEnd uint64) error {
+	request, err := h.requests.GenerateRetransmissionRequest(gapStart, gapEnd)
 	if err != nil {
-		return fmt.Errorf("failed to send auth packet: %v", err)
+		return err
+	}
+
+	// 添加分帧字节
+	frame, err := h.framer.Encode(request)
+	if err != nil {
+		return fmt.Errorf("failed to frame retransmission request: %v", err)
 	}
 
-	log.Printf("Sent auth packet to %s: %d bytes", m.GetCurrentServer().Name, len(packetWithDelimiter))
+	if err := m.writeFrame(conn, h, frame); err != nil {
+		return fmt.Errorf("failed to send retransmission request: %v", err)
+	}
+
+	log.Printf("Sent retransmission request to %s: %d-%d", h.server.Name, gapStart, gapEnd)
 	return nil
 }
 
 // sendHeartbeat 发送心跳包
-// 参数: conn - 连接
+// 参数: conn - 连接, h - 连接会话
 // 返回: 错误信息
-func (m *Manager) sendHeartbeat(conn net.Conn) error {
-	heartbeatPacket := m.heartbeatManager.GenerateHeartbeatPacket()
-
-	// 设置写超时
-	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+func (m *Manager) sendHeartbeat(conn net.Conn, h *connSession) error {
+	heartbeatPacket := h.heartbeatManager.GenerateHeartbeatPacket()
 
-	_, err := conn.Write(heartbeatPacket)
+	// 添加分帧字节
+	frame, err := h.framer.Encode(heartbeatPacket)
 	if err != nil {
+		return fmt.Errorf("failed to frame heartbeat: %v", err)
+	}
+
+	if err := m.writeFrame(conn, h, frame); err != nil {
 		return fmt.Errorf("failed to send heartbeat: %v", err)
 	}
 
 	// 更新心跳发送时间
-	m.heartbeatManager.UpdateHeartbeatSent()
+	h.heartbeatManager.UpdateHeartbeatSent()
+	h.stats.recordHeartbeatSent()
+
+	log.Printf("Sent heartbeat packet to %s", h.server.Name)
+	return nil
+}
+
+// writeFrame 向源服务器写入一帧数据并计入连接会话统计
+// 参数: conn - 连接, h - 连接会话, frame - 已添加分帧字节的数据
+// 返回: 错误信息
+func (m *Manager) writeFrame(conn net.Conn, h *connSession, frame []byte) error {
+	// 设置写超时
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 
-	log.Printf("Sent heartbeat packet to %s", m.GetCurrentServer().Name)
+	if _, err := conn.Write(frame); err != nil {
+		return err
+	}
+	h.stats.recordWrite(len(frame))
 	return nil
 }
 
@@ -487,10 +1252,83 @@ func (m *Manager) GetStatus() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return map[string]interface{}{
-		"is_running":     m.isRunning,
-		"current_server": m.currentServer.Name,
-		"failure_counts": m.failureCounts,
-		"last_fail_time": m.lastFailTime,
+	failureCounts := make(map[string]int, len(m.failureCounts))
+	for id, count := range m.failureCounts {
+		failureCounts[id] = count
+	}
+	lastFailTime := make(map[string]time.Time, len(m.lastFailTime))
+	for id, t := range m.lastFailTime {
+		lastFailTime[id] = t
+	}
+	connectedServers := make(map[string]time.Time, len(m.connectedAt))
+	for id, t := range m.connectedAt {
+		connectedServers[id] = t
+	}
+
+	servers := make([]map[string]interface{}, 0, len(m.servers))
+	for _, server := range m.servers {
+		auth := map[string]interface{}{"state": AuthStateNone.String()}
+		if session, exists := m.authSessions[server.ID]; exists {
+			auth = session.GetStatus()
+		}
+		if status, rejected := m.authRejected[server.ID]; rejected {
+			auth["state"] = AuthStateRejected.String()
+			auth["rejected_status"] = status
+		}
+
+		var health map[string]interface{}
+		if result := m.healthChecker.LastResult(server.ID); result != nil {
+			health = result.GetStatus()
+		}
+
+		servers = append(servers, map[string]interface{}{
+			"id":            server.ID,
+			"name":          server.Name,
+			"address":       server.Address,
+			"enabled":       server.Enabled,
+			"priority":      server.Priority,
+			"weight":        serverWeight(server),
+			"failure_count": m.failureCounts[server.ID],
+			"available":     m.isAvailable(server),
+			"is_current":    m.currentServer != nil && m.currentServer.ID == server.ID,
+			"is_standby":    m.standby != nil && m.standby.server.ID == server.ID,
+			"connected":     !m.connectedAt[server.ID].IsZero(),
+			"framing":       m.framings[server.ID].Type,
+			"auth":          auth,
+			"health":        health,
+			"tls":           m.dialers[server.ID].GetStatus(),
+			"proxy":         m.dialers[server.ID].ProxyStatus(),
+			"reconnect":     m.reconnects[server.ID].GetStatus(),
+		})
 	}
+
+	currentName := ""
+	if m.currentServer != nil {
+		currentName = m.currentServer.Name
+	}
+
+	status := map[string]interface{}{
+		"is_running":        m.isRunning,
+		"mode":              m.config.Mode,
+		"current_server":    currentName,
+		"servers":           servers,
+		"connected_servers": connectedServers,
+		"failure_counts":    failureCounts,
+		"last_fail_time":    lastFailTime,
+		"dedup":             m.deduplicator.GetStatus(),
+		"sequence":          m.sequenceTracker.GetStatus(),
+	}
+	if m.config.WarmStandby {
+		status["standby"] = m.standbyStatus()
+		status["standby_promotions"] = m.standbyPromotions
+	}
+	if m.payloadInspector != nil {
+		status["payload"] = m.payloadInspector.GetStatus()
+	}
+	if m.recorder != nil {
+		status["capture"] = m.recorder.GetStatus()
+	}
+	status["sessions"], status["session_history"] = m.sessions.GetStatus()
+
+	return status
 }
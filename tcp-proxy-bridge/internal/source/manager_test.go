@@ -0,0 +1,227 @@
+// internal/source/manager_test.go
+package source
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/framing"
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// testConfigYAML 测试使用的最小配置：两台源服务器，使用全局分隔符分帧
+const testConfigYAML = `
+source_servers:
+  servers:
+    - id: "src-1"
+      name: "source-1"
+      address: "127.0.0.1:1"
+      enabled: true
+      priority: 0
+    - id: "src-2"
+      name: "source-2"
+      address: "127.0.0.1:2"
+      enabled: true
+      priority: 1
+authentication:
+  token: "11111111111111111111111111111111"
+  source_id: 802
+  host_id: 20
+heartbeat:
+  interval: "60s"
+  read_idle_timeout: "60s"
+delimiter:
+  separator: "7878787888888888"
+  max_packet_length: 4096
+`
+
+// newTestManager 加载测试配置并创建源服务器管理器
+// 参数: configure - 加载配置后对配置的调整（可为nil）
+func newTestManager(t *testing.T, configure func(cfg *config.Config)) *Manager {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+// testConn 记录写入数据的连接，读取由测试直接调用处理流程代替
+type testConn struct {
+	replayConn
+	mu      sync.Mutex
+	written []byte
+}
+
+func (c *testConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, b...)
+	return len(b), nil
+}
+
+// testLink 测试用的源连接：一个连接会话、记录写入的连接和投递结果
+type testLink struct {
+	t       *testing.T
+	m       *Manager
+	h       *connSession
+	conn    *testConn
+	encoder framing.Framer // 模拟源服务器一侧的分帧器
+
+	mu        sync.Mutex
+	delivered []*PacketMeta
+}
+
+// newTestLink 为指定服务器创建测试连接
+// 参数: serverID - 服务器ID
+func newTestLink(t *testing.T, m *Manager, serverID string) *testLink {
+	t.Helper()
+	server, exists := m.serverIndex[serverID]
+	if !exists {
+		t.Fatalf("unknown server %s", serverID)
+	}
+	h := m.newConnSession(server, false)
+	remote := "10.0.0.1:8888"
+	h.stats = newSession(0, h, remote)
+	return &testLink{
+		t:       t,
+		m:       m,
+		h:       h,
+		conn:    &testConn{replayConn: replayConn{remote: remote}},
+		encoder: m.newFramer(server),
+	}
+}
+
+// handle 投递处理函数，记录投递的数据包
+func (l *testLink) handle(data []byte, meta *PacketMeta) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.delivered = append(l.delivered, meta)
+	return nil
+}
+
+// deliveredNos 获取已投递数据包的包序号
+func (l *testLink) deliveredNos() []uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	nos := make([]uint64, 0, len(l.delivered))
+	for _, meta := range l.delivered {
+		nos = append(nos, meta.Header.PackageNo)
+	}
+	return nos
+}
+
+// receive 以源服务器的分帧方式编码数据包，作为一次读取交给连接的处理流程
+// 返回: 处理流程返回的错误（如认证被拒绝）
+func (l *testLink) receive(packets ...[]byte) error {
+	l.t.Helper()
+	var chunk []byte
+	for _, packet := range packets {
+		frame, err := l.encoder.Encode(packet)
+		if err != nil {
+			l.t.Fatalf("Encode: %v", err)
+		}
+		chunk = append(chunk, frame...)
+	}
+
+	frames, err := l.m.decodeFrames(l.h, chunk)
+	if err != nil {
+		l.t.Fatalf("decodeFrames: %v", err)
+	}
+	for _, frame := range frames {
+		if err := l.m.handlePacket(l.conn, l.h, frame, l.handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authenticate 发送认证包，并以指定状态码应答
+// 返回: 处理认证应答返回的错误
+func (l *testLink) authenticate(status uint8) error {
+	l.t.Helper()
+	if err := l.m.sendAuthPacket(l.conn, l.h); err != nil {
+		l.t.Fatalf("sendAuthPacket: %v", err)
+	}
+	return l.receive(l.authResponse(status))
+}
+
+// authResponse 构建认证应答包 (XFType099)
+func (l *testLink) authResponse(status uint8) []byte {
+	l.t.Helper()
+	now := time.Now()
+	data, err := l.m.payloadRegistry.EncodeSegment(&xftype.DataSegment{
+		Year:  uint16(now.Year()),
+		Month: uint8(now.Month()),
+		Day:   uint8(now.Day()),
+		Items: []xftype.DataItem{{Type: xftype.TypeAuthResponse, Body: &xftype.XFType099{Token: "token", Status: status}}},
+	})
+	if err != nil {
+		l.t.Fatalf("EncodeSegment: %v", err)
+	}
+	return l.packet(&BasePackage{SourceInfo: 20, HostInfo: 802, DataSumLength: uint32(len(data)), Data: data})
+}
+
+// dataPacket 构建数据包
+// 参数: sourceInfo - 信源, no - 包序号
+func (l *testLink) dataPacket(sourceInfo uint32, no uint64) []byte {
+	data := []byte("payload")
+	return l.packet(&BasePackage{SourceInfo: sourceInfo, HostInfo: 20, PackageNo: no, CurrentDataItem: 1, DataSumLength: uint32(len(data)), Data: data})
+}
+
+// packet 按管理器的编解码配置序列化数据包
+func (l *testLink) packet(pkg *BasePackage) []byte {
+	l.t.Helper()
+	encoded, err := l.m.authManager.codec.Marshal(pkg)
+	if err != nil {
+		l.t.Fatalf("Marshal: %v", err)
+	}
+	return encoded
+}
+
+// sentFrames 按源服务器的分帧方式切分连接上已写入的数据
+func (l *testLink) sentFrames() [][]byte {
+	l.t.Helper()
+	l.conn.mu.Lock()
+	written := append([]byte{}, l.conn.written...)
+	l.conn.mu.Unlock()
+
+	frames, err := l.m.newFramer(l.h.server).Decode(written)
+	if err != nil {
+		l.t.Fatalf("Decode written frames: %v", err)
+	}
+	return frames
+}
+
+// sent 解析连接上已写入的请求包（跳过心跳帧）
+func (l *testLink) sent() []*BasePackage {
+	l.t.Helper()
+	var packages []*BasePackage
+	for _, frame := range l.sentFrames() {
+		if bytes.Equal(frame, HeartbeatPacket) {
+			continue
+		}
+		pkg, err := l.m.authManager.codec.Unmarshal(frame)
+		if err != nil {
+			l.t.Fatalf("Unmarshal written frame: %v", err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
@@ -0,0 +1,121 @@
+// internal/source/payload.go
+package source
+
+import (
+	"fmt"
+	"sync"
+
+	"tcp-proxy-bridge/internal/xftype"
+)
+
+// payloadTypeStats 单个信息类型的解码统计
+type payloadTypeStats struct {
+	name      string // 类型名称
+	decoded   int64  // 解码成功数量
+	failed    int64  // 解码失败数量
+	lastError string // 最近一次解码错误
+}
+
+// PayloadInspector 数据段解码校验器
+// 在协议解析之后使用报文编解码器注册表（内置类型 + 布局定义）解码数据段，
+// 结构错误或已注册类型解码失败的数据包视为无效
+type PayloadInspector struct {
+	mu          sync.Mutex
+	registry    *xftype.Registry             // 报文编解码器注册表
+	dropInvalid bool                         // 校验失败时是否丢弃
+	inspected   int64                        // 已校验数据包数量
+	invalid     int64                        // 无效数据包数量
+	unknown     int64                        // 未注册类型的数据项数量
+	types       map[uint16]*payloadTypeStats // 各信息类型统计
+	lastError   string                       // 最近一次校验错误
+}
+
+// NewPayloadInspector 创建数据段解码校验器
+// 参数: registry - 报文编解码器注册表, dropInvalid - 校验失败时是否丢弃
+// 返回: 校验器实例
+func NewPayloadInspector(registry *xftype.Registry, dropInvalid bool) *PayloadInspector {
+	return &PayloadInspector{
+		registry:    registry,
+		dropInvalid: dropInvalid,
+		types:       make(map[uint16]*payloadTypeStats),
+	}
+}
+
+// Inspect 解码并校验数据包的数据段
+// 未注册类型的数据项只计数，不视为错误
+// 参数: pkg - 解析后的数据包
+// 返回: 解码后的数据段和校验错误
+func (pi *PayloadInspector) Inspect(pkg *BasePackage) (*xftype.DataSegment, error) {
+	seg, err := pi.registry.DecodeSegment(pkg.Data)
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	pi.inspected++
+	if err != nil {
+		pi.invalid++
+		pi.lastError = err.Error()
+		return seg, err
+	}
+
+	var itemErr error
+	for _, item := range seg.Items {
+		if item.Name == "" {
+			pi.unknown++
+			continue
+		}
+
+		stats, exists := pi.types[item.Type]
+		if !exists {
+			stats = &payloadTypeStats{name: item.Name}
+			pi.types[item.Type] = stats
+		}
+		if item.Error != "" {
+			stats.failed++
+			stats.lastError = item.Error
+			if itemErr == nil {
+				itemErr = fmt.Errorf("item type %d (%s): %s", item.Type, item.Name, item.Error)
+			}
+			continue
+		}
+		stats.decoded++
+	}
+
+	if itemErr != nil {
+		pi.invalid++
+		pi.lastError = itemErr.Error()
+	}
+	return seg, itemErr
+}
+
+// DropInvalid 校验失败时是否丢弃数据包
+func (pi *PayloadInspector) DropInvalid() bool {
+	return pi.dropInvalid
+}
+
+// GetStatus 获取校验统计
+// 返回: 状态信息
+func (pi *PayloadInspector) GetStatus() map[string]interface{} {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	types := make(map[string]interface{}, len(pi.types))
+	for t, stats := range pi.types {
+		types[fmt.Sprintf("%d", t)] = map[string]interface{}{
+			"name":       stats.name,
+			"decoded":    stats.decoded,
+			"failed":     stats.failed,
+			"last_error": stats.lastError,
+		}
+	}
+
+	return map[string]interface{}{
+		"registered_types": pi.registry.Types(),
+		"drop_invalid":     pi.dropInvalid,
+		"inspected":        pi.inspected,
+		"invalid":          pi.invalid,
+		"unknown_items":    pi.unknown,
+		"types":            types,
+		"last_error":       pi.lastError,
+	}
+}
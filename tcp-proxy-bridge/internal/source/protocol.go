@@ -3,11 +3,12 @@ package source
 
 import (
 	"bytes"
-	"encoding/binary"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"log"
 	"time"
+
+	"tcp-proxy-bridge/internal/metrics"
 )
 
 // ProtocolHandler 协议处理器
@@ -16,16 +17,22 @@ type ProtocolHandler struct {
 	heartbeatInterval time.Duration // 心跳间隔
 	lastHeartbeat     time.Time     // 最后心跳时间
 	buffer            []byte        // 数据缓冲区
+	codec             *PackageCodec // BasePackage编解码器
+	errors            int           // 尚未取走的解析错误数
 }
 
 // NewProtocolHandler 创建协议处理器
-// 参数: heartbeatInterval - 心跳间隔
+// 参数: heartbeatInterval - 心跳间隔, codec - BasePackage编解码器（为nil时使用不带校验和的编解码器）
 // 返回: 协议处理器实例
-func NewProtocolHandler(heartbeatInterval time.Duration) *ProtocolHandler {
+func NewProtocolHandler(heartbeatInterval time.Duration, codec *PackageCodec) *ProtocolHandler {
+	if codec == nil {
+		codec = DefaultCodec
+	}
 	return &ProtocolHandler{
 		heartbeatInterval: heartbeatInterval,
 		lastHeartbeat:     time.Now(),
 		buffer:            make([]byte, 0, 4096),
+		codec:             codec,
 	}
 }
 
@@ -58,7 +65,7 @@ func (ph *ProtocolHandler) ProcessData(data []byte) ([]*BasePackage, error) {
 	// 循环处理缓冲区中的数据
 	for {
 		// 检查是否有足够的数据进行解析
-		if len(ph.buffer) < 32 { // 最小包头长度
+		if len(ph.buffer) < BasePackageHeaderSize { // 最小包头长度
 			break
 		}
 
@@ -69,36 +76,38 @@ func (ph *ProtocolHandler) ProcessData(data []byte) ([]*BasePackage, error) {
 			continue
 		}
 
-		// 解析数据包长度
-		dataLength, err := ph.parseDataLength(ph.buffer)
+		// 解析完整数据包长度（包头+数据+校验和）
+		totalLength, err := ph.codec.PacketLength(ph.buffer)
 		if err != nil {
 			log.Printf("Failed to parse data length: %v", err)
+			if errors.Is(err, ErrLengthMismatch) {
+				metrics.IncLengthMismatches()
+			}
 			// 如果解析失败，清空缓冲区
+			ph.errors++
 			ph.buffer = ph.buffer[:0]
 			break
 		}
 
-		// 计算完整数据包长度
-		totalLength := 32 + dataLength // 32字节包头 + 数据长度
-
 		// 检查是否有完整的数据包
-		if len(ph.buffer) < int(totalLength) {
+		if len(ph.buffer) < totalLength {
 			break
 		}
 
 		// 解析完整数据包
-		pkg, err := ph.parsePackage(ph.buffer[:int(totalLength)])
+		pkg, err := ph.parsePackage(ph.buffer[:totalLength])
 		if err != nil {
 			log.Printf("Failed to parse package: %v", err)
+			ph.errors++
 			// 移除错误的数据包
-			ph.buffer = ph.buffer[int(totalLength):]
+			ph.buffer = ph.buffer[totalLength:]
 			continue
 		}
 
 		packages = append(packages, pkg)
 
 		// 从缓冲区移除已处理的数据
-		ph.buffer = ph.buffer[int(totalLength):]
+		ph.buffer = ph.buffer[totalLength:]
 	}
 
 	return packages, nil
@@ -120,47 +129,21 @@ func (ph *ProtocolHandler) handleHeartbeat() {
 	log.Printf("Received heartbeat packet at %v", ph.lastHeartbeat)
 }
 
-// parseDataLength 解析数据长度
-// 参数: data - 数据
-// 返回: 数据长度和错误信息
-func (ph *ProtocolHandler) parseDataLength(data []byte) (uint32, error) {
-	if len(data) < 32 {
-		return 0, fmt.Errorf("insufficient data for header")
-	}
-
-	// 当前数据段长度在第20-23字节 (跳过信源4字节+信宿4字节+包序号8字节+当前数据项2字节+当前数据段长度4字节)
-	dataLength := binary.BigEndian.Uint32(data[18:22])
-
-	return dataLength, nil
-}
-
 // parsePackage 解析完整数据包
 // 参数: data - 完整数据包
-// 返回: 解析后的数据包和错误信息
+// 返回: 解析后的数据包和错误信息（长度或校验和不一致时计入对应指标）
 func (ph *ProtocolHandler) parsePackage(data []byte) (*BasePackage, error) {
-	if len(data) < 32 {
-		return nil, fmt.Errorf("insufficient data for package header")
-	}
-
-	pkg := &BasePackage{
-		Timestamp: time.Now(),
-	}
-
-	// 解析包头
-	pkg.SourceInfo = binary.BigEndian.Uint32(data[0:4])                // 信源
-	pkg.HostInfo = binary.BigEndian.Uint32(data[4:8])                  // 信宿
-	pkg.PackageNo = binary.BigEndian.Uint64(data[8:16])                // 包序号
-	pkg.CurrentDataItem = binary.BigEndian.Uint16(data[16:18])         // 当前数据项
-	pkg.DataSumLength = binary.BigEndian.Uint32(data[18:22])           // 当前数据段长度
-	pkg.RetransmissionFlag = binary.BigEndian.Uint16(data[22:24])      // 重复标志
-	pkg.RetransmissionData = binary.BigEndian.Uint16(data[24:26])      // 重发数据项
-	pkg.RetransmissionSumLength = binary.BigEndian.Uint32(data[26:30]) // 重发数据段长度
-
-	// 解析数据内容
-	if len(data) > 32 {
-		pkg.Data = make([]byte, len(data)-32)
-		copy(pkg.Data, data[32:])
+	pkg, err := ph.codec.Unmarshal(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrChecksumMismatch):
+			metrics.IncChecksumMismatches()
+		case errors.Is(err, ErrLengthMismatch):
+			metrics.IncLengthMismatches()
+		}
+		return nil, err
 	}
+	pkg.Timestamp = time.Now()
 
 	log.Printf("Parsed package: Source=%d, Host=%d, PackageNo=%d, DataLength=%d",
 		pkg.SourceInfo, pkg.HostInfo, pkg.PackageNo, pkg.DataSumLength)
@@ -197,6 +180,14 @@ func (ph *ProtocolHandler) GetBufferSize() int {
 	return len(ph.buffer)
 }
 
+// TakeErrors 获取上次调用以来的解析错误数并清零
+// 返回: 解析错误数
+func (ph *ProtocolHandler) TakeErrors() int {
+	n := ph.errors
+	ph.errors = 0
+	return n
+}
+
 // ClearBuffer 清空缓冲区
 func (ph *ProtocolHandler) ClearBuffer() {
 	ph.buffer = ph.buffer[:0]
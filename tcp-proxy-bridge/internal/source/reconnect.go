@@ -0,0 +1,229 @@
+// internal/source/reconnect.go
+package source
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，暂不尝试连接
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Backoff 指数退避计时器
+// 第n次等待时间为 initial * multiplier^n，不超过max，并叠加±jitter比例的随机抖动
+type Backoff struct {
+	mu         sync.Mutex
+	initial    time.Duration // 首次等待时间
+	max        time.Duration // 最大等待时间
+	multiplier float64       // 退避倍数
+	jitter     float64       // 随机抖动比例
+	attempt    int           // 已连续退避次数
+	last       time.Duration // 最近一次等待时间
+}
+
+// NewBackoff 创建指数退避计时器
+// 参数: cfg - 重连配置
+// 返回: 退避计时器实例
+func NewBackoff(cfg config.ReconnectConfig) *Backoff {
+	return &Backoff{
+		initial:    cfg.InitialBackoff,
+		max:        cfg.MaxBackoff,
+		multiplier: cfg.Multiplier,
+		jitter:     cfg.Jitter,
+	}
+}
+
+// Next 获取下一次等待时间并增加退避次数
+// 返回: 等待时间
+func (b *Backoff) Next() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delay := float64(b.initial) * math.Pow(b.multiplier, float64(b.attempt))
+	if delay > float64(b.max) {
+		delay = float64(b.max)
+	} else {
+		b.attempt++
+	}
+
+	// 抖动范围 [delay*(1-jitter), delay*(1+jitter)]，避免多个连接同时重连
+	delay += delay * b.jitter * (rand.Float64()*2 - 1)
+	b.last = time.Duration(delay)
+	return b.last
+}
+
+// Reset 连接成功后重置退避
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.attempt = 0
+	b.last = 0
+}
+
+// GetStatus 获取退避状态
+// 返回: 状态信息
+func (b *Backoff) GetStatus() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return map[string]interface{}{
+		"attempt":         b.attempt,
+		"last_backoff_ms": b.last.Milliseconds(),
+	}
+}
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常，允许连接
+	BreakerOpen                         // 熔断，冷却期内拒绝连接
+	BreakerHalfOpen                     // 半开，允许一次试探连接
+)
+
+// String 获取熔断器状态名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker 单台服务器的连接熔断器
+//
+//	closed --连续失败达到阈值--> open --冷却期结束--> half-open --试探成功--> closed
+//	                                                     |--试探失败--> open
+type CircuitBreaker struct {
+	mu           sync.Mutex
+	state        BreakerState  // 当前状态
+	threshold    int           // 连续失败阈值
+	cooldown     time.Duration // 冷却时间
+	failures     int           // 连续失败次数
+	openedAt     time.Time     // 最近一次熔断时间
+	trialPending bool          // 半开状态下是否已放行试探连接
+	opens        int           // 熔断次数
+}
+
+// NewCircuitBreaker 创建熔断器
+// 参数: threshold - 连续失败阈值, cooldown - 冷却时间
+// 返回: 熔断器实例
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow 判断是否允许尝试连接
+// 冷却期结束后进入半开状态，只放行一次试探连接
+// 返回: 是否允许
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = BreakerHalfOpen
+		cb.trialPending = true
+		return true
+	case BreakerHalfOpen:
+		if cb.trialPending {
+			return false
+		}
+		cb.trialPending = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Available 判断服务器是否可参与选择（不改变状态）
+// 返回: 熔断器未打开或冷却期已结束时为true
+func (cb *CircuitBreaker) Available() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state != BreakerOpen || time.Since(cb.openedAt) >= cb.cooldown
+}
+
+// RecordSuccess 记录连接成功，恢复为关闭状态
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = BreakerClosed
+	cb.failures = 0
+	cb.trialPending = false
+}
+
+// RecordFailure 记录连接失败
+// 返回: 本次失败是否导致熔断
+func (cb *CircuitBreaker) RecordFailure() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.state == BreakerHalfOpen || (cb.state == BreakerClosed && cb.failures >= cb.threshold) {
+		cb.state = BreakerOpen
+		cb.openedAt = time.Now()
+		cb.trialPending = false
+		cb.opens++
+		return true
+	}
+	return false
+}
+
+// State 获取当前状态
+// 返回: 熔断器状态
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// GetStatus 获取熔断器状态
+// 返回: 状态信息
+func (cb *CircuitBreaker) GetStatus() map[string]interface{} {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	status := map[string]interface{}{
+		"state":                cb.state.String(),
+		"consecutive_failures": cb.failures,
+		"opens":                cb.opens,
+	}
+	if cb.state == BreakerOpen {
+		status["opened_at"] = cb.openedAt
+		status["retry_after"] = cb.openedAt.Add(cb.cooldown)
+	}
+	return status
+}
+
+// reconnectState 单台服务器的重连状态
+type reconnectState struct {
+	backoff *Backoff        // 重连退避
+	breaker *CircuitBreaker // 连接熔断器
+}
+
+// GetStatus 获取重连状态
+// 返回: 状态信息
+func (rs *reconnectState) GetStatus() map[string]interface{} {
+	status := rs.breaker.GetStatus()
+	for k, v := range rs.backoff.GetStatus() {
+		status[k] = v
+	}
+	return status
+}
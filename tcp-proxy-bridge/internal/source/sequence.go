@@ -0,0 +1,337 @@
+// internal/source/sequence.go
+package source
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// SequenceResult 包序号检查结果
+type SequenceResult int
+
+// 包序号检查结果常量定义
+const (
+	SequenceInOrder    SequenceResult = iota // 顺序到达
+	SequenceGap                              // 跳号，之前存在缺失的数据包
+	SequenceOutOfOrder                       // 乱序到达，填补了之前的缺口
+	SequenceFilled                           // 重传包填补了缺口
+	SequenceDuplicate                        // 重复数据包
+	SequenceReset                            // 序号向前或向后跳变过大，视为源端序号重置
+)
+
+// 序号跟踪默认参数
+const (
+	defaultMaxGapSize     = 1000             // 单次缺口最大长度
+	defaultMaxMissing     = 10000            // 每个信源最多跟踪的缺失包数量
+	defaultGapTimeout     = 30 * time.Second // 缺失包等待补齐的超时时间
+	maxSequenceEventCount = 100              // 保留的最近序号事件数量
+)
+
+// String 获取检查结果名称
+// 返回: 结果名称
+func (r SequenceResult) String() string {
+	switch r {
+	case SequenceInOrder:
+		return "in_order"
+	case SequenceGap:
+		return "gap"
+	case SequenceOutOfOrder:
+		return "out_of_order"
+	case SequenceFilled:
+		return "filled"
+	case SequenceDuplicate:
+		return "duplicate"
+	case SequenceReset:
+		return "reset"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(r))
+	}
+}
+
+// SequenceCheck 包序号检查结果详情
+type SequenceCheck struct {
+	Result   SequenceResult // 检查结果
+	GapStart uint64         // 缺口起始包序号（仅SequenceGap有效）
+	GapEnd   uint64         // 缺口结束包序号（仅SequenceGap有效，包含）
+	Settled  bool           // 检查后该信源没有等待补齐的缺失包（之前的数据包都已收到或已放弃）
+}
+
+// SequenceEvent 序号异常事件记录
+type SequenceEvent struct {
+	SourceInfo uint32    `json:"source_info"` // 信源
+	ServerID   string    `json:"server_id"`   // 接收该包的源服务器
+	Type       string    `json:"type"`        // 事件类型
+	From       uint64    `json:"from"`        // 起始包序号
+	To         uint64    `json:"to"`          // 结束包序号
+	Time       time.Time `json:"time"`        // 发生时间
+}
+
+// sequenceState 单个信源的序号状态
+type sequenceState struct {
+	expected uint64               // 期望的下一个包序号
+	missing  map[uint64]time.Time // 缺失的包序号及检测时间
+	serverID string               // 最近推进期望序号的源服务器
+}
+
+// SequenceTracker 包序号跟踪器
+// 按信源跟踪BasePackage.PackageNo，检测缺口、重复和乱序数据包
+type SequenceTracker struct {
+	mu         sync.Mutex
+	maxGapSize uint64                    // 单次缺口最大长度，向前或向后跳变超过该值视为序号重置
+	maxMissing int                       // 每个信源最多跟踪的缺失包数量
+	gapTimeout time.Duration             // 缺失包等待补齐的超时时间
+	states     map[uint32]*sequenceState // 各信源序号状态
+	events     []SequenceEvent           // 最近的序号异常事件
+
+	// 统计信息
+	gaps        int64 // 检测到的缺口次数
+	missingPkgs int64 // 累计缺失的数据包数量
+	duplicates  int64 // 重复数据包数量
+	outOfOrder  int64 // 乱序数据包数量
+	filled      int64 // 被重传包填补的数量
+	lost        int64 // 超时仍未补齐的数据包数量
+	resets      int64 // 序号重置次数
+}
+
+// NewSequenceTracker 创建包序号跟踪器
+// 参数: maxGapSize - 单次缺口最大长度, maxMissing - 最多跟踪的缺失包数量, gapTimeout - 缺失包超时时间
+// 返回: 包序号跟踪器实例
+func NewSequenceTracker(maxGapSize uint64, maxMissing int, gapTimeout time.Duration) *SequenceTracker {
+	if maxGapSize == 0 {
+		maxGapSize = defaultMaxGapSize
+	}
+	if maxMissing <= 0 {
+		maxMissing = defaultMaxMissing
+	}
+	if gapTimeout <= 0 {
+		gapTimeout = defaultGapTimeout
+	}
+
+	return &SequenceTracker{
+		maxGapSize: maxGapSize,
+		maxMissing: maxMissing,
+		gapTimeout: gapTimeout,
+		states:     make(map[uint32]*sequenceState),
+	}
+}
+
+// Check 检查数据包序号并更新跟踪状态
+// 参数: pkg - 解析后的数据包, serverID - 接收该包的源服务器ID
+// 返回: 检查结果
+func (st *SequenceTracker) Check(pkg *BasePackage, serverID string) SequenceCheck {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	check := st.check(pkg, serverID)
+	check.Settled = len(st.states[pkg.SourceInfo].missing) == 0
+	return check
+}
+
+// check 检查数据包序号并更新跟踪状态
+// 调用方需持有锁
+// 参数: pkg - 解析后的数据包, serverID - 接收该包的源服务器ID
+// 返回: 检查结果（不含Settled）
+func (st *SequenceTracker) check(pkg *BasePackage, serverID string) SequenceCheck {
+	now := time.Now()
+	no := pkg.PackageNo
+
+	state, exists := st.states[pkg.SourceInfo]
+	if !exists {
+		// 首个数据包，建立序号基线
+		st.states[pkg.SourceInfo] = &sequenceState{
+			expected: no + 1,
+			missing:  make(map[uint64]time.Time),
+			serverID: serverID,
+		}
+		return SequenceCheck{Result: SequenceInOrder}
+	}
+
+	st.expireMissing(pkg.SourceInfo, state, serverID, now)
+
+	// 重传包：填补缺口或作为重复包丢弃，不推进期望序号
+	if pkg.RetransmissionFlag != 0 && no < state.expected {
+		if _, missing := state.missing[no]; missing {
+			delete(state.missing, no)
+			st.filled++
+			return SequenceCheck{Result: SequenceFilled}
+		}
+		st.duplicates++
+		return SequenceCheck{Result: SequenceDuplicate}
+	}
+
+	switch {
+	case no == state.expected:
+		state.expected++
+		state.serverID = serverID
+		return SequenceCheck{Result: SequenceInOrder}
+
+	case no > state.expected:
+		gapStart, gapEnd := state.expected, no-1
+
+		// 序号跳变过大，视为源端序号重置
+		if no-gapStart > st.maxGapSize {
+			return st.reset(pkg.SourceInfo, state, serverID, no, now)
+		}
+
+		state.expected = no + 1
+		state.serverID = serverID
+
+		for seq := gapStart; seq <= gapEnd; seq++ {
+			if len(state.missing) >= st.maxMissing {
+				break
+			}
+			state.missing[seq] = now
+		}
+
+		st.gaps++
+		st.missingPkgs += int64(gapEnd - gapStart + 1)
+		st.recordEvent(pkg.SourceInfo, serverID, "gap", gapStart, gapEnd, now)
+		log.Printf("Sequence gap detected for source %d: missing %d-%d (%d packages)",
+			pkg.SourceInfo, gapStart, gapEnd, gapEnd-gapStart+1)
+		return SequenceCheck{Result: SequenceGap, GapStart: gapStart, GapEnd: gapEnd}
+
+	case state.expected-no > st.maxGapSize:
+		// 序号大幅回退（例如源端重新从1开始编号），视为源端序号重置，
+		// 否则之后的数据包都小于期望序号，会一直被当作重复包丢弃
+		return st.reset(pkg.SourceInfo, state, serverID, no, now)
+
+	default:
+		if _, missing := state.missing[no]; missing {
+			delete(state.missing, no)
+			st.outOfOrder++
+			st.recordEvent(pkg.SourceInfo, serverID, "out_of_order", no, no, now)
+			return SequenceCheck{Result: SequenceOutOfOrder}
+		}
+
+		st.duplicates++
+		st.recordEvent(pkg.SourceInfo, serverID, "duplicate", no, no, now)
+		return SequenceCheck{Result: SequenceDuplicate}
+	}
+}
+
+// reset 以当前数据包重新建立信源的序号基线，放弃所有缺失包
+// 调用方需持有锁
+// 参数: sourceInfo - 信源, state - 序号状态, serverID - 源服务器ID, no - 当前包序号, now - 当前时间
+// 返回: 检查结果
+func (st *SequenceTracker) reset(sourceInfo uint32, state *sequenceState, serverID string, no uint64, now time.Time) SequenceCheck {
+	expected := state.expected
+	state.expected = no + 1
+	state.missing = make(map[uint64]time.Time)
+	state.serverID = serverID
+
+	st.resets++
+	st.recordEvent(sourceInfo, serverID, "reset", expected, no, now)
+	log.Printf("Sequence reset detected for source %d: expected %d, got %d", sourceInfo, expected, no)
+	return SequenceCheck{Result: SequenceReset}
+}
+
+// Rebaseline 清除由指定源服务器推进的信源序号状态
+// 连接认证通过后调用：源端可能在断开期间重新编号，由新连接的第一个数据包（或续传检查点）重新建立基线。
+// 由其他仍在接收的源服务器推进的信源保持不变
+// 参数: serverID - 源服务器ID
+// 返回: 清除的信源数量
+func (st *SequenceTracker) Rebaseline(serverID string) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cleared := 0
+	for sourceInfo, state := range st.states {
+		if state.serverID == serverID {
+			delete(st.states, sourceInfo)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// Seed 以续传检查点建立信源的序号基线
+// 已在跟踪的信源（由其他连接推进）保持不变
+// 参数: sourceInfo - 信源, last - 已写入数据库的最后一个包序号
+// 返回: 是否建立了基线
+func (st *SequenceTracker) Seed(sourceInfo uint32, last uint64) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.states[sourceInfo]; exists {
+		return false
+	}
+	st.states[sourceInfo] = &sequenceState{
+		expected: last + 1,
+		missing:  make(map[uint64]time.Time),
+	}
+	return true
+}
+
+// expireMissing 清理超时仍未补齐的缺失包
+// 调用方需持有锁
+// 参数: sourceInfo - 信源, state - 序号状态, serverID - 源服务器ID, now - 当前时间
+func (st *SequenceTracker) expireMissing(sourceInfo uint32, state *sequenceState, serverID string, now time.Time) {
+	var expired int64
+	var minSeq, maxSeq uint64
+	for seq, detectedAt := range state.missing {
+		if now.Sub(detectedAt) >= st.gapTimeout {
+			delete(state.missing, seq)
+			if expired == 0 || seq < minSeq {
+				minSeq = seq
+			}
+			if seq > maxSeq {
+				maxSeq = seq
+			}
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		st.lost += expired
+		st.recordEvent(sourceInfo, serverID, "lost", minSeq, maxSeq, now)
+		log.Printf("Gave up waiting for %d missing packages from source %d", expired, sourceInfo)
+	}
+}
+
+// recordEvent 记录序号异常事件，只保留最近的事件
+// 调用方需持有锁
+func (st *SequenceTracker) recordEvent(sourceInfo uint32, serverID, eventType string, from, to uint64, now time.Time) {
+	st.events = append(st.events, SequenceEvent{
+		SourceInfo: sourceInfo,
+		ServerID:   serverID,
+		Type:       eventType,
+		From:       from,
+		To:         to,
+		Time:       now,
+	})
+	if len(st.events) > maxSequenceEventCount {
+		st.events = st.events[len(st.events)-maxSequenceEventCount:]
+	}
+}
+
+// GetStatus 获取序号跟踪状态
+// 返回: 状态信息
+func (st *SequenceTracker) GetStatus() map[string]interface{} {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	sources := make(map[string]interface{}, len(st.states))
+	for sourceInfo, state := range st.states {
+		sources[fmt.Sprintf("%d", sourceInfo)] = map[string]interface{}{
+			"expected": state.expected,
+			"missing":  len(state.missing),
+		}
+	}
+
+	events := make([]SequenceEvent, len(st.events))
+	copy(events, st.events)
+
+	return map[string]interface{}{
+		"sources":          sources,
+		"gaps":             st.gaps,
+		"missing_packages": st.missingPkgs,
+		"duplicates":       st.duplicates,
+		"out_of_order":     st.outOfOrder,
+		"filled":           st.filled,
+		"lost":             st.lost,
+		"resets":           st.resets,
+		"recent_events":    events,
+	}
+}
@@ -0,0 +1,263 @@
+// internal/source/sequence_test.go
+package source
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// sequenceStep 一个数据包及期望的检查结果
+type sequenceStep struct {
+	no         uint64         // 包序号
+	retransmit bool           // 是否为重传包
+	want       SequenceResult // 期望结果
+	gapStart   uint64         // 期望的缺口起始（仅SequenceGap）
+	gapEnd     uint64         // 期望的缺口结束（仅SequenceGap）
+}
+
+func TestSequenceTrackerCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sequenceStep
+	}{
+		{
+			name: "in order",
+			steps: []sequenceStep{
+				{no: 1, want: SequenceInOrder},
+				{no: 2, want: SequenceInOrder},
+				{no: 3, want: SequenceInOrder},
+			},
+		},
+		{
+			name: "gap then out of order fill",
+			steps: []sequenceStep{
+				{no: 1, want: SequenceInOrder},
+				{no: 4, want: SequenceGap, gapStart: 2, gapEnd: 3},
+				{no: 3, want: SequenceOutOfOrder},
+				{no: 2, want: SequenceOutOfOrder},
+				{no: 5, want: SequenceInOrder},
+			},
+		},
+		{
+			name: "gap filled by retransmission",
+			steps: []sequenceStep{
+				{no: 10, want: SequenceInOrder},
+				{no: 13, want: SequenceGap, gapStart: 11, gapEnd: 12},
+				{no: 11, retransmit: true, want: SequenceFilled},
+				{no: 11, retransmit: true, want: SequenceDuplicate},
+				{no: 12, retransmit: true, want: SequenceFilled},
+				{no: 14, want: SequenceInOrder},
+			},
+		},
+		{
+			name: "duplicates",
+			steps: []sequenceStep{
+				{no: 1, want: SequenceInOrder},
+				{no: 2, want: SequenceInOrder},
+				{no: 2, want: SequenceDuplicate},
+				{no: 1, want: SequenceDuplicate},
+				{no: 3, want: SequenceInOrder},
+			},
+		},
+		{
+			name: "forward reset",
+			steps: []sequenceStep{
+				{no: 1, want: SequenceInOrder},
+				{no: 200, want: SequenceReset},
+				{no: 201, want: SequenceInOrder},
+			},
+		},
+		{
+			// 源端从大序号重新从1开始编号，之后的数据包不能一直被当作重复包
+			name: "backward reset",
+			steps: []sequenceStep{
+				{no: 1000000, want: SequenceInOrder},
+				{no: 1000001, want: SequenceInOrder},
+				{no: 1, want: SequenceReset},
+				{no: 2, want: SequenceInOrder},
+				{no: 3, want: SequenceInOrder},
+			},
+		},
+		{
+			name: "small backward jump is duplicate",
+			steps: []sequenceStep{
+				{no: 500, want: SequenceInOrder},
+				{no: 450, want: SequenceDuplicate},
+				{no: 501, want: SequenceInOrder},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := NewSequenceTracker(100, 1000, time.Minute)
+			for i, step := range tt.steps {
+				pkg := &BasePackage{SourceInfo: 7, PackageNo: step.no}
+				if step.retransmit {
+					pkg.RetransmissionFlag = 1
+				}
+				check := st.Check(pkg, "server-a")
+				if check.Result != step.want {
+					t.Fatalf("step %d (no=%d): result = %s, want %s", i, step.no, check.Result, step.want)
+				}
+				if step.want == SequenceGap && (check.GapStart != step.gapStart || check.GapEnd != step.gapEnd) {
+					t.Errorf("step %d (no=%d): gap = %d-%d, want %d-%d",
+						i, step.no, check.GapStart, check.GapEnd, step.gapStart, step.gapEnd)
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceTrackerSourcesIndependent(t *testing.T) {
+	st := NewSequenceTracker(100, 1000, time.Minute)
+	st.Check(&BasePackage{SourceInfo: 1, PackageNo: 10}, "server-a")
+	st.Check(&BasePackage{SourceInfo: 2, PackageNo: 50}, "server-a")
+
+	if got := st.Check(&BasePackage{SourceInfo: 1, PackageNo: 11}, "server-a").Result; got != SequenceInOrder {
+		t.Errorf("source 1: result = %s, want in_order", got)
+	}
+	if got := st.Check(&BasePackage{SourceInfo: 2, PackageNo: 50}, "server-a").Result; got != SequenceDuplicate {
+		t.Errorf("source 2: result = %s, want duplicate", got)
+	}
+}
+
+func TestSequenceTrackerMissingExpires(t *testing.T) {
+	st := NewSequenceTracker(100, 1000, 10*time.Millisecond)
+	st.Check(&BasePackage{SourceInfo: 1, PackageNo: 1}, "server-a")
+	st.Check(&BasePackage{SourceInfo: 1, PackageNo: 3}, "server-a")
+
+	time.Sleep(20 * time.Millisecond)
+
+	// 缺失包超时后被记为丢失，迟到的包按重复包丢弃
+	if got := st.Check(&BasePackage{SourceInfo: 1, PackageNo: 2}, "server-a").Result; got != SequenceDuplicate {
+		t.Errorf("late package: result = %s, want duplicate", got)
+	}
+	if lost := st.GetStatus()["lost"].(int64); lost != 1 {
+		t.Errorf("lost = %d, want 1", lost)
+	}
+}
+
+func TestSequenceTrackerRebaseline(t *testing.T) {
+	st := NewSequenceTracker(100, 1000, time.Minute)
+	st.Check(&BasePackage{SourceInfo: 1, PackageNo: 40}, "server-a")
+	st.Check(&BasePackage{SourceInfo: 2, PackageNo: 40}, "server-b")
+
+	if cleared := st.Rebaseline("server-a"); cleared != 1 {
+		t.Fatalf("Rebaseline cleared %d sources, want 1", cleared)
+	}
+
+	// server-a推进的信源由新连接的第一个数据包重新建立基线
+	if got := st.Check(&BasePackage{SourceInfo: 1, PackageNo: 30}, "server-a").Result; got != SequenceInOrder {
+		t.Errorf("source 1 after rebaseline: result = %s, want in_order", got)
+	}
+	// 其他服务器推进的信源保持不变
+	if got := st.Check(&BasePackage{SourceInfo: 2, PackageNo: 30}, "server-a").Result; got != SequenceDuplicate {
+		t.Errorf("source 2 after rebaseline: result = %s, want duplicate", got)
+	}
+}
+
+func TestSequenceTrackerSeed(t *testing.T) {
+	st := NewSequenceTracker(100, 1000, time.Minute)
+	if !st.Seed(1, 20) {
+		t.Fatal("Seed returned false for new source")
+	}
+	if st.Seed(1, 5) {
+		t.Error("Seed overwrote tracked source")
+	}
+
+	if got := st.Check(&BasePackage{SourceInfo: 1, PackageNo: 20}, "server-a").Result; got != SequenceDuplicate {
+		t.Errorf("package at checkpoint: result = %s, want duplicate", got)
+	}
+	if got := st.Check(&BasePackage{SourceInfo: 1, PackageNo: 21}, "server-a").Result; got != SequenceInOrder {
+		t.Errorf("package after checkpoint: result = %s, want in_order", got)
+	}
+}
+
+func TestSequenceTrackerSettled(t *testing.T) {
+	st := NewSequenceTracker(100, 1000, time.Minute)
+	steps := []struct {
+		no      uint64
+		settled bool
+	}{
+		{no: 4, settled: true},
+		{no: 7, settled: false}, // 5-6缺失
+		{no: 8, settled: false},
+		{no: 5, settled: false},
+		{no: 6, settled: true},
+		{no: 9, settled: true},
+		{no: 500, settled: true}, // 序号重置放弃缺失包
+	}
+
+	for i, step := range steps {
+		check := st.Check(&BasePackage{SourceInfo: 1, PackageNo: step.no}, "server-a")
+		if check.Settled != step.settled {
+			t.Errorf("step %d (no=%d, %s): settled = %v, want %v", i, step.no, check.Result, check.Settled, step.settled)
+		}
+	}
+}
+
+// retransmittedPacket 构建源服务器重发的数据包（重复标志置1）
+func (l *testLink) retransmittedPacket(sourceInfo uint32, no uint64) []byte {
+	data := []byte("payload")
+	return l.packet(&BasePackage{SourceInfo: sourceInfo, HostInfo: 20, PackageNo: no, CurrentDataItem: 1,
+		DataSumLength: uint32(len(data)), RetransmissionFlag: 1, Data: data})
+}
+
+// retransmissionRequests 获取连接上已发送的重传请求区间
+func (l *testLink) retransmissionRequests() [][2]uint64 {
+	var ranges [][2]uint64
+	for _, pkg := range l.sent() {
+		if pkg.RetransmissionFlag == RetransmissionRequestFlag {
+			ranges = append(ranges, [2]uint64{binary.BigEndian.Uint64(pkg.Data[0:8]), binary.BigEndian.Uint64(pkg.Data[8:16])})
+		}
+	}
+	return ranges
+}
+
+func TestManagerRetransmission(t *testing.T) {
+	for _, request := range []bool{true, false} {
+		name := "request_retransmission"
+		if !request {
+			name = "no request"
+		}
+		t.Run(name, func(t *testing.T) {
+			m := newTestManager(t, func(cfg *config.Config) {
+				cfg.Sequence.RequestRetransmission = request
+			})
+			l := newTestLink(t, m, "src-1")
+			if err := l.authenticate(AuthStatusAccepted); err != nil {
+				t.Fatal(err)
+			}
+
+			l.receiveRange(7, 1, 2)
+			l.receiveRange(7, 5, 6) // 3-4缺失
+			for _, packet := range [][]byte{
+				l.retransmittedPacket(7, 3),
+				l.retransmittedPacket(7, 3), // 重复的重传包丢弃
+				l.retransmittedPacket(7, 1), // 不在缺口中的重传包丢弃
+				l.dataPacket(7, 4),          // 迟到的原始包填补缺口
+			} {
+				if err := l.receive(packet); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if nos := l.deliveredNos(); !reflect.DeepEqual(nos, []uint64{1, 2, 5, 6, 3, 4}) {
+				t.Errorf("delivered %v, want [1 2 5 6 3 4]", nos)
+			}
+
+			var want [][2]uint64
+			if request {
+				want = [][2]uint64{{3, 4}}
+			}
+			if got := l.retransmissionRequests(); !reflect.DeepEqual(got, want) {
+				t.Errorf("retransmission requests %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// internal/source/session.go
+package source
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session 单个源连接的会话统计
+// 从连接建立到断开，记录收发字节数和帧数、解析错误、无效数据包、帧超长、心跳时间和认证状态。
+// 计数器可以在读取循环和状态查询之间并发访问
+type Session struct {
+	ID          uint64    // 会话编号（进程内递增）
+	ServerID    string    // 服务器ID
+	ServerName  string    // 服务器名称
+	Remote      string    // 对端地址
+	ConnectedAt time.Time // 连接建立时间
+
+	auth    *AuthSession // 连接的认证状态机
+	standby *atomic.Bool // 是否为热备连接（与连接会话共用）
+
+	bytesIn        atomic.Int64 // 读取的字节数
+	bytesOut       atomic.Int64 // 写入的字节数
+	framesIn       atomic.Int64 // 分帧得到的帧数
+	framesOut      atomic.Int64 // 发送的帧数（认证、心跳、重传和续传请求）
+	packages       atomic.Int64 // 协议解析得到的数据包数
+	delivered      atomic.Int64 // 交给数据处理函数的数据包数
+	parseErrors    atomic.Int64 // 分帧和协议解析错误数
+	invalid        atomic.Int64 // 数据段校验失败的数据包数
+	invalidDropped atomic.Int64 // 校验失败后被丢弃的数据包数
+	frameOverflows atomic.Int64 // 超过最大帧长度被丢弃的次数
+	heartbeatSent  atomic.Int64 // 最后发送心跳的时间（Unix纳秒）
+	heartbeatRecv  atomic.Int64 // 最后收到心跳的时间（Unix纳秒）
+	lastRead       atomic.Int64 // 最后读取到数据的时间（Unix纳秒）
+
+	mu          sync.Mutex
+	closedAt    time.Time // 连接断开时间
+	closeReason string    // 断开原因
+}
+
+// newSession 创建连接会话
+// 参数: id - 会话编号, h - 连接会话, remote - 对端地址
+// 返回: 会话实例
+func newSession(id uint64, h *connSession, remote string) *Session {
+	return &Session{
+		ID:          id,
+		ServerID:    h.server.ID,
+		ServerName:  h.server.Name,
+		Remote:      remote,
+		ConnectedAt: time.Now(),
+		auth:        h.auth,
+		standby:     &h.standby,
+	}
+}
+
+// recordRead 记录一次读取
+func (s *Session) recordRead(n int) {
+	s.bytesIn.Add(int64(n))
+	s.lastRead.Store(time.Now().UnixNano())
+}
+
+// recordWrite 记录一帧写入
+func (s *Session) recordWrite(n int) {
+	s.bytesOut.Add(int64(n))
+	s.framesOut.Add(1)
+}
+
+// recordHeartbeatSent 记录心跳发送时间
+func (s *Session) recordHeartbeatSent() {
+	s.heartbeatSent.Store(time.Now().UnixNano())
+}
+
+// recordHeartbeatReceived 记录心跳接收时间
+func (s *Session) recordHeartbeatReceived() {
+	s.heartbeatRecv.Store(time.Now().UnixNano())
+}
+
+// close 记录连接断开
+// 参数: err - 断开原因（为nil时表示正常关闭）
+func (s *Session) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closedAt = time.Now()
+	if err != nil {
+		s.closeReason = err.Error()
+	}
+}
+
+// GetStatus 获取会话状态
+// 返回: 状态信息
+func (s *Session) GetStatus() map[string]interface{} {
+	s.mu.Lock()
+	closedAt, closeReason := s.closedAt, s.closeReason
+	s.mu.Unlock()
+
+	end := closedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	status := map[string]interface{}{
+		"id":              s.ID,
+		"server":          s.ServerID,
+		"server_name":     s.ServerName,
+		"remote":          s.Remote,
+		"standby":         s.standby.Load(),
+		"connected_at":    s.ConnectedAt,
+		"duration":        end.Sub(s.ConnectedAt).Round(time.Millisecond).String(),
+		"bytes_in":        s.bytesIn.Load(),
+		"bytes_out":       s.bytesOut.Load(),
+		"frames_in":       s.framesIn.Load(),
+		"frames_out":      s.framesOut.Load(),
+		"packages":        s.packages.Load(),
+		"delivered":       s.delivered.Load(),
+		"parse_errors":    s.parseErrors.Load(),
+		"invalid":         s.invalid.Load(),
+		"invalid_dropped": s.invalidDropped.Load(),
+		"frame_overflows": s.frameOverflows.Load(),
+		"auth":            s.auth.GetStatus(),
+	}
+	if sent := s.heartbeatSent.Load(); sent != 0 {
+		status["last_heartbeat_sent"] = time.Unix(0, sent)
+	}
+	if recv := s.heartbeatRecv.Load(); recv != 0 {
+		status["last_heartbeat_recv"] = time.Unix(0, recv)
+	}
+	if !closedAt.IsZero() {
+		status["closed_at"] = closedAt
+		status["close_reason"] = closeReason
+	}
+	return status
+}
+
+// sessionRegistry 连接会话登记
+// 记录当前的连接会话，并保留最近结束的会话
+type sessionRegistry struct {
+	mu          sync.Mutex
+	nextID      uint64              // 下一个会话编号
+	active      map[uint64]*Session // 当前的连接会话
+	history     []*Session          // 最近结束的会话（按结束顺序）
+	historySize int                 // 保留的历史会话数量
+}
+
+// newSessionRegistry 创建连接会话登记
+// 参数: historySize - 保留的历史会话数量
+// 返回: 会话登记实例
+func newSessionRegistry(historySize int) *sessionRegistry {
+	return &sessionRegistry{
+		nextID:      1,
+		active:      make(map[uint64]*Session),
+		historySize: historySize,
+	}
+}
+
+// open 为新连接创建并登记会话
+// 参数: h - 连接会话, remote - 对端地址
+// 返回: 会话实例
+func (r *sessionRegistry) open(h *connSession, remote string) *Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := newSession(r.nextID, h, remote)
+	r.nextID++
+	r.active[session.ID] = session
+	return session
+}
+
+// close 结束会话并移入历史记录
+// 参数: session - 会话, err - 断开原因
+func (r *sessionRegistry) close(session *Session, err error) {
+	session.close(err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.active, session.ID)
+	if r.historySize <= 0 {
+		return
+	}
+	r.history = append(r.history, session)
+	if len(r.history) > r.historySize {
+		r.history = r.history[len(r.history)-r.historySize:]
+	}
+}
+
+// healthy 服务器是否有认证通过且仍在收到数据的连接（数据连接或热备连接）
+// 参数: serverID - 服务器ID, maxIdle - 最后一次读取距今的最长时间
+// 返回: 是否存在健康的连接
+func (r *sessionRegistry) healthy(serverID string, maxIdle time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.active {
+		if session.ServerID != serverID || !session.auth.CanDeliver() {
+			continue
+		}
+		if last := session.lastRead.Load(); last != 0 && time.Since(time.Unix(0, last)) < maxIdle {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStatus 获取会话状态
+// 返回: 当前会话（按编号排序）和历史会话（最近结束的在前）
+func (r *sessionRegistry) GetStatus() (active, history []map[string]interface{}) {
+	r.mu.Lock()
+	sessions := make([]*Session, 0, len(r.active))
+	for _, session := range r.active {
+		sessions = append(sessions, session)
+	}
+	closed := make([]*Session, len(r.history))
+	copy(closed, r.history)
+	r.mu.Unlock()
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+
+	active = make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		active = append(active, session.GetStatus())
+	}
+	history = make([]map[string]interface{}, 0, len(closed))
+	for i := len(closed) - 1; i >= 0; i-- {
+		history = append(history, closed[i].GetStatus())
+	}
+	return active, history
+}
@@ -0,0 +1,260 @@
+// internal/source/standby.go
+package source
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// standbyCheckInterval 热备连接状态检查间隔
+const standbyCheckInterval = time.Second
+
+// standbyConn 热备连接
+// 与备用服务器保持连接、认证和心跳，但不投递数据；切换时直接提升为当前连接
+type standbyConn struct {
+	server *config.SourceServer // 热备服务器
+	conn   net.Conn             // 连接
+	h      *connSession         // 连接会话
+	since  time.Time            // 连接建立时间
+
+	done     chan struct{} // 连接结束时关闭
+	err      error         // 连接结束原因（done关闭后有效）
+	promoted chan struct{} // 提升为当前连接时关闭
+	once     sync.Once
+}
+
+// promote 将热备连接提升为当前连接，开始投递数据
+// 续传由热备连接的读取循环完成：读取循环在投递第一个数据包之前（或被唤醒后立即）续传一次；
+// 仍在认证中的连接在认证通过时续传
+func (sc *standbyConn) promote() {
+	sc.once.Do(func() {
+		sc.h.standby.Store(false)
+		close(sc.promoted)
+		// 唤醒阻塞在读取上的读取循环
+		sc.conn.SetReadDeadline(time.Now())
+	})
+}
+
+// standbyLoop 维持与备用服务器的热备连接，直到上下文取消或管理器关闭
+// 热备目标为当前服务器之外优先级最高的可用服务器；热备连接被提升后立即为下一台服务器建立新的热备连接
+// 参数: ctx - 上下文, dataHandler - 数据处理函数（热备连接提升后使用）
+func (m *Manager) standbyLoop(ctx context.Context, dataHandler DataHandler) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.shutdownChan:
+			return
+		default:
+		}
+
+		target := m.standbyTarget()
+		if target == nil {
+			// 没有可用的备用服务器，稍后再检查
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.shutdownChan:
+				return
+			case <-time.After(standbyCheckInterval):
+			}
+			continue
+		}
+
+		sc, err := m.openStandby(ctx, target, dataHandler)
+		if err != nil {
+			log.Printf("Failed to open warm standby connection to %s: %v", target.Name, err)
+			m.recordFailure(target.ID)
+			if !m.waitReconnect(ctx, target) {
+				return
+			}
+			continue
+		}
+
+		if m.watchStandby(ctx, sc) {
+			continue
+		}
+
+		// 热备连接断开，按退避时间等待后重连
+		if _, rejected := sc.err.(*AuthRejectedError); rejected {
+			continue
+		}
+		if !m.waitReconnect(ctx, target) {
+			return
+		}
+	}
+}
+
+// openStandby 连接备用服务器并启动热备连接的读取循环
+// 参数: ctx - 上下文, server - 备用服务器, dataHandler - 数据处理函数
+// 返回: 热备连接和错误信息
+func (m *Manager) openStandby(ctx context.Context, server *config.SourceServer, dataHandler DataHandler) (*standbyConn, error) {
+	log.Printf("Opening warm standby connection to %s (%s)", server.Name, server.Address)
+
+	conn, err := m.connectWithRetry(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	h := m.newConnSession(server, true)
+	h.standby.Store(true)
+
+	sc := &standbyConn{
+		server:   server,
+		conn:     conn,
+		h:        h,
+		since:    time.Now(),
+		done:     make(chan struct{}),
+		promoted: make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.standby = sc
+	m.mu.Unlock()
+	m.markConnected(server.ID, true)
+
+	go func() {
+		sc.err = m.readDataFromSource(ctx, conn, h, dataHandler)
+		conn.Close()
+		m.markConnected(server.ID, false)
+		close(sc.done)
+
+		m.mu.Lock()
+		if m.standby == sc {
+			m.standby = nil
+		}
+		m.mu.Unlock()
+	}()
+
+	return sc, nil
+}
+
+// watchStandby 监视热备连接，直到连接被提升、断开或不再适合作为热备
+// 热备服务器不可用，或成为当前服务器后未被提升（已另外建立了当前连接）时关闭热备连接
+// 参数: ctx - 上下文, sc - 热备连接
+// 返回: 热备连接是否已被提升
+func (m *Manager) watchStandby(ctx context.Context, sc *standbyConn) bool {
+	ticker := time.NewTicker(standbyCheckInterval)
+	defer ticker.Stop()
+
+	currentChecks := 0
+	for {
+		select {
+		case <-sc.promoted:
+			return true
+		case <-sc.done:
+			log.Printf("Warm standby connection to %s ended: %v", sc.server.Name, sc.err)
+			return false
+		case <-ticker.C:
+			m.mu.RLock()
+			available := m.isAvailable(sc.server)
+			isCurrent := m.currentServer != nil && m.currentServer.ID == sc.server.ID
+			m.mu.RUnlock()
+
+			if isCurrent {
+				currentChecks++
+			} else {
+				currentChecks = 0
+			}
+
+			if !available || currentChecks > 1 {
+				log.Printf("Closing warm standby connection to %s", sc.server.Name)
+				m.closeStandby(sc)
+			}
+		}
+	}
+}
+
+// closeStandby 关闭热备连接并等待读取循环退出
+// 参数: sc - 热备连接
+func (m *Manager) closeStandby(sc *standbyConn) {
+	m.mu.Lock()
+	if m.standby == sc {
+		m.standby = nil
+	}
+	m.mu.Unlock()
+
+	sc.conn.Close()
+	<-sc.done
+}
+
+// takeStandby 取出指定服务器的热备连接并提升为当前连接
+// 参数: serverID - 服务器ID
+// 返回: 热备连接，没有可用的热备连接时返回nil
+func (m *Manager) takeStandby(serverID string) *standbyConn {
+	m.mu.Lock()
+	sc := m.standby
+	if sc == nil || sc.server.ID != serverID {
+		m.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-sc.done:
+		m.mu.Unlock()
+		return nil
+	default:
+	}
+	m.standby = nil
+	m.standbyPromotions++
+	m.mu.Unlock()
+
+	log.Printf("Promoting warm standby connection to %s", sc.server.Name)
+	sc.promote()
+	return sc
+}
+
+// switchToStandby 当前连接断开时直接切换到已认证的热备服务器，不等待故障切换阈值
+// 参数: server - 断开的当前服务器
+// 返回: 是否已切换
+func (m *Manager) switchToStandby(server *config.SourceServer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sc := m.standby
+	if sc == nil || m.currentServer == nil || m.currentServer.ID != server.ID {
+		return false
+	}
+	if !sc.h.auth.CanDeliver() || !m.isAvailable(sc.server) {
+		return false
+	}
+
+	log.Printf("Switching from %s to warm standby %s", server.Name, sc.server.Name)
+	m.currentServer = sc.server
+	return true
+}
+
+// standbyTarget 选择热备服务器
+// 已有的热备服务器仍然可用时保持不变，避免同优先级服务器之间来回切换热备连接
+// 返回: 热备服务器，没有可用的备用服务器时返回nil
+func (m *Manager) standbyTarget() *config.SourceServer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.currentServer == nil {
+		return nil
+	}
+	if sc := m.standby; sc != nil && sc.server.ID != m.currentServer.ID && m.isAvailable(sc.server) {
+		return sc.server
+	}
+	return m.selectServer(m.currentServer.ID)
+}
+
+// standbyStatus 获取热备连接状态
+// 调用方需持有锁
+// 返回: 状态信息，没有热备连接时返回nil
+func (m *Manager) standbyStatus() map[string]interface{} {
+	sc := m.standby
+	if sc == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"server":     sc.server.Name,
+		"since":      sc.since,
+		"auth_state": sc.h.auth.State().String(),
+	}
+}
@@ -0,0 +1,225 @@
+// internal/source/standby_test.go
+package source
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+	"tcp-proxy-bridge/internal/database"
+	"tcp-proxy-bridge/internal/framing"
+)
+
+// newTestStandby 为指定服务器创建热备测试连接
+func newTestStandby(t *testing.T, m *Manager, serverID string) (*testLink, *standbyConn) {
+	t.Helper()
+	l := newTestLink(t, m, serverID)
+	l.h.standby.Store(true)
+	return l, &standbyConn{
+		server:   l.h.server,
+		conn:     l.conn,
+		h:        l.h,
+		since:    time.Now(),
+		done:     make(chan struct{}),
+		promoted: make(chan struct{}),
+	}
+}
+
+// testCheckpoints 信源7已写入到包序号5的检查点
+func testCheckpoints() *fakeCheckpointStore {
+	return &fakeCheckpointStore{checkpoints: []*database.SourceCheckpoint{
+		{SourceInfo: 7, HostInfo: 20, PackageNo: 5},
+	}}
+}
+
+func TestStandbyPromotion(t *testing.T) {
+	t.Run("authenticated before promotion", func(t *testing.T) {
+		m := newTestManager(t, nil)
+		store := testCheckpoints()
+		m.SetCheckpointStore(store)
+		l, sc := newTestStandby(t, m, "src-2")
+
+		// 热备连接认证通过后不续传，也不投递数据
+		if err := l.authenticate(AuthStatusAccepted); err != nil {
+			t.Fatal(err)
+		}
+		l.receive(l.dataPacket(7, 6))
+		if requests := l.resumeRequests(); len(requests) != 0 || store.loads != 0 {
+			t.Fatalf("standby sent resume requests %+v", requests)
+		}
+		if nos := l.deliveredNos(); len(nos) != 0 {
+			t.Fatalf("standby delivered %v", nos)
+		}
+
+		// 提升只切换状态，续传由读取循环在投递数据之前完成
+		sc.promote()
+		select {
+		case <-sc.promoted:
+		default:
+			t.Fatal("promoted not signalled")
+		}
+		if store.loads != 0 {
+			t.Fatal("promote resumed outside the read loop")
+		}
+
+		// 热备期间丢弃的数据包由续传重新请求
+		l.receive(l.dataPacket(7, 6), l.dataPacket(7, 7))
+		want := []resumeRequest{{7, 20, 6}}
+		if got := l.resumeRequests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("resume requests %+v, want %+v", got, want)
+		}
+		if nos := l.deliveredNos(); !reflect.DeepEqual(nos, []uint64{6, 7}) {
+			t.Errorf("delivered %v, want [6 7]", nos)
+		}
+
+		// 重复提升和后续数据不再续传
+		sc.promote()
+		l.receive(l.dataPacket(7, 8))
+		if err := m.resumeOnce(l.conn, l.h); err != nil {
+			t.Fatal(err)
+		}
+		if store.loads != 1 || len(l.resumeRequests()) != 1 {
+			t.Errorf("loads = %d, resume requests = %d, want exactly one resume", store.loads, len(l.resumeRequests()))
+		}
+	})
+
+	t.Run("promoted before authentication", func(t *testing.T) {
+		m := newTestManager(t, nil)
+		store := testCheckpoints()
+		m.SetCheckpointStore(store)
+		l, sc := newTestStandby(t, m, "src-2")
+
+		// 仍在认证中的连接被提升后，在认证通过时续传
+		if err := m.sendAuthPacket(l.conn, l.h); err != nil {
+			t.Fatal(err)
+		}
+		sc.promote()
+		l.receive(l.dataPacket(7, 6))
+		if store.loads != 0 {
+			t.Fatal("resumed before authentication")
+		}
+
+		if err := l.receive(l.authResponse(AuthStatusAccepted)); err != nil {
+			t.Fatal(err)
+		}
+		l.receive(l.dataPacket(7, 6))
+		if got := l.resumeRequests(); store.loads != 1 || len(got) != 1 {
+			t.Errorf("loads = %d, resume requests %+v, want exactly one resume", store.loads, got)
+		}
+		if nos := l.deliveredNos(); !reflect.DeepEqual(nos, []uint64{6}) {
+			t.Errorf("delivered %v, want [6]", nos)
+		}
+	})
+}
+
+func TestTakeStandbyResumesOnReadLoop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	m := newTestManager(t, func(cfg *config.Config) {
+		cfg.SourceServers.Servers[1].Address = listener.Addr().String()
+	})
+	m.SetCheckpointStore(testCheckpoints())
+	server := m.serverIndex["src-2"]
+	packets := newTestLink(t, m, "src-2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc, err := m.openStandby(ctx, server, packets.handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.closeStandby(sc)
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	source := &fakeSource{t: t, m: m, conn: conn, framer: m.newFramer(server)}
+
+	// 认证通过后保持热备，不续传
+	if auth := source.next(); auth == nil {
+		t.Fatal("no auth packet")
+	}
+	frame, err := source.framer.Encode(packets.authResponse(AuthStatusAccepted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "standby authenticated", sc.h.auth.CanDeliver)
+
+	// 提升后没有数据到达时，读取循环被唤醒并立即续传
+	if m.takeStandby("src-2") != sc {
+		t.Fatal("takeStandby did not return the standby connection")
+	}
+	pkg := source.next()
+	if pkg == nil || pkg.RetransmissionFlag != ResumeRequestFlag {
+		t.Fatalf("first packet after promotion = %+v, want resume request", pkg)
+	}
+}
+
+// fakeSource 模拟源服务器一侧，读取桥接服务发送的请求包
+type fakeSource struct {
+	t      *testing.T
+	m      *Manager
+	conn   net.Conn
+	framer framing.Framer
+	frames [][]byte
+}
+
+// next 读取下一个请求包（跳过心跳帧）
+// 返回: 请求包，连接关闭时返回nil
+func (s *fakeSource) next() *BasePackage {
+	s.t.Helper()
+	buffer := make([]byte, 4096)
+	for {
+		for len(s.frames) > 0 {
+			frame := s.frames[0]
+			s.frames = s.frames[1:]
+			if bytes.Equal(frame, HeartbeatPacket) {
+				continue
+			}
+			pkg, err := s.m.authManager.codec.Unmarshal(frame)
+			if err != nil {
+				s.t.Fatalf("Unmarshal: %v", err)
+			}
+			return pkg
+		}
+
+		s.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := s.conn.Read(buffer)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				s.t.Fatal("timed out waiting for packet")
+			}
+			return nil
+		}
+		frames, err := s.framer.Decode(buffer[:n])
+		if err != nil {
+			s.t.Fatalf("Decode: %v", err)
+		}
+		s.frames = append(s.frames, frames...)
+	}
+}
+
+// waitUntil 等待条件满足
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
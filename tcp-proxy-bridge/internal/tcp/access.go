@@ -0,0 +1,249 @@
+// internal/tcp/access.go
+package tcp
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// accessRules 解析后的访问控制规则
+type accessRules struct {
+	allow []*net.IPNet // 允许的地址范围
+	deny  []*net.IPNet // 拒绝的地址范围
+}
+
+// parseAccessRules 解析访问控制条目
+// 参数: allow - 允许的条目, deny - 拒绝的条目
+// 返回: 访问控制规则和错误信息
+func parseAccessRules(allow, deny []string) (accessRules, error) {
+	var rules accessRules
+	for _, entry := range allow {
+		ipNet, err := config.ParseIPNet(entry)
+		if err != nil {
+			return rules, fmt.Errorf("allow: %v", err)
+		}
+		rules.allow = append(rules.allow, ipNet)
+	}
+	for _, entry := range deny {
+		ipNet, err := config.ParseIPNet(entry)
+		if err != nil {
+			return rules, fmt.Errorf("deny: %v", err)
+		}
+		rules.deny = append(rules.deny, ipNet)
+	}
+	return rules, nil
+}
+
+// accessSnapshot 生效中的访问控制规则
+// 重新加载时整体替换，检查连接时无锁读取
+type accessSnapshot struct {
+	static accessRules // 配置文件中的规则
+	file   accessRules // 访问控制列表文件中的规则
+}
+
+// allowed 检查来源IP是否允许连接
+// deny优先于allow；没有任何allow条目时允许所有未被拒绝的地址
+func (s *accessSnapshot) allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if containsIP(s.static.deny, ip) || containsIP(s.file.deny, ip) {
+		return false
+	}
+	if len(s.static.allow) == 0 && len(s.file.allow) == 0 {
+		return true
+	}
+	return containsIP(s.static.allow, ip) || containsIP(s.file.allow, ip)
+}
+
+// AccessList 可热更新的来源IP访问控制列表
+// 访问控制列表文件由Refresh定期比较修改时间和大小，发生变化则重新加载；配置文件中的allow/deny
+// 由Reload整体替换（例如收到SIGHUP后）。加载失败时继续使用上一次成功加载的规则
+type AccessList struct {
+	current atomic.Pointer[accessSnapshot] // 生效中的规则
+
+	mu         sync.Mutex          // 保护以下重新加载状态，串行化重新加载
+	cfg        config.AccessConfig // 访问控制配置
+	modTime    time.Time           // 最近一次加载的文件修改时间
+	size       int64               // 最近一次加载的文件大小
+	loadedAt   time.Time           // 最近一次成功加载的时间
+	reloads    int                 // 重新加载次数（不含首次加载）
+	lastError  string              // 最近一次加载错误
+	failedMod  time.Time           // 加载失败时的文件修改时间，避免对同一版本重复尝试
+	failedSize int64               // 加载失败时的文件大小
+}
+
+// NewAccessList 创建来源IP访问控制列表
+// 参数: cfg - 访问控制配置
+// 返回: 访问控制列表和错误信息（配置的条目非法或首次加载文件失败时返回错误）
+func NewAccessList(cfg config.AccessConfig) (*AccessList, error) {
+	a := &AccessList{}
+	if err := a.apply(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Allowed 检查来源IP是否允许连接
+// 参数: ip - 来源IP
+// 返回: 是否允许
+func (a *AccessList) Allowed(ip net.IP) bool {
+	return a.current.Load().allowed(ip)
+}
+
+// Refresh 检查访问控制列表文件，发生变化时重新加载
+// 返回: 规则是否发生变化
+func (a *AccessList) Refresh() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg.File == "" || !a.changed() {
+		return false
+	}
+
+	rules, info, err := a.loadFile(a.cfg.File)
+	if err != nil {
+		a.lastError = err.Error()
+		log.Printf("Failed to reload access list %s, keeping previous rules: %v", a.cfg.File, err)
+		return false
+	}
+
+	a.current.Store(&accessSnapshot{static: a.current.Load().static, file: rules})
+	a.loaded(info)
+	a.reloads++
+	log.Printf("Access list reloaded from %s: %d allow, %d deny", a.cfg.File, len(rules.allow), len(rules.deny))
+	return true
+}
+
+// Reload 以新的访问控制配置替换全部规则（配置文件中的条目和访问控制列表文件）
+// 参数: cfg - 新的访问控制配置
+// 返回: 错误信息（条目非法或文件加载失败时保持原有规则）
+func (a *AccessList) Reload(cfg config.AccessConfig) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.apply(cfg); err != nil {
+		a.lastError = err.Error()
+		return err
+	}
+	a.reloads++
+	snapshot := a.current.Load()
+	log.Printf("Access list reloaded: %d allow, %d deny",
+		len(snapshot.static.allow)+len(snapshot.file.allow), len(snapshot.static.deny)+len(snapshot.file.deny))
+	return nil
+}
+
+// apply 解析访问控制配置并替换生效中的规则
+// 调用方需持有锁（首次加载除外）
+func (a *AccessList) apply(cfg config.AccessConfig) error {
+	static, err := parseAccessRules(cfg.Allow, cfg.Deny)
+	if err != nil {
+		return err
+	}
+
+	var rules accessRules
+	var info os.FileInfo
+	if cfg.File != "" {
+		if rules, info, err = a.loadFile(cfg.File); err != nil {
+			return err
+		}
+	}
+
+	a.current.Store(&accessSnapshot{static: static, file: rules})
+	a.cfg = cfg
+	a.loaded(info)
+	return nil
+}
+
+// GetStatus 获取访问控制列表状态
+// 返回: 状态信息
+func (a *AccessList) GetStatus() map[string]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot := a.current.Load()
+	return map[string]interface{}{
+		"allow":      len(snapshot.static.allow) + len(snapshot.file.allow),
+		"deny":       len(snapshot.static.deny) + len(snapshot.file.deny),
+		"file":       a.cfg.File,
+		"loaded_at":  a.loadedAt,
+		"reloads":    a.reloads,
+		"last_error": a.lastError,
+	}
+}
+
+// changed 检查访问控制列表文件是否发生变化
+// 调用方需持有锁
+func (a *AccessList) changed() bool {
+	info, err := os.Stat(a.cfg.File)
+	if err != nil {
+		// 文件暂时不可读（例如正在替换），下次再检查
+		return false
+	}
+	if info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return false
+	}
+	return !info.ModTime().Equal(a.failedMod) || info.Size() != a.failedSize
+}
+
+// loaded 记录加载成功
+// 调用方需持有锁
+// 参数: info - 加载的访问控制列表文件信息（没有文件时为nil）
+func (a *AccessList) loaded(info os.FileInfo) {
+	if info != nil {
+		a.modTime, a.size = info.ModTime(), info.Size()
+	} else {
+		a.modTime, a.size = time.Time{}, 0
+	}
+	a.failedMod, a.failedSize = time.Time{}, 0
+	a.loadedAt = time.Now()
+	a.lastError = ""
+}
+
+// loadFile 加载访问控制列表文件
+// 调用方需持有锁（首次加载除外）
+// 参数: path - 文件路径
+// 返回: 访问控制规则、文件信息和错误信息
+func (a *AccessList) loadFile(path string) (accessRules, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return accessRules{}, nil, fmt.Errorf("failed to stat access list: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return accessRules{}, nil, fmt.Errorf("failed to read access list: %v", err)
+	}
+
+	var entries struct {
+		Allow []string `yaml:"allow"`
+		Deny  []string `yaml:"deny"`
+	}
+	rules, err := accessRules{}, yaml.Unmarshal(data, &entries)
+	if err == nil {
+		rules, err = parseAccessRules(entries.Allow, entries.Deny)
+	}
+	if err != nil {
+		a.failedMod, a.failedSize = info.ModTime(), info.Size()
+		return accessRules{}, nil, fmt.Errorf("invalid access list %s: %v", path, err)
+	}
+	return rules, info, nil
+}
+
+// containsIP 检查IP是否属于任一地址范围
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+// internal/tcp/access_test.go
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+func TestAccessListAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.AccessConfig
+		ip    string
+		allow bool
+	}{
+		{name: "no rules", ip: "203.0.113.5", allow: true},
+		{name: "allow cidr", cfg: config.AccessConfig{Allow: []string{"10.0.0.0/8"}}, ip: "10.1.2.3", allow: true},
+		{name: "outside allow", cfg: config.AccessConfig{Allow: []string{"10.0.0.0/8"}}, ip: "192.168.1.1", allow: false},
+		{name: "bare ip", cfg: config.AccessConfig{Allow: []string{"192.168.1.20"}}, ip: "192.168.1.20", allow: true},
+		{name: "bare ip exact", cfg: config.AccessConfig{Allow: []string{"192.168.1.20"}}, ip: "192.168.1.21", allow: false},
+		{
+			name:  "deny wins over allow",
+			cfg:   config.AccessConfig{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.9.0.0/16"}},
+			ip:    "10.9.1.1",
+			allow: false,
+		},
+		{name: "deny only", cfg: config.AccessConfig{Deny: []string{"10.9.0.0/16"}}, ip: "10.8.1.1", allow: true},
+		{name: "ipv6", cfg: config.AccessConfig{Allow: []string{"2001:db8::/32"}}, ip: "2001:db8::1", allow: true},
+		{name: "ipv4 mapped loopback", cfg: config.AccessConfig{Deny: []string{"127.0.0.1"}}, ip: "::ffff:127.0.0.1", allow: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAccessList(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewAccessList: %v", err)
+			}
+			if got := a.Allowed(net.ParseIP(tt.ip)); got != tt.allow {
+				t.Errorf("Allowed(%s) = %v, want %v", tt.ip, got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestAccessListInvalid(t *testing.T) {
+	for _, cfg := range []config.AccessConfig{
+		{Allow: []string{"10.0.0.0/33"}},
+		{Deny: []string{"not-an-ip"}},
+		{File: filepath.Join(t.TempDir(), "missing.yaml")},
+	} {
+		if _, err := NewAccessList(cfg); err == nil {
+			t.Errorf("NewAccessList(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestAccessListFileRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.yaml")
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile("deny: [\"10.0.0.1\"]\n")
+	a, err := NewAccessList(config.AccessConfig{Deny: []string{"10.0.0.2"}, File: path})
+	if err != nil {
+		t.Fatalf("NewAccessList: %v", err)
+	}
+	if a.Allowed(net.ParseIP("10.0.0.1")) || a.Allowed(net.ParseIP("10.0.0.2")) {
+		t.Fatal("file and static deny entries not applied")
+	}
+	if a.Refresh() {
+		t.Error("Refresh reported change for unchanged file")
+	}
+
+	// 文件内容变化后重新加载，配置文件中的条目保持不变
+	writeFile("deny: [\"10.0.0.3\", \"10.0.0.4\"]\n")
+	if !a.Refresh() {
+		t.Fatal("Refresh did not reload changed file")
+	}
+	if !a.Allowed(net.ParseIP("10.0.0.1")) || a.Allowed(net.ParseIP("10.0.0.3")) || a.Allowed(net.ParseIP("10.0.0.2")) {
+		t.Error("rules after refresh are wrong")
+	}
+
+	// 非法内容保持上一次成功加载的规则
+	writeFile("deny: [\"bogus-entry-that-is-longer\"]\n")
+	if a.Refresh() {
+		t.Error("Refresh reported change for invalid file")
+	}
+	if a.Allowed(net.ParseIP("10.0.0.3")) {
+		t.Error("previous rules lost after invalid file")
+	}
+	if status := a.GetStatus(); status["last_error"] == "" || status["reloads"] != 1 {
+		t.Errorf("status = %v, want last_error set and 1 reload", status)
+	}
+}
+
+func TestAccessListReload(t *testing.T) {
+	a, err := NewAccessList(config.AccessConfig{Allow: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("NewAccessList: %v", err)
+	}
+
+	if err := a.Reload(config.AccessConfig{Allow: []string{"192.168.0.0/16"}}); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if a.Allowed(net.ParseIP("10.1.1.1")) || !a.Allowed(net.ParseIP("192.168.1.1")) {
+		t.Error("inline rules not replaced by Reload")
+	}
+
+	// 非法配置保持原有规则
+	if err := a.Reload(config.AccessConfig{Allow: []string{"bad"}}); err == nil {
+		t.Error("Reload accepted invalid entry")
+	}
+	if !a.Allowed(net.ParseIP("192.168.1.1")) {
+		t.Error("previous rules lost after invalid reload")
+	}
+}
+
+func TestServerReloadAccessClosesDeniedConnections(t *testing.T) {
+	s := NewServer(&config.ServerConfig{}, nil)
+	access, err := NewAccessList(config.AccessConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.access = access
+
+	denied, deniedPeer := net.Pipe()
+	kept, keptPeer := net.Pipe()
+	defer keptPeer.Close()
+	defer kept.Close()
+	s.connections[denied] = "10.9.0.1"
+	s.connections[kept] = "10.8.0.1"
+
+	if err := s.ReloadAccess(config.AccessConfig{Deny: []string{"10.9.0.0/16"}}); err != nil {
+		t.Fatalf("ReloadAccess: %v", err)
+	}
+
+	if _, err := deniedPeer.Read(make([]byte, 1)); err == nil {
+		t.Error("denied connection still open")
+	}
+	kept.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := kept.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("allowed connection closed: %v", err)
+	}
+	if revoked := s.GetStatus()["revoked"]; revoked != int64(1) {
+		t.Errorf("revoked = %v, want 1", revoked)
+	}
+}
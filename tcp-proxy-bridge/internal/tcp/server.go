@@ -3,6 +3,9 @@ package tcp
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -10,31 +13,43 @@ import (
 	"time"
 
 	"tcp-proxy-bridge/internal/config"
-	"tcp-proxy-bridge/internal/database"
+	"tcp-proxy-bridge/internal/framing"
 	"tcp-proxy-bridge/internal/metrics"
+	"tcp-proxy-bridge/internal/source"
+	"tcp-proxy-bridge/internal/tlsutil"
 )
 
 // Server TCP服务器实现
 // 负责监听TCP端口，接收客户端连接并处理数据
 type Server struct {
 	config      *config.ServerConfig // 服务器配置
-	db          *database.Postgres   // 数据库实例
+	dataHandler source.DataHandler   // 数据处理函数（与主动连接的源服务器共用处理流水线）
 	listener    net.Listener         // TCP监听器
 	wg          sync.WaitGroup       // 等待组，用于优雅关闭
 	mu          sync.RWMutex         // 读写锁，保护共享状态
 	isRunning   bool                 // 服务器运行状态
-	connections map[net.Conn]bool    // 活跃连接集合
+	connections map[net.Conn]string  // 活跃连接及其来源IP
+	perIP       map[string]int       // 各来源IP的活跃连接数
+	access      *AccessList          // 来源IP访问控制列表
+	tls         *tlsutil.Reloader    // 监听端口TLS配置（未启用TLS时为nil）
+	rejected    map[string]int64     // 按原因统计的被拒绝连接数
+	revoked     int64                // 访问控制列表更新后被关闭的连接数
 }
 
+// accessCheckInterval 检查访问控制列表文件变化的间隔
+const accessCheckInterval = 5 * time.Second
+
 // NewServer 创建新的TCP服务器实例
-// 参数: cfg - 服务器配置, db - 数据库实例
+// 参数: cfg - 服务器配置, dataHandler - 数据处理函数
 // 返回: TCP服务器实例
-func NewServer(cfg *config.ServerConfig, db *database.Postgres) *Server {
+func NewServer(cfg *config.ServerConfig, dataHandler source.DataHandler) *Server {
 	return &Server{
 		config:      cfg,
-		db:          db,
+		dataHandler: dataHandler,
 		isRunning:   false,
-		connections: make(map[net.Conn]bool),
+		connections: make(map[net.Conn]string),
+		perIP:       make(map[string]int),
+		rejected:    make(map[string]int64),
 	}
 }
 
@@ -43,6 +58,33 @@ func NewServer(cfg *config.ServerConfig, db *database.Postgres) *Server {
 // 返回: 错误信息
 func (s *Server) Start(ctx context.Context) error {
 	var err error
+
+	// 提前校验分帧配置，避免每个连接创建分帧器时失败
+	if _, err = framing.New(s.config.ListenerFraming()); err != nil {
+		return fmt.Errorf("invalid listener framing: %v", err)
+	}
+
+	// 加载来源IP访问控制列表
+	access, err := NewAccessList(s.config.Access)
+	if err != nil {
+		return fmt.Errorf("invalid listener access list: %v", err)
+	}
+	s.mu.Lock()
+	s.access = access
+	s.mu.Unlock()
+
+	// 加载监听端口TLS证书
+	var tlsReloader *tlsutil.Reloader
+	if s.config.TLS.Enabled {
+		tlsReloader, err = tlsutil.NewServerReloader(s.config.TLS)
+		if err != nil {
+			return fmt.Errorf("invalid listener tls: %v", err)
+		}
+		s.mu.Lock()
+		s.tls = tlsReloader
+		s.mu.Unlock()
+	}
+
 	// 构建监听地址
 	addr := fmt.Sprintf(":%d", s.config.TCPListenPort)
 
@@ -52,6 +94,17 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start TCP server on port %d: %v", s.config.TCPListenPort, err)
 	}
 
+	// 启用TLS时每个连接在处理协程中完成握手，访问控制和连接数限制仍按来源IP在握手前检查；
+	// 每次握手获取最新的证书，证书文件替换后无需重启
+	if tlsReloader != nil {
+		s.listener = tls.NewListener(s.listener, &tls.Config{
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return tlsReloader.ServerConfig(), nil
+			},
+		})
+		log.Printf("TCP server TLS enabled (client_auth=%s, identity=%s)", s.config.TLS.ClientAuth, s.config.TLS.Identity)
+	}
+
 	// 设置服务器运行状态
 	s.mu.Lock()
 	s.isRunning = true
@@ -59,8 +112,9 @@ func (s *Server) Start(ctx context.Context) error {
 
 	log.Printf("TCP server started successfully, listening on port %d", s.config.TCPListenPort)
 
-	// 启动连接清理器
+	// 启动连接清理器和访问控制列表文件检查
 	go s.connectionCleaner(ctx)
+	go s.accessWatcher(ctx)
 
 	// 主接受循环
 	for {
@@ -86,27 +140,63 @@ func (s *Server) Start(ctx context.Context) error {
 				continue
 			}
 
-			// 记录新连接并启动处理协程
-			s.mu.Lock()
-			s.connections[conn] = true
-			s.mu.Unlock()
+			// 检查访问控制和连接数限制，通过后记录新连接
+			sourceIP := remoteIP(conn)
+			if reason := s.admit(conn, sourceIP); reason != "" {
+				log.Printf("Rejected connection from %s: %s", conn.RemoteAddr(), reason)
+				metrics.IncConnectionsRejected(reason)
+				conn.Close()
+				continue
+			}
 
 			// 更新活跃连接指标
 			metrics.IncActiveConnections()
 
 			s.wg.Add(1)
-			go s.handleConnection(ctx, conn)
+			go s.handleConnection(ctx, conn, sourceIP)
 		}
 	}
 }
 
+// admit 检查是否接受新连接，接受时记录到连接集合
+// 参数: conn - 新连接, sourceIP - 来源IP
+// 返回: 拒绝原因（metrics.Reject*常量），接受时为空
+func (s *Server) admit(conn net.Conn, sourceIP string) string {
+	allowed := s.access.Allowed(net.ParseIP(sourceIP))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reason := ""
+	switch {
+	case !allowed:
+		reason = metrics.RejectAccessDenied
+	case s.config.MaxConnections > 0 && len(s.connections) >= s.config.MaxConnections:
+		reason = metrics.RejectMaxConnections
+	case s.config.MaxConnectionsPerIP > 0 && s.perIP[sourceIP] >= s.config.MaxConnectionsPerIP:
+		reason = metrics.RejectMaxConnectionsPerIP
+	}
+	if reason != "" {
+		s.rejected[reason]++
+		return reason
+	}
+
+	s.connections[conn] = sourceIP
+	s.perIP[sourceIP]++
+	return ""
+}
+
 // handleConnection 处理单个TCP连接
-// 参数: ctx - 上下文, conn - TCP连接
-func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
+// 参数: ctx - 上下文, conn - TCP连接, sourceIP - 来源IP
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn, sourceIP string) {
 	// 确保连接最终被关闭并从连接集合中移除
 	defer func() {
 		s.mu.Lock()
 		delete(s.connections, conn)
+		s.perIP[sourceIP]--
+		if s.perIP[sourceIP] <= 0 {
+			delete(s.perIP, sourceIP)
+		}
 		s.mu.Unlock()
 		conn.Close()
 		s.wg.Done()
@@ -116,6 +206,35 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 	remoteAddr := conn.RemoteAddr().String()
 	log.Printf("New TCP connection established from: %s", remoteAddr)
 
+	// 完成TLS握手并获取客户端证书身份
+	identity, err := s.handshake(ctx, conn)
+	if err != nil {
+		log.Printf("Rejected connection from %s: TLS handshake failed: %v", remoteAddr, err)
+		metrics.IncConnectionsRejected(metrics.RejectTLSHandshake)
+		s.mu.Lock()
+		s.rejected[metrics.RejectTLSHandshake]++
+		s.mu.Unlock()
+		return
+	}
+	if identity != "" {
+		log.Printf("TCP connection from %s authenticated as: %s", remoteAddr, identity)
+	}
+
+	// 每个连接使用独立的分帧器
+	framer, err := framing.New(s.config.ListenerFraming())
+	if err != nil {
+		log.Printf("Failed to create framer for %s: %v", remoteAddr, err)
+		return
+	}
+
+	// 连接关闭时缓冲区中不完整的帧无法再补齐，丢弃并计数
+	defer func() {
+		if buffered := framer.Buffered(); buffered > 0 {
+			log.Printf("Discarding %d bytes of incomplete %s frame from %s", buffered, framer.Name(), remoteAddr)
+			metrics.IncFramesMalformed()
+		}
+	}()
+
 	// 创建读取缓冲区
 	buffer := make([]byte, s.config.MaxMessageSize)
 
@@ -142,47 +261,162 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 			}
 
 			if n > 0 {
-				// 复制接收到的数据
-				data := make([]byte, n)
-				copy(data, buffer[:n])
-
-				// 处理接收到的数据
-				if err := s.processReceivedData(remoteAddr, data); err != nil {
-					log.Printf("Failed to process data from %s: %v", remoteAddr, err)
-					// 注意：这里不增加错误计数，因为数据库保存失败已经在processReceivedData中记录了
-				} else {
-					log.Printf("Successfully processed %d bytes from %s", n, remoteAddr)
-					// 增加成功接收消息计数
-					metrics.IncMessagesReceived()
+				// 按分帧方式切分出完整的消息，超长或格式错误的帧被丢弃
+				frames, err := framer.Decode(buffer[:n])
+				if err != nil {
+					log.Printf("Error decoding %s frames from %s: %v", framer.Name(), remoteAddr, err)
+					if errors.Is(err, framing.ErrFrameTooLarge) {
+						metrics.IncFramesOversized()
+					} else {
+						metrics.IncFramesMalformed()
+					}
+				}
+
+				// 处理每条完整的消息
+				for _, data := range frames {
+					if err := s.processReceivedData(sourceIP, identity, data); err != nil {
+						log.Printf("Failed to process data from %s: %v", remoteAddr, err)
+						// 注意：这里不增加错误计数，因为处理失败已经在processReceivedData中记录了
+					} else {
+						log.Printf("Successfully processed %d bytes from %s", len(data), remoteAddr)
+						// 增加成功接收消息计数
+						metrics.IncMessagesReceived()
+					}
+				}
+
+				// 长度字段类分帧方式丢失帧边界后无法重新同步，断开连接由客户端重连
+				if errors.Is(err, framing.ErrOutOfSync) {
+					log.Printf("Closing connection from %s: %s frame boundary lost", remoteAddr, framer.Name())
+					return
 				}
 			}
 		}
 	}
 }
 
+// handshake 完成TLS握手并获取来源身份
+// 握手受读取超时限制，避免不发送ClientHello的连接长期占用
+// 参数: ctx - 上下文, conn - 连接
+// 返回: 来源身份（未启用TLS或客户端未提供证书时为空）和错误信息
+func (s *Server) handshake(ctx context.Context, conn net.Conn) (string, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return "", nil
+	}
+
+	conn.SetDeadline(time.Now().Add(s.config.ReadTimeout))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return "", err
+	}
+	conn.SetDeadline(time.Time{})
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", nil
+	}
+	return certIdentity(certs[0], s.config.TLS.Identity), nil
+}
+
+// certIdentity 获取客户端证书的身份
+// 参数: cert - 客户端证书, mode - 身份取值方式（config.Identity*常量）
+// 返回: 证书主题，或依次取第一个DNS、URI、邮箱、IP类型的SAN（证书没有SAN时使用主题）
+func certIdentity(cert *x509.Certificate, mode string) string {
+	if mode == config.IdentitySAN {
+		switch {
+		case len(cert.DNSNames) > 0:
+			return cert.DNSNames[0]
+		case len(cert.URIs) > 0:
+			return cert.URIs[0].String()
+		case len(cert.EmailAddresses) > 0:
+			return cert.EmailAddresses[0]
+		case len(cert.IPAddresses) > 0:
+			return cert.IPAddresses[0].String()
+		}
+	}
+	return cert.Subject.String()
+}
+
 // processReceivedData 处理接收到的TCP数据
-// 参数: sourceIP - 数据来源IP, data - 接收到的数据
+// 参数: sourceIP - 数据来源IP, identity - 来源身份（客户端证书）, data - 接收到的数据
 // 返回: 错误信息
-func (s *Server) processReceivedData(sourceIP string, data []byte) error {
-	// 创建消息对象
-	message := &database.Message{
-		SourceIP:     sourceIP,
-		OriginalData: data,
-		DataLength:   len(data),
-		Status:       database.StatusReceived,
-	}
-
-	// 保存消息到数据库
-	if err := s.db.SaveMessage(message); err != nil {
-		// 数据库保存失败，增加错误计数
+func (s *Server) processReceivedData(sourceIP, identity string, data []byte) error {
+	// 监听端口接收的数据没有BasePackage包头，源服务器ID固定为listener
+	meta := &source.PacketMeta{
+		ServerID:       config.ListenerSourceGroup,
+		SourceIP:       sourceIP,
+		SourceIdentity: identity,
+	}
+
+	// 交给处理流水线，通过后放入入库队列
+	if err := s.dataHandler(data, meta); err != nil {
+		// 处理失败，增加错误计数
 		metrics.IncMessageErrors()
-		return fmt.Errorf("failed to save message from %s to database: %v", sourceIP, err)
+		return fmt.Errorf("failed to process message from %s: %v", sourceIP, err)
+	}
+
+	return nil
+}
+
+// accessWatcher 定期检查访问控制列表文件，规则变化后关闭不再允许的连接
+// 参数: ctx - 上下文
+func (s *Server) accessWatcher(ctx context.Context) {
+	ticker := time.NewTicker(accessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.access.Refresh() {
+				s.enforceAccess()
+			}
+		}
+	}
+}
+
+// ReloadAccess 以新的访问控制配置替换全部规则，并关闭不再允许的连接
+// 参数: cfg - 新的访问控制配置
+// 返回: 错误信息（服务器未启动、条目非法或文件加载失败时保持原有规则）
+func (s *Server) ReloadAccess(cfg config.AccessConfig) error {
+	s.mu.RLock()
+	access := s.access
+	s.mu.RUnlock()
+	if access == nil {
+		return fmt.Errorf("TCP server is not running")
 	}
 
-	log.Printf("Successfully saved message %d from %s to database", message.ID, sourceIP)
+	if err := access.Reload(cfg); err != nil {
+		return err
+	}
+	s.enforceAccess()
 	return nil
 }
 
+// enforceAccess 关闭来源IP不再被访问控制列表允许的连接
+// 连接关闭后由处理协程移除并更新连接计数
+func (s *Server) enforceAccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closed := 0
+	for conn, sourceIP := range s.connections {
+		if s.access.Allowed(net.ParseIP(sourceIP)) {
+			continue
+		}
+		// 已关闭但处理协程尚未移除的连接不重复计数
+		if err := conn.Close(); err == nil {
+			log.Printf("Closed connection from %s: denied by updated access list", conn.RemoteAddr())
+			closed++
+		}
+	}
+
+	if closed > 0 {
+		s.revoked += int64(closed)
+		log.Printf("Closed %d connections denied by updated access list", closed)
+	}
+}
+
 // connectionCleaner 连接清理器，定期检查并清理失效连接
 // 参数: ctx - 上下文
 func (s *Server) connectionCleaner(ctx context.Context) {
@@ -209,11 +443,9 @@ func (s *Server) cleanStaleConnections() {
 		// 尝试发送空数据来检测连接是否仍然活跃
 		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 		if _, err := conn.Write([]byte{}); err != nil {
-			// 连接已失效，关闭并移除
+			// 连接已失效，关闭后由处理协程移除并更新连接计数
 			conn.Close()
-			delete(s.connections, conn)
 			cleanedCount++
-			metrics.DecActiveConnections()
 		}
 	}
 
@@ -237,12 +469,10 @@ func (s *Server) Stop(ctx context.Context) {
 		s.listener.Close()
 	}
 
-	// 关闭所有活跃连接
+	// 关闭所有活跃连接，由处理协程移除并更新连接计数
 	s.mu.Lock()
 	for conn := range s.connections {
 		conn.Close()
-		delete(s.connections, conn)
-		metrics.DecActiveConnections()
 	}
 	s.mu.Unlock()
 
@@ -262,6 +492,60 @@ func (s *Server) Stop(ctx context.Context) {
 	}
 }
 
+// remoteIP 获取连接的来源IP
+// 参数: conn - 连接
+// 返回: 来源IP（无法解析时为远端地址）
+func remoteIP(conn net.Conn) string {
+	remoteAddr := conn.RemoteAddr().String()
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return ip
+}
+
+// GetStatus 获取TCP服务器状态
+// 返回: 状态信息
+func (s *Server) GetStatus() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	perIP := make(map[string]int, len(s.perIP))
+	for ip, count := range s.perIP {
+		perIP[ip] = count
+	}
+	rejected := make(map[string]int64, len(s.rejected))
+	for reason, count := range s.rejected {
+		rejected[reason] = count
+	}
+
+	status := map[string]interface{}{
+		"running":                s.isRunning,
+		"port":                   s.config.TCPListenPort,
+		"connections":            len(s.connections),
+		"max_connections":        s.config.MaxConnections,
+		"max_connections_per_ip": s.config.MaxConnectionsPerIP,
+		"connections_per_ip":     perIP,
+		"rejected":               rejected,
+		"revoked":                s.revoked,
+	}
+	if s.access != nil {
+		status["access"] = s.access.GetStatus()
+	}
+	if s.tls != nil {
+		status["tls"] = s.tls.GetStatus()
+	}
+	return status
+}
+
+// IsRunning 获取服务器是否正在监听
+// 返回: 运行状态
+func (s *Server) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isRunning
+}
+
 // GetConnectionCount 获取当前活跃连接数
 // 返回: 活跃连接数量
 func (s *Server) GetConnectionCount() int {
@@ -0,0 +1,259 @@
+// internal/tlsutil/reloader.go
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"tcp-proxy-bridge/internal/config"
+)
+
+// fileStamp 证书文件的修改标记
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader 可热更新的TLS配置
+// 每次获取配置时检查证书文件的修改时间和大小，发生变化则重新加载；
+// 重新加载失败时继续使用上一次成功加载的配置
+type Reloader struct {
+	mu         sync.Mutex
+	cfg        config.TLSConfig     // TLS配置（服务端的CAFile为校验客户端证书的CA）
+	server     bool                 // 是否为服务端配置
+	clientAuth tls.ClientAuthType   // 服务端对客户端证书的要求
+	stamps     map[string]fileStamp // 各证书文件最近一次加载时的标记
+	tlsConfig  *tls.Config          // 当前生效的TLS配置
+	loadedAt   time.Time            // 最近一次成功加载的时间
+	reloads    int                  // 重新加载次数（不含首次加载）
+	lastError  string               // 最近一次加载错误
+	lastFailed map[string]fileStamp // 加载失败时的文件标记，避免对同一版本重复尝试
+}
+
+// NewClientReloader 创建客户端TLS配置
+// 参数: cfg - TLS配置
+// 返回: TLS配置实例和错误信息（首次加载失败时返回错误）
+func NewClientReloader(cfg config.TLSConfig) (*Reloader, error) {
+	r := &Reloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewServerReloader 创建服务端TLS配置（监听端口使用）
+// 参数: cfg - 监听端口TLS配置
+// 返回: TLS配置实例和错误信息（首次加载失败时返回错误）
+func NewServerReloader(cfg config.ListenerTLSConfig) (*Reloader, error) {
+	r := &Reloader{
+		cfg: config.TLSConfig{
+			Enabled:    cfg.Enabled,
+			CAFile:     cfg.ClientCAFile,
+			CertFile:   cfg.CertFile,
+			KeyFile:    cfg.KeyFile,
+			MinVersion: cfg.MinVersion,
+		},
+		server: true,
+	}
+	switch cfg.ClientAuth {
+	case config.ClientAuthOptional:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case config.ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		r.clientAuth = tls.NoClientCert
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig 获取服务端TLS配置
+// 每次握手时获取最新的证书，用于tls.Config.GetConfigForClient
+// 返回: TLS配置副本
+func (r *Reloader) ServerConfig() *tls.Config {
+	return r.current()
+}
+
+// ClientConfig 获取客户端TLS配置
+// 返回: TLS配置副本
+func (r *Reloader) ClientConfig() *tls.Config {
+	return r.current()
+}
+
+// current 获取当前TLS配置，证书文件变化时先重新加载
+// 返回: TLS配置副本
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.changed() {
+		if err := r.load(); err != nil {
+			r.lastError = err.Error()
+			log.Printf("Failed to reload TLS certificates, keeping previous ones: %v", err)
+		} else {
+			r.reloads++
+			log.Printf("TLS certificates reloaded (%s)", r.describe())
+		}
+	}
+	return r.tlsConfig.Clone()
+}
+
+// GetStatus 获取TLS配置状态
+// 返回: 状态信息
+func (r *Reloader) GetStatus() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return map[string]interface{}{
+		"ca_file":     r.cfg.CAFile,
+		"cert_file":   r.cfg.CertFile,
+		"server_name": r.cfg.ServerName,
+		"min_version": r.minVersionName(),
+		"mutual":      r.mutual(),
+		"loaded_at":   r.loadedAt,
+		"reloads":     r.reloads,
+		"last_error":  r.lastError,
+	}
+}
+
+// mutual 是否为双向TLS
+// 客户端配置了客户端证书，或服务端要求校验客户端证书
+func (r *Reloader) mutual() bool {
+	if r.server {
+		return r.clientAuth != tls.NoClientCert
+	}
+	return r.cfg.CertFile != ""
+}
+
+// files 获取需要监控的证书文件
+func (r *Reloader) files() []string {
+	var files []string
+	for _, path := range []string{r.cfg.CAFile, r.cfg.CertFile, r.cfg.KeyFile} {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// stampFiles 读取证书文件的当前标记
+func (r *Reloader) stampFiles() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// changed 检查证书文件是否发生变化
+// 调用方需持有锁
+func (r *Reloader) changed() bool {
+	stamps, err := r.stampFiles()
+	if err != nil {
+		// 文件暂时不可读（例如正在替换），下次再检查
+		return false
+	}
+	return !sameStamps(stamps, r.stamps) && !sameStamps(stamps, r.lastFailed)
+}
+
+// load 加载证书文件并生成TLS配置
+// 调用方需持有锁（首次加载除外）
+func (r *Reloader) load() error {
+	stamps, err := r.stampFiles()
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := r.build()
+	if err != nil {
+		r.lastFailed = stamps
+		return err
+	}
+
+	r.tlsConfig = tlsConfig
+	r.stamps = stamps
+	r.lastFailed = nil
+	r.loadedAt = time.Now()
+	r.lastError = ""
+	return nil
+}
+
+// build 根据配置生成TLS配置
+func (r *Reloader) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: r.cfg.ServerName,
+		MinVersion: config.TLSVersions[r.minVersionName()],
+	}
+
+	if r.cfg.CAFile != "" {
+		pem, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates in CA file %s", r.cfg.CAFile)
+		}
+		if r.server {
+			tlsConfig.ClientCAs = pool
+		} else {
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	if r.cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			if r.server {
+				return nil, fmt.Errorf("failed to load server certificate: %v", err)
+			}
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if r.server {
+		tlsConfig.ClientAuth = r.clientAuth
+	}
+
+	return tlsConfig, nil
+}
+
+// minVersionName 获取最低TLS版本名称
+func (r *Reloader) minVersionName() string {
+	if r.cfg.MinVersion == "" {
+		return config.DefaultTLSMinVersion
+	}
+	return r.cfg.MinVersion
+}
+
+// describe 描述当前加载的证书文件
+func (r *Reloader) describe() string {
+	return fmt.Sprintf("ca=%s cert=%s", r.cfg.CAFile, r.cfg.CertFile)
+}
+
+// sameStamps 比较两组文件标记是否一致
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		other, exists := b[path]
+		if !exists || !stamp.modTime.Equal(other.modTime) || stamp.size != other.size {
+			return false
+		}
+	}
+	return true
+}
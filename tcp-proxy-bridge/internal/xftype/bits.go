@@ -0,0 +1,196 @@
+// internal/xftype/bits.go
+package xftype
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BitReader 按位读取器（高位在前）
+type BitReader struct {
+	data []byte // 原始数据
+	pos  int    // 当前位偏移
+}
+
+// NewBitReader 创建按位读取器
+// 参数: data - 原始数据
+// 返回: 读取器实例
+func NewBitReader(data []byte) *BitReader {
+	return &BitReader{data: data}
+}
+
+// ReadBits 读取指定位数的无符号整数
+// 参数: n - 位数 (1-64)
+// 返回: 读取的值和错误信息
+func (r *BitReader) ReadBits(n int) (uint64, error) {
+	if n < 0 || n > 64 {
+		return 0, fmt.Errorf("invalid bit width: %d", n)
+	}
+	if n > r.Remaining() {
+		return 0, fmt.Errorf("insufficient data: need %d bits at offset %d, have %d", n, r.pos, r.Remaining())
+	}
+
+	var v uint64
+	for i := 0; i < n; i++ {
+		b := r.data[r.pos/8] >> (7 - uint(r.pos%8)) & 1
+		v = v<<1 | uint64(b)
+		r.pos++
+	}
+	return v, nil
+}
+
+// ReadBitString 读取指定位数并以"0101"形式的字符串返回
+// 参数: n - 位数
+// 返回: 二进制字符串和错误信息
+func (r *BitReader) ReadBitString(n int) (string, error) {
+	if n < 0 || n > r.Remaining() {
+		return "", fmt.Errorf("insufficient data: need %d bits at offset %d, have %d", n, r.pos, r.Remaining())
+	}
+
+	var sb strings.Builder
+	sb.Grow(n)
+	for i := 0; i < n; i++ {
+		if r.data[r.pos/8]>>(7-uint(r.pos%8))&1 == 1 {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+		r.pos++
+	}
+	return sb.String(), nil
+}
+
+// ReadBytes 读取指定字节数（当前位置必须字节对齐）
+// 参数: n - 字节数
+// 返回: 字节数组和错误信息
+func (r *BitReader) ReadBytes(n int) ([]byte, error) {
+	if r.pos%8 != 0 {
+		return nil, fmt.Errorf("byte read at unaligned bit offset %d", r.pos)
+	}
+	if n < 0 || n*8 > r.Remaining() {
+		return nil, fmt.Errorf("insufficient data: need %d bytes at offset %d, have %d bits", n, r.pos/8, r.Remaining())
+	}
+
+	out := make([]byte, n)
+	copy(out, r.data[r.pos/8:r.pos/8+n])
+	r.pos += n * 8
+	return out, nil
+}
+
+// Remaining 获取剩余位数
+// 返回: 剩余位数
+func (r *BitReader) Remaining() int {
+	return len(r.data)*8 - r.pos
+}
+
+// Pos 获取当前位偏移
+// 返回: 位偏移
+func (r *BitReader) Pos() int {
+	return r.pos
+}
+
+// Seek 跳转到指定位偏移
+// 参数: pos - 位偏移
+// 返回: 错误信息
+func (r *BitReader) Seek(pos int) error {
+	if pos < 0 || pos > len(r.data)*8 {
+		return fmt.Errorf("seek to bit offset %d out of range (%d bits)", pos, len(r.data)*8)
+	}
+	r.pos = pos
+	return nil
+}
+
+// BitWriter 按位写入器（高位在前）
+type BitWriter struct {
+	buf []byte // 已写入数据
+	n   int    // 已写入位数
+}
+
+// NewBitWriter 创建按位写入器
+// 返回: 写入器实例
+func NewBitWriter() *BitWriter {
+	return &BitWriter{}
+}
+
+// WriteBits 写入指定位数的无符号整数
+// 参数: v - 值, n - 位数 (1-64)
+// 返回: 错误信息
+func (w *BitWriter) WriteBits(v uint64, n int) error {
+	if n < 0 || n > 64 {
+		return fmt.Errorf("invalid bit width: %d", n)
+	}
+	if n < 64 && v>>uint(n) != 0 {
+		return fmt.Errorf("value %d does not fit in %d bits", v, n)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(byte(v >> uint(i) & 1))
+	}
+	return nil
+}
+
+// WriteBitString 写入"0101"形式的二进制字符串
+// 参数: s - 二进制字符串
+// 返回: 错误信息
+func (w *BitWriter) WriteBitString(s string) error {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '0':
+			w.writeBit(0)
+		case '1':
+			w.writeBit(1)
+		default:
+			return fmt.Errorf("invalid bit string character '%c' at %d", s[i], i)
+		}
+	}
+	return nil
+}
+
+// WriteBytes 写入字节数组（当前位置必须字节对齐）
+// 参数: data - 字节数组
+// 返回: 错误信息
+func (w *BitWriter) WriteBytes(data []byte) error {
+	if w.n%8 != 0 {
+		return fmt.Errorf("byte write at unaligned bit offset %d", w.n)
+	}
+	w.buf = append(w.buf, data...)
+	w.n += len(data) * 8
+	return nil
+}
+
+// writeBit 写入单个位
+// 参数: b - 位值 (0/1)
+func (w *BitWriter) writeBit(b byte) {
+	if w.n%8 == 0 {
+		w.buf = append(w.buf, 0)
+	}
+	if b == 1 {
+		w.buf[w.n/8] |= 1 << (7 - uint(w.n%8))
+	}
+	w.n++
+}
+
+// PadTo 补0直到指定位偏移
+// 参数: pos - 位偏移（不能小于已写入位数）
+// 返回: 错误信息
+func (w *BitWriter) PadTo(pos int) error {
+	if pos < w.n {
+		return fmt.Errorf("pad to bit offset %d overlaps written data (%d bits)", pos, w.n)
+	}
+	for w.n < pos {
+		w.writeBit(0)
+	}
+	return nil
+}
+
+// Len 获取已写入位数
+// 返回: 位数
+func (w *BitWriter) Len() int {
+	return w.n
+}
+
+// Bytes 获取写入结果，末尾不足一字节的部分补0
+// 返回: 字节数组
+func (w *BitWriter) Bytes() []byte {
+	return w.buf
+}
@@ -0,0 +1,242 @@
+// internal/xftype/codec.go
+package xftype
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec 报文体编解码器
+// 每种信息类型编号(XFType)对应一个编解码器
+type Codec interface {
+	Type() uint16                            // 信息类型编号
+	Name() string                            // 类型名称
+	Decode(data []byte) (interface{}, error) // 将报文体解码为结构化数据
+	Encode(v interface{}) ([]byte, error)    // 将结构化数据编码为报文体
+}
+
+// Message 内置报文结构
+// 通过fields方法描述字段布局，同一份定义同时用于解码和编码
+type Message interface {
+	MessageType() uint16
+	fields(c *fieldCodec)
+}
+
+// HexBytes 以十六进制字符串形式输出JSON的字节数组
+type HexBytes []byte
+
+// MarshalJSON 输出十六进制字符串
+func (b HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+// UnmarshalJSON 解析十六进制字符串
+func (b *HexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// fieldCodec 字段编解码器
+// 解码时从BitReader读取字段值，编码时向BitWriter写入字段值
+type fieldCodec struct {
+	decoding bool       // 是否为解码方向
+	r        *BitReader // 解码读取器
+	w        *BitWriter // 编码写入器
+	err      error      // 第一个发生的错误
+}
+
+// pos 获取当前位偏移
+func (c *fieldCodec) pos() int {
+	if c.decoding {
+		return c.r.Pos()
+	}
+	return c.w.Len()
+}
+
+// fail 记录错误，之后的字段操作全部跳过
+func (c *fieldCodec) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// seek 跳转到指定位偏移，编码时以0补齐
+func (c *fieldCodec) seek(pos int) {
+	if c.err != nil {
+		return
+	}
+	var err error
+	if c.decoding {
+		err = c.r.Seek(pos)
+	} else {
+		err = c.w.PadTo(pos)
+	}
+	if err != nil {
+		c.fail(err)
+	}
+}
+
+// uint 编解码无符号整数字段
+func (c *fieldCodec) uint(v *uint64, bits int) {
+	if c.err != nil {
+		return
+	}
+	if c.decoding {
+		value, err := c.r.ReadBits(bits)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		*v = value
+		return
+	}
+	if err := c.w.WriteBits(*v, bits); err != nil {
+		c.fail(err)
+	}
+}
+
+// u8 编解码8位以内的整数字段
+func (c *fieldCodec) u8(v *uint8, bits int) {
+	x := uint64(*v)
+	c.uint(&x, bits)
+	*v = uint8(x)
+}
+
+// u16 编解码16位以内的整数字段
+func (c *fieldCodec) u16(v *uint16, bits int) {
+	x := uint64(*v)
+	c.uint(&x, bits)
+	*v = uint16(x)
+}
+
+// u32 编解码32位以内的整数字段
+func (c *fieldCodec) u32(v *uint32, bits int) {
+	x := uint64(*v)
+	c.uint(&x, bits)
+	*v = uint32(x)
+}
+
+// u64 编解码64位以内的整数字段
+func (c *fieldCodec) u64(v *uint64, bits int) {
+	c.uint(v, bits)
+}
+
+// bytes 编解码定长字节字段
+func (c *fieldCodec) bytes(v *HexBytes, n int) {
+	if c.err != nil {
+		return
+	}
+	if c.decoding {
+		data, err := c.r.ReadBytes(n)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		*v = data
+		return
+	}
+	if len(*v) != n {
+		c.fail(fmt.Errorf("field length %d, expected %d bytes", len(*v), n))
+		return
+	}
+	if err := c.w.WriteBytes(*v); err != nil {
+		c.fail(err)
+	}
+}
+
+// bitString 编解码定长二进制串字段，n<0表示读取剩余全部位
+func (c *fieldCodec) bitString(v *string, n int) {
+	if c.err != nil {
+		return
+	}
+	if c.decoding {
+		if n < 0 {
+			n = c.r.Remaining()
+		}
+		s, err := c.r.ReadBitString(n)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		*v = s
+		return
+	}
+	if n >= 0 && len(*v) != n {
+		c.fail(fmt.Errorf("bit field length %d, expected %d", len(*v), n))
+		return
+	}
+	if err := c.w.WriteBitString(*v); err != nil {
+		c.fail(err)
+	}
+}
+
+// restBytes 编解码剩余全部字节
+func (c *fieldCodec) restBytes(v *HexBytes) {
+	if c.err != nil {
+		return
+	}
+	if c.decoding {
+		c.bytes(v, c.r.Remaining()/8)
+		return
+	}
+	c.bytes(v, len(*v))
+}
+
+// structCodec 基于Message字段定义的内置编解码器
+type structCodec struct {
+	typ  uint16         // 信息类型编号
+	name string         // 类型名称
+	new  func() Message // 创建空报文结构
+}
+
+// Type 获取信息类型编号
+func (sc *structCodec) Type() uint16 { return sc.typ }
+
+// Name 获取类型名称
+func (sc *structCodec) Name() string { return sc.name }
+
+// Decode 解码报文体
+// 参数: data - 报文体
+// 返回: 报文结构和错误信息
+func (sc *structCodec) Decode(data []byte) (interface{}, error) {
+	msg := sc.new()
+	c := &fieldCodec{decoding: true, r: NewBitReader(data)}
+	msg.fields(c)
+	if c.err != nil {
+		return nil, fmt.Errorf("decode %s: %v", sc.name, c.err)
+	}
+	return msg, nil
+}
+
+// Encode 编码报文体
+// 参数: v - 报文结构（指针）
+// 返回: 报文体和错误信息
+func (sc *structCodec) Encode(v interface{}) ([]byte, error) {
+	msg, ok := v.(Message)
+	if !ok || msg.MessageType() != sc.typ {
+		return nil, fmt.Errorf("encode %s: unexpected value type %T", sc.name, v)
+	}
+
+	c := &fieldCodec{w: NewBitWriter()}
+	msg.fields(c)
+	if c.err != nil {
+		return nil, fmt.Errorf("encode %s: %v", sc.name, c.err)
+	}
+	return c.w.Bytes(), nil
+}
+
+// newStructCodec 创建内置编解码器
+// 参数: name - 类型名称, newFn - 创建空报文结构的函数
+// 返回: 编解码器实例
+func newStructCodec(name string, newFn func() Message) Codec {
+	return &structCodec{typ: newFn().MessageType(), name: name, new: newFn}
+}
@@ -0,0 +1,715 @@
+// internal/xftype/layout.go
+package xftype
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 布局字段类型
+const (
+	FieldKindUint   = "uint"   // 无符号整数（默认）
+	FieldKindInt    = "int"    // 有符号整数（补码）
+	FieldKindBytes  = "bytes"  // 字节数组
+	FieldKindString = "string" // ASCII字符串，末尾的0被去除
+	FieldKindBits   = "bits"   // 二进制串
+	FieldKindGroup  = "group"  // 字段组
+)
+
+// 字节序
+const (
+	EndianBig    = "big"    // 大端（默认）
+	EndianLittle = "little" // 小端
+)
+
+// RepeatUntilEnd 重复直到数据结束
+const RepeatUntilEnd = "*"
+
+// LayoutFile 报文布局定义文件
+type LayoutFile struct {
+	Layouts []Layout `yaml:"layouts"` // 布局定义列表
+}
+
+// Layout 报文布局定义
+type Layout struct {
+	Type   uint16        `yaml:"type"`   // 信息类型编号
+	Name   string        `yaml:"name"`   // 类型名称
+	Fields []LayoutField `yaml:"fields"` // 字段列表
+}
+
+// LayoutField 布局字段定义
+//
+// 字段按定义顺序依次编解码，引用的字段（length/switch/repeat）必须在之前定义。
+// switch字段根据已解码字段的值选择分支，分支中的字段与外层字段位于同一层级；
+// repeat字段按次数重复解析子字段，结果为列表。
+type LayoutField struct {
+	Name   string `yaml:"name"`   // 字段名称
+	Kind   string `yaml:"kind"`   // 字段类型: uint/int/bytes/string/bits/group
+	Bits   int    `yaml:"bits"`   // 位宽
+	Bytes  int    `yaml:"bytes"`  // 字节宽度（与bits二选一）
+	Offset *int   `yaml:"offset"` // 相对报文起始的位偏移（不设置则紧接上一字段）
+	Endian string `yaml:"endian"` // 字节序: big/little（仅整数字段，位宽须为8的倍数）
+	Length string `yaml:"length"` // 引用字段作为长度（bytes/string为字节数，bits为位数）
+
+	Switch  string                   `yaml:"switch"`  // 分支选择字段
+	Cases   map[string][]LayoutField `yaml:"cases"`   // 分支: 选择字段的十进制值 -> 字段列表
+	Default []LayoutField            `yaml:"default"` // 无匹配分支时使用的字段列表
+
+	Repeat string        `yaml:"repeat"` // 重复次数: 数字、字段名或"*"（直到数据结束）
+	Fields []LayoutField `yaml:"fields"` // 字段组/重复段的子字段
+
+	Values []int64 `yaml:"values"` // 允许的取值
+	Min    *int64  `yaml:"min"`    // 最小值
+	Max    *int64  `yaml:"max"`    // 最大值
+}
+
+// LoadLayoutFiles 加载报文布局定义文件
+// 参数: paths - 布局定义文件路径列表
+// 返回: 布局定义列表和错误信息
+func LoadLayoutFiles(paths []string) ([]*Layout, error) {
+	var layouts []*Layout
+	seen := make(map[uint16]string)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layout file %s: %v", path, err)
+		}
+
+		var file LayoutFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse layout file %s: %v", path, err)
+		}
+
+		for i := range file.Layouts {
+			layout := &file.Layouts[i]
+			if err := layout.Validate(); err != nil {
+				return nil, fmt.Errorf("layout file %s: %v", path, err)
+			}
+			if previous, exists := seen[layout.Type]; exists {
+				return nil, fmt.Errorf("layout file %s: message type %d already defined in %s", path, layout.Type, previous)
+			}
+			seen[layout.Type] = path
+			layouts = append(layouts, layout)
+		}
+	}
+
+	return layouts, nil
+}
+
+// Validate 验证布局定义
+// 返回: 错误信息
+func (l *Layout) Validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("layout for message type %d has no name", l.Type)
+	}
+	if len(l.Fields) == 0 {
+		return fmt.Errorf("layout %s has no fields", l.Name)
+	}
+	if err := validateLayoutFields(l.Fields, make(map[string]bool)); err != nil {
+		return fmt.Errorf("layout %s: %v", l.Name, err)
+	}
+	return nil
+}
+
+// validateLayoutFields 验证字段列表
+// 参数: fields - 字段列表, known - 已定义的字段名称（会被更新）
+// 返回: 错误信息
+func validateLayoutFields(fields []LayoutField, known map[string]bool) error {
+	for i := range fields {
+		f := &fields[i]
+		if f.Offset != nil && *f.Offset < 0 {
+			return fmt.Errorf("field %s: negative offset %d", f.Name, *f.Offset)
+		}
+
+		// 分支字段
+		if f.Switch != "" {
+			if !known[f.Switch] {
+				return fmt.Errorf("switch references undefined field %s", f.Switch)
+			}
+			if len(f.Cases) == 0 {
+				return fmt.Errorf("switch on %s has no cases", f.Switch)
+			}
+			branches := make([][]LayoutField, 0, len(f.Cases)+1)
+			for key, branch := range f.Cases {
+				if _, err := strconv.ParseInt(key, 10, 64); err != nil {
+					return fmt.Errorf("switch on %s: invalid case value %q", f.Switch, key)
+				}
+				branches = append(branches, branch)
+			}
+			branches = append(branches, f.Default)
+
+			// 各分支中定义的字段在分支之后都视为已定义
+			merged := make(map[string]bool)
+			for _, branch := range branches {
+				scope := copyNames(known)
+				if err := validateLayoutFields(branch, scope); err != nil {
+					return fmt.Errorf("switch on %s: %v", f.Switch, err)
+				}
+				for name := range scope {
+					merged[name] = true
+				}
+			}
+			for name := range merged {
+				known[name] = true
+			}
+			continue
+		}
+
+		if f.Name == "" {
+			return fmt.Errorf("field #%d has no name", i)
+		}
+		if known[f.Name] {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+
+		// 重复段和字段组
+		if f.Repeat != "" || f.Kind == FieldKindGroup {
+			if len(f.Fields) == 0 {
+				return fmt.Errorf("field %s has no sub fields", f.Name)
+			}
+			if f.Repeat != "" && f.Repeat != RepeatUntilEnd {
+				if _, err := strconv.Atoi(f.Repeat); err != nil && !known[f.Repeat] {
+					return fmt.Errorf("field %s: repeat references undefined field %s", f.Name, f.Repeat)
+				}
+			}
+			if err := validateLayoutFields(f.Fields, copyNames(known)); err != nil {
+				return fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			known[f.Name] = true
+			continue
+		}
+
+		if err := validateScalarField(f, known); err != nil {
+			return err
+		}
+		known[f.Name] = true
+	}
+	return nil
+}
+
+// validateScalarField 验证标量字段
+func validateScalarField(f *LayoutField, known map[string]bool) error {
+	if f.Bits != 0 && f.Bytes != 0 {
+		return fmt.Errorf("field %s: bits and bytes are mutually exclusive", f.Name)
+	}
+	if f.Bits < 0 || f.Bytes < 0 {
+		return fmt.Errorf("field %s: negative width", f.Name)
+	}
+	if f.Length != "" && !known[f.Length] {
+		return fmt.Errorf("field %s: length references undefined field %s", f.Name, f.Length)
+	}
+
+	switch f.Kind {
+	case "", FieldKindUint, FieldKindInt:
+		width := f.width()
+		if width < 1 || width > 64 {
+			return fmt.Errorf("field %s: integer width must be 1-64 bits, got %d", f.Name, width)
+		}
+		switch f.Endian {
+		case "", EndianBig:
+		case EndianLittle:
+			if width%8 != 0 {
+				return fmt.Errorf("field %s: little endian requires a width multiple of 8 bits", f.Name)
+			}
+		default:
+			return fmt.Errorf("field %s: invalid endian %s", f.Name, f.Endian)
+		}
+		if f.Length != "" {
+			return fmt.Errorf("field %s: length is not allowed for integer fields", f.Name)
+		}
+	case FieldKindBytes, FieldKindString:
+		if f.Bits != 0 {
+			return fmt.Errorf("field %s: %s fields use bytes, not bits", f.Name, f.Kind)
+		}
+	case FieldKindBits:
+		if f.Bytes != 0 {
+			return fmt.Errorf("field %s: bits fields use bits, not bytes", f.Name)
+		}
+	default:
+		return fmt.Errorf("field %s: invalid kind %s", f.Name, f.Kind)
+	}
+
+	if f.Kind != "" && f.Kind != FieldKindUint && f.Kind != FieldKindInt &&
+		(len(f.Values) > 0 || f.Min != nil || f.Max != nil) {
+		return fmt.Errorf("field %s: values/min/max are only allowed for integer fields", f.Name)
+	}
+	return nil
+}
+
+// width 获取整数字段位宽
+func (f *LayoutField) width() int {
+	if f.Bytes > 0 {
+		return f.Bytes * 8
+	}
+	return f.Bits
+}
+
+// copyNames 复制字段名称集合
+func copyNames(names map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(names))
+	for name := range names {
+		out[name] = true
+	}
+	return out
+}
+
+// layoutCodec 基于布局定义的编解码器
+// 解码结果为map[string]interface{}，编码输入为同样结构的map（也可来自JSON）
+type layoutCodec struct {
+	layout *Layout
+}
+
+// NewLayoutCodec 根据布局定义创建编解码器
+// 参数: layout - 布局定义
+// 返回: 编解码器和错误信息
+func NewLayoutCodec(layout *Layout) (Codec, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	return &layoutCodec{layout: layout}, nil
+}
+
+// Type 获取信息类型编号
+func (lc *layoutCodec) Type() uint16 { return lc.layout.Type }
+
+// Name 获取类型名称
+func (lc *layoutCodec) Name() string { return lc.layout.Name }
+
+// Decode 按布局解码报文体
+// 参数: data - 报文体
+// 返回: 字段值映射和错误信息
+func (lc *layoutCodec) Decode(data []byte) (interface{}, error) {
+	c := &fieldCodec{decoding: true, r: NewBitReader(data)}
+	scope := &layoutScope{values: make(map[string]interface{})}
+	runLayoutFields(c, lc.layout.Fields, scope)
+	if c.err != nil {
+		return nil, fmt.Errorf("decode %s: %v", lc.layout.Name, c.err)
+	}
+	return scope.values, nil
+}
+
+// Encode 按布局编码报文体
+// 参数: v - 字段值映射
+// 返回: 报文体和错误信息
+func (lc *layoutCodec) Encode(v interface{}) ([]byte, error) {
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("encode %s: unexpected value type %T", lc.layout.Name, v)
+	}
+
+	c := &fieldCodec{w: NewBitWriter()}
+	runLayoutFields(c, lc.layout.Fields, &layoutScope{values: values})
+	if c.err != nil {
+		return nil, fmt.Errorf("encode %s: %v", lc.layout.Name, c.err)
+	}
+	return c.w.Bytes(), nil
+}
+
+// layoutScope 字段值作用域
+// 引用字段时先在当前层查找，找不到再到外层查找
+type layoutScope struct {
+	values map[string]interface{}
+	parent *layoutScope
+}
+
+// lookup 查找整数字段值
+func (s *layoutScope) lookup(name string) (int64, error) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if v, exists := scope.values[name]; exists {
+			return toInt64(v)
+		}
+	}
+	return 0, fmt.Errorf("field %s not found", name)
+}
+
+// runLayoutFields 按字段列表编解码
+// 参数: c - 字段编解码器, fields - 字段列表, scope - 当前作用域
+func runLayoutFields(c *fieldCodec, fields []LayoutField, scope *layoutScope) {
+	for i := range fields {
+		if c.err != nil {
+			return
+		}
+
+		f := &fields[i]
+		if f.Offset != nil {
+			c.seek(*f.Offset)
+		}
+
+		switch {
+		case f.Switch != "":
+			runLayoutSwitch(c, f, scope)
+		case f.Repeat != "":
+			runLayoutRepeat(c, f, scope)
+		case f.Kind == FieldKindGroup:
+			runLayoutGroup(c, f, scope)
+		default:
+			runLayoutScalar(c, f, scope)
+		}
+	}
+}
+
+// runLayoutSwitch 编解码分支字段
+func runLayoutSwitch(c *fieldCodec, f *LayoutField, scope *layoutScope) {
+	key, err := scope.lookup(f.Switch)
+	if err != nil {
+		c.fail(fmt.Errorf("switch: %v", err))
+		return
+	}
+
+	branch, exists := f.Cases[strconv.FormatInt(key, 10)]
+	if !exists {
+		if f.Default == nil {
+			c.fail(fmt.Errorf("switch on %s: no case for value %d", f.Switch, key))
+			return
+		}
+		branch = f.Default
+	}
+	runLayoutFields(c, branch, scope)
+}
+
+// runLayoutGroup 编解码字段组
+func runLayoutGroup(c *fieldCodec, f *LayoutField, scope *layoutScope) {
+	child := &layoutScope{parent: scope}
+	if c.decoding {
+		child.values = make(map[string]interface{})
+		runLayoutFields(c, f.Fields, child)
+		scope.values[f.Name] = child.values
+		return
+	}
+
+	values, ok := scope.values[f.Name].(map[string]interface{})
+	if !ok {
+		c.fail(fmt.Errorf("field %s: expected object, got %T", f.Name, scope.values[f.Name]))
+		return
+	}
+	child.values = values
+	runLayoutFields(c, f.Fields, child)
+}
+
+// runLayoutRepeat 编解码重复段
+func runLayoutRepeat(c *fieldCodec, f *LayoutField, scope *layoutScope) {
+	count := -1
+	if f.Repeat != RepeatUntilEnd {
+		n, err := strconv.Atoi(f.Repeat)
+		if err != nil {
+			v, lookupErr := scope.lookup(f.Repeat)
+			if lookupErr != nil {
+				c.fail(fmt.Errorf("field %s repeat: %v", f.Name, lookupErr))
+				return
+			}
+			n = int(v)
+		}
+		if n < 0 {
+			c.fail(fmt.Errorf("field %s: negative repeat count %d", f.Name, n))
+			return
+		}
+		count = n
+	}
+
+	if c.decoding {
+		items := make([]interface{}, 0)
+		for i := 0; (count < 0 && c.r.Remaining() > 0) || i < count; i++ {
+			child := &layoutScope{values: make(map[string]interface{}), parent: scope}
+			runLayoutFields(c, f.Fields, child)
+			if c.err != nil {
+				c.err = fmt.Errorf("field %s[%d]: %v", f.Name, i, c.err)
+				return
+			}
+			items = append(items, child.values)
+		}
+		scope.values[f.Name] = items
+		return
+	}
+
+	items, err := toList(scope.values[f.Name])
+	if err != nil {
+		c.fail(fmt.Errorf("field %s: %v", f.Name, err))
+		return
+	}
+	if count >= 0 && len(items) != count {
+		c.fail(fmt.Errorf("field %s: %d items, repeat count is %d", f.Name, len(items), count))
+		return
+	}
+	for i, item := range items {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			c.fail(fmt.Errorf("field %s[%d]: expected object, got %T", f.Name, i, item))
+			return
+		}
+		runLayoutFields(c, f.Fields, &layoutScope{values: values, parent: scope})
+	}
+}
+
+// runLayoutScalar 编解码标量字段
+func runLayoutScalar(c *fieldCodec, f *LayoutField, scope *layoutScope) {
+	switch f.Kind {
+	case "", FieldKindUint, FieldKindInt:
+		runLayoutInteger(c, f, scope)
+
+	case FieldKindBytes, FieldKindString:
+		n := f.Bytes
+		if f.Length != "" {
+			v, err := scope.lookup(f.Length)
+			if err != nil {
+				c.fail(fmt.Errorf("field %s length: %v", f.Name, err))
+				return
+			}
+			n = int(v)
+		}
+
+		if c.decoding {
+			if n == 0 && f.Length == "" {
+				n = c.r.Remaining() / 8
+			}
+			var raw HexBytes
+			c.bytes(&raw, n)
+			if f.Kind == FieldKindString {
+				scope.values[f.Name] = strings.TrimRight(string(raw), "\x00")
+			} else {
+				scope.values[f.Name] = raw
+			}
+			return
+		}
+
+		raw, err := toBytes(scope.values[f.Name], f.Kind)
+		if err != nil {
+			c.fail(fmt.Errorf("field %s: %v", f.Name, err))
+			return
+		}
+		switch {
+		case f.Length != "" && len(raw) != n:
+			c.fail(fmt.Errorf("field %s: length %d does not match %s=%d", f.Name, len(raw), f.Length, n))
+			return
+		case f.Length == "" && n > 0:
+			if len(raw) > n {
+				c.fail(fmt.Errorf("field %s: length %d exceeds %d bytes", f.Name, len(raw), n))
+				return
+			}
+			padded := make(HexBytes, n)
+			copy(padded, raw)
+			raw = padded
+		}
+		c.bytes(&raw, len(raw))
+
+	case FieldKindBits:
+		n := f.Bits
+		if f.Length != "" {
+			v, err := scope.lookup(f.Length)
+			if err != nil {
+				c.fail(fmt.Errorf("field %s length: %v", f.Name, err))
+				return
+			}
+			n = int(v)
+		} else if n == 0 {
+			n = -1
+		}
+
+		if c.decoding {
+			var s string
+			c.bitString(&s, n)
+			scope.values[f.Name] = s
+			return
+		}
+
+		s, ok := scope.values[f.Name].(string)
+		if !ok {
+			c.fail(fmt.Errorf("field %s: expected bit string, got %T", f.Name, scope.values[f.Name]))
+			return
+		}
+		c.bitString(&s, n)
+	}
+}
+
+// runLayoutInteger 编解码整数字段并校验取值
+func runLayoutInteger(c *fieldCodec, f *LayoutField, scope *layoutScope) {
+	width := f.width()
+	little := f.Endian == EndianLittle
+	signed := f.Kind == FieldKindInt
+
+	if c.decoding {
+		var raw uint64
+		c.uint(&raw, width)
+		if c.err != nil {
+			return
+		}
+		if little {
+			raw = swapBytes(raw, width/8)
+		}
+
+		if signed {
+			value := signExtend(raw, width)
+			if err := checkLayoutValue(f, value); err != nil {
+				c.fail(err)
+				return
+			}
+			scope.values[f.Name] = value
+			return
+		}
+
+		if err := checkLayoutValue(f, int64(raw)); err != nil {
+			c.fail(err)
+			return
+		}
+		scope.values[f.Name] = raw
+		return
+	}
+
+	value, err := toInt64(scope.values[f.Name])
+	if err != nil {
+		c.fail(fmt.Errorf("field %s: %v", f.Name, err))
+		return
+	}
+	if err := checkLayoutValue(f, value); err != nil {
+		c.fail(err)
+		return
+	}
+
+	var raw uint64
+	if signed {
+		min, max := int64(-1)<<uint(width-1), int64(1)<<uint(width-1)-1
+		if width < 64 && (value < min || value > max) {
+			c.fail(fmt.Errorf("field %s: value %d does not fit in %d signed bits", f.Name, value, width))
+			return
+		}
+		raw = uint64(value)
+		if width < 64 {
+			raw &= 1<<uint(width) - 1
+		}
+	} else {
+		if value < 0 {
+			c.fail(fmt.Errorf("field %s: negative value %d for unsigned field", f.Name, value))
+			return
+		}
+		raw = uint64(value)
+	}
+	if little {
+		raw = swapBytes(raw, width/8)
+	}
+	c.uint(&raw, width)
+}
+
+// checkLayoutValue 校验整数字段取值
+func checkLayoutValue(f *LayoutField, value int64) error {
+	if len(f.Values) > 0 {
+		allowed := false
+		for _, v := range f.Values {
+			if v == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("field %s: value %d not in allowed values %v", f.Name, value, f.Values)
+		}
+	}
+	if f.Min != nil && value < *f.Min {
+		return fmt.Errorf("field %s: value %d below minimum %d", f.Name, value, *f.Min)
+	}
+	if f.Max != nil && value > *f.Max {
+		return fmt.Errorf("field %s: value %d above maximum %d", f.Name, value, *f.Max)
+	}
+	return nil
+}
+
+// swapBytes 翻转低n个字节的字节序
+func swapBytes(v uint64, n int) uint64 {
+	var out uint64
+	for i := 0; i < n; i++ {
+		out = out<<8 | v&0xFF
+		v >>= 8
+	}
+	return out
+}
+
+// signExtend 将width位补码扩展为int64
+func signExtend(v uint64, width int) int64 {
+	shift := uint(64 - width)
+	return int64(v<<shift) >> shift
+}
+
+// toInt64 将字段值转换为int64（支持JSON解析得到的float64）
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint8:
+		return int64(n), nil
+	case float64:
+		if n != float64(int64(n)) {
+			return 0, fmt.Errorf("non-integer value %v", n)
+		}
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", v)
+	}
+}
+
+// toBytes 将字段值转换为字节数组
+// bytes字段接受字节数组或十六进制字符串，string字段接受字符串
+func toBytes(v interface{}, kind string) (HexBytes, error) {
+	switch b := v.(type) {
+	case HexBytes:
+		return b, nil
+	case []byte:
+		return b, nil
+	case string:
+		if kind == FieldKindString {
+			return HexBytes(b), nil
+		}
+		decoded, err := hex.DecodeString(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string: %v", err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("expected %s, got %T", kind, v)
+	}
+}
+
+// toList 将字段值转换为列表
+func toList(v interface{}) ([]interface{}, error) {
+	switch items := v.(type) {
+	case []interface{}:
+		return items, nil
+	case []map[string]interface{}:
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = item
+		}
+		return out, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected list, got %T", v)
+	}
+}
+
+// RegisterLayouts 将布局定义注册为编解码器
+// 参数: layouts - 布局定义列表
+// 返回: 错误信息（类型编号冲突等）
+func (r *Registry) RegisterLayouts(layouts []*Layout) error {
+	for _, layout := range layouts {
+		codec, err := NewLayoutCodec(layout)
+		if err != nil {
+			return err
+		}
+		if err := r.Register(codec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
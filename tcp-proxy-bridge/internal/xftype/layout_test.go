@@ -0,0 +1,323 @@
+// internal/xftype/layout_test.go
+package xftype
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// parseLayout 解析YAML格式的单个布局定义
+func parseLayout(t *testing.T, src string) (*Layout, error) {
+	t.Helper()
+	var layout Layout
+	if err := yaml.Unmarshal([]byte(src), &layout); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	return &layout, layout.Validate()
+}
+
+func TestLayoutCodecDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		data   []byte
+		want   map[string]interface{}
+	}{
+		{
+			name: "integers",
+			layout: `
+name: integers
+fields:
+  - {name: high, bits: 4}
+  - {name: low, bits: 4}
+  - {name: little, bytes: 2, endian: little}
+  - {name: signed, kind: int, bits: 8}
+  - {name: big, bytes: 4}
+`,
+			data: []byte{0xA5, 0x34, 0x12, 0xFE, 0x00, 0x01, 0x00, 0x02},
+			want: map[string]interface{}{
+				"high": uint64(0xA), "low": uint64(0x5), "little": uint64(0x1234),
+				"signed": int64(-2), "big": uint64(0x00010002),
+			},
+		},
+		{
+			name: "string and bytes",
+			layout: `
+name: strings
+fields:
+  - {name: raw_length, bits: 8}
+  - {name: label, kind: string, bytes: 6}
+  - {name: raw, kind: bytes, length: raw_length}
+  - {name: rest, kind: bytes}
+`,
+			data: []byte{0x02, 'a', 'b', 0x00, 0x00, 0x00, 0x00, 0xDE, 0xAD, 0x01, 0x02},
+			want: map[string]interface{}{
+				"raw_length": uint64(2), "label": "ab",
+				"raw": HexBytes{0xDE, 0xAD}, "rest": HexBytes{0x01, 0x02},
+			},
+		},
+		{
+			name: "bits and offset",
+			layout: `
+name: bits
+fields:
+  - {name: flags, kind: bits, bits: 3}
+  - {name: value, bits: 8, offset: 16}
+  - {name: tail, kind: bits}
+`,
+			data: []byte{0xA0, 0x00, 0x07, 0xF0},
+			want: map[string]interface{}{"flags": "101", "value": uint64(7), "tail": "11110000"},
+		},
+		{
+			name:   "switch case",
+			layout: switchLayout,
+			data:   []byte{0x01, 0x2A},
+			want:   map[string]interface{}{"kind": uint64(1), "short": uint64(42)},
+		},
+		{
+			name:   "switch default",
+			layout: switchLayout,
+			data:   []byte{0x09, 0x01, 0x02},
+			want:   map[string]interface{}{"kind": uint64(9), "long": uint64(0x0102)},
+		},
+		{
+			name: "group and repeat",
+			layout: `
+name: nested
+fields:
+  - {name: count, bits: 8}
+  - name: header
+    kind: group
+    fields:
+      - {name: flags, bits: 8}
+  - name: items
+    repeat: count
+    fields:
+      - {name: id, bits: 8}
+      - {name: size, bits: 8}
+      - {name: payload, kind: bytes, length: size}
+  - name: trailer
+    repeat: "*"
+    fields:
+      - {name: v, bits: 8}
+`,
+			data: []byte{0x02, 0x80, 0x01, 0x01, 0xAA, 0x02, 0x00, 0x07, 0x08},
+			want: map[string]interface{}{
+				"count":  uint64(2),
+				"header": map[string]interface{}{"flags": uint64(0x80)},
+				"items": []interface{}{
+					map[string]interface{}{"id": uint64(1), "size": uint64(1), "payload": HexBytes{0xAA}},
+					map[string]interface{}{"id": uint64(2), "size": uint64(0), "payload": HexBytes{}},
+				},
+				"trailer": []interface{}{
+					map[string]interface{}{"v": uint64(7)},
+					map[string]interface{}{"v": uint64(8)},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := parseLayout(t, tt.layout)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			codec, err := NewLayoutCodec(layout)
+			if err != nil {
+				t.Fatalf("NewLayoutCodec: %v", err)
+			}
+
+			decoded, err := codec.Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.want) {
+				t.Fatalf("Decode = %#v, want %#v", decoded, tt.want)
+			}
+
+			// 解码结果重新编码后应与原始报文一致
+			encoded, err := codec.Encode(decoded)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if !bytes.Equal(encoded, tt.data) {
+				t.Errorf("Encode = %x, want %x", encoded, tt.data)
+			}
+		})
+	}
+}
+
+// switchLayout 带默认分支的分支字段布局
+const switchLayout = `
+name: switch
+fields:
+  - {name: kind, bits: 8}
+  - switch: kind
+    cases:
+      "1":
+        - {name: short, bits: 8}
+    default:
+      - {name: long, bits: 16}
+`
+
+func TestLayoutCodecDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		data   []byte
+		errMsg string
+	}{
+		{
+			name:   "value not allowed",
+			layout: "name: v\nfields:\n  - {name: version, bits: 8, values: [1, 2]}\n",
+			data:   []byte{0x03},
+			errMsg: "not in allowed values",
+		},
+		{
+			name:   "above maximum",
+			layout: "name: v\nfields:\n  - {name: count, bits: 8, max: 10}\n",
+			data:   []byte{0x0B},
+			errMsg: "above maximum",
+		},
+		{
+			name:   "below minimum",
+			layout: "name: v\nfields:\n  - {name: offset, kind: int, bits: 8, min: -1}\n",
+			data:   []byte{0xFE},
+			errMsg: "below minimum",
+		},
+		{
+			name:   "truncated",
+			layout: "name: v\nfields:\n  - {name: value, bytes: 4}\n",
+			data:   []byte{0x00, 0x01},
+			errMsg: "insufficient data",
+		},
+		{
+			name:   "length beyond data",
+			layout: "name: v\nfields:\n  - {name: n, bits: 8}\n  - {name: s, kind: string, length: n}\n",
+			data:   []byte{0x05, 'a'},
+			errMsg: "insufficient data",
+		},
+		{
+			name:   "switch without matching case",
+			layout: "name: v\nfields:\n  - {name: k, bits: 8}\n  - switch: k\n    cases:\n      \"1\":\n        - {name: x, bits: 8}\n",
+			data:   []byte{0x02, 0x00},
+			errMsg: "no case for value 2",
+		},
+		{
+			name:   "repeat item truncated",
+			layout: "name: v\nfields:\n  - name: items\n    repeat: \"2\"\n    fields:\n      - {name: v, bits: 16}\n",
+			data:   []byte{0x00, 0x01, 0x02},
+			errMsg: "items[1]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := parseLayout(t, tt.layout)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			codec, _ := NewLayoutCodec(layout)
+			if _, err := codec.Decode(tt.data); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Decode error = %v, want it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestLayoutValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		errMsg string
+	}{
+		{name: "no name", layout: "fields:\n  - {name: a, bits: 8}\n", errMsg: "has no name"},
+		{name: "no fields", layout: "name: v\n", errMsg: "has no fields"},
+		{name: "bits and bytes", layout: "name: v\nfields:\n  - {name: a, bits: 8, bytes: 1}\n", errMsg: "mutually exclusive"},
+		{name: "integer too wide", layout: "name: v\nfields:\n  - {name: a, bits: 65}\n", errMsg: "1-64 bits"},
+		{name: "little endian odd width", layout: "name: v\nfields:\n  - {name: a, bits: 12, endian: little}\n", errMsg: "multiple of 8"},
+		{name: "invalid kind", layout: "name: v\nfields:\n  - {name: a, kind: float, bits: 32}\n", errMsg: "invalid kind"},
+		{name: "duplicate field", layout: "name: v\nfields:\n  - {name: a, bits: 8}\n  - {name: a, bits: 8}\n", errMsg: "duplicate field"},
+		{name: "undefined length", layout: "name: v\nfields:\n  - {name: s, kind: string, length: n}\n", errMsg: "undefined field n"},
+		{name: "undefined switch", layout: "name: v\nfields:\n  - switch: k\n    cases:\n      \"1\":\n        - {name: x, bits: 8}\n", errMsg: "undefined field k"},
+		{name: "invalid case value", layout: "name: v\nfields:\n  - {name: k, bits: 8}\n  - switch: k\n    cases:\n      one:\n        - {name: x, bits: 8}\n", errMsg: "invalid case value"},
+		{name: "undefined repeat", layout: "name: v\nfields:\n  - name: items\n    repeat: count\n    fields:\n      - {name: v, bits: 8}\n", errMsg: "undefined field count"},
+		{name: "values on bytes", layout: "name: v\nfields:\n  - {name: a, kind: bytes, bytes: 2, values: [1]}\n", errMsg: "only allowed for integer fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseLayout(t, tt.layout); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate error = %v, want it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestExampleLayoutFile(t *testing.T) {
+	layouts, err := LoadLayoutFiles([]string{"../../configs/layouts/example.yaml"})
+	if err != nil {
+		t.Fatalf("LoadLayoutFiles: %v", err)
+	}
+	r := NewRegistry()
+	if err := r.RegisterLayouts(layouts); err != nil {
+		t.Fatalf("RegisterLayouts: %v", err)
+	}
+
+	// PartnerTrack: 版本2，24位接收方，1个点，备注"ok"
+	body := []byte{
+		0x02, 0x00, 0x12, 0x34, 0x56, // version, receiver_type, receiver
+		0x01, 0x00, // point_count（小端）
+		0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x10, 0x00, 0x20, // longitude=-1, latitude=16, speed=32
+		0x02, 'o', 'k', // remark_length, remark
+	}
+	segment := append([]byte{0x07, 0xEA, 0x0A, 0x11, 0x01, 0x2D, 0x00, byte(len(body))}, body...)
+
+	seg, err := r.DecodeSegment(segment)
+	if err != nil {
+		t.Fatalf("DecodeSegment: %v", err)
+	}
+	if len(seg.Items) != 1 || seg.Items[0].Error != "" {
+		t.Fatalf("items = %+v, want one decoded item", seg.Items)
+	}
+	item := seg.Items[0]
+	if item.Name != "PartnerTrack" {
+		t.Errorf("item name = %s, want PartnerTrack", item.Name)
+	}
+
+	got, _ := json.Marshal(item.Body)
+	want := `{"point_count":1,"points":[{"latitude":16,"longitude":-1,"speed":32}],"receiver":1193046,"receiver_type":0,"remark":"ok","remark_length":2,"version":2}`
+	if string(got) != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+
+	// JSON往返后重新编码，与原始数据段一致
+	var decoded DataSegment
+	raw, _ := json.Marshal(seg)
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	encoded, err := r.EncodeSegment(&decoded)
+	if err != nil {
+		t.Fatalf("EncodeSegment: %v", err)
+	}
+	if !bytes.Equal(encoded, segment) {
+		t.Errorf("EncodeSegment = %x, want %x", encoded, segment)
+	}
+
+	// 版本号不在允许范围内时记录错误，保留原始内容
+	segment[8] = 0x03
+	seg, err = r.DecodeSegment(segment)
+	if err != nil {
+		t.Fatalf("DecodeSegment: %v", err)
+	}
+	if seg.Items[0].Error == "" || seg.Items[0].Body != nil {
+		t.Errorf("invalid version decoded without error: %+v", seg.Items[0])
+	}
+}
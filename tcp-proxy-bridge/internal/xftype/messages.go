@@ -0,0 +1,484 @@
+// internal/xftype/messages.go
+package xftype
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 信息类型编号
+const (
+	TypeGroundOutbound   uint16 = 1   // XFType001 地面出站报文
+	TypeInstruction      uint16 = 2   // XFType002 指令报文
+	TypePosition         uint16 = 3   // XFType003 位置报告
+	TypeMessage          uint16 = 4   // XFType004 通信报文
+	TypeFixedDomain      uint16 = 5   // XFType005 固定域广播
+	TypeNetworkMgmt      uint16 = 6   // XFType006 网管报文
+	TypeReceipt          uint16 = 54  // XFType054 回执
+	TypeAuthResponse     uint16 = 99  // XFType099 认证应答
+	TypeAuthRequest      uint16 = 100 // XFType100 认证请求
+	TypeShortMessage     uint16 = 153 // XFType153 短报文
+	TypeShortMessageResp uint16 = 154 // XFType154 短报文应答
+	TypeHeartbeat        uint16 = 203 // XFType203 心跳
+)
+
+// 通信报文类别
+const (
+	MessageClassPointToPoint uint8 = 0x2 // 点对点报文（010）
+)
+
+// 通信报文编码方式
+const (
+	CodingGB2312 uint8 = 0x0 // 汉字（GB2312）
+	CodingBCD    uint8 = 0x1 // BCD码
+	CodingMixed  uint8 = 0x2 // 混合编码
+)
+
+// builtinCodecs 内置编解码器列表
+func builtinCodecs() []Codec {
+	return []Codec{
+		newStructCodec("XFType001", func() Message { return &XFType001{} }),
+		newStructCodec("XFType002", func() Message { return &XFType002{} }),
+		newStructCodec("XFType003", func() Message { return &XFType003{} }),
+		newStructCodec("XFType004", func() Message { return &XFType004{} }),
+		newStructCodec("XFType005", func() Message { return &XFType005{} }),
+		newStructCodec("XFType006", func() Message { return &XFType006{} }),
+		newStructCodec("XFType054", func() Message { return &XFType054{} }),
+		newStructCodec("XFType099", func() Message { return &XFType099{} }),
+		newStructCodec("XFType100", func() Message { return &XFType100{} }),
+		newStructCodec("XFType153", func() Message { return &XFType153{} }),
+		newStructCodec("XFType154", func() Message { return &XFType154{} }),
+		newStructCodec("XFType203", func() Message { return &XFType203{} }),
+	}
+}
+
+// receiverAddress 按用户类型编解码接收地址
+// 用户类型0使用24位地址，1使用48位地址
+func receiverAddress(c *fieldCodec, userType uint8, addr *uint64) {
+	switch userType {
+	case 0:
+		c.u64(addr, 24)
+	case 1:
+		c.u64(addr, 48)
+	default:
+		c.fail(fmt.Errorf("unsupported receiver type %d", userType))
+	}
+}
+
+// XFType001 地面出站报文
+type XFType001 struct {
+	Departure     uint8  `json:"departure"`      // 出站类型
+	Sender        uint32 `json:"sender"`         // 发信方地址
+	Satellite     uint8  `json:"satellite"`      // 卫星号
+	Beam          uint8  `json:"beam"`           // 波束号
+	ReceiverType  uint8  `json:"receiver_type"`  // 接收方类型
+	Receiver      uint64 `json:"receiver"`       // 接收方地址
+	MessageLength uint16 `json:"message_length"` // 电文长度
+	Remain        uint8  `json:"remain"`         // 保留位
+	Frame         uint8  `json:"frame"`          // 帧标识
+	Emergency     uint8  `json:"emergency"`      // 紧急标识
+	Report        uint8  `json:"report"`         // 报告方式
+	SegmentSender uint32 `json:"segment_sender"` // 地面段发信方地址
+	Location      string `json:"location"`       // 位置信息（93位二进制串）
+	Content       string `json:"content"`        // 电文内容（二进制串）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType001) MessageType() uint16 { return TypeGroundOutbound }
+
+func (m *XFType001) fields(c *fieldCodec) {
+	c.u8(&m.Departure, 8)
+	c.u32(&m.Sender, 24)
+	c.u8(&m.Satellite, 8)
+	c.u8(&m.Beam, 8)
+	c.u8(&m.ReceiverType, 8)
+	c.u64(&m.Receiver, 48)
+	c.u16(&m.MessageLength, 16)
+	c.u8(&m.Remain, 1)
+	c.u8(&m.Frame, 2)
+	c.u8(&m.Emergency, 2)
+	c.u8(&m.Report, 3)
+	c.u32(&m.SegmentSender, 24)
+	c.bitString(&m.Location, 93)
+	c.bitString(&m.Content, -1)
+}
+
+// XFType002 指令报文
+type XFType002 struct {
+	Departure       uint8  `json:"departure"`        // 出站类型
+	ReceiverType    uint8  `json:"receiver_type"`    // 接收方类型
+	Receiver        uint64 `json:"receiver"`         // 接收方地址
+	MessageLength   uint16 `json:"message_length"`   // 电文长度
+	Remain          uint8  `json:"remain"`           // 保留位
+	Frame           uint8  `json:"frame"`            // 帧标识
+	Emergency       uint8  `json:"emergency"`        // 紧急标识
+	Report          uint8  `json:"report"`           // 报告方式
+	Sender          uint32 `json:"sender"`           // 发信方地址
+	InstructionType uint8  `json:"instruction_type"` // 指令类型
+	InstructionCode string `json:"instruction_code"` // 指令代码（二进制串）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType002) MessageType() uint16 { return TypeInstruction }
+
+func (m *XFType002) fields(c *fieldCodec) {
+	c.u8(&m.Departure, 8)
+	c.u8(&m.ReceiverType, 8)
+	c.u64(&m.Receiver, 48)
+	c.u16(&m.MessageLength, 16)
+	c.u8(&m.Remain, 1)
+	c.u8(&m.Frame, 2)
+	c.u8(&m.Emergency, 2)
+	c.u8(&m.Report, 3)
+	c.u32(&m.Sender, 24)
+	c.u8(&m.InstructionType, 4)
+	c.bitString(&m.InstructionCode, -1)
+}
+
+// Position 位置信息
+type Position struct {
+	WeekIndex   uint8  `json:"week_index"`   // 周计数（0本周，1上周）
+	WeekSeconds uint32 `json:"week_seconds"` // 周内秒
+	LonSign     uint8  `json:"lon_sign"`     // 经度符号（1为西经）
+	Longitude   uint32 `json:"longitude"`    // 经度
+	LatSign     uint8  `json:"lat_sign"`     // 纬度符号（1为南纬）
+	Latitude    uint32 `json:"latitude"`     // 纬度
+	HeightSign  uint8  `json:"height_sign"`  // 高程符号（1为负）
+	Height      uint32 `json:"height"`       // 高程（分米）
+}
+
+// Time 计算定位时刻
+// 参数: now - 参考时间（接收时间）
+// 返回: 定位时刻
+func (p *Position) Time(now time.Time) time.Time {
+	return weekTime(now, p.WeekIndex, p.WeekSeconds)
+}
+
+// XFType003 位置报告
+type XFType003 struct {
+	Departure     uint8     `json:"departure"`          // 出站类型
+	ReceiverType  uint8     `json:"receiver_type"`      // 接收方类型
+	Receiver      uint64    `json:"receiver"`           // 接收方地址
+	MessageLength uint16    `json:"message_length"`     // 电文长度
+	Remain        uint8     `json:"remain"`             // 保留位
+	Frame         uint8     `json:"frame"`              // 帧标识
+	Flag          uint8     `json:"flag"`               // 位置报告标志
+	Report        uint8     `json:"report"`             // 报告方式（标志为01或11时存在）
+	Sender        uint32    `json:"sender"`             // 发信方地址
+	Position      *Position `json:"position,omitempty"` // 位置信息（标志为01时存在）
+	Status        string    `json:"status"`             // 状态信息（二进制串）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType003) MessageType() uint16 { return TypePosition }
+
+func (m *XFType003) fields(c *fieldCodec) {
+	c.u8(&m.Departure, 8)
+	c.u8(&m.ReceiverType, 8)
+	receiverAddress(c, m.ReceiverType, &m.Receiver)
+	c.u16(&m.MessageLength, 16)
+	c.u8(&m.Remain, 1)
+	c.u8(&m.Frame, 2)
+	c.u8(&m.Flag, 2)
+	if m.Flag == 0x1 || m.Flag == 0x3 {
+		c.u8(&m.Report, 3)
+	}
+	c.u32(&m.Sender, 24)
+	if m.Flag == 0x1 {
+		if c.decoding {
+			m.Position = &Position{}
+		} else if m.Position == nil {
+			c.fail(fmt.Errorf("position required when flag is 01"))
+			return
+		}
+		p := m.Position
+		c.u8(&p.WeekIndex, 1)
+		c.u32(&p.WeekSeconds, 20)
+		c.u8(&p.LonSign, 1)
+		c.u32(&p.Longitude, 23)
+		c.u8(&p.LatSign, 1)
+		c.u32(&p.Latitude, 22)
+		c.u8(&p.HeightSign, 1)
+		c.u32(&p.Height, 24)
+	}
+	c.bitString(&m.Status, -1)
+}
+
+// XFType004 通信报文
+type XFType004 struct {
+	Departure     uint8  `json:"departure"`      // 出站类型
+	ReceiverType  uint8  `json:"receiver_type"`  // 接收方类型
+	Receiver      uint64 `json:"receiver"`       // 接收方地址
+	MessageLength uint16 `json:"message_length"` // 信息段长度（位）
+	Remain        uint8  `json:"remain"`         // 保留位
+	Communication uint8  `json:"communication"`  // 通信方式
+	Class         uint8  `json:"class"`          // 报文类别
+
+	// 以下字段仅在点对点报文（类别010）中存在
+	SenderType  uint8  `json:"sender_type"`    // 发信方类型
+	ActualTime  uint8  `json:"actual_time"`    // 是否实时
+	FirstFrame  uint8  `json:"first_frame"`    // 首帧标识
+	Consecutive uint8  `json:"consecutive"`    // 连续帧标识
+	Sender      uint64 `json:"sender"`         // 发信方地址
+	WeekIndex   uint8  `json:"week_index"`     // 周计数
+	WeekSeconds uint32 `json:"week_seconds"`   // 周内秒
+	Coding      uint8  `json:"coding"`         // 编码方式
+	Data        string `json:"data"`           // 电文数据（二进制串）
+	Text        string `json:"text,omitempty"` // BCD编码时的解码文本
+
+	Rest string `json:"rest"` // 剩余位（二进制串）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType004) MessageType() uint16 { return TypeMessage }
+
+func (m *XFType004) fields(c *fieldCodec) {
+	c.u8(&m.Departure, 8)
+	c.u8(&m.ReceiverType, 8)
+	receiverAddress(c, m.ReceiverType, &m.Receiver)
+	c.u16(&m.MessageLength, 16)
+	start := c.pos()
+	c.u8(&m.Remain, 1)
+	c.u8(&m.Communication, 2)
+	c.u8(&m.Class, 3)
+	if m.Class == MessageClassPointToPoint {
+		c.u8(&m.SenderType, 1)
+		c.u8(&m.ActualTime, 1)
+		c.u8(&m.FirstFrame, 1)
+		c.u8(&m.Consecutive, 1)
+		receiverAddress(c, m.SenderType, &m.Sender)
+		c.u8(&m.WeekIndex, 1)
+		c.u32(&m.WeekSeconds, 20)
+		c.u8(&m.Coding, 4)
+
+		// 电文数据长度受信息段长度约束
+		dataBits := int(m.MessageLength) - (c.pos() - start)
+		if c.decoding {
+			if dataBits < 0 || dataBits > c.r.Remaining() {
+				dataBits = c.r.Remaining()
+			}
+			c.bitString(&m.Data, dataBits)
+			if m.Coding == CodingBCD {
+				m.Text = decodeBCD(m.Data)
+			}
+		} else {
+			c.bitString(&m.Data, len(m.Data))
+		}
+	}
+	c.bitString(&m.Rest, -1)
+}
+
+// XFType005 固定域广播
+type XFType005 struct {
+	Satellite   uint8    `json:"satellite"`    // 卫星号
+	Beam        uint8    `json:"beam"`         // 波束号
+	SuperFrame  uint32   `json:"super_frame"`  // 超帧号
+	Week        uint16   `json:"week"`         // 周计数
+	Frequency   uint8    `json:"frequency"`    // 频点
+	Delay       uint8    `json:"delay"`        // 时延
+	Integrity   uint8    `json:"integrity"`    // 完好性
+	Remain1     uint8    `json:"remain1"`      // 保留位1
+	Remain2     uint16   `json:"remain2"`      // 保留位2
+	RepeatFlags uint64   `json:"repeat_flags"` // 重复标志
+	Rest        HexBytes `json:"rest"`         // 剩余内容
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType005) MessageType() uint16 { return TypeFixedDomain }
+
+func (m *XFType005) fields(c *fieldCodec) {
+	c.u8(&m.Satellite, 6)
+	c.u8(&m.Beam, 4)
+	c.u32(&m.SuperFrame, 17)
+	c.u16(&m.Week, 13)
+	c.u8(&m.Frequency, 4)
+	c.u8(&m.Delay, 4)
+	c.u8(&m.Integrity, 2)
+	c.u8(&m.Remain1, 5)
+	c.u16(&m.Remain2, 9)
+	c.u64(&m.RepeatFlags, 64)
+	c.restBytes(&m.Rest)
+}
+
+// XFType006 网管报文
+type XFType006 struct {
+	Flag          uint8  `json:"flag"`           // 标志
+	Sender        uint32 `json:"sender"`         // 发信方地址
+	MessageLength uint16 `json:"message_length"` // 电文长度
+	Remain        uint8  `json:"remain"`         // 保留位
+	NetworkMgmt   uint8  `json:"network_mgmt"`   // 网管类型
+	Job           uint8  `json:"job"`            // 作业类型
+	Content       string `json:"content"`        // 网管内容（二进制串）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType006) MessageType() uint16 { return TypeNetworkMgmt }
+
+func (m *XFType006) fields(c *fieldCodec) {
+	c.u8(&m.Flag, 8)
+	c.u32(&m.Sender, 24)
+	c.u16(&m.MessageLength, 16)
+	c.u8(&m.Remain, 1)
+	c.u8(&m.NetworkMgmt, 2)
+	c.u8(&m.Job, 5)
+	c.bitString(&m.Content, -1)
+}
+
+// XFType054 回执
+type XFType054 struct {
+	MessageNo  uint32 `json:"message_no"`  // 报文编号
+	SenderType uint8  `json:"sender_type"` // 发信方类型
+	Sender     uint64 `json:"sender"`      // 发信方地址
+	Receiver   uint32 `json:"receiver"`    // 接收方地址
+	Hour       uint8  `json:"hour"`        // 时
+	Minute     uint8  `json:"minute"`      // 分
+	Second     uint8  `json:"second"`      // 秒
+	Result     uint8  `json:"result"`      // 结果
+	Reason     uint8  `json:"reason"`      // 原因
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType054) MessageType() uint16 { return TypeReceipt }
+
+func (m *XFType054) fields(c *fieldCodec) {
+	c.u32(&m.MessageNo, 32)
+	c.u8(&m.SenderType, 8)
+	c.u64(&m.Sender, 48)
+	c.u32(&m.Receiver, 24)
+	c.u8(&m.Hour, 8)
+	c.u8(&m.Minute, 8)
+	c.u8(&m.Second, 8)
+	c.u8(&m.Result, 8)
+	c.u8(&m.Reason, 8)
+}
+
+// XFType099 认证应答
+type XFType099 struct {
+	Token  string `json:"token"`  // 认证令牌（32字节ASCII）
+	Status uint8  `json:"status"` // 认证结果（1为成功，0为失败）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType099) MessageType() uint16 { return TypeAuthResponse }
+
+func (m *XFType099) fields(c *fieldCodec) {
+	tokenField(c, &m.Token)
+	c.u8(&m.Status, 8)
+}
+
+// XFType100 认证请求
+type XFType100 struct {
+	Token string `json:"token"` // 认证令牌（32字节ASCII）
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType100) MessageType() uint16 { return TypeAuthRequest }
+
+func (m *XFType100) fields(c *fieldCodec) {
+	tokenField(c, &m.Token)
+}
+
+// tokenLength 认证令牌长度
+const tokenLength = 32
+
+// tokenField 编解码32字节认证令牌，不足部分补零
+func tokenField(c *fieldCodec, token *string) {
+	if c.decoding {
+		var raw HexBytes
+		c.bytes(&raw, tokenLength)
+		*token = strings.TrimRight(string(raw), "\x00")
+		return
+	}
+	if len(*token) > tokenLength {
+		c.fail(fmt.Errorf("token too long: %d bytes", len(*token)))
+		return
+	}
+	raw := make(HexBytes, tokenLength)
+	copy(raw, *token)
+	c.bytes(&raw, tokenLength)
+}
+
+// XFType153 短报文
+type XFType153 struct {
+	InfoNo       uint32   `json:"info_no"`       // 信息编号
+	SenderType   uint8    `json:"sender_type"`   // 发信方类型
+	Sender       uint64   `json:"sender"`        // 发信方地址
+	ReceiverType uint8    `json:"receiver_type"` // 接收方类型
+	Receiver     uint64   `json:"receiver"`      // 接收方地址
+	LinkMode     uint8    `json:"link_mode"`     // 链路方式
+	LinkSupport  uint32   `json:"link_support"`  // 链路支持
+	Length       uint16   `json:"length"`        // 内容长度
+	ContentType  uint8    `json:"content_type"`  // 内容类型
+	SubType      uint8    `json:"sub_type"`      // 内容子类型
+	Content      HexBytes `json:"content"`       // 内容
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType153) MessageType() uint16 { return TypeShortMessage }
+
+func (m *XFType153) fields(c *fieldCodec) {
+	c.u32(&m.InfoNo, 32)
+	c.u8(&m.SenderType, 8)
+	c.u64(&m.Sender, 48)
+	c.u8(&m.ReceiverType, 8)
+	c.u64(&m.Receiver, 48)
+	c.u8(&m.LinkMode, 8)
+	c.u32(&m.LinkSupport, 24)
+	c.u16(&m.Length, 16)
+	c.u8(&m.ContentType, 8)
+	c.u8(&m.SubType, 8)
+	c.restBytes(&m.Content)
+}
+
+// XFType154 短报文应答
+type XFType154 struct {
+	Content HexBytes `json:"content"` // 应答内容
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType154) MessageType() uint16 { return TypeShortMessageResp }
+
+func (m *XFType154) fields(c *fieldCodec) {
+	c.restBytes(&m.Content)
+}
+
+// XFType203 心跳
+type XFType203 struct {
+	Content HexBytes `json:"content"` // 心跳内容
+}
+
+// MessageType 获取信息类型编号
+func (m *XFType203) MessageType() uint16 { return TypeHeartbeat }
+
+func (m *XFType203) fields(c *fieldCodec) {
+	c.restBytes(&m.Content)
+}
+
+// weekTime 根据周计数和周内秒计算时刻
+// 周起点为参考时间所在周的周日0点（UTC），周计数为1时表示上一周
+func weekTime(now time.Time, weekIndex uint8, weekSeconds uint32) time.Time {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).
+		AddDate(0, 0, -int(now.Weekday()))
+	if weekIndex == 1 {
+		start = start.AddDate(0, 0, -7)
+	}
+	return start.Add(time.Duration(weekSeconds) * time.Second)
+}
+
+// decodeBCD 将二进制串按4位一组解码为数字字符串
+func decodeBCD(bits string) string {
+	var sb strings.Builder
+	for i := 0; i+4 <= len(bits); i += 4 {
+		var digit byte
+		for _, b := range bits[i : i+4] {
+			digit = digit<<1 | byte(b-'0')
+		}
+		if digit > 9 {
+			break
+		}
+		sb.WriteByte('0' + digit)
+	}
+	return sb.String()
+}
@@ -0,0 +1,210 @@
+// internal/xftype/registry.go
+package xftype
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry 报文编解码器注册表
+// 按信息类型编号查找编解码器，负责BasePackage数据段的整体解析
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[uint16]Codec // 信息类型编号到编解码器的映射
+}
+
+// DataSegment 数据段结构（对应BasePackage.Data）
+// 格式: 年(2字节) + 月(1字节) + 日(1字节) + 若干数据项
+type DataSegment struct {
+	Year  uint16     `json:"year"`  // 年
+	Month uint8      `json:"month"` // 月
+	Day   uint8      `json:"day"`   // 日
+	Items []DataItem `json:"items"` // 数据项列表
+}
+
+// DataItem 数据项结构
+// 格式: 信息类型编号(2字节) + 信息内容长度(2字节) + 信息内容
+type DataItem struct {
+	Type   uint16      `json:"type"`            // 信息类型编号
+	Length uint16      `json:"length"`          // 信息内容长度
+	Name   string      `json:"name,omitempty"`  // 类型名称
+	Body   interface{} `json:"body,omitempty"`  // 解码后的信息内容
+	Raw    HexBytes    `json:"raw"`             // 原始信息内容
+	Error  string      `json:"error,omitempty"` // 解码错误信息
+}
+
+// NewRegistry 创建注册表，并注册全部内置XFType编解码器
+// 返回: 注册表实例
+func NewRegistry() *Registry {
+	r := &Registry{codecs: make(map[uint16]Codec)}
+	for _, codec := range builtinCodecs() {
+		r.codecs[codec.Type()] = codec
+	}
+	return r
+}
+
+// Register 注册编解码器
+// 参数: codec - 编解码器
+// 返回: 类型编号已被注册时返回错误
+func (r *Registry) Register(codec Codec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.codecs[codec.Type()]; exists {
+		return fmt.Errorf("message type %d already registered as %s", codec.Type(), existing.Name())
+	}
+	r.codecs[codec.Type()] = codec
+	return nil
+}
+
+// Lookup 查找编解码器
+// 参数: messageType - 信息类型编号
+// 返回: 编解码器和是否存在
+func (r *Registry) Lookup(messageType uint16) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	codec, exists := r.codecs[messageType]
+	return codec, exists
+}
+
+// Types 获取已注册的信息类型编号列表
+// 返回: 升序排列的类型编号
+func (r *Registry) Types() []uint16 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]uint16, 0, len(r.codecs))
+	for t := range r.codecs {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// DecodeBody 解码单个报文体
+// 参数: messageType - 信息类型编号, data - 报文体
+// 返回: 结构化数据和错误信息
+func (r *Registry) DecodeBody(messageType uint16, data []byte) (interface{}, error) {
+	codec, exists := r.Lookup(messageType)
+	if !exists {
+		return nil, fmt.Errorf("no codec registered for message type %d", messageType)
+	}
+	return codec.Decode(data)
+}
+
+// EncodeBody 编码单个报文体
+// 参数: messageType - 信息类型编号, v - 结构化数据
+// 返回: 报文体和错误信息
+func (r *Registry) EncodeBody(messageType uint16, v interface{}) ([]byte, error) {
+	codec, exists := r.Lookup(messageType)
+	if !exists {
+		return nil, fmt.Errorf("no codec registered for message type %d", messageType)
+	}
+	return codec.Encode(v)
+}
+
+// DecodeSegment 解析数据段及其中的全部数据项
+// 未注册或解码失败的数据项保留原始内容并记录错误，不影响其他数据项
+// 参数: data - 数据段（BasePackage.Data）
+// 返回: 数据段结构和错误信息
+func (r *Registry) DecodeSegment(data []byte) (*DataSegment, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("data segment too short: %d bytes", len(data))
+	}
+
+	seg := &DataSegment{
+		Year:  binary.BigEndian.Uint16(data[0:2]),
+		Month: data[2],
+		Day:   data[3],
+	}
+
+	offset := 4
+	for offset < len(data) {
+		if len(data)-offset < 4 {
+			return seg, fmt.Errorf("truncated data item header at offset %d", offset)
+		}
+
+		item := DataItem{
+			Type:   binary.BigEndian.Uint16(data[offset : offset+2]),
+			Length: binary.BigEndian.Uint16(data[offset+2 : offset+4]),
+		}
+		offset += 4
+
+		end := offset + int(item.Length)
+		if end > len(data) {
+			return seg, fmt.Errorf("data item type %d length %d exceeds segment at offset %d",
+				item.Type, item.Length, offset)
+		}
+		item.Raw = append(HexBytes(nil), data[offset:end]...)
+		offset = end
+
+		if codec, exists := r.Lookup(item.Type); exists {
+			item.Name = codec.Name()
+			body, err := codec.Decode(item.Raw)
+			if err != nil {
+				item.Error = err.Error()
+			} else {
+				item.Body = body
+			}
+		} else {
+			item.Error = "unknown message type"
+		}
+
+		seg.Items = append(seg.Items, item)
+	}
+
+	return seg, nil
+}
+
+// EncodeSegment 编码数据段
+// 数据项有Body时使用对应编解码器编码，否则使用Raw
+// 参数: seg - 数据段结构
+// 返回: 数据段字节和错误信息
+func (r *Registry) EncodeSegment(seg *DataSegment) ([]byte, error) {
+	out := make([]byte, 4, 64)
+	binary.BigEndian.PutUint16(out[0:2], seg.Year)
+	out[2] = seg.Month
+	out[3] = seg.Day
+
+	for _, item := range seg.Items {
+		body := []byte(item.Raw)
+		if item.Body != nil {
+			encoded, err := r.EncodeBody(item.Type, item.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = encoded
+		}
+		if len(body) > 0xFFFF {
+			return nil, fmt.Errorf("data item type %d too long: %d bytes", item.Type, len(body))
+		}
+
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint16(header[0:2], item.Type)
+		binary.BigEndian.PutUint16(header[2:4], uint16(len(body)))
+		out = append(out, header...)
+		out = append(out, body...)
+	}
+
+	return out, nil
+}
+
+// DecodeSegmentJSON 解析数据段并输出JSON
+// 参数: data - 数据段（BasePackage.Data）
+// 返回: JSON数据和错误信息
+func (r *Registry) DecodeSegmentJSON(data []byte) ([]byte, error) {
+	seg, err := r.DecodeSegment(data)
+	if seg == nil {
+		return nil, err
+	}
+
+	out, jsonErr := json.Marshal(seg)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	return out, err
+}
@@ -1,9 +0,0 @@
-// go.mod
-module tcp-proxy-bridge
-
-go 1.21
-
-require (
-	github.com/lib/pq v1.10.9 // PostgreSQL驱动
-	gopkg.in/yaml.v2 v2.4.0 // YAML配置解析
-)
@@ -8,11 +8,14 @@
 CREATE TABLE IF NOT EXISTS message_queue (
     id BIGSERIAL PRIMARY KEY,                          -- 消息ID，自增主键
     source_ip INET NOT NULL,                           -- 源IP地址
+    source_identity TEXT NULL,                         -- 来源身份（TLS客户端证书主题或SAN）
     original_data BYTEA NOT NULL,                      -- 原始消息数据（二进制格式）
     data_length INTEGER NOT NULL,                      -- 数据长度（字节数）
     created_at TIMESTAMP DEFAULT NOW(),                -- 消息创建时间
     processed_at TIMESTAMP NULL,                       -- 消息处理完成时间
-    status VARCHAR(20) DEFAULT 'received'              -- 消息状态: received-已接收
+    status VARCHAR(20) DEFAULT 'received',             -- 消息状态: received-已接收
+    source_group VARCHAR(64) NOT NULL DEFAULT 'default', -- 源分组名称
+    attributes JSONB NULL                              -- 处理流水线附加的属性
 );
 
 -- 表注释
@@ -21,11 +24,14 @@ COMMENT ON TABLE message_queue IS 'TCP消息队列表，存储所有接收到的
 -- 字段注释
 COMMENT ON COLUMN message_queue.id IS '消息唯一标识，自增主键';
 COMMENT ON COLUMN message_queue.source_ip IS '消息来源IP地址';
+COMMENT ON COLUMN message_queue.source_identity IS '消息来源身份：监听端口TLS客户端证书的主题或SAN（未使用客户端证书时为NULL）';
 COMMENT ON COLUMN message_queue.original_data IS '原始消息二进制数据';
 COMMENT ON COLUMN message_queue.data_length IS '消息数据长度（字节）';
 COMMENT ON COLUMN message_queue.created_at IS '消息创建时间';
 COMMENT ON COLUMN message_queue.processed_at IS '消息处理完成时间';
 COMMENT ON COLUMN message_queue.status IS '消息状态: received-已接收';
+COMMENT ON COLUMN message_queue.source_group IS '消息所属的源分组（未配置source_groups时为default）';
+COMMENT ON COLUMN message_queue.attributes IS '处理流水线enrich阶段附加的属性（未配置时为NULL）';
 
 -- =============================================
 -- 目标投递状态表：记录每个消息到每个目标服务器的投递状态
@@ -121,6 +127,48 @@ COMMENT ON COLUMN target_servers.last_success_at IS '最后一次成功发送时
 COMMENT ON COLUMN target_servers.created_at IS '记录创建时间';
 COMMENT ON COLUMN target_servers.updated_at IS '记录最后更新时间';
 
+-- =============================================
+-- 续传检查点表：记录每个信源/信宿已写入数据库的最后一个包序号
+-- =============================================
+CREATE TABLE IF NOT EXISTS source_checkpoints (
+    source_group VARCHAR(64) NOT NULL DEFAULT 'default', -- 源分组名称
+    source_info BIGINT NOT NULL,                       -- 信源
+    host_info BIGINT NOT NULL,                         -- 信宿
+    package_no BIGINT NOT NULL,                        -- 已写入的最后一个包序号
+    updated_at TIMESTAMP DEFAULT NOW(),                -- 更新时间
+    PRIMARY KEY (source_group, source_info, host_info)
+);
+
+-- 表注释
+COMMENT ON TABLE source_checkpoints IS '续传检查点表，重连或切换后从检查点请求续传';
+
+-- 字段注释
+COMMENT ON COLUMN source_checkpoints.source_group IS '源分组名称（各分组的检查点互相独立）';
+COMMENT ON COLUMN source_checkpoints.source_info IS '信源';
+COMMENT ON COLUMN source_checkpoints.host_info IS '信宿';
+COMMENT ON COLUMN source_checkpoints.package_no IS '已写入数据库的最后一个包序号（与消息在同一事务中更新）';
+COMMENT ON COLUMN source_checkpoints.updated_at IS '检查点更新时间';
+
+-- =============================================
+-- 已有数据库升级：增加源分组字段
+-- =============================================
+ALTER TABLE message_queue ADD COLUMN IF NOT EXISTS source_group VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE source_checkpoints ADD COLUMN IF NOT EXISTS source_group VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE source_checkpoints DROP CONSTRAINT IF EXISTS source_checkpoints_pkey;
+ALTER TABLE source_checkpoints ADD PRIMARY KEY (source_group, source_info, host_info);
+
+-- =============================================
+-- 已有数据库升级：增加处理流水线附加属性字段
+-- =============================================
+ALTER TABLE message_queue ADD COLUMN IF NOT EXISTS attributes JSONB NULL;
+
+-- =============================================
+-- 已有数据库升级：增加来源身份字段
+-- =============================================
+-- 证书主题包含多个O/OU时可能很长，不限制长度
+ALTER TABLE message_queue ADD COLUMN IF NOT EXISTS source_identity TEXT NULL;
+ALTER TABLE message_queue ALTER COLUMN source_identity TYPE TEXT;
+
 -- =============================================
 -- 性能优化索引
 -- =============================================
@@ -135,6 +183,9 @@ COMMENT ON INDEX idx_message_queue_created_at IS '消息创建时间索引，用
 CREATE INDEX IF NOT EXISTS idx_message_queue_source_ip ON message_queue(source_ip);
 COMMENT ON INDEX idx_message_queue_source_ip IS '消息来源IP索引，用于按来源查询';
 
+CREATE INDEX IF NOT EXISTS idx_message_queue_source_group ON message_queue(source_group, created_at);
+COMMENT ON INDEX idx_message_queue_source_group IS '源分组索引，用于按分组查询消息';
+
 -- 目标投递状态表索引
 CREATE INDEX IF NOT EXISTS idx_delivery_status_message ON target_delivery_status(message_id);
 COMMENT ON INDEX idx_delivery_status_message IS '投递状态消息ID索引，用于关联查询';